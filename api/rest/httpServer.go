@@ -74,10 +74,13 @@ func (s *HTTPServer) Start() error {
 }
 
 type Services struct {
-	BlacklistService   core.IBlacklistsService
-	SystemStateService core.ISystemStateService
-	ServiceDeskService core.IServiceDeskService
-	UsersService       core.IUsersService
-	AuthService        core.IAuthService
-	SMTPService        core.ISMTPService
+	BlacklistService            core.IBlacklistsService
+	BlacklistImportFeedsService core.IBlacklistImportFeedsService
+	BlacklistExportFeedsService core.IBlacklistExportFeedsService
+	SystemStateService          core.ISystemStateService
+	ServiceDeskService          core.IServiceDeskService
+	UsersService                core.IUsersService
+	AuthService                 core.IAuthService
+	SMTPService                 core.ISMTPService
+	MISPService                 core.IMISPService
 }
@@ -46,7 +46,7 @@ func CreateRouter(services Services, basePath string, allowedOrigins []string, a
 			"X-Forwarded-*",
 			"X-Requested-With",
 		},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "X-Delta-Cursor", "X-Delta-More"},
 		AllowCredentials: true,
 		AllowWildcard:    true,
 		MaxAge:           12 * time.Hour,
@@ -57,9 +57,11 @@ func CreateRouter(services Services, basePath string, allowedOrigins []string, a
 	baseRouteV1 := router.Group(basePath)
 
 	// API groups
-	routing.NewBlacklistsRouter(services.BlacklistService, baseRouteV1, authMiddleware)
+	routing.NewBlacklistsRouter(services.BlacklistService, services.BlacklistImportFeedsService, services.BlacklistExportFeedsService, baseRouteV1, authMiddleware)
+	routing.NewTAXIIRouter(services.BlacklistService, baseRouteV1, authMiddleware)
 	routing.NewSystemStateRouter(services.SystemStateService, baseRouteV1, authMiddleware)
 	routing.NewServiceDeskRouter(services.ServiceDeskService, baseRouteV1)
+	routing.NewMISPRouter(services.MISPService, baseRouteV1, authMiddleware)
 	routing.NewUsersRouter(services.UsersService, baseRouteV1, authMiddleware)
 
 	routing.NewAuthRouter(services.AuthService, baseRouteV1, authMiddleware)
@@ -0,0 +1,143 @@
+package routing
+
+import (
+	apiErrors "domain_threat_intelligence_api/api/rest/error"
+	"domain_threat_intelligence_api/api/rest/success"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// GetAllowlist returns all allowlist entries
+//
+// @Summary            Get allowlist
+// @Description        Returns all allowlist entries: IPs and networks, domains and URL patterns, which are never blacklisted
+// @Tags               Blacklists
+// @Security           ApiKeyAuth
+// @Router             /blacklists/allowlist [get]
+// @ProduceAccessToken json
+// @Success            200              {object} []blacklistEntities.AllowlistEntry
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetAllowlist(c *gin.Context) {
+	entries, err := r.service.RetrieveAllowlist()
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// GetAllowlistConflicts returns blacklisted hosts conflicting with allowlist entry
+//
+// @Summary            Get allowlist entry conflicts
+// @Description        Returns active blacklisted hosts matching allowlist entry, which were blacklisted before entry was added
+// @Tags               Blacklists
+// @Security           ApiKeyAuth
+// @Router             /blacklists/allowlist/{entry_id}/conflicts [get]
+// @ProduceAccessToken json
+// @Param              entry_id path        int      true "Allowlist entry ID"
+// @Success            200                  {object} []blacklistEntities.BlacklistedHost
+// @Failure            401,400     {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetAllowlistConflicts(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("entry_id"), 10, 64)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	hosts, err := r.service.RetrieveAllowlistConflicts(id)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, hosts)
+}
+
+// PutAllowlistEntry creates or updates allowlist entry
+//
+// @Summary            Save allowlist entry
+// @Description        Creates new allowlist entry or updates existing one if ID defined. Returns saved entry and already blacklisted hosts conflicting with it.
+// @Tags               Blacklists
+// @Security           ApiKeyAuth
+// @Router             /blacklists/allowlist [put]
+// @ProduceAccessToken json
+// @Param              entry   body              allowlistEntryParams true "entry to save"
+// @Success            201              {object} allowlistEntryResponse
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) PutAllowlistEntry(c *gin.Context) {
+	var params allowlistEntryParams
+
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	entry, conflicts, err := r.service.SaveAllowlistEntry(blacklistEntities.AllowlistEntry{
+		ID:                params.ID,
+		Type:              params.Type,
+		Value:             params.Value,
+		IncludeSubdomains: params.IncludeSubdomains,
+		Description:       params.Description,
+	})
+
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	if conflicts == nil {
+		conflicts = []blacklistEntities.BlacklistedHost{}
+	}
+
+	c.JSON(http.StatusCreated, allowlistEntryResponse{
+		Entry:     entry,
+		Conflicts: conflicts,
+	})
+}
+
+type allowlistEntryParams struct {
+	ID                uint64 `json:"ID"`
+	Type              string `json:"Type" binding:"required,oneof=ip domain url"`
+	Value             string `json:"Value" binding:"required"`
+	IncludeSubdomains bool   `json:"IncludeSubdomains"`
+	Description       string `json:"Description"`
+}
+
+type allowlistEntryResponse struct {
+	Entry blacklistEntities.AllowlistEntry `json:"Entry"`
+	// Conflicts contains active blacklisted hosts matching entry
+	Conflicts []blacklistEntities.BlacklistedHost `json:"Conflicts"`
+}
+
+// DeleteAllowlistEntry accepts and deletes single allowlist entry
+//
+// @Summary            Delete allowlist entry
+// @Description        Accepts and deletes single allowlist entry
+// @Tags               Blacklists
+// @Security           ApiKeyAuth
+// @Router             /blacklists/allowlist [delete]
+// @ProduceAccessToken json
+// @Param              id               body      byIDParams true "record ID to delete"
+// @Success            200              {object} success.DatabaseResponse
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) DeleteAllowlistEntry(c *gin.Context) {
+	var params byIDParams
+
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	rows, err := r.service.DeleteAllowlistEntry(params.ID)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	success.DeletedResponse(c, rows)
+}
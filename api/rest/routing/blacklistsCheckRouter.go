@@ -0,0 +1,73 @@
+package routing
+
+import (
+	apiErrors "domain_threat_intelligence_api/api/rest/error"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// GetCheckValue checks if single value is blacklisted
+//
+// @Summary            Check value
+// @Description        Detects type of value (IP, domain, URL or email) and returns all blacklist entries matching it. IP addresses are matched by network containment, domains by exact and parent domain match, URLs by normalized URL hash and URL host.
+// @Tags               Blacklists
+// @Security           ApiKeyAuth
+// @Router             /blacklists/check [get]
+// @ProduceAccessToken json
+// @Param              value   query             string true "IP address, domain, URL or email"
+// @Success            200              {object} blacklistEntities.BlacklistCheckResult
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetCheckValue(c *gin.Context) {
+	var params checkValueParams
+
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	results, err := r.service.CheckValues([]string{params.Value})
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results[0])
+}
+
+type checkValueParams struct {
+	Value string `form:"value" binding:"required"`
+}
+
+// PostCheckValues checks if values are blacklisted
+//
+// @Summary            Check values
+// @Description        Bulk version of value check, returns check result for every value in same order
+// @Tags               Blacklists
+// @Security           ApiKeyAuth
+// @Router             /blacklists/check [post]
+// @ProduceAccessToken json
+// @Param              values body              checkValuesParams true "values to check"
+// @Success            200              {object} []blacklistEntities.BlacklistCheckResult
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) PostCheckValues(c *gin.Context) {
+	var params checkValuesParams
+
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	results, err := r.service.CheckValues(params.Values)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+type checkValuesParams struct {
+	Values []string `json:"Values" binding:"required,min=1,max=10000"`
+}
@@ -0,0 +1,314 @@
+package routing
+
+import (
+	apiErrors "domain_threat_intelligence_api/api/rest/error"
+	"domain_threat_intelligence_api/api/rest/success"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GetExportFeeds returns all public export feeds with their tokens
+//
+// @Summary            Get export feeds
+// @Description        Returns all public export feeds with issued tokens, token values are not returned
+// @Tags               Blacklists, Export
+// @Security           ApiKeyAuth
+// @Router             /blacklists/export/feed [get]
+// @ProduceAccessToken json
+// @Success            200              {object} []blacklistEntities.BlacklistExportFeed
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetExportFeeds(c *gin.Context) {
+	feeds, err := r.exportFeedsService.RetrieveAllFeeds()
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, feeds)
+}
+
+// GetExportFeed returns single public export feed
+//
+// @Summary            Get export feed
+// @Description        Returns single public export feed with issued tokens, token values are not returned
+// @Tags               Blacklists, Export
+// @Security           ApiKeyAuth
+// @Router             /blacklists/export/feed/{feed_id} [get]
+// @ProduceAccessToken json
+// @Param              feed_id path           int      true "Feed ID"
+// @Success            200                    {object} blacklistEntities.BlacklistExportFeed
+// @Failure            401,400       {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetExportFeed(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("feed_id"), 10, 64)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	feed, err := r.exportFeedsService.RetrieveFeed(id)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	} else if feed.ID == 0 {
+		apiErrors.DatabaseEntityNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, feed)
+}
+
+// PutExportFeed creates or updates public export feed
+//
+// @Summary            Save export feed
+// @Description        Creates new public export feed or updates existing one if ID defined
+// @Tags               Blacklists, Export
+// @Security           ApiKeyAuth
+// @Router             /blacklists/export/feed [put]
+// @ProduceAccessToken json
+// @Param              feed    body              exportFeedParams true "feed to save"
+// @Success            201              {object} blacklistEntities.BlacklistExportFeed
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) PutExportFeed(c *gin.Context) {
+	var params exportFeedParams
+
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	feed, err := r.exportFeedsService.SaveFeed(blacklistEntities.BlacklistExportFeed{
+		ID:          params.ID,
+		Name:        params.Name,
+		Description: params.Description,
+		Format:      blacklistEntities.ExportFormat(params.Format),
+		IsEnabled:   params.IsEnabled,
+		Filter:      datatypes.NewJSONType(params.Filter),
+	})
+
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, feed)
+}
+
+type exportFeedParams struct {
+	ID          uint64                                  `json:"ID"`
+	Name        string                                  `json:"Name" binding:"required"`
+	Description string                                  `json:"Description"`
+	Format      string                                  `json:"Format" binding:"required,oneof=txt hosts rpz squid iptables nftables suricata snort"`
+	IsEnabled   bool                                    `json:"IsEnabled"`
+	Filter      blacklistEntities.BlacklistExportFilter `json:"Filter"`
+}
+
+// DeleteExportFeed accepts and deletes single public export feed
+//
+// @Summary            Delete export feed
+// @Description        Accepts and deletes single public export feed, all its tokens stop working
+// @Tags               Blacklists, Export
+// @Security           ApiKeyAuth
+// @Router             /blacklists/export/feed [delete]
+// @ProduceAccessToken json
+// @Param              id               body      byIDParams true "record ID to delete"
+// @Success            200              {object} success.DatabaseResponse
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) DeleteExportFeed(c *gin.Context) {
+	var params byIDParams
+
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	rows, err := r.exportFeedsService.DeleteFeed(params.ID)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	success.DeletedResponse(c, rows)
+}
+
+// PostExportFeedToken issues new consumer token for public export feed
+//
+// @Summary            Issue export feed token
+// @Description        Issues new long-lived consumer token for public export feed. Token value is returned only once.
+// @Tags               Blacklists, Export
+// @Security           ApiKeyAuth
+// @Router             /blacklists/export/feed/{feed_id}/token [post]
+// @ProduceAccessToken json
+// @Param              feed_id path           int      true "Feed ID"
+// @Param              token   body           exportFeedTokenParams true "token consumer"
+// @Success            201                    {object} blacklistEntities.BlacklistExportFeedToken
+// @Failure            401,400       {object} apiErrors.APIError
+func (r *BlacklistsRouter) PostExportFeedToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("feed_id"), 10, 64)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	var params exportFeedTokenParams
+
+	err = c.ShouldBindJSON(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	token, err := r.exportFeedsService.IssueToken(id, params.Consumer, params.ExpiresAt)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+type exportFeedTokenParams struct {
+	Consumer  string     `json:"Consumer" binding:"required"`
+	ExpiresAt *time.Time `json:"ExpiresAt"`
+}
+
+// DeleteExportFeedToken revokes single export feed token
+//
+// @Summary            Revoke export feed token
+// @Description        Revokes single export feed token
+// @Tags               Blacklists, Export
+// @Security           ApiKeyAuth
+// @Router             /blacklists/export/feed/token [delete]
+// @ProduceAccessToken json
+// @Param              id               body      byIDParams true "token ID to revoke"
+// @Success            200              {object} success.DatabaseResponse
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) DeleteExportFeedToken(c *gin.Context) {
+	var params byIDParams
+
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	rows, err := r.exportFeedsService.RevokeToken(params.ID)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	success.DeletedResponse(c, rows)
+}
+
+// GetExportFeedAccessLog returns export feed access log
+//
+// @Summary            Get export feed access log
+// @Description        Returns export feed requests made with feed tokens
+// @Tags               Blacklists, Export
+// @Security           ApiKeyAuth
+// @Router             /blacklists/export/feed/access [get]
+// @ProduceAccessToken json
+// @Param              feed_id               query             uint64 false "Export feed ID"
+// @Param              token_id              query             uint64 false "Export feed token ID"
+// @Param              limit                 query             int          true  "Query limit"
+// @Param              offset                query             int          false "Query offset"
+// @Success            200                            {object} []blacklistEntities.BlacklistExportFeedAccess
+// @Failure            401,400               {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetExportFeedAccessLog(c *gin.Context) {
+	var params blacklistEntities.BlacklistExportFeedAccessFilter
+
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	log, err := r.exportFeedsService.RetrieveAccessLog(params)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, log)
+}
+
+// GetPublicFeed returns rendered export feed by consumer token. Does not require authentication.
+// Supports conditional requests with If-None-Match and If-Modified-Since headers.
+//
+// @Summary            Get public feed
+// @Description        Returns rendered export feed by consumer token, responds with 304 if feed was not modified
+// @Tags               Feeds
+// @Router             /feeds/{token} [get]
+// @Param              token path string true "Feed token"
+// @Produce            plain
+// @Success            200 {file}   file
+// @Success            304
+// @Failure            401,404 {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetPublicFeed(c *gin.Context) {
+	token, err := r.exportFeedsService.RetrieveFeedByToken(c.Param("token"))
+	if err != nil {
+		if token.ID != 0 {
+			r.exportFeedsService.LogAccess(token, c.ClientIP(), c.Request.UserAgent(), http.StatusNotFound)
+			apiErrors.DatabaseEntityNotFound(c)
+			return
+		}
+
+		c.JSON(http.StatusUnauthorized, apiErrors.APIError{
+			StatusCode:   http.StatusUnauthorized,
+			ErrorCode:    apiErrors.AuthFailedErrorCode,
+			ErrorMessage: err.Error(),
+			ErrorModule:  "feeds",
+		})
+		return
+	}
+
+	data, etag, lastModified, err := r.exportFeedsService.RenderFeed(*token.Feed)
+	if err != nil {
+		r.exportFeedsService.LogAccess(token, c.ClientIP(), c.Request.UserAgent(), http.StatusInternalServerError)
+		apiErrors.FileProcessingErrorResponse(c, err)
+		return
+	}
+
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", "no-cache")
+
+	if isFeedNotModified(c, etag, lastModified) {
+		r.exportFeedsService.LogAccess(token, c.ClientIP(), c.Request.UserAgent(), http.StatusNotModified)
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	r.exportFeedsService.LogAccess(token, c.ClientIP(), c.Request.UserAgent(), http.StatusOK)
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", data)
+}
+
+// isFeedNotModified checks conditional request headers, If-None-Match takes precedence over If-Modified-Since
+func isFeedNotModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	if inm := c.GetHeader("If-None-Match"); len(inm) > 0 {
+		for _, v := range strings.Split(inm, ",") {
+			v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+			if v == etag || v == "*" {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if ims := c.GetHeader("If-Modified-Since"); len(ims) > 0 {
+		t, err := http.ParseTime(ims)
+		if err == nil && !lastModified.Truncate(time.Second).After(t) {
+			return true
+		}
+	}
+
+	return false
+}
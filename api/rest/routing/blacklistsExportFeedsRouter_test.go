@@ -0,0 +1,104 @@
+package routing
+
+import (
+	"domain_threat_intelligence_api/cmd/core"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeExportFeedsService serves single feed by token "valid" and records access log statuses
+type fakeExportFeedsService struct {
+	core.IBlacklistExportFeedsService
+
+	lastModified time.Time
+	statuses     []int
+}
+
+func (s *fakeExportFeedsService) RetrieveFeedByToken(token string) (blacklistEntities.BlacklistExportFeedToken, error) {
+	switch token {
+	case "valid":
+		return blacklistEntities.BlacklistExportFeedToken{ID: 1, FeedID: 1, Feed: &blacklistEntities.BlacklistExportFeed{ID: 1}}, nil
+	case "disabled":
+		return blacklistEntities.BlacklistExportFeedToken{ID: 2, FeedID: 2}, errors.New("feed not found or disabled")
+	default:
+		return blacklistEntities.BlacklistExportFeedToken{}, errors.New("feed token invalid or expired")
+	}
+}
+
+func (s *fakeExportFeedsService) RenderFeed(feed blacklistEntities.BlacklistExportFeed) ([]byte, string, time.Time, error) {
+	return []byte("evil.com\n"), `"etag"`, s.lastModified, nil
+}
+
+func (s *fakeExportFeedsService) LogAccess(token blacklistEntities.BlacklistExportFeedToken, remoteAddr, userAgent string, statusCode int) {
+	s.statuses = append(s.statuses, statusCode)
+}
+
+func TestGetPublicFeed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	lastModified := time.Date(2024, 1, 1, 12, 0, 0, 500, time.UTC)
+
+	tests := []struct {
+		name    string
+		token   string
+		headers map[string]string
+
+		wantStatus int
+		wantLogged bool
+	}{
+		{name: "feed rendered", token: "valid", wantStatus: http.StatusOK, wantLogged: true},
+		{name: "etag matches", token: "valid", headers: map[string]string{"If-None-Match": `"other", W/"etag"`}, wantStatus: http.StatusNotModified, wantLogged: true},
+		{name: "etag does not match", token: "valid", headers: map[string]string{"If-None-Match": `"other"`}, wantStatus: http.StatusOK, wantLogged: true},
+		{name: "any etag", token: "valid", headers: map[string]string{"If-None-Match": "*"}, wantStatus: http.StatusNotModified, wantLogged: true},
+		{name: "not modified since", token: "valid", headers: map[string]string{"If-Modified-Since": "Mon, 01 Jan 2024 12:00:00 GMT"}, wantStatus: http.StatusNotModified, wantLogged: true},
+		{name: "modified since", token: "valid", headers: map[string]string{"If-Modified-Since": "Mon, 01 Jan 2024 11:59:59 GMT"}, wantStatus: http.StatusOK, wantLogged: true},
+		{
+			name:       "etag takes precedence",
+			token:      "valid",
+			headers:    map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": "Mon, 01 Jan 2024 12:00:00 GMT"},
+			wantStatus: http.StatusOK,
+			wantLogged: true,
+		},
+		{name: "disabled feed", token: "disabled", wantStatus: http.StatusNotFound, wantLogged: true},
+		{name: "invalid token", token: "invalid", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &fakeExportFeedsService{lastModified: lastModified}
+			router := &BlacklistsRouter{exportFeedsService: service}
+
+			engine := gin.New()
+			engine.GET("/feeds/:token", router.GetPublicFeed)
+
+			request := httptest.NewRequest(http.MethodGet, "/feeds/"+tt.token, nil)
+			for k, v := range tt.headers {
+				request.Header.Set(k, v)
+			}
+
+			response := httptest.NewRecorder()
+			engine.ServeHTTP(response, request)
+
+			if response.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", response.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus == http.StatusOK && (response.Header().Get("ETag") != `"etag"` || response.Body.String() != "evil.com\n") {
+				t.Errorf("unexpected feed response: %v, %s", response.Header(), response.Body.String())
+			}
+
+			if tt.wantStatus == http.StatusNotModified && response.Body.Len() != 0 {
+				t.Errorf("body sent with not modified status: %s", response.Body.String())
+			}
+
+			if logged := len(service.statuses) == 1 && service.statuses[0] == tt.wantStatus; logged != tt.wantLogged {
+				t.Errorf("got access log %v, want logged %t", service.statuses, tt.wantLogged)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package routing
+
+import (
+	apiErrors "domain_threat_intelligence_api/api/rest/error"
+	"domain_threat_intelligence_api/api/rest/success"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// GetImportFeeds returns all remote import feeds
+//
+// @Summary            Get import feeds
+// @Description        Returns all remote import feeds without credentials
+// @Tags               Blacklists, Import
+// @Security           ApiKeyAuth
+// @Router             /blacklists/import/feed [get]
+// @ProduceAccessToken json
+// @Success            200              {object} []blacklistEntities.BlacklistImportFeed
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetImportFeeds(c *gin.Context) {
+	feeds, err := r.feedsService.RetrieveAllFeeds()
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, feeds)
+}
+
+// GetImportFeed returns single remote import feed
+//
+// @Summary            Get import feed
+// @Description        Returns single remote import feed without credentials
+// @Tags               Blacklists, Import
+// @Security           ApiKeyAuth
+// @Router             /blacklists/import/feed/{feed_id} [get]
+// @ProduceAccessToken json
+// @Param              feed_id path           int      true "Feed ID"
+// @Success            200                    {object} blacklistEntities.BlacklistImportFeed
+// @Failure            401,400       {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetImportFeed(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("feed_id"), 10, 64)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	feed, err := r.feedsService.RetrieveFeed(id)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	} else if feed.ID == 0 {
+		apiErrors.DatabaseEntityNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, feed)
+}
+
+// PutImportFeed creates or updates remote import feed
+//
+// @Summary            Save import feed
+// @Description        Creates new remote import feed or updates existing one if ID defined. Empty secrets are not updated.
+// @Tags               Blacklists, Import
+// @Security           ApiKeyAuth
+// @Router             /blacklists/import/feed [put]
+// @ProduceAccessToken json
+// @Param              feed    body              importFeedParams true "feed to save"
+// @Success            201              {object} blacklistEntities.BlacklistImportFeed
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) PutImportFeed(c *gin.Context) {
+	var params importFeedParams
+
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	feed, err := r.feedsService.SaveFeed(blacklistEntities.BlacklistImportFeed{
+		ID:                params.ID,
+		Name:              params.Name,
+		Description:       params.Description,
+		URL:               params.URL,
+		Format:            blacklistEntities.ImportFeedFormat(params.Format),
+		PollingInterval:   params.PollingInterval,
+		IsEnabled:         params.IsEnabled,
+		ExtractAll:        params.ExtractAll,
+		TAXIICollectionID: params.TAXIICollectionID,
+		AuthLogin:         params.AuthLogin,
+		AuthPassword:      params.AuthPassword,
+		AuthToken:         params.AuthToken,
+		SourceID:          params.SourceID,
+	})
+
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, feed)
+}
+
+type importFeedParams struct {
+	ID                uint64 `json:"ID"`
+	Name              string `json:"Name" binding:"required"`
+	Description       string `json:"Description"`
+	URL               string `json:"URL" binding:"required,url"`
+	Format            string `json:"Format" binding:"required,oneof=stix csv txt taxii"`
+	PollingInterval   uint64 `json:"PollingInterval" binding:"required,min=1"`
+	IsEnabled         bool   `json:"IsEnabled"`
+	ExtractAll        bool   `json:"ExtractAll"`
+	TAXIICollectionID string `json:"TAXIICollectionID"`
+	AuthLogin         string `json:"AuthLogin"`
+	AuthPassword      string `json:"AuthPassword"`
+	AuthToken         string `json:"AuthToken"`
+	SourceID          uint64 `json:"SourceID" binding:"required"`
+}
+
+// DeleteImportFeed accepts and deletes single remote import feed
+//
+// @Summary            Delete import feed
+// @Description        Accepts and deletes single remote import feed
+// @Tags               Blacklists, Import
+// @Security           ApiKeyAuth
+// @Router             /blacklists/import/feed [delete]
+// @ProduceAccessToken json
+// @Param              id               body      byIDParams true "record ID to delete"
+// @Success            200              {object} success.DatabaseResponse
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) DeleteImportFeed(c *gin.Context) {
+	var params byIDParams
+
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	rows, err := r.feedsService.DeleteFeed(params.ID)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	success.DeletedResponse(c, rows)
+}
+
+// PostRunImportFeed pulls remote import feed immediately and starts import job
+//
+// @Summary            Run import feed
+// @Description        Pulls remote import feed immediately and starts import job, returns incomplete import event
+// @Tags               Blacklists, Import
+// @Security           ApiKeyAuth
+// @Router             /blacklists/import/feed/{feed_id}/run [post]
+// @ProduceAccessToken json
+// @Param              feed_id path           int      true "Feed ID"
+// @Success            202                    {object} blacklistEntities.BlacklistImportEvent
+// @Failure            401,400       {object} apiErrors.APIError
+func (r *BlacklistsRouter) PostRunImportFeed(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("feed_id"), 10, 64)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	event, err := r.feedsService.RunFeed(id)
+	if err != nil {
+		apiErrors.FileProcessingErrorResponse(c, err)
+		return
+	}
+
+	go r.recountStatisticsOnImport(event.ID)
+
+	c.JSON(http.StatusAccepted, event)
+}
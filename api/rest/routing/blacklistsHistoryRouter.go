@@ -0,0 +1,80 @@
+package routing
+
+import (
+	apiErrors "domain_threat_intelligence_api/api/rest/error"
+	"domain_threat_intelligence_api/api/rest/success"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgtype"
+	"net/http"
+)
+
+// GetHostHistory returns all revisions of blacklisted host
+//
+// @Summary            Get host history
+// @Description        Returns all revisions of blacklisted host of any type: creation, updates, deletion and restore with host state before and after change, user or import event which made it. The latest revision goes first.
+// @Tags               Blacklists
+// @Security           ApiKeyAuth
+// @Router             /blacklists/host/{host_id}/history [get]
+// @ProduceAccessToken json
+// @Param              host_id path         string   true "Host UUID"
+// @Success            200                  {object} []blacklistEntities.BlacklistHostRevision
+// @Failure            401,400     {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetHostHistory(c *gin.Context) {
+	uuid := pgtype.UUID{}
+	err := uuid.Set(c.Param("host_id"))
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	revisions, err := r.service.RetrieveHostHistory(uuid)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// PostRestoreHost restores soft deleted blacklisted host
+//
+// @Summary            Restore deleted host
+// @Description        Makes soft deleted blacklisted host of any type active again. Expired host gets new expiration date by TTL policy. Allowlisted hosts are not restored.
+// @Tags               Blacklists
+// @Security           ApiKeyAuth
+// @Router             /blacklists/host/{host_id}/restore [post]
+// @ProduceAccessToken json
+// @Param              host_id path         string   true "Host UUID"
+// @Success            201                  {object} success.DatabaseResponse
+// @Failure            401,400     {object} apiErrors.APIError
+func (r *BlacklistsRouter) PostRestoreHost(c *gin.Context) {
+	uuid := pgtype.UUID{}
+	err := uuid.Set(c.Param("host_id"))
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	rows, err := r.service.RestoreHost(uuid, contextUserID(c))
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	success.SavedResponse(c, rows)
+}
+
+// contextUserID returns ID of user authorized by request token, so changes made by request are linked with user
+func contextUserID(c *gin.Context) *uint64 {
+	value, ok := c.Get("user_id")
+	if !ok {
+		return nil
+	}
+
+	id, ok := value.(uint64)
+	if !ok {
+		return nil
+	}
+
+	return &id
+}
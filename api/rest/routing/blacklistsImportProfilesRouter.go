@@ -0,0 +1,160 @@
+package routing
+
+import (
+	apiErrors "domain_threat_intelligence_api/api/rest/error"
+	"domain_threat_intelligence_api/api/rest/success"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"net/http"
+	"strconv"
+)
+
+// GetImportProfiles returns all CSV import profiles
+//
+// @Summary            Get import profiles
+// @Description        Returns all CSV import profiles
+// @Tags               Blacklists, Import
+// @Security           ApiKeyAuth
+// @Router             /blacklists/import/profile [get]
+// @ProduceAccessToken json
+// @Success            200              {object} []blacklistEntities.BlacklistImportProfile
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetImportProfiles(c *gin.Context) {
+	profiles, err := r.service.RetrieveAllImportProfiles()
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profiles)
+}
+
+// GetImportProfile returns single CSV import profile
+//
+// @Summary            Get import profile
+// @Description        Returns single CSV import profile
+// @Tags               Blacklists, Import
+// @Security           ApiKeyAuth
+// @Router             /blacklists/import/profile/{profile_id} [get]
+// @ProduceAccessToken json
+// @Param              profile_id path        int      true "Profile ID"
+// @Success            200                    {object} blacklistEntities.BlacklistImportProfile
+// @Failure            401,400       {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetImportProfile(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("profile_id"), 10, 64)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	profile, err := r.service.RetrieveImportProfile(id)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	} else if profile.ID == 0 {
+		apiErrors.DatabaseEntityNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// PutImportProfile creates or updates CSV import profile
+//
+// @Summary            Save import profile
+// @Description        Creates new CSV import profile or updates existing one if ID defined
+// @Tags               Blacklists, Import
+// @Security           ApiKeyAuth
+// @Router             /blacklists/import/profile [put]
+// @ProduceAccessToken json
+// @Param              profile body              importProfileParams true "profile to save"
+// @Success            201              {object} blacklistEntities.BlacklistImportProfile
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) PutImportProfile(c *gin.Context) {
+	var params importProfileParams
+
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	profile, err := r.service.SaveImportProfile(blacklistEntities.BlacklistImportProfile{
+		ID:                   params.ID,
+		Name:                 params.Name,
+		Description:          params.Description,
+		Delimiter:            params.Delimiter,
+		TypeColumn:           params.TypeColumn,
+		ValueColumn:          params.ValueColumn,
+		SourceColumn:         params.SourceColumn,
+		DiscoveredAtColumn:   params.DiscoveredAtColumn,
+		DescriptionColumn:    params.DescriptionColumn,
+		ConfidenceColumn:     params.ConfidenceColumn,
+		SeverityColumn:       params.SeverityColumn,
+		CategoryColumn:       params.CategoryColumn,
+		KillChainPhaseColumn: params.KillChainPhaseColumn,
+		DateLayout:           params.DateLayout,
+		DefaultType:          params.DefaultType,
+		TypeMapping:          datatypes.NewJSONType(params.TypeMapping),
+		SourceMapping:        datatypes.NewJSONType(params.SourceMapping),
+		DefaultSourceID:      params.DefaultSourceID,
+	})
+
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, profile)
+}
+
+type importProfileParams struct {
+	ID                   uint64            `json:"ID"`
+	Name                 string            `json:"Name" binding:"required"`
+	Description          string            `json:"Description"`
+	Delimiter            string            `json:"Delimiter"`
+	TypeColumn           string            `json:"TypeColumn"`
+	ValueColumn          string            `json:"ValueColumn" binding:"required"`
+	SourceColumn         string            `json:"SourceColumn"`
+	DiscoveredAtColumn   string            `json:"DiscoveredAtColumn"`
+	DescriptionColumn    string            `json:"DescriptionColumn"`
+	ConfidenceColumn     string            `json:"ConfidenceColumn"`
+	SeverityColumn       string            `json:"SeverityColumn"`
+	CategoryColumn       string            `json:"CategoryColumn"`
+	KillChainPhaseColumn string            `json:"KillChainPhaseColumn"`
+	DateLayout           string            `json:"DateLayout"`
+	DefaultType          string            `json:"DefaultType" binding:"omitempty,oneof=ip domain url email"`
+	TypeMapping          map[string]string `json:"TypeMapping"`
+	SourceMapping        map[string]uint64 `json:"SourceMapping"`
+	DefaultSourceID      uint64            `json:"DefaultSourceID"`
+}
+
+// DeleteImportProfile accepts and deletes single CSV import profile
+//
+// @Summary            Delete import profile
+// @Description        Accepts and deletes single CSV import profile
+// @Tags               Blacklists, Import
+// @Security           ApiKeyAuth
+// @Router             /blacklists/import/profile [delete]
+// @ProduceAccessToken json
+// @Param              id               body      byIDParams true "record ID to delete"
+// @Success            200              {object} success.DatabaseResponse
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) DeleteImportProfile(c *gin.Context) {
+	var params byIDParams
+
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	rows, err := r.service.DeleteImportProfile(params.ID)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	success.DeletedResponse(c, rows)
+}
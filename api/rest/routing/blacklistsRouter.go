@@ -6,26 +6,29 @@ import (
 	"domain_threat_intelligence_api/api/rest/success"
 	"domain_threat_intelligence_api/cmd/core"
 	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
-	"encoding/csv"
+	"domain_threat_intelligence_api/cmd/xlsx"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgtype"
 	"io"
-	"net"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
 	"slices"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type BlacklistsRouter struct {
-	service        core.IBlacklistsService
-	path           *gin.RouterGroup
-	authMiddleware *auth.MiddlewareService
+	service            core.IBlacklistsService
+	feedsService       core.IBlacklistImportFeedsService
+	exportFeedsService core.IBlacklistExportFeedsService
+	path               *gin.RouterGroup
+	authMiddleware     *auth.MiddlewareService
 
 	cachedValues struct {
 		stats BlacklistedStatistics
@@ -41,6 +44,9 @@ type BlacklistedStatistics struct {
 
 	CreatedByDate    HostsByDate `json:"CreatedByDate"`
 	DiscoveredByDate HostsByDate `json:"DiscoveredByDate"`
+
+	// Tags contains number of active hosts by tag
+	Tags []blacklistEntities.BlacklistTagCount `json:"Tags"`
 }
 
 type HostsByDate struct {
@@ -52,8 +58,8 @@ type HostsByDate struct {
 	Emails  []uint64 `json:"Emails"`
 }
 
-func NewBlacklistsRouter(service core.IBlacklistsService, path *gin.RouterGroup, auth *auth.MiddlewareService) *BlacklistsRouter {
-	router := BlacklistsRouter{service: service, path: path}
+func NewBlacklistsRouter(service core.IBlacklistsService, feedsService core.IBlacklistImportFeedsService, exportFeedsService core.IBlacklistExportFeedsService, path *gin.RouterGroup, auth *auth.MiddlewareService) *BlacklistsRouter {
+	router := BlacklistsRouter{service: service, feedsService: feedsService, exportFeedsService: exportFeedsService, path: path}
 
 	blacklistsGroup := path.Group("/blacklists")
 	blacklistsGroup.Use(auth.RequireAuth())
@@ -63,6 +69,7 @@ func NewBlacklistsRouter(service core.IBlacklistsService, path *gin.RouterGroup,
 
 	{
 		blacklistsGroup.GET("/ip", router.GetBlackListedIPsByFilter)
+		blacklistsGroup.GET("/ip/contains", router.GetBlackListedIPsContaining)
 		blacklistsWriteGroup.PUT("/ip", router.PutBlackListedIPs)
 		blacklistsWriteGroup.DELETE("/ip", router.DeleteBlackListedIP)
 	}
@@ -85,7 +92,37 @@ func NewBlacklistsRouter(service core.IBlacklistsService, path *gin.RouterGroup,
 		blacklistsWriteGroup.DELETE("/email", router.DeleteBlackListedEmail)
 	}
 
-	blacklistsGroup.GET("/host", router.GetBlackListedHostsByFilter)
+	{
+		blacklistsGroup.GET("/host", router.GetBlackListedHostsByFilter)
+		blacklistsGroup.GET("/host/:host_id/history", router.GetHostHistory)
+		blacklistsWriteGroup.POST("/host/:host_id/restore", router.PostRestoreHost)
+	}
+
+	{
+		blacklistsGroup.GET("/check", router.GetCheckValue)
+		blacklistsGroup.POST("/check", router.PostCheckValues)
+	}
+
+	{
+		blacklistsGroup.GET("/ttl-policy", router.GetTTLPolicies)
+		blacklistsWriteGroup.PUT("/ttl-policy", router.PutTTLPolicy)
+		blacklistsWriteGroup.DELETE("/ttl-policy", router.DeleteTTLPolicy)
+	}
+
+	{
+		blacklistsGroup.GET("/allowlist", router.GetAllowlist)
+		blacklistsGroup.GET("/allowlist/:entry_id/conflicts", router.GetAllowlistConflicts)
+		blacklistsWriteGroup.PUT("/allowlist", router.PutAllowlistEntry)
+		blacklistsWriteGroup.DELETE("/allowlist", router.DeleteAllowlistEntry)
+	}
+
+	{
+		blacklistsGroup.GET("/tag", router.GetTags)
+		blacklistsWriteGroup.PUT("/tag", router.PutTag)
+		blacklistsWriteGroup.DELETE("/tag", router.DeleteTag)
+		blacklistsWriteGroup.PUT("/tag/hosts", router.PutTagHosts)
+		blacklistsWriteGroup.DELETE("/tag/hosts", router.DeleteTagHosts)
+	}
 
 	blacklistImportGroup := blacklistsGroup.Group("/import")
 	blacklistImportGroup.Use(auth.RequireRole(4003))
@@ -96,20 +133,63 @@ func NewBlacklistsRouter(service core.IBlacklistsService, path *gin.RouterGroup,
 	{
 		blacklistImportGroup.POST("/csv", router.PostImportBlacklistsFromCSVFile)
 		blacklistImportGroup.POST("/stix", router.PostImportBlacklistsFromSTIXFile)
+		blacklistImportGroup.POST("/misp", router.PostImportBlacklistsFromMISPFile)
+		blacklistImportGroup.POST("/text", router.PostImportBlacklistsFromText)
+		blacklistImportGroup.GET("/preview/:preview_id", router.GetImportPreview)
+		blacklistImportGroup.POST("/preview/:preview_id/commit", router.PostCommitImportPreview)
 		blacklistImportGroup.GET("/event", router.GetImportEventByFilter)
 		blacklistImportGroup.GET("/event/:event_id", router.GetImportEvent)
+		blacklistImportGroup.GET("/event/:event_id/progress", router.GetImportEventProgress)
+		blacklistImportGroup.POST("/event/:event_id/cancel", router.PostCancelImportEvent)
+		blacklistImportGroup.GET("/event/:event_id/errors", router.GetImportEventErrors)
+		blacklistImportGroup.GET("/event/:event_id/errors/csv", router.GetImportEventErrorsCSV)
 		blacklistsImportWriteGroup.DELETE("/event", router.DeleteImportEvent)
 	}
 
+	{
+		blacklistImportGroup.GET("/profile", router.GetImportProfiles)
+		blacklistImportGroup.GET("/profile/:profile_id", router.GetImportProfile)
+		blacklistsImportWriteGroup.PUT("/profile", router.PutImportProfile)
+		blacklistsImportWriteGroup.DELETE("/profile", router.DeleteImportProfile)
+	}
+
+	{
+		blacklistImportGroup.GET("/feed", router.GetImportFeeds)
+		blacklistImportGroup.GET("/feed/:feed_id", router.GetImportFeed)
+		blacklistImportGroup.POST("/feed/:feed_id/run", router.PostRunImportFeed)
+		blacklistsImportWriteGroup.PUT("/feed", router.PutImportFeed)
+		blacklistsImportWriteGroup.DELETE("/feed", router.DeleteImportFeed)
+	}
+
 	blacklistExportGroup := blacklistsGroup.Group("/export")
 	blacklistExportGroup.Use(auth.RequireRole(4004))
 
 	{
 		blacklistExportGroup.POST("/csv", router.PostExportBlacklistsToCSV)
+		blacklistExportGroup.POST("/xlsx", router.PostExportBlacklistsToXLSX)
 		blacklistExportGroup.POST("/json", router.PostExportBlacklistsToJSON)
+		blacklistExportGroup.POST("/stix", router.PostExportBlacklistsToSTIX)
+		blacklistExportGroup.POST("/list", router.PostExportBlacklistsToList)
+		blacklistExportGroup.POST("/delta", router.PostExportBlacklistsDelta)
 		blacklistExportGroup.POST("/naumen", router.PostExportBlacklistsToNaumen)
 	}
 
+	blacklistsExportWriteGroup := blacklistExportGroup.Group("")
+	blacklistsExportWriteGroup.Use(auth.RequireRole(4002))
+
+	{
+		blacklistExportGroup.GET("/feed", router.GetExportFeeds)
+		blacklistExportGroup.GET("/feed/:feed_id", router.GetExportFeed)
+		blacklistExportGroup.GET("/feed/access", router.GetExportFeedAccessLog)
+		blacklistsExportWriteGroup.PUT("/feed", router.PutExportFeed)
+		blacklistsExportWriteGroup.DELETE("/feed", router.DeleteExportFeed)
+		blacklistsExportWriteGroup.POST("/feed/:feed_id/token", router.PostExportFeedToken)
+		blacklistsExportWriteGroup.DELETE("/feed/token", router.DeleteExportFeedToken)
+	}
+
+	// public feeds are accessed with feed tokens only
+	path.GET("/feeds/:token", router.GetPublicFeed)
+
 	blacklistsGroup.GET("/sources", router.GetBlackListSources).Use(auth.RequireRole(4001))
 	blacklistsGroup.GET("/stats", router.GetStatistics)
 
@@ -131,7 +211,14 @@ func NewBlacklistsRouter(service core.IBlacklistsService, path *gin.RouterGroup,
 // @Param              is_active             query          bool           false "Is active"
 // @Param              created_after   query       string            false "Created timestamp is after"
 // @Param              created_before  query       string            false "Created timestamp is before"
+// @Param              expires_before  query       string            false "Expires before date, selects hosts expiring soon"
+// @Param              min_confidence  query       int               false "Minimal confidence score, 0-100"
+// @Param              min_severity    query       string            false "Minimal severity: low, medium, high or critical"
+// @Param              category[]      query       []string          false "Threat categories, hosts of any category selected" collectionFormat(multi)
+// @Param              kill_chain_phase query      string            false "Kill chain phase, like delivery or command-and-control"
+// @Param              tag_id[]        query       []uint64          false "Tag IDs, hosts with any of tags selected" collectionFormat(multi)
 // @Param              search_string   query       string            false "value to search"
+// @Param              domain          query       string            false "Domain to match exactly or by parent domains blacklisted with all subdomains"
 // @Param              limit                       query             int     true  "Query limit"
 // @Param              offset                      query             int     false "Query offset"
 // @Success            200                                  {object} []blacklistEntities.BlacklistedHost
@@ -177,7 +264,13 @@ func (r *BlacklistsRouter) GetBlackListedHostsByFilter(c *gin.Context) {
 // @Param              is_active             query          bool           false "Is active"
 // @Param              created_after   query       string            false "Created timestamp is after"
 // @Param              created_before  query       string            false "Created timestamp is before"
-// @Param              search_string   query       string            false "CIDR to search (must include IP/MASK)"
+// @Param              expires_before  query       string            false "Expires before date, selects hosts expiring soon"
+// @Param              min_confidence  query       int               false "Minimal confidence score, 0-100"
+// @Param              min_severity    query       string            false "Minimal severity: low, medium, high or critical"
+// @Param              category[]      query       []string          false "Threat categories, hosts of any category selected" collectionFormat(multi)
+// @Param              kill_chain_phase query      string            false "Kill chain phase, like delivery or command-and-control"
+// @Param              tag_id[]        query       []uint64          false "Tag IDs, hosts with any of tags selected" collectionFormat(multi)
+// @Param              search_string   query       string            false "IPv4/IPv6 address or CIDR to search, returns networks inside and networks covering it"
 // @Param              limit                       query             int     true  "Query limit"
 // @Param              offset                      query             int     false "Query offset"
 // @Success            200                                  {object} []blacklistEntities.BlacklistedIP
@@ -202,10 +295,14 @@ func (r *BlacklistsRouter) GetBlackListedIPsByFilter(c *gin.Context) {
 	}
 
 	// check if search string is IP or IP with mask
-	_, _, err = net.ParseCIDR(params.SearchString)
-	if len(params.SearchString) > 0 && err != nil {
-		apiErrors.ParamsErrorResponse(c, err)
-		return
+	if len(params.SearchString) > 0 {
+		ip, err := blacklistEntities.ParseInet(params.SearchString)
+		if err != nil {
+			apiErrors.ParamsErrorResponse(c, err)
+			return
+		}
+
+		params.SearchString = ip.IPNet.String()
 	}
 
 	ips, err := r.service.RetrieveIPsByFilter(params)
@@ -230,7 +327,14 @@ func (r *BlacklistsRouter) GetBlackListedIPsByFilter(c *gin.Context) {
 // @Param              is_active             query          bool           false "Is active"
 // @Param              created_after   query       string            false "Created timestamp is after"
 // @Param              created_before  query       string            false "Created timestamp is before"
+// @Param              expires_before  query       string            false "Expires before date, selects hosts expiring soon"
+// @Param              min_confidence  query       int               false "Minimal confidence score, 0-100"
+// @Param              min_severity    query       string            false "Minimal severity: low, medium, high or critical"
+// @Param              category[]      query       []string          false "Threat categories, hosts of any category selected" collectionFormat(multi)
+// @Param              kill_chain_phase query      string            false "Kill chain phase, like delivery or command-and-control"
+// @Param              tag_id[]        query       []uint64          false "Tag IDs, hosts with any of tags selected" collectionFormat(multi)
 // @Param              search_string   query       string            false "Substring to search"
+// @Param              domain          query       string            false "Domain to match exactly or by parent domains blacklisted with all subdomains"
 // @Param              limit                       query             int     true  "Query limit"
 // @Param              offset                      query             int     false "Query offset"
 // @Success            200                                  {object} []blacklistEntities.BlacklistedDomain
@@ -276,6 +380,12 @@ func (r *BlacklistsRouter) GetBlackListedDomainsByFilter(c *gin.Context) {
 // @Param              is_active             query          bool           false "Is active"
 // @Param              created_after   query       string            false "Created timestamp is after"
 // @Param              created_before  query       string            false "Created timestamp is before"
+// @Param              expires_before  query       string            false "Expires before date, selects hosts expiring soon"
+// @Param              min_confidence  query       int               false "Minimal confidence score, 0-100"
+// @Param              min_severity    query       string            false "Minimal severity: low, medium, high or critical"
+// @Param              category[]      query       []string          false "Threat categories, hosts of any category selected" collectionFormat(multi)
+// @Param              kill_chain_phase query      string            false "Kill chain phase, like delivery or command-and-control"
+// @Param              tag_id[]        query       []uint64          false "Tag IDs, hosts with any of tags selected" collectionFormat(multi)
 // @Param              search_string   query       string            false "Substring to search"
 // @Param              limit                       query             int     true  "Query limit"
 // @Param              offset                      query             int     false "Query offset"
@@ -322,6 +432,12 @@ func (r *BlacklistsRouter) GetBlackListedURLsByFilter(c *gin.Context) {
 // @Param              is_active             query          bool           false "Is active"
 // @Param              created_after   query       string            false "Created timestamp is after"
 // @Param              created_before  query       string            false "Created timestamp is before"
+// @Param              expires_before  query       string            false "Expires before date, selects hosts expiring soon"
+// @Param              min_confidence  query       int               false "Minimal confidence score, 0-100"
+// @Param              min_severity    query       string            false "Minimal severity: low, medium, high or critical"
+// @Param              category[]      query       []string          false "Threat categories, hosts of any category selected" collectionFormat(multi)
+// @Param              kill_chain_phase query      string            false "Kill chain phase, like delivery or command-and-control"
+// @Param              tag_id[]        query       []uint64          false "Tag IDs, hosts with any of tags selected" collectionFormat(multi)
 // @Param              search_string   query       string            false "Substring to search"
 // @Param              limit                       query             int     true  "Query limit"
 // @Param              offset                      query             int     false "Query offset"
@@ -377,14 +493,24 @@ func (r *BlacklistsRouter) PutBlackListedDomains(c *gin.Context) {
 
 	var domains []blacklistEntities.BlacklistedDomain
 	for _, h := range params.Hosts {
+		urn, includeSubdomains, err := blacklistEntities.ParseDomainPattern(h.Host)
+		if err != nil {
+			apiErrors.ParamsErrorResponse(c, err)
+			return
+		}
+
 		domains = append(domains, blacklistEntities.BlacklistedDomain{
-			URN:         h.Host,
-			Description: h.Description,
-			SourceID:    h.SourceID,
+			URN:               urn,
+			OriginalURN:       h.Host,
+			IncludeSubdomains: includeSubdomains,
+			Description:       h.Description,
+			SourceID:          h.SourceID,
+			ValidUntil:        h.ValidUntil,
+			ThreatAttributes:  h.toThreatAttributes(),
 		})
 	}
 
-	rows, err := r.service.SaveDomains(domains)
+	rows, err := r.service.SaveDomains(domains, contextUserID(c))
 	if err != nil {
 		apiErrors.DatabaseErrorResponse(c, err)
 		return
@@ -393,6 +519,55 @@ func (r *BlacklistsRouter) PutBlackListedDomains(c *gin.Context) {
 	success.SavedResponse(c, rows)
 }
 
+// GetBlackListedIPsContaining checks if IP address is covered by any blacklisted address or network
+//
+// @Summary            Blacklisted networks containing IP
+// @Description        Returns all blacklisted addresses and networks covering IPv4/IPv6 address or network, the most specific first
+// @Tags               Blacklists
+// @Security           ApiKeyAuth
+// @Router             /blacklists/ip/contains [get]
+// @ProduceAccessToken json
+// @Param              ip      query             string true "IPv4/IPv6 address or CIDR"
+// @Success            200              {object} ipContainmentResponse
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetBlackListedIPsContaining(c *gin.Context) {
+	var params ipContainmentParams
+
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	ip, err := blacklistEntities.ParseInet(params.IP)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	ips, err := r.service.RetrieveIPsContaining(ip.IPNet.String())
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ipContainmentResponse{
+		IP:        ip.IPNet.String(),
+		IsCovered: len(ips) > 0,
+		Networks:  ips,
+	})
+}
+
+type ipContainmentParams struct {
+	IP string `form:"ip" binding:"required"`
+}
+
+type ipContainmentResponse struct {
+	IP        string                            `json:"IP"`
+	IsCovered bool                              `json:"IsCovered"`
+	Networks  []blacklistEntities.BlacklistedIP `json:"Networks"`
+}
+
 // PutBlackListedIPs accepts and saves list of blacklisted IPs
 //
 // @Summary            Save blacklisted ips
@@ -415,22 +590,22 @@ func (r *BlacklistsRouter) PutBlackListedIPs(c *gin.Context) {
 
 	var ips []blacklistEntities.BlacklistedIP
 	for _, h := range params.Hosts {
-		var ipAddress = pgtype.Inet{}
-
-		err = ipAddress.Set(h.Host)
+		ipAddress, err := blacklistEntities.ParseInet(h.Host)
 		if err != nil {
 			apiErrors.ParamsErrorResponse(c, err)
 			return
 		}
 
 		ips = append(ips, blacklistEntities.BlacklistedIP{
-			IPAddress:   ipAddress,
-			Description: h.Description,
-			SourceID:    h.SourceID,
+			IPAddress:        ipAddress,
+			Description:      h.Description,
+			SourceID:         h.SourceID,
+			ValidUntil:       h.ValidUntil,
+			ThreatAttributes: h.toThreatAttributes(),
 		})
 	}
 
-	rows, err := r.service.SaveIPs(ips)
+	rows, err := r.service.SaveIPs(ips, contextUserID(c))
 	if err != nil {
 		apiErrors.DatabaseErrorResponse(c, err)
 		return
@@ -462,13 +637,15 @@ func (r *BlacklistsRouter) PutBlackListedURLs(c *gin.Context) {
 	var urls []blacklistEntities.BlacklistedURL
 	for _, h := range params.Hosts {
 		urls = append(urls, blacklistEntities.BlacklistedURL{
-			URL:         h.Host,
-			Description: h.Description,
-			SourceID:    h.SourceID,
+			URL:              h.Host,
+			Description:      h.Description,
+			SourceID:         h.SourceID,
+			ValidUntil:       h.ValidUntil,
+			ThreatAttributes: h.toThreatAttributes(),
 		})
 	}
 
-	rows, err := r.service.SaveURLs(urls)
+	rows, err := r.service.SaveURLs(urls, contextUserID(c))
 	if err != nil {
 		apiErrors.DatabaseErrorResponse(c, err)
 		return
@@ -500,13 +677,15 @@ func (r *BlacklistsRouter) PutBlackListedEmails(c *gin.Context) {
 	var emails []blacklistEntities.BlacklistedEmail
 	for _, h := range params.Hosts {
 		emails = append(emails, blacklistEntities.BlacklistedEmail{
-			Email:       h.Host,
-			Description: h.Description,
-			SourceID:    h.SourceID,
+			Email:            h.Host,
+			Description:      h.Description,
+			SourceID:         h.SourceID,
+			ValidUntil:       h.ValidUntil,
+			ThreatAttributes: h.toThreatAttributes(),
 		})
 	}
 
-	rows, err := r.service.SaveEmails(emails)
+	rows, err := r.service.SaveEmails(emails, contextUserID(c))
 	if err != nil {
 		apiErrors.DatabaseErrorResponse(c, err)
 		return
@@ -520,9 +699,30 @@ type blacklistInsertParams struct {
 		Host        string `json:"host" binding:"required"`
 		SourceID    uint64 `json:"source_id" binding:"required"`
 		Description string `json:"description,omitempty"`
+		// ValidUntil sets expiration date of host, if not defined, TTL policy is applied
+		ValidUntil *time.Time `json:"valid_until,omitempty"`
+
+		threatAttributesParams
 	} `json:"hosts" binding:"required,min=1,dive"` // issue: https://github.com/gin-gonic/gin/issues/3436
 }
 
+// threatAttributesParams defines threat attributes of saved host, unknown categories are ignored
+type threatAttributesParams struct {
+	Confidence     int      `json:"confidence,omitempty" binding:"min=0,max=100"`
+	Severity       string   `json:"severity,omitempty" binding:"omitempty,oneof=low medium high critical"`
+	Categories     []string `json:"categories,omitempty"`
+	KillChainPhase string   `json:"kill_chain_phase,omitempty"`
+}
+
+func (p threatAttributesParams) toThreatAttributes() blacklistEntities.ThreatAttributes {
+	return blacklistEntities.ThreatAttributes{
+		Confidence:     p.Confidence,
+		Severity:       p.Severity,
+		Categories:     p.Categories,
+		KillChainPhase: p.KillChainPhase,
+	}
+}
+
 // DeleteBlackListedIP accepts and deletes single blacklisted IP
 //
 // @Summary            Delete blacklisted IP
@@ -551,7 +751,7 @@ func (r *BlacklistsRouter) DeleteBlackListedIP(c *gin.Context) {
 		return
 	}
 
-	rows, err := r.service.DeleteIP(uuid)
+	rows, err := r.service.DeleteIP(uuid, contextUserID(c))
 	if err != nil {
 		apiErrors.DatabaseErrorResponse(c, err)
 		return
@@ -588,7 +788,7 @@ func (r *BlacklistsRouter) DeleteBlackListedDomain(c *gin.Context) {
 		return
 	}
 
-	rows, err := r.service.DeleteDomain(uuid)
+	rows, err := r.service.DeleteDomain(uuid, contextUserID(c))
 	if err != nil {
 		apiErrors.DatabaseErrorResponse(c, err)
 		return
@@ -625,7 +825,7 @@ func (r *BlacklistsRouter) DeleteBlackListedURL(c *gin.Context) {
 		return
 	}
 
-	rows, err := r.service.DeleteURL(uuid)
+	rows, err := r.service.DeleteURL(uuid, contextUserID(c))
 	if err != nil {
 		apiErrors.DatabaseErrorResponse(c, err)
 		return
@@ -662,7 +862,7 @@ func (r *BlacklistsRouter) DeleteBlackListedEmail(c *gin.Context) {
 		return
 	}
 
-	rows, err := r.service.DeleteEmail(uuid)
+	rows, err := r.service.DeleteEmail(uuid, contextUserID(c))
 	if err != nil {
 		apiErrors.DatabaseErrorResponse(c, err)
 		return
@@ -679,18 +879,22 @@ type byIDParams struct {
 	ID uint64 `json:"ID" binding:"required"`
 }
 
-// PostImportBlacklistsFromCSVFile accepts and imports blacklisted hosts from CSV file
+// PostImportBlacklistsFromCSVFile accepts and imports blacklisted hosts from CSV or XLSX file
 //
-// @Summary            Import blacklisted hosts from CSV file
-// @Description        Accepts and imports blacklisted hosts from CSV file
+// @Summary            Import blacklisted hosts from CSV or XLSX file
+// @Description        Accepts and imports blacklisted hosts from CSV or XLSX files. XLSX sheet is read with same column mapping as CSV. Several files are imported by single import job.
 // @Tags               Blacklists, Import
 // @Security           ApiKeyAuth
 // @Router             /blacklists/import/csv [post]
 // @ProduceAccessToken json
-// @Param              file_upload            formData file     true "file to import"
+// @Param              file_upload            formData file     true "files to import"
 // @Param              discovered_at formData string   true     "discovery date"
 // @Param              extract_all            formData string   true     "other types extraction"
-// @Success            201                                      {object} blacklistEntities.BlacklistImportEvent
+// @Param              dry_run      formData string   false    "only parse and classify hosts, returns preview"
+// @Param              profile_id   formData uint64   false    "import profile ID, FinCERT layout used if not defined"
+// @Param              sheet        formData string   false    "XLSX sheet name, first sheet used if not defined"
+// @Success            200                            {object} blacklistEntities.BlacklistImportPreview
+// @Success            202                                      {object} blacklistEntities.BlacklistImportEvent
 // @Failure            401,400                         {object} apiErrors.APIError
 func (r *BlacklistsRouter) PostImportBlacklistsFromCSVFile(c *gin.Context) {
 	form, err := c.MultipartForm()
@@ -723,37 +927,76 @@ func (r *BlacklistsRouter) PostImportBlacklistsFromCSVFile(c *gin.Context) {
 		return
 	}
 
-	var event blacklistEntities.BlacklistImportEvent
+	profile, err := r.importProfileFromForm(form)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	var sheet string
+	if len(form.Value["sheet"]) == 1 {
+		sheet = form.Value["sheet"][0]
+	}
+
+	// all files are read before import, so single job imports all of them or none
+	var data [][]string
 
 	for _, f := range files {
-		switch filepath.Ext(f.Filename) {
-		case ".csv":
+		switch strings.ToLower(filepath.Ext(f.Filename)) {
+		case ".csv", ".xlsx":
 			openedFile, err := f.Open()
 			if err != nil {
 				apiErrors.FileDecodingErrorResponse(c, err)
 				return
 			}
+			defer openedFile.Close()
+
+			var file [][]string
+			if strings.ToLower(filepath.Ext(f.Filename)) == ".xlsx" {
+				file, err = readXLSXFile(openedFile, sheet, profile.DateLayout)
+			} else {
+				file, err = profile.NewCSVReader(openedFile).ReadAll()
+			}
 
-			csvReader := csv.NewReader(openedFile)
-			data, err := csvReader.ReadAll()
 			if err != nil {
 				apiErrors.FileReadingErrorResponse(c, err)
 				return
 			}
 
-			event, err = r.service.ImportFromCSV(data, discoveredAt, extractAll)
-			if err != nil {
-				apiErrors.FileProcessingErrorResponse(c, err)
-			}
+			data = appendCSVFile(data, file)
 		default:
 			apiErrors.FileExtensionNotSupportedErrorResponse(c, errors.New("file extension not supported"))
 			return
 		}
 	}
 
-	go r.recountStatistics()
+	if isDryRun(form) {
+		preview, err := r.service.PreviewImportFromCSV(data, profile, discoveredAt, extractAll, 0, contextUserID(c))
+		if err != nil {
+			apiErrors.FileProcessingErrorResponse(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, preview)
+		return
+	}
+
+	event, err := r.service.StartImportJob(blacklistEntities.BlacklistImportJob{
+		Type:         blacklistEntities.ImportJobCSV,
+		ExtractAll:   extractAll,
+		DiscoveredAt: discoveredAt,
+		CSV:          data,
+		CSVProfile:   &profile,
+		CreatedByID:  contextUserID(c),
+	})
+	if err != nil {
+		apiErrors.FileProcessingErrorResponse(c, err)
+		return
+	}
+
+	go r.recountStatisticsOnImport(event.ID)
 
-	c.JSON(http.StatusCreated, event)
+	c.JSON(http.StatusAccepted, event)
 }
 
 // PostImportBlacklistsFromSTIXFile accepts and imports blacklisted hosts from STIX 2.0 file
@@ -767,7 +1010,9 @@ func (r *BlacklistsRouter) PostImportBlacklistsFromCSVFile(c *gin.Context) {
 // @ProduceAccessToken json
 // @Param              file_upload  formData file     true     "files to import"
 // @Param              extract_all  formData string   true     "other types extraction"
-// @Success            201                            {object} blacklistEntities.BlacklistImportEvent
+// @Param              dry_run      formData string   false    "only parse and classify hosts, returns preview"
+// @Success            200                            {object} blacklistEntities.BlacklistImportPreview
+// @Success            202                            {object} blacklistEntities.BlacklistImportEvent
 // @Failure            401,400               {object} apiErrors.APIError
 func (r *BlacklistsRouter) PostImportBlacklistsFromSTIXFile(c *gin.Context) {
 	form, err := c.MultipartForm()
@@ -798,6 +1043,7 @@ func (r *BlacklistsRouter) PostImportBlacklistsFromSTIXFile(c *gin.Context) {
 			apiErrors.FileDecodingErrorResponse(c, err)
 			return
 		}
+		defer openedFile.Close()
 
 		file, err := io.ReadAll(openedFile)
 		if err != nil {
@@ -825,153 +1071,621 @@ func (r *BlacklistsRouter) PostImportBlacklistsFromSTIXFile(c *gin.Context) {
 		}
 	}
 
-	event, err := r.service.ImportFromSTIX2(bundles, extractAll)
+	if isDryRun(form) {
+		preview, err := r.service.PreviewImportFromSTIX2(bundles, extractAll, 0, contextUserID(c))
+		if err != nil {
+			apiErrors.FileProcessingErrorResponse(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, preview)
+		return
+	}
+
+	event, err := r.service.StartImportJob(blacklistEntities.BlacklistImportJob{
+		Type:         blacklistEntities.ImportJobSTIX,
+		ExtractAll:   extractAll,
+		STIX2Bundles: bundles,
+		CreatedByID:  contextUserID(c),
+	})
 	if err != nil {
 		apiErrors.FileProcessingErrorResponse(c, err)
 		return
 	}
 
-	go r.recountStatistics()
+	go r.recountStatisticsOnImport(event.ID)
 
-	c.JSON(http.StatusCreated, event)
+	c.JSON(http.StatusAccepted, event)
 }
 
-// GetImportEvent returns import event data with all included blacklisted hosts
+// PostImportBlacklistsFromMISPFile accepts and imports blacklisted hosts from MISP JSON event export
 //
-// @Summary            Get import event
-// @Description        Returns import event data with all included blacklisted hosts
+// @Summary            Import blacklisted hosts from file (MISP)
+// @Description        Accepts and imports blacklisted hosts from MISP JSON event exports. Only attributes with to_ids flag are imported.
 // @Tags               Blacklists, Import
 // @Security           ApiKeyAuth
-// @Router             /blacklists/import/event/{event_id} [get]
+// @Router             /blacklists/import/misp [post]
+// @Accept             mpfd
 // @ProduceAccessToken json
-// @Param              event_id path          int      true "Event ID"
-// @Success            200                    {object} blacklistEntities.BlacklistImportEvent
-// @Failure            401,400       {object} apiErrors.APIError
-func (r *BlacklistsRouter) GetImportEvent(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("event_id"), 10, 64)
+// @Param              file_upload  formData file     true     "files to import"
+// @Param              extract_all  formData string   true     "other types extraction"
+// @Param              source_id    formData uint64   false    "source to attribute hosts to"
+// @Success            202                            {object} blacklistEntities.BlacklistImportEvent
+// @Failure            401,400               {object} apiErrors.APIError
+func (r *BlacklistsRouter) PostImportBlacklistsFromMISPFile(c *gin.Context) {
+	form, err := c.MultipartForm()
 	if err != nil {
 		apiErrors.ParamsErrorResponse(c, err)
 		return
 	}
 
-	event, err := r.service.RetrieveImportEvent(id)
-	if err != nil {
-		apiErrors.DatabaseErrorResponse(c, err)
+	files := form.File["file_upload"]
+	if len(files) == 0 {
+		apiErrors.ParamsErrorResponse(c, errors.New("files not provided"))
 		return
-	} else if event.ID == 0 {
-		apiErrors.DatabaseEntityNotFound(c)
+	}
+
+	extractAll := false
+	e := form.Value["extract_all"]
+	if len(e) == 1 && e[0] == "true" {
+		extractAll = true
+	}
+
+	var sourceID uint64
+	if v := form.Value["source_id"]; len(v) == 1 && len(v[0]) > 0 {
+		sourceID, err = strconv.ParseUint(v[0], 10, 64)
+		if err != nil {
+			apiErrors.ParamsErrorResponse(c, err)
+			return
+		}
+	}
+
+	var events []blacklistEntities.MISPEvent
+
+	for _, f := range files {
+		openedFile, err := f.Open()
+		if err != nil {
+			apiErrors.FileDecodingErrorResponse(c, err)
+			return
+		}
+		defer openedFile.Close()
+
+		file, err := io.ReadAll(openedFile)
+		if err != nil {
+			apiErrors.FileReadingErrorResponse(c, err)
+			return
+		}
+
+		switch filepath.Ext(f.Filename) {
+		case ".json":
+			parsed, err := blacklistEntities.ParseMISPEvents(file)
+			if err != nil {
+				apiErrors.FileDecodingErrorResponse(c, err)
+				return
+			}
+
+			events = append(events, parsed...)
+		default:
+			apiErrors.FileExtensionNotSupportedErrorResponse(c, errors.New("file extension not supported"))
+			return
+		}
+	}
+
+	event, err := r.service.StartImportJob(blacklistEntities.BlacklistImportJob{
+		Type:        blacklistEntities.ImportJobMISP,
+		SourceID:    sourceID,
+		ExtractAll:  extractAll,
+		MISPEvents:  events,
+		CreatedByID: contextUserID(c),
+	})
+	if err != nil {
+		apiErrors.FileProcessingErrorResponse(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, event)
+	go r.recountStatisticsOnImport(event.ID)
+
+	c.JSON(http.StatusAccepted, event)
 }
 
-// GetImportEventByFilter returns import events without data
+// PostImportBlacklistsFromText extracts indicators from free text and imports them on confirmation
 //
-// @Summary            Get import events list
-// @Description        Returns import events without data
+// @Summary            Import blacklisted hosts from free text
+// @Description        Extracts IPs, CIDRs, domains, URLs and emails (including defanged forms) from text. Returns extracted indicators as preview, if import not confirmed. If confirmed, imports previewed indicators, or all extracted from text, if indicators not provided.
 // @Tags               Blacklists, Import
 // @Security           ApiKeyAuth
-// @Router             /blacklists/import/event [get]
+// @Router             /blacklists/import/text [post]
 // @ProduceAccessToken json
-// @Param              created_after  query  string   false    "Created timestamp is after"
-// @Param              created_before query  string   false    "Created timestamp is before"
-// @Param              type                  query             string false "Type to search"
-// @Param              limit                 query             int          true  "Query limit"
-// @Param              offset                query             int          false "Query offset"
-// @Success            200                            {object} []blacklistEntities.BlacklistImportEvent
-// @Failure            401,400               {object} apiErrors.APIError
-func (r *BlacklistsRouter) GetImportEventByFilter(c *gin.Context) {
-	var params blacklistEntities.BlacklistImportEventFilter
+// @Param              text body textImportParams true "text to extract indicators from"
+// @Success            200                        {object} blacklistEntities.TextIndicators
+// @Success            202                        {object} blacklistEntities.BlacklistImportEvent
+// @Failure            401,400           {object} apiErrors.APIError
+func (r *BlacklistsRouter) PostImportBlacklistsFromText(c *gin.Context) {
+	var params textImportParams
 
-	err := c.ShouldBindQuery(&params)
+	err := c.ShouldBindJSON(&params)
 	if err != nil {
 		apiErrors.ParamsErrorResponse(c, err)
 		return
 	}
 
-	if params.CreatedBefore != nil && !params.CreatedBefore.IsZero() {
-		var d = params.CreatedBefore.Add((24*60 - 1) * time.Minute) // set to end of the day
-		params.CreatedBefore = &d
+	var indicators blacklistEntities.TextIndicators
+	if params.Confirm && params.Indicators != nil {
+		indicators = *params.Indicators
+	} else if len(params.Text) > 0 {
+		indicators = blacklistEntities.ExtractIndicatorsFromText(params.Text)
+	} else {
+		apiErrors.ParamsErrorResponse(c, errors.New("text not provided"))
+		return
 	}
 
-	events, err := r.service.RetrieveImportEventsByFilter(params)
+	if !params.Confirm {
+		c.JSON(http.StatusOK, indicators)
+		return
+	}
+
+	discoveredAt := time.Now()
+	if params.DiscoveredAt != nil && !params.DiscoveredAt.IsZero() {
+		discoveredAt = *params.DiscoveredAt
+	}
+
+	event, err := r.service.StartImportJob(blacklistEntities.BlacklistImportJob{
+		Type:         blacklistEntities.ImportJobText,
+		SourceID:     params.SourceID,
+		ExtractAll:   params.ExtractAll,
+		DiscoveredAt: discoveredAt,
+		Values:       indicators.Values(),
+		CreatedByID:  contextUserID(c),
+	})
 	if err != nil {
-		apiErrors.DatabaseErrorResponse(c, err)
+		apiErrors.FileProcessingErrorResponse(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, events)
+	go r.recountStatisticsOnImport(event.ID)
+
+	c.JSON(http.StatusAccepted, event)
 }
 
-// DeleteImportEvent accepts and deletes single blacklist import event
+type textImportParams struct {
+	Text         string                            `json:"Text"`
+	Confirm      bool                              `json:"Confirm"`
+	ExtractAll   bool                              `json:"ExtractAll"`
+	SourceID     uint64                            `json:"SourceID"`
+	DiscoveredAt *time.Time                        `json:"DiscoveredAt"`
+	Indicators   *blacklistEntities.TextIndicators `json:"Indicators"`
+}
+
+// GetImportPreview returns import dry-run result
 //
-// @Summary            Delete blacklist import event
-// @Description        Accepts and deletes single blacklist import event
+// @Summary            Get import preview
+// @Description        Returns import dry-run result with new, existing and invalid hosts
 // @Tags               Blacklists, Import
 // @Security           ApiKeyAuth
-// @Router             /blacklists/import/event [delete]
+// @Router             /blacklists/import/preview/{preview_id} [get]
 // @ProduceAccessToken json
-// @Param              id               body      byIDParams true "record ID to delete"
-// @Success            200              {object} success.DatabaseResponse
-// @Failure            401,400 {object} apiErrors.APIError
-// @Failure            401              {object} apiErrors.APIError
-func (r *BlacklistsRouter) DeleteImportEvent(c *gin.Context) {
-	var params byIDParams
-
-	err := c.ShouldBindJSON(&params)
-	if err != nil {
-		apiErrors.ParamsErrorResponse(c, err)
-		return
-	}
-
-	rows, err := r.service.DeleteImportEvent(params.ID)
+// @Param              preview_id path        string   true "Preview ID"
+// @Success            200                    {object} blacklistEntities.BlacklistImportPreview
+// @Failure            401,404       {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetImportPreview(c *gin.Context) {
+	preview, err := r.service.RetrieveImportPreview(c.Param("preview_id"), contextUserID(c))
 	if err != nil {
+		apiErrors.DatabaseEntityNotFound(c)
 		return
 	}
 
-	success.DeletedResponse(c, rows)
+	c.JSON(http.StatusOK, preview)
 }
 
-// PostExportBlacklistsToCSV accepts filters and returns exported blacklisted hosts in CSV
+// PostCommitImportPreview imports all hosts of import dry-run result
 //
-// @Summary            Exports blacklisted hosts into CSV
-// @Description        Accepts filters and returns exported blacklisted hosts in CSV
-// @Tags               Blacklists, Export
+// @Summary            Commit import preview
+// @Description        Imports all hosts of import dry-run result without uploading file again. Preview can be committed only once.
+// @Tags               Blacklists, Import
 // @Security           ApiKeyAuth
-// @Router             /blacklists/export/csv [post]
+// @Router             /blacklists/import/preview/{preview_id}/commit [post]
 // @ProduceAccessToken json
-// @Param              source_id[]                    query  []uint64 false "Source type IDs" collectionFormat(multi)
-// @Param              created_after           query  string          false "Created timestamp is after"
-// @Param              created_before          query  string          false "Created timestamp is before"
-// @Param              discovered_after  query string        false    "Discovery timestamp is after"
-// @Param              discovered_before query string        false    "Discovery timestamp is before"
-// @ProduceAccessToken application/csv
-// @Success            200              {file}  file
-// @Failure            401,400 {object} apiErrors.APIError
-func (r *BlacklistsRouter) PostExportBlacklistsToCSV(c *gin.Context) {
-	params := blacklistEntities.BlacklistSearchFilter{}
-
-	err := c.ShouldBindQuery(&params)
+// @Param              preview_id path        string   true "Preview ID"
+// @Success            202                    {object} blacklistEntities.BlacklistImportEvent
+// @Failure            401,400       {object} apiErrors.APIError
+func (r *BlacklistsRouter) PostCommitImportPreview(c *gin.Context) {
+	event, err := r.service.StartImportJob(blacklistEntities.BlacklistImportJob{
+		Type:        blacklistEntities.ImportJobPreview,
+		PreviewID:   c.Param("preview_id"),
+		CreatedByID: contextUserID(c),
+	})
 	if err != nil {
 		apiErrors.ParamsErrorResponse(c, err)
 		return
 	}
 
-	if params.CreatedBefore != nil && !params.CreatedBefore.IsZero() {
-		var d = params.CreatedBefore.Add((24*60 - 1) * time.Minute) // set to end of the day
-		params.CreatedBefore = &d
+	go r.recountStatisticsOnImport(event.ID)
+
+	c.JSON(http.StatusAccepted, event)
+}
+
+// readXLSXFile reads workbook sheet rows, date cells are formatted with profile date layout
+func readXLSXFile(file multipart.File, sheet string, dateLayout string) ([][]string, error) {
+	b, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
 	}
 
-	if params.DiscoveredBefore != nil && !params.DiscoveredBefore.IsZero() {
-		var d = params.DiscoveredBefore.Add((24*60 - 1) * time.Minute) // set to end of the day
-		params.DiscoveredBefore = &d
+	if len(dateLayout) == 0 {
+		dateLayout = "2006-01-02"
 	}
 
-	params.Limit = 0
-	params.Offset = 0
+	return xlsx.ReadSheet(b, sheet, dateLayout)
+}
 
-	jsonBytes, err := r.service.ExportToCSV(params)
+// appendCSVFile appends rows of file to rows of previously read files. Columns are matched by header name, columns
+// missing in previous files are added to header, so files with different column order are imported as single file.
+func appendCSVFile(data [][]string, file [][]string) [][]string {
+	if len(data) == 0 {
+		return file
+	}
+
+	if len(file) == 0 {
+		return data
+	}
+
+	name := func(h string) string {
+		return strings.TrimSpace(strings.TrimPrefix(h, "\uFEFF"))
+	}
+
+	// position of every file column in merged header
+	positions := make([]int, len(file[0]))
+	for i, h := range file[0] {
+		positions[i] = slices.IndexFunc(data[0], func(v string) bool { return name(v) == name(h) })
+		if positions[i] == -1 {
+			data[0] = append(data[0], name(h))
+			positions[i] = len(data[0]) - 1
+		}
+	}
+
+	for _, row := range file[1:] {
+		merged := make([]string, len(data[0]))
+		for i, v := range row {
+			if i < len(positions) {
+				merged[positions[i]] = v
+			}
+		}
+
+		data = append(data, merged)
+	}
+
+	return data
+}
+
+// isDryRun checks if import form requests only preview of imported hosts
+func isDryRun(form *multipart.Form) bool {
+	v := form.Value["dry_run"]
+	return len(v) == 1 && v[0] == "true"
+}
+
+// importProfileFromForm returns CSV import profile selected in import form, FinCERT profile is returned if none selected
+func (r *BlacklistsRouter) importProfileFromForm(form *multipart.Form) (blacklistEntities.BlacklistImportProfile, error) {
+	v := form.Value["profile_id"]
+	if len(v) == 0 || len(v[0]) == 0 {
+		return blacklistEntities.DefaultImportProfile(), nil
+	}
+
+	id, err := strconv.ParseUint(v[0], 10, 64)
+	if err != nil {
+		return blacklistEntities.BlacklistImportProfile{}, errors.New("import profile ID malformed")
+	}
+
+	profile, err := r.service.RetrieveImportProfile(id)
+	if err != nil {
+		return blacklistEntities.BlacklistImportProfile{}, err
+	} else if profile.ID == 0 {
+		return blacklistEntities.BlacklistImportProfile{}, fmt.Errorf("import profile #%d not found", id)
+	}
+
+	return profile, nil
+}
+
+// GetImportEvent returns import event data with all included blacklisted hosts
+//
+// @Summary            Get import event
+// @Description        Returns import event data with all included blacklisted hosts
+// @Tags               Blacklists, Import
+// @Security           ApiKeyAuth
+// @Router             /blacklists/import/event/{event_id} [get]
+// @ProduceAccessToken json
+// @Param              event_id path          int      true "Event ID"
+// @Success            200                    {object} blacklistEntities.BlacklistImportEvent
+// @Failure            401,400       {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetImportEvent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("event_id"), 10, 64)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	event, err := r.service.RetrieveImportEvent(id)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	} else if event.ID == 0 {
+		apiErrors.DatabaseEntityNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+// GetImportEventProgress streams import event progress as server-sent events until import is complete
+//
+// @Summary            Subscribe to import progress
+// @Description        Streams import event summary as server-sent events every second until import job is complete
+// @Tags               Blacklists, Import
+// @Security           ApiKeyAuth
+// @Router             /blacklists/import/event/{event_id}/progress [get]
+// @Produce            text/event-stream
+// @Param              event_id path          int      true "Event ID"
+// @Success            200                    {object} blacklistEntities.BlacklistImportEvent
+// @Failure            401,400       {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetImportEventProgress(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("event_id"), 10, 64)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	event, err := r.service.RetrieveImportEvent(id)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	} else if event.ID == 0 {
+		apiErrors.DatabaseEntityNotFound(c)
+		return
+	}
+
+	// stream lasts longer than server write timeout
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		event, err = r.service.RetrieveImportEvent(id)
+		if err != nil {
+			c.SSEvent("error", err.Error())
+			return false
+		}
+
+		c.SSEvent("progress", event)
+		if event.IsComplete {
+			return false
+		}
+
+		select {
+		case <-ticker.C:
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// PostCancelImportEvent cancels running import job
+//
+// @Summary            Cancel import
+// @Description        Cancels running import job after current batch is saved. Hosts saved before cancellation are kept.
+// @Tags               Blacklists, Import
+// @Security           ApiKeyAuth
+// @Router             /blacklists/import/event/{event_id}/cancel [post]
+// @ProduceAccessToken json
+// @Param              event_id path          int      true "Event ID"
+// @Success            202
+// @Failure            401,400       {object} apiErrors.APIError
+func (r *BlacklistsRouter) PostCancelImportEvent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("event_id"), 10, 64)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	err = r.service.CancelImportJob(id)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// GetImportEventErrors returns import error log
+//
+// @Summary            Get import errors
+// @Description        Returns values, which could not be parsed or saved on import, with their location in imported data and reason
+// @Tags               Blacklists, Import
+// @Security           ApiKeyAuth
+// @Router             /blacklists/import/event/{event_id}/errors [get]
+// @ProduceAccessToken json
+// @Param              event_id path          int      true  "Event ID"
+// @Param              limit    query         int      true  "Query limit"
+// @Param              offset   query         int      false "Query offset"
+// @Success            200                    {object} []blacklistEntities.BlacklistImportError
+// @Failure            401,400       {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetImportEventErrors(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("event_id"), 10, 64)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	var params blacklistEntities.BlacklistImportErrorFilter
+
+	err = c.ShouldBindQuery(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	importErrors, err := r.service.RetrieveImportErrors(id, params)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, importErrors)
+}
+
+// GetImportEventErrorsCSV returns import error log as CSV file
+//
+// @Summary            Export import errors
+// @Description        Returns import error log as CSV file. Type and value columns are named same as in FinCERT CSV, so fixed file can be imported again.
+// @Tags               Blacklists, Import
+// @Security           ApiKeyAuth
+// @Router             /blacklists/import/event/{event_id}/errors/csv [get]
+// @Produce            text/csv
+// @Param              event_id path          int      true  "Event ID"
+// @Success            200              {file}  file
+// @Failure            401,400       {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetImportEventErrorsCSV(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("event_id"), 10, 64)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	csvBytes, err := r.service.ExportImportErrorsToCSV(id)
+	if err != nil {
+		apiErrors.FileProcessingErrorResponse(c, err)
+		return
+	}
+
+	pattern := fmt.Sprintf("import_%d_errors.*.csv", id)
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		apiErrors.FileProcessingErrorResponse(c, err)
+		return
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(csvBytes)
+	if err != nil {
+		apiErrors.FileProcessingErrorResponse(c, err)
+		return
+	}
+
+	c.FileAttachment(file.Name(), filepath.Base(file.Name()))
+}
+
+// GetImportEventByFilter returns import events without data
+//
+// @Summary            Get import events list
+// @Description        Returns import events without data
+// @Tags               Blacklists, Import
+// @Security           ApiKeyAuth
+// @Router             /blacklists/import/event [get]
+// @ProduceAccessToken json
+// @Param              created_after  query  string   false    "Created timestamp is after"
+// @Param              created_before query  string   false    "Created timestamp is before"
+// @Param              type                  query             string false "Type to search"
+// @Param              feed_id               query             uint64 false "Import feed ID"
+// @Param              limit                 query             int          true  "Query limit"
+// @Param              offset                query             int          false "Query offset"
+// @Success            200                            {object} []blacklistEntities.BlacklistImportEvent
+// @Failure            401,400               {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetImportEventByFilter(c *gin.Context) {
+	var params blacklistEntities.BlacklistImportEventFilter
+
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	if params.CreatedBefore != nil && !params.CreatedBefore.IsZero() {
+		var d = params.CreatedBefore.Add((24*60 - 1) * time.Minute) // set to end of the day
+		params.CreatedBefore = &d
+	}
+
+	events, err := r.service.RetrieveImportEventsByFilter(params)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// DeleteImportEvent accepts and deletes single blacklist import event
+//
+// @Summary            Delete blacklist import event
+// @Description        Accepts and deletes single blacklist import event
+// @Tags               Blacklists, Import
+// @Security           ApiKeyAuth
+// @Router             /blacklists/import/event [delete]
+// @ProduceAccessToken json
+// @Param              id               body      byIDParams true "record ID to delete"
+// @Success            200              {object} success.DatabaseResponse
+// @Failure            401,400 {object} apiErrors.APIError
+// @Failure            401              {object} apiErrors.APIError
+func (r *BlacklistsRouter) DeleteImportEvent(c *gin.Context) {
+	var params byIDParams
+
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	rows, err := r.service.DeleteImportEvent(params.ID)
+	if err != nil {
+		return
+	}
+
+	success.DeletedResponse(c, rows)
+}
+
+// PostExportBlacklistsToCSV accepts filters and returns exported blacklisted hosts in CSV
+//
+// @Summary            Exports blacklisted hosts into CSV
+// @Description        Accepts filters and returns exported blacklisted hosts in CSV
+// @Tags               Blacklists, Export
+// @Security           ApiKeyAuth
+// @Router             /blacklists/export/csv [post]
+// @ProduceAccessToken json
+// @Param              source_id[]                    query  []uint64 false "Source type IDs" collectionFormat(multi)
+// @Param              created_after           query  string          false "Created timestamp is after"
+// @Param              created_before          query  string          false "Created timestamp is before"
+// @Param              discovered_after  query string        false    "Discovery timestamp is after"
+// @Param              discovered_before query string        false    "Discovery timestamp is before"
+// @Param              min_confidence    query int           false    "Minimal confidence score, 0-100"
+// @Param              min_severity      query string        false    "Minimal severity: low, medium, high or critical"
+// @Param              category[]        query []string      false    "Threat categories, hosts of any category selected" collectionFormat(multi)
+// @Param              kill_chain_phase  query string        false    "Kill chain phase, like delivery or command-and-control"
+// @Param              tag_id[]          query []uint64      false    "Tag IDs, hosts with any of tags selected" collectionFormat(multi)
+// @ProduceAccessToken application/csv
+// @Success            200              {file}  file
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) PostExportBlacklistsToCSV(c *gin.Context) {
+	params := blacklistEntities.BlacklistSearchFilter{}
+
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	if params.CreatedBefore != nil && !params.CreatedBefore.IsZero() {
+		var d = params.CreatedBefore.Add((24*60 - 1) * time.Minute) // set to end of the day
+		params.CreatedBefore = &d
+	}
+
+	if params.DiscoveredBefore != nil && !params.DiscoveredBefore.IsZero() {
+		var d = params.DiscoveredBefore.Add((24*60 - 1) * time.Minute) // set to end of the day
+		params.DiscoveredBefore = &d
+	}
+
+	params.Limit = 0
+	params.Offset = 0
+
+	jsonBytes, err := r.service.ExportToCSV(params)
 	if err != nil {
 		apiErrors.FileProcessingErrorResponse(c, err)
 		return
@@ -994,6 +1708,72 @@ func (r *BlacklistsRouter) PostExportBlacklistsToCSV(c *gin.Context) {
 	c.FileAttachment(file.Name(), filepath.Base(file.Name()))
 }
 
+// PostExportBlacklistsToXLSX accepts filters and returns exported blacklisted hosts in XLSX workbook with sheet for every host type
+//
+// @Summary            Exports blacklisted hosts into XLSX
+// @Description        Accepts filters and returns exported blacklisted hosts in XLSX workbook
+// @Tags               Blacklists, Export
+// @Security           ApiKeyAuth
+// @Router             /blacklists/export/xlsx [post]
+// @ProduceAccessToken json
+// @Param              source_id[]                    query  []uint64 false "Source type IDs" collectionFormat(multi)
+// @Param              created_after           query  string          false "Created timestamp is after"
+// @Param              created_before          query  string          false "Created timestamp is before"
+// @Param              discovered_after  query string        false    "Discovery timestamp is after"
+// @Param              discovered_before query string        false    "Discovery timestamp is before"
+// @Param              min_confidence    query int           false    "Minimal confidence score, 0-100"
+// @Param              min_severity      query string        false    "Minimal severity: low, medium, high or critical"
+// @Param              category[]        query []string      false    "Threat categories, hosts of any category selected" collectionFormat(multi)
+// @Param              kill_chain_phase  query string        false    "Kill chain phase, like delivery or command-and-control"
+// @Param              tag_id[]          query []uint64      false    "Tag IDs, hosts with any of tags selected" collectionFormat(multi)
+// @ProduceAccessToken application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Success            200              {file}  file
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) PostExportBlacklistsToXLSX(c *gin.Context) {
+	params := blacklistEntities.BlacklistSearchFilter{}
+
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	if params.CreatedBefore != nil && !params.CreatedBefore.IsZero() {
+		var d = params.CreatedBefore.Add((24*60 - 1) * time.Minute) // set to end of the day
+		params.CreatedBefore = &d
+	}
+
+	if params.DiscoveredBefore != nil && !params.DiscoveredBefore.IsZero() {
+		var d = params.DiscoveredBefore.Add((24*60 - 1) * time.Minute) // set to end of the day
+		params.DiscoveredBefore = &d
+	}
+
+	params.Limit = 0
+	params.Offset = 0
+
+	xlsxBytes, err := r.service.ExportToXLSX(params)
+	if err != nil {
+		apiErrors.FileProcessingErrorResponse(c, err)
+		return
+	}
+
+	pattern := fmt.Sprintf("export_%d.*.xlsx", time.Now().Unix())
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		apiErrors.FileProcessingErrorResponse(c, err)
+		return
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(xlsxBytes)
+	if err != nil {
+		apiErrors.FileProcessingErrorResponse(c, err)
+		return
+	}
+
+	c.FileAttachment(file.Name(), filepath.Base(file.Name()))
+}
+
 // PostExportBlacklistsToJSON accepts filters and returns exported blacklisted hosts in JSON. ref: https://github.com/swaggo/swag/issues/726
 //
 // @Summary            Exports blacklisted hosts into JSON
@@ -1007,6 +1787,11 @@ func (r *BlacklistsRouter) PostExportBlacklistsToCSV(c *gin.Context) {
 // @Param              created_before          query  string          false "Created timestamp is before"
 // @Param              discovered_after  query string        false    "Discovery timestamp is after"
 // @Param              discovered_before query string        false    "Discovery timestamp is before"
+// @Param              min_confidence    query int           false    "Minimal confidence score, 0-100"
+// @Param              min_severity      query string        false    "Minimal severity: low, medium, high or critical"
+// @Param              category[]        query []string      false    "Threat categories, hosts of any category selected" collectionFormat(multi)
+// @Param              kill_chain_phase  query string        false    "Kill chain phase, like delivery or command-and-control"
+// @Param              tag_id[]          query []uint64      false    "Tag IDs, hosts with any of tags selected" collectionFormat(multi)
 // @ProduceAccessToken application/json
 // @Success            200              {file}  file
 // @Failure            401,400 {object} apiErrors.APIError
@@ -1057,6 +1842,183 @@ func (r *BlacklistsRouter) PostExportBlacklistsToJSON(c *gin.Context) {
 	c.FileAttachment(file.Name(), filepath.Base(file.Name()))
 }
 
+// PostExportBlacklistsToSTIX accepts filters and returns exported blacklisted hosts as STIX 2.1 bundle
+//
+// @Summary            Exports blacklisted hosts into STIX 2.1 bundle
+// @Description        Accepts filters and returns STIX 2.1 bundle with identity for every source and indicator for every host. Deleted hosts are exported as revoked.
+// @Tags               Blacklists, Export
+// @Security           ApiKeyAuth
+// @Router             /blacklists/export/stix [post]
+// @ProduceAccessToken json
+// @Param              source_id[]                    query  []uint64 false "Source type IDs" collectionFormat(multi)
+// @Param              import_event_id   query uint64        false    "Import event ID"
+// @Param              is_active         query bool          false    "Is active"
+// @Param              created_after           query  string          false "Created timestamp is after"
+// @Param              created_before          query  string          false "Created timestamp is before"
+// @ProduceAccessToken application/json
+// @Success            200              {file}  file
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) PostExportBlacklistsToSTIX(c *gin.Context) {
+	params := blacklistEntities.BlacklistSearchFilter{}
+
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	if params.CreatedBefore != nil && !params.CreatedBefore.IsZero() {
+		var d = params.CreatedBefore.Add((24*60 - 1) * time.Minute) // set to end of the day
+		params.CreatedBefore = &d
+	}
+
+	params.Limit = 0
+	params.Offset = 0
+
+	jsonBytes, err := r.service.ExportToSTIX(params)
+	if err != nil {
+		apiErrors.FileProcessingErrorResponse(c, err)
+		return
+	}
+
+	pattern := fmt.Sprintf("export_%d.*.stix.json", time.Now().Unix())
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		apiErrors.FileProcessingErrorResponse(c, err)
+		return
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(jsonBytes)
+	if err != nil {
+		apiErrors.FileProcessingErrorResponse(c, err)
+		return
+	}
+
+	c.FileAttachment(file.Name(), filepath.Base(file.Name()))
+}
+
+// PostExportBlacklistsToList accepts filters and returns exported blacklisted hosts in format for network devices or DNS servers
+//
+// @Summary            Exports blacklisted hosts into blocklist
+// @Description        Accepts filters and returns blacklisted hosts as plain text list, hosts file, RPZ zone, Squid ACL, ipset, nftables set or Suricata/Snort IP reputation list
+// @Tags               Blacklists, Export
+// @Security           ApiKeyAuth
+// @Router             /blacklists/export/list [post]
+// @ProduceAccessToken json
+// @Param              format            query string        true     "Export format" Enums(txt, hosts, rpz, squid, iptables, nftables, suricata, snort)
+// @Param              source_id[]                    query  []uint64 false "Source type IDs" collectionFormat(multi)
+// @Param              import_event_id   query uint64        false    "Import event ID"
+// @Param              is_active         query bool          false    "Is active"
+// @Param              only_new          query bool          false    "Only hosts created in last 2 hours"
+// @Param              created_after           query  string          false "Created timestamp is after"
+// @Param              created_before          query  string          false "Created timestamp is before"
+// @Param              discovered_after  query string        false    "Discovery timestamp is after"
+// @Param              discovered_before query string        false    "Discovery timestamp is before"
+// @Param              min_confidence    query int           false    "Minimal confidence score, 0-100"
+// @Param              min_severity      query string        false    "Minimal severity: low, medium, high or critical"
+// @Param              category[]        query []string      false    "Threat categories, hosts of any category selected" collectionFormat(multi)
+// @Param              kill_chain_phase  query string        false    "Kill chain phase, like delivery or command-and-control"
+// @Param              tag_id[]          query []uint64      false    "Tag IDs, hosts with any of tags selected" collectionFormat(multi)
+// @ProduceAccessToken text/plain
+// @Success            200              {file}  file
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) PostExportBlacklistsToList(c *gin.Context) {
+	params := blacklistEntities.BlacklistExportFilter{}
+
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	format := blacklistEntities.ExportFormat(c.Query("format"))
+	if !slices.Contains(blacklistEntities.ExportFormats, format) {
+		apiErrors.ParamsErrorResponse(c, errors.New("export format not supported"))
+		return
+	}
+
+	if params.CreatedBefore != nil && !params.CreatedBefore.IsZero() {
+		var d = params.CreatedBefore.Add((24*60 - 1) * time.Minute) // set to end of the day
+		params.CreatedBefore = &d
+	}
+
+	if params.DiscoveredBefore != nil && !params.DiscoveredBefore.IsZero() {
+		var d = params.DiscoveredBefore.Add((24*60 - 1) * time.Minute) // set to end of the day
+		params.DiscoveredBefore = &d
+	}
+
+	listBytes, err := r.service.ExportToFormat(format, params)
+	if err != nil {
+		apiErrors.FileProcessingErrorResponse(c, err)
+		return
+	}
+
+	pattern := fmt.Sprintf("export_%d.*.%s", time.Now().Unix(), format)
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		apiErrors.FileProcessingErrorResponse(c, err)
+		return
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(listBytes)
+	if err != nil {
+		apiErrors.FileProcessingErrorResponse(c, err)
+		return
+	}
+
+	c.FileAttachment(file.Name(), filepath.Base(file.Name()))
+}
+
+// PostExportBlacklistsDelta returns hosts added, updated and removed since cursor
+//
+// @Summary            Exports blacklisted hosts changed since cursor
+// @Description        Returns hosts added, updated and removed since cursor or timestamp in JSON, CSV or STIX format. Cursor for next request is returned in X-Delta-Cursor header, X-Delta-More header is set to true if delta was cut by limit.
+// @Tags               Blacklists, Export
+// @Security           ApiKeyAuth
+// @Router             /blacklists/export/delta [post]
+// @ProduceAccessToken json
+// @Param              format            query string        true     "Export format" Enums(json, csv, stix)
+// @Param              cursor            query string        false    "Cursor returned by previous delta request"
+// @Param              since             query string        false    "Changed timestamp is after (RFC3339), ignored if cursor defined"
+// @Param              limit             query int           false    "Max number of hosts, 10000 by default, 100000 at most"
+// @Param              source_id[]                    query  []uint64 false "Source type IDs" collectionFormat(multi)
+// @Success            200              {object} blacklistEntities.BlacklistDelta
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) PostExportBlacklistsDelta(c *gin.Context) {
+	params := blacklistEntities.BlacklistDeltaFilter{}
+
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	var contentType string
+
+	format := blacklistEntities.ExportFormat(c.Query("format"))
+	switch format {
+	case blacklistEntities.ExportFormatJSON, blacklistEntities.ExportFormatSTIX:
+		contentType = "application/json"
+	case blacklistEntities.ExportFormatCSV:
+		contentType = "text/csv"
+	default:
+		apiErrors.ParamsErrorResponse(c, errors.New("export format not supported"))
+		return
+	}
+
+	data, cursor, more, err := r.service.ExportDelta(format, params)
+	if err != nil {
+		apiErrors.FileProcessingErrorResponse(c, err)
+		return
+	}
+
+	c.Header("X-Delta-Cursor", cursor)
+	c.Header("X-Delta-More", strconv.FormatBool(more))
+	c.Data(http.StatusOK, contentType, data)
+}
+
 // PostExportBlacklistsToNaumen sends service call to Naumen Service Desk with hosts selected to block by filter
 //
 // @Summary            Send hosts to Naumen Service Desk
@@ -1070,6 +2032,12 @@ func (r *BlacklistsRouter) PostExportBlacklistsToJSON(c *gin.Context) {
 // @Param              is_active             query          bool           false "Is active"
 // @Param              created_after   query       string            false "Created timestamp is after"
 // @Param              created_before  query       string            false "Created timestamp is before"
+// @Param              expires_before  query       string            false "Expires before date, selects hosts expiring soon"
+// @Param              min_confidence  query       int               false "Minimal confidence score, 0-100"
+// @Param              min_severity    query       string            false "Minimal severity: low, medium, high or critical"
+// @Param              category[]      query       []string          false "Threat categories, hosts of any category selected" collectionFormat(multi)
+// @Param              kill_chain_phase query      string            false "Kill chain phase, like delivery or command-and-control"
+// @Param              tag_id[]        query       []uint64          false "Tag IDs, hosts with any of tags selected" collectionFormat(multi)
 // @Param              search_string   query       string            false "Substring to search"
 // @Success            201                                  {object} serviceDeskEntities.ServiceDeskTicket
 // @Failure            401,400                     {object} apiErrors.APIError
@@ -1150,12 +2118,32 @@ func (r *BlacklistsRouter) GetBlackListSources(c *gin.Context) {
 	c.JSON(http.StatusOK, sources)
 }
 
+// recountStatisticsOnImport waits for import job to complete and recounts statistics
+func (r *BlacklistsRouter) recountStatisticsOnImport(eventID uint64) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		event, err := r.service.RetrieveImportEvent(eventID)
+		if err != nil || event.ID == 0 || event.IsComplete {
+			break
+		}
+	}
+
+	r.recountStatistics()
+}
+
 func (r *BlacklistsRouter) recountStatistics() {
 	now := time.Now()
 	r.cachedValues.stats.LastEval = &now
 
 	r.cachedValues.stats.TotalIPs, r.cachedValues.stats.TotalURLs, r.cachedValues.stats.TotalDomains, r.cachedValues.stats.TotalEmails = r.service.RetrieveTotalStatistics()
 
+	tags, err := r.service.RetrieveTagStatistics()
+	if err == nil {
+		r.cachedValues.stats.Tags = tags
+	}
+
 	// var byDate = make(map[string]*[3]uint64)
 
 	statisticsByCreationDate, err := r.service.RetrieveByCreationDateStatistics(now.Add(-time.Hour*24*31*2), now)
@@ -0,0 +1,67 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAppendCSVFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		files [][][]string
+		want  [][]string
+	}{
+		{
+			name: "single file",
+			files: [][][]string{
+				{{"type", "value"}, {"ip", "10.0.0.1"}},
+			},
+			want: [][]string{{"type", "value"}, {"ip", "10.0.0.1"}},
+		},
+		{
+			name: "same header",
+			files: [][][]string{
+				{{"type", "value"}, {"ip", "10.0.0.1"}},
+				{{"type", "value"}, {"domain", "example.com"}},
+			},
+			want: [][]string{{"type", "value"}, {"ip", "10.0.0.1"}, {"domain", "example.com"}},
+		},
+		{
+			name: "different column order and BOM",
+			files: [][][]string{
+				{{"\uFEFFtype", "value"}, {"ip", "10.0.0.1"}},
+				{{"\uFEFFvalue", "type"}, {"example.com", "domain"}},
+			},
+			want: [][]string{{"\uFEFFtype", "value"}, {"ip", "10.0.0.1"}, {"domain", "example.com"}},
+		},
+		{
+			name: "new column",
+			files: [][][]string{
+				{{"value"}, {"10.0.0.1"}},
+				{{"value", "source"}, {"example.com", "vendor"}},
+			},
+			want: [][]string{{"value", "source"}, {"10.0.0.1"}, {"example.com", "vendor"}},
+		},
+		{
+			name: "empty file",
+			files: [][][]string{
+				{{"value"}, {"10.0.0.1"}},
+				{},
+			},
+			want: [][]string{{"value"}, {"10.0.0.1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data [][]string
+			for _, f := range tt.files {
+				data = appendCSVFile(data, f)
+			}
+
+			if !reflect.DeepEqual(data, tt.want) {
+				t.Errorf("got %v, want %v", data, tt.want)
+			}
+		})
+	}
+}
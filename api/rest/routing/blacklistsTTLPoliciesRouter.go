@@ -0,0 +1,102 @@
+package routing
+
+import (
+	apiErrors "domain_threat_intelligence_api/api/rest/error"
+	"domain_threat_intelligence_api/api/rest/success"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// GetTTLPolicies returns all TTL policies
+//
+// @Summary            Get TTL policies
+// @Description        Returns all policies defining lifetime of blacklisted hosts by source and host type
+// @Tags               Blacklists
+// @Security           ApiKeyAuth
+// @Router             /blacklists/ttl-policy [get]
+// @ProduceAccessToken json
+// @Success            200              {object} []blacklistEntities.BlacklistTTLPolicy
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetTTLPolicies(c *gin.Context) {
+	policies, err := r.service.RetrieveAllTTLPolicies()
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// PutTTLPolicy creates or updates TTL policy
+//
+// @Summary            Save TTL policy
+// @Description        Creates new TTL policy or updates existing one if ID defined. Policy applies to hosts saved after update.
+// @Tags               Blacklists
+// @Security           ApiKeyAuth
+// @Router             /blacklists/ttl-policy [put]
+// @ProduceAccessToken json
+// @Param              policy  body              ttlPolicyParams true "policy to save"
+// @Success            201              {object} blacklistEntities.BlacklistTTLPolicy
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) PutTTLPolicy(c *gin.Context) {
+	var params ttlPolicyParams
+
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	policy, err := r.service.SaveTTLPolicy(blacklistEntities.BlacklistTTLPolicy{
+		ID:          params.ID,
+		SourceID:    params.SourceID,
+		Type:        params.Type,
+		TTLDays:     params.TTLDays,
+		Description: params.Description,
+	})
+
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, policy)
+}
+
+type ttlPolicyParams struct {
+	ID          uint64 `json:"ID"`
+	SourceID    uint64 `json:"SourceID"`
+	Type        string `json:"Type" binding:"omitempty,oneof=ip domain url email"`
+	TTLDays     uint64 `json:"TTLDays" binding:"required,min=1"`
+	Description string `json:"Description"`
+}
+
+// DeleteTTLPolicy accepts and deletes single TTL policy
+//
+// @Summary            Delete TTL policy
+// @Description        Accepts and deletes single TTL policy. Expiration dates of already saved hosts are not changed.
+// @Tags               Blacklists
+// @Security           ApiKeyAuth
+// @Router             /blacklists/ttl-policy [delete]
+// @ProduceAccessToken json
+// @Param              id               body      byIDParams true "record ID to delete"
+// @Success            200              {object} success.DatabaseResponse
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) DeleteTTLPolicy(c *gin.Context) {
+	var params byIDParams
+
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	rows, err := r.service.DeleteTTLPolicy(params.ID)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	success.DeletedResponse(c, rows)
+}
@@ -0,0 +1,196 @@
+package routing
+
+import (
+	apiErrors "domain_threat_intelligence_api/api/rest/error"
+	"domain_threat_intelligence_api/api/rest/success"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgtype"
+	"net/http"
+)
+
+// GetTags returns all tags
+//
+// @Summary            Get tags
+// @Description        Returns all tags grouping blacklisted hosts by campaign, incident or threat actor
+// @Tags               Blacklists
+// @Security           ApiKeyAuth
+// @Router             /blacklists/tag [get]
+// @ProduceAccessToken json
+// @Success            200              {object} []blacklistEntities.BlacklistTag
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) GetTags(c *gin.Context) {
+	tags, err := r.service.RetrieveAllTags()
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// PutTag creates or updates tag
+//
+// @Summary            Save tag
+// @Description        Creates new tag or updates existing one if ID defined
+// @Tags               Blacklists
+// @Security           ApiKeyAuth
+// @Router             /blacklists/tag [put]
+// @ProduceAccessToken json
+// @Param              tag     body              tagParams true "tag to save"
+// @Success            201              {object} blacklistEntities.BlacklistTag
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) PutTag(c *gin.Context) {
+	var params tagParams
+
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	tag, err := r.service.SaveTag(blacklistEntities.BlacklistTag{
+		ID:          params.ID,
+		Name:        params.Name,
+		Kind:        params.Kind,
+		Description: params.Description,
+	})
+
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, tag)
+}
+
+type tagParams struct {
+	ID          uint64 `json:"ID"`
+	Name        string `json:"Name" binding:"required,max=256"`
+	Kind        string `json:"Kind" binding:"omitempty,oneof=campaign incident actor"`
+	Description string `json:"Description"`
+}
+
+// DeleteTag accepts and deletes single tag
+//
+// @Summary            Delete tag
+// @Description        Accepts and deletes single tag, hosts tagged by it are not deleted
+// @Tags               Blacklists
+// @Security           ApiKeyAuth
+// @Router             /blacklists/tag [delete]
+// @ProduceAccessToken json
+// @Param              id               body      byIDParams true "record ID to delete"
+// @Success            200              {object} success.DatabaseResponse
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) DeleteTag(c *gin.Context) {
+	var params byIDParams
+
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	rows, err := r.service.DeleteTag(params.ID)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	success.DeletedResponse(c, rows)
+}
+
+// PutTagHosts tags list of hosts
+//
+// @Summary            Tag hosts
+// @Description        Links list of blacklisted hosts of any type with tags, existing links are kept
+// @Tags               Blacklists
+// @Security           ApiKeyAuth
+// @Router             /blacklists/tag/hosts [put]
+// @ProduceAccessToken json
+// @Param              hosts   body              tagHostsParams true "tags and hosts to link"
+// @Success            201              {object} success.DatabaseResponse
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) PutTagHosts(c *gin.Context) {
+	var params tagHostsParams
+
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	hosts, err := params.hosts()
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	rows, err := r.service.TagHosts(params.TagIDs, hosts)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	success.SavedResponse(c, rows)
+}
+
+// DeleteTagHosts untags list of hosts
+//
+// @Summary            Untag hosts
+// @Description        Unlinks list of blacklisted hosts of any type from tags
+// @Tags               Blacklists
+// @Security           ApiKeyAuth
+// @Router             /blacklists/tag/hosts [delete]
+// @ProduceAccessToken json
+// @Param              hosts   body              tagHostsParams true "tags and hosts to unlink"
+// @Success            200              {object} success.DatabaseResponse
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *BlacklistsRouter) DeleteTagHosts(c *gin.Context) {
+	var params tagHostsParams
+
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	hosts, err := params.hosts()
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	rows, err := r.service.UntagHosts(params.TagIDs, hosts)
+	if err != nil {
+		apiErrors.DatabaseErrorResponse(c, err)
+		return
+	}
+
+	success.DeletedResponse(c, rows)
+}
+
+type tagHostsParams struct {
+	TagIDs []uint64 `json:"TagIDs" binding:"required,min=1"`
+	Hosts  []struct {
+		UUID string `json:"UUID" binding:"uuid4,required"`
+		Type string `json:"Type" binding:"required,oneof=ip domain url email"`
+	} `json:"Hosts" binding:"required,min=1,max=10000,dive"`
+}
+
+func (p tagHostsParams) hosts() ([]blacklistEntities.BlacklistedHost, error) {
+	var hosts []blacklistEntities.BlacklistedHost
+
+	for _, h := range p.Hosts {
+		uuid := pgtype.UUID{}
+
+		err := uuid.Set(h.UUID)
+		if err != nil {
+			return nil, err
+		}
+
+		hosts = append(hosts, blacklistEntities.BlacklistedHost{UUID: uuid, Type: h.Type})
+	}
+
+	return hosts, nil
+}
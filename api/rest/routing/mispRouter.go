@@ -0,0 +1,134 @@
+package routing
+
+import (
+	"domain_threat_intelligence_api/api/rest/auth"
+	apiErrors "domain_threat_intelligence_api/api/rest/error"
+	"domain_threat_intelligence_api/cmd/core"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+type MISPRouter struct {
+	service core.IMISPService
+	path    *gin.RouterGroup
+}
+
+func NewMISPRouter(service core.IMISPService, path *gin.RouterGroup, auth *auth.MiddlewareService) *MISPRouter {
+	router := MISPRouter{service: service, path: path}
+
+	mispGroup := path.Group("/integrations/misp")
+	mispGroup.Use(auth.RequireAuth())
+
+	{
+		mispGroup.GET("/availability", router.GetAvailability)
+		mispGroup.POST("/sync", auth.RequireRole(4003), router.PostSync)
+		mispGroup.POST("/push", auth.RequireRole(4004), router.PostPushHosts)
+	}
+
+	return &router
+}
+
+// GetAvailability returns if MISP integration is configured and enabled
+//
+// @Summary            MISP availability
+// @Description        Returns if MISP integration is configured and enabled
+// @Tags               Integrations
+// @Security           ApiKeyAuth
+// @Router             /integrations/misp/availability [get]
+// @ProduceAccessToken json
+// @Success            200 {object} mispAvailabilityResponse
+// @Failure            401 {object} apiErrors.APIError
+func (r *MISPRouter) GetAvailability(c *gin.Context) {
+	c.JSON(http.StatusOK, mispAvailabilityResponse{IsAvailable: r.service.IsAvailable()})
+}
+
+type mispAvailabilityResponse struct {
+	IsAvailable bool `json:"IsAvailable"`
+}
+
+// PostSync runs MISP synchronization immediately
+//
+// @Summary            Synchronize with MISP
+// @Description        Pulls events changed since last synchronization from MISP and imports their IDS attributes
+// @Tags               Integrations
+// @Security           ApiKeyAuth
+// @Router             /integrations/misp/sync [post]
+// @ProduceAccessToken json
+// @Success            201 {object} blacklistEntities.BlacklistImportEvent
+// @Success            204
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *MISPRouter) PostSync(c *gin.Context) {
+	if !r.service.IsAvailable() {
+		apiErrors.ParamsErrorResponse(c, errors.New("misp integration not available"))
+		return
+	}
+
+	event, err := r.service.Sync()
+	if err != nil {
+		apiErrors.InternalErrorResponse(c, err)
+		return
+	}
+
+	// no new events found
+	if event.ID == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusCreated, event)
+}
+
+// PostPushHosts pushes blacklisted hosts to MISP as new event
+//
+// @Summary            Push hosts to MISP
+// @Description        Creates MISP event containing blacklisted hosts selected by filter. Manually added hosts are pushed if no source defined.
+// @Tags               Integrations
+// @Security           ApiKeyAuth
+// @Router             /integrations/misp/push [post]
+// @ProduceAccessToken json
+// @Param              source_id[]       query []uint64 false "Source type IDs" collectionFormat(multi)
+// @Param              import_event_id   query uint64   false "Import event ID"
+// @Param              is_active         query bool     false "Is rule active"
+// @Param              created_after     query string   false "Created timestamp is after"
+// @Param              created_before    query string   false "Created timestamp is before"
+// @Param              discovered_after  query string   false "Discovered timestamp is after"
+// @Param              discovered_before query string   false "Discovered timestamp is before"
+// @Success            201 {object} blacklistEntities.MISPEvent
+// @Failure            401,400 {object} apiErrors.APIError
+func (r *MISPRouter) PostPushHosts(c *gin.Context) {
+	if !r.service.IsAvailable() {
+		apiErrors.ParamsErrorResponse(c, errors.New("misp integration not available"))
+		return
+	}
+
+	params := blacklistEntities.BlacklistSearchFilter{}
+
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	if params.CreatedBefore != nil && !params.CreatedBefore.IsZero() {
+		var d = params.CreatedBefore.Add((24*60 - 1) * time.Minute) // set to end of the day
+		params.CreatedBefore = &d
+	}
+
+	if len(params.SourceIDs) == 0 {
+		params.SourceIDs = []uint64{blacklistEntities.SourceManual}
+	}
+
+	params.Limit = 0
+	params.Offset = 0
+
+	event, err := r.service.PushHosts(params)
+	if err != nil {
+		apiErrors.InternalErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, event)
+}
@@ -30,6 +30,7 @@ func NewSystemStateRouter(service core.ISystemStateService, path *gin.RouterGrou
 		systemWriteStateGroup.POST("/dynamic/smtp", router.PostUpdateSMTPConfig)
 		systemWriteStateGroup.POST("/dynamic/naumen", router.PostUpdateNaumenConfig)
 		systemWriteStateGroup.POST("/dynamic/naumen/blacklists", router.PostUpdateNaumenBlacklistServiceConfig)
+		systemWriteStateGroup.POST("/dynamic/misp", router.PostUpdateMISPConfig)
 	}
 
 	systemResetStateGroup := systemStateGroup.Group("")
@@ -176,6 +177,44 @@ type naumenBlacklistServiceConfigUpdateParams struct {
 	HostTypes   []string `json:"HostTypes" binding:"required"`
 }
 
+// PostUpdateMISPConfig updates dynamic MISP integration configuration
+//
+// @Summary            Update dynamic MISP configuration
+// @Description        Updates dynamic MISP instance connection and synchronization configuration
+// @Tags               Configuration
+// @Security           ApiKeyAuth
+// @Router             /system/dynamic/misp [post]
+// @ProduceAccessToken json
+// @Param              mispConfig body mispConfigUpdateParams true "dynamic misp configuration"
+// @Success            202
+// @Failure            401,400 {object} error.APIError
+func (r *SystemStateRouter) PostUpdateMISPConfig(c *gin.Context) {
+	params := mispConfigUpdateParams{}
+
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	err = r.service.UpdateMISPConfig(params.Enabled, params.VerifyTLS, params.URL, params.Key, params.SyncInterval, params.SourceID)
+	if err != nil {
+		apiErrors.ParamsErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+type mispConfigUpdateParams struct {
+	Enabled      bool   `json:"Enabled"`
+	VerifyTLS    bool   `json:"VerifyTLS"`
+	URL          string `json:"URL" binding:"required,url"`
+	Key          string `json:"Key" binding:"required"`
+	SyncInterval uint64 `json:"SyncInterval" binding:"required,min=1"`
+	SourceID     uint64 `json:"SourceID" binding:"required"`
+}
+
 // PostResetConfig resets all dynamic configuration variables
 //
 // @Summary            Return all dynamic configuration variables to default
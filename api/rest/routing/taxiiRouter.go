@@ -0,0 +1,301 @@
+package routing
+
+import (
+	"domain_threat_intelligence_api/api/rest/auth"
+	"domain_threat_intelligence_api/cmd/core"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"domain_threat_intelligence_api/cmd/integrations/taxii"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgtype"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	taxiiDefaultLimit = 100
+	taxiiMaxLimit     = 1000
+)
+
+// TAXIIRouter serves blacklisted hosts as read-only TAXII 2.1 API. Each blacklist source is exposed as single collection.
+// reference: https://docs.oasis-open.org/cti/taxii/v2.1/taxii-v2.1.html
+type TAXIIRouter struct {
+	service core.IBlacklistsService
+	path    *gin.RouterGroup
+}
+
+func NewTAXIIRouter(service core.IBlacklistsService, path *gin.RouterGroup, auth *auth.MiddlewareService) *TAXIIRouter {
+	router := TAXIIRouter{service: service, path: path}
+
+	taxiiGroup := path.Group("/taxii2")
+	taxiiGroup.Use(bearerToAPIKey(), auth.RequireAuth(), auth.RequireRole(4004))
+
+	{
+		taxiiGroup.GET("/", router.GetDiscovery)
+		taxiiGroup.GET("/blacklists/", router.GetAPIRoot)
+		taxiiGroup.GET("/blacklists/collections/", router.GetCollections)
+		taxiiGroup.GET("/blacklists/collections/:collection_id/", router.GetCollection)
+		taxiiGroup.GET("/blacklists/collections/:collection_id/objects/", router.GetObjects)
+	}
+
+	return &router
+}
+
+// GetDiscovery returns TAXII server discovery resource
+//
+// @Summary            TAXII discovery
+// @Description        Returns TAXII 2.1 server discovery resource with single API root
+// @Tags               TAXII
+// @Security           ApiKeyAuth
+// @Router             /taxii2/ [get]
+// @ProduceAccessToken application/taxii+json;version=2.1
+// @Success            200 {object} taxii.Discovery
+// @Failure            401,403 {object} taxii.Error
+func (r *TAXIIRouter) GetDiscovery(c *gin.Context) {
+	root := r.path.BasePath() + "/taxii2/blacklists/"
+
+	taxiiResponse(c, http.StatusOK, taxii.Discovery{
+		Title:       "Domain Threat Intelligence TAXII server",
+		Description: "Blacklisted hosts as STIX 2.1 indicators",
+		Default:     root,
+		APIRoots:    []string{root},
+	})
+}
+
+// GetAPIRoot returns TAXII API root resource
+//
+// @Summary            TAXII API root
+// @Description        Returns TAXII 2.1 API root containing blacklist collections
+// @Tags               TAXII
+// @Security           ApiKeyAuth
+// @Router             /taxii2/blacklists/ [get]
+// @ProduceAccessToken application/taxii+json;version=2.1
+// @Success            200 {object} taxii.APIRoot
+// @Failure            401,403 {object} taxii.Error
+func (r *TAXIIRouter) GetAPIRoot(c *gin.Context) {
+	taxiiResponse(c, http.StatusOK, taxii.APIRoot{
+		Title:            "Blacklists",
+		Description:      "Blacklisted IPs, domains, URLs and emails grouped by source",
+		Versions:         []string{taxii.MediaType},
+		MaxContentLength: 0,
+	})
+}
+
+// GetCollections returns all TAXII collections, one per blacklist source
+//
+// @Summary            TAXII collections
+// @Description        Returns TAXII 2.1 collections, one per blacklist source
+// @Tags               TAXII
+// @Security           ApiKeyAuth
+// @Router             /taxii2/blacklists/collections/ [get]
+// @ProduceAccessToken application/taxii+json;version=2.1
+// @Success            200 {object} object
+// @Failure            401,403,500 {object} taxii.Error
+func (r *TAXIIRouter) GetCollections(c *gin.Context) {
+	sources, err := r.service.RetrieveAllSources()
+	if err != nil {
+		taxiiErrorResponse(c, http.StatusInternalServerError, "failed to retrieve collections", err.Error())
+		return
+	}
+
+	var collections = make([]taxii.Collection, 0, len(sources))
+	for _, s := range sources {
+		collections = append(collections, sourceToCollection(s))
+	}
+
+	taxiiResponse(c, http.StatusOK, struct {
+		Collections []taxii.Collection `json:"collections,omitempty"`
+	}{collections})
+}
+
+// GetCollection returns single TAXII collection
+//
+// @Summary            TAXII collection
+// @Description        Returns single TAXII 2.1 collection
+// @Tags               TAXII
+// @Security           ApiKeyAuth
+// @Router             /taxii2/blacklists/collections/{collection_id}/ [get]
+// @ProduceAccessToken application/taxii+json;version=2.1
+// @Param              collection_id path string true "Collection ID"
+// @Success            200 {object} taxii.Collection
+// @Failure            401,403,404,500 {object} taxii.Error
+func (r *TAXIIRouter) GetCollection(c *gin.Context) {
+	source, ok := r.findSource(c)
+	if !ok {
+		return
+	}
+
+	taxiiResponse(c, http.StatusOK, sourceToCollection(source))
+}
+
+// GetObjects returns page of STIX indicators from collection
+//
+// @Summary            TAXII collection objects
+// @Description        Returns page of STIX 2.1 indicators from collection, ordered by date added. If added_after defined, deleted hosts are returned as revoked indicators.
+// @Tags               TAXII
+// @Security           ApiKeyAuth
+// @Router             /taxii2/blacklists/collections/{collection_id}/objects/ [get]
+// @ProduceAccessToken application/taxii+json;version=2.1
+// @Param              collection_id path  string true  "Collection ID"
+// @Param              added_after   query string false "Return objects added after timestamp (RFC3339)"
+// @Param              match[type]   query string false "Comma separated STIX object types"
+// @Param              limit         query int    false "Max objects on page"
+// @Param              next          query string false "Next page token returned by previous page"
+// @Success            200 {object} taxii.Envelope
+// @Failure            400,401,403,404,500 {object} taxii.Error
+func (r *TAXIIRouter) GetObjects(c *gin.Context) {
+	source, ok := r.findSource(c)
+	if !ok {
+		return
+	}
+
+	filter := blacklistEntities.BlacklistSearchFilter{
+		Limit:         taxiiDefaultLimit,
+		SourceIDs:     []uint64{source.ID},
+		SortByUpdated: true,
+	}
+
+	if v := c.Query("added_after"); len(v) > 0 {
+		addedAfter, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			taxiiErrorResponse(c, http.StatusBadRequest, "invalid added_after parameter", err.Error())
+			return
+		}
+
+		// deleted hosts are served as revoked indicators to clients polling by added_after
+		filter.UpdatedAfter = &addedAfter
+		filter.IsActive = new(bool)
+	}
+
+	if v := c.Query("limit"); len(v) > 0 {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			taxiiErrorResponse(c, http.StatusBadRequest, "invalid limit parameter", "limit must be positive integer")
+			return
+		}
+
+		filter.Limit = min(limit, taxiiMaxLimit)
+	}
+
+	// next token points to the last object of previous page by its change time and UUID
+	if v := c.Query("next"); len(v) > 0 {
+		changedAt, id, err := blacklistEntities.DecodeDeltaCursor(v)
+		if err != nil || id.Status != pgtype.Present {
+			taxiiErrorResponse(c, http.StatusBadRequest, "invalid next parameter", "next token malformed")
+			return
+		}
+
+		filter.UpdatedAfter, filter.UpdatedAfterUUID = &changedAt, id
+	}
+
+	// only indicators are served from collections
+	if v := c.Query("match[type]"); len(v) > 0 && !slices.Contains(strings.Split(v, ","), "indicator") {
+		taxiiResponse(c, http.StatusOK, taxii.Envelope{More: false})
+		return
+	}
+
+	// one more host requested to find out if next page exists
+	filter.Limit++
+
+	hosts, err := r.service.RetrieveHostsByFilter(filter)
+	if err != nil {
+		taxiiErrorResponse(c, http.StatusInternalServerError, "failed to retrieve objects", err.Error())
+		return
+	}
+
+	envelope := taxii.Envelope{}
+
+	if len(hosts) == filter.Limit {
+		hosts = hosts[:len(hosts)-1]
+
+		last := hosts[len(hosts)-1]
+
+		envelope.More = true
+		envelope.Next = blacklistEntities.EncodeDeltaCursor(last.ChangedAt(), last.UUID)
+	}
+
+	for _, h := range hosts {
+		indicator, err := blacklistEntities.NewSTIX2Indicator(h)
+		if err != nil {
+			slog.Warn("failed to build stix indicator: " + err.Error())
+			continue
+		}
+
+		envelope.Objects = append(envelope.Objects, indicator)
+	}
+
+	if len(hosts) > 0 {
+		c.Header("X-TAXII-Date-Added-First", hosts[0].ChangedAt().UTC().Format(time.RFC3339Nano))
+		c.Header("X-TAXII-Date-Added-Last", hosts[len(hosts)-1].ChangedAt().UTC().Format(time.RFC3339Nano))
+	}
+
+	taxiiResponse(c, http.StatusOK, envelope)
+}
+
+// findSource returns blacklist source by collection ID from path. Writes error response if source not found.
+func (r *TAXIIRouter) findSource(c *gin.Context) (blacklistEntities.BlacklistSource, bool) {
+	sources, err := r.service.RetrieveAllSources()
+	if err != nil {
+		taxiiErrorResponse(c, http.StatusInternalServerError, "failed to retrieve collections", err.Error())
+		return blacklistEntities.BlacklistSource{}, false
+	}
+
+	id := c.Param("collection_id")
+	for _, s := range sources {
+		if blacklistEntities.SourceUUID(s.ID) == id {
+			return s, true
+		}
+	}
+
+	taxiiErrorResponse(c, http.StatusNotFound, "collection not found", "collection '"+id+"' does not exist")
+	return blacklistEntities.BlacklistSource{}, false
+}
+
+func sourceToCollection(source blacklistEntities.BlacklistSource) taxii.Collection {
+	return taxii.Collection{
+		ID:          blacklistEntities.SourceUUID(source.ID),
+		Title:       source.Name,
+		Description: source.Description,
+		CanRead:     true,
+		CanWrite:    false,
+		MediaTypes:  []string{"application/stix+json;version=2.1"},
+	}
+}
+
+// bearerToAPIKey allows TAXII clients to pass access token with "Authorization: Bearer" header
+func bearerToAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(c.GetHeader("x-api-key")) == 0 {
+			token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+			if ok {
+				c.Request.Header.Set("x-api-key", token)
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func taxiiResponse(c *gin.Context, status int, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		taxiiErrorResponse(c, http.StatusInternalServerError, "failed to encode response", err.Error())
+		return
+	}
+
+	c.Data(status, taxii.MediaType, data)
+}
+
+func taxiiErrorResponse(c *gin.Context, status int, title, description string) {
+	data, _ := json.Marshal(taxii.Error{
+		Title:       title,
+		Description: description,
+		HTTPStatus:  strconv.Itoa(status),
+	})
+
+	c.Data(status, taxii.MediaType, data)
+}
@@ -0,0 +1,112 @@
+package routing
+
+import (
+	"domain_threat_intelligence_api/cmd/core"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"domain_threat_intelligence_api/cmd/integrations/taxii"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgtype"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// fakeTAXIIService serves defined hosts from single source and records hosts filters
+type fakeTAXIIService struct {
+	core.IBlacklistsService
+
+	hosts   []blacklistEntities.BlacklistedHost
+	filters []blacklistEntities.BlacklistSearchFilter
+}
+
+func (s *fakeTAXIIService) RetrieveAllSources() ([]blacklistEntities.BlacklistSource, error) {
+	return []blacklistEntities.BlacklistSource{{ID: blacklistEntities.SourceKaspersky, Name: "Kaspersky"}}, nil
+}
+
+func (s *fakeTAXIIService) RetrieveHostsByFilter(filter blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedHost, error) {
+	s.filters = append(s.filters, filter)
+	return s.hosts[:min(filter.Limit, len(s.hosts))], nil
+}
+
+func TestGetObjects(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	addedAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	changed := addedAfter.Add(time.Hour)
+
+	service := &fakeTAXIIService{hosts: []blacklistEntities.BlacklistedHost{
+		{UUID: pgtype.UUID{Bytes: [16]byte{1}, Status: pgtype.Present}, Type: "domain", Host: "active.com", CreatedAt: changed, UpdatedAt: changed},
+		{
+			UUID: pgtype.UUID{Bytes: [16]byte{2}, Status: pgtype.Present}, Type: "domain", Host: "removed.com", CreatedAt: addedAfter, UpdatedAt: addedAfter,
+			DeletedAt: gorm.DeletedAt{Time: changed, Valid: true},
+		},
+		{UUID: pgtype.UUID{Bytes: [16]byte{3}, Status: pgtype.Present}, Type: "domain", Host: "next.com", CreatedAt: changed, UpdatedAt: changed},
+	}}
+	router := &TAXIIRouter{service: service}
+
+	engine := gin.New()
+	engine.GET("/collections/:collection_id/objects/", router.GetObjects)
+
+	get := func(query url.Values) (*httptest.ResponseRecorder, taxii.Envelope) {
+		path := "/collections/" + blacklistEntities.SourceUUID(blacklistEntities.SourceKaspersky) + "/objects/?" + query.Encode()
+
+		response := httptest.NewRecorder()
+		engine.ServeHTTP(response, httptest.NewRequest(http.MethodGet, path, nil))
+
+		var envelope taxii.Envelope
+		_ = json.Unmarshal(response.Body.Bytes(), &envelope)
+
+		return response, envelope
+	}
+
+	response, envelope := get(url.Values{"added_after": {addedAfter.Format(time.RFC3339)}, "limit": {"2"}})
+	if response.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", response.Code, response.Body.String())
+	}
+
+	// deleted hosts are selected by added_after
+	filter := service.filters[0]
+	if filter.IsActive == nil || *filter.IsActive || !filter.UpdatedAfter.Equal(addedAfter) || filter.Limit != 3 || !filter.SortByUpdated {
+		t.Errorf("unexpected hosts filter: %+v", filter)
+	}
+
+	if len(envelope.Objects) != 2 || envelope.Objects[0].Revoked != nil || envelope.Objects[1].Revoked == nil || !*envelope.Objects[1].Revoked {
+		t.Errorf("deleted host not served as revoked indicator: %+v", envelope.Objects)
+	}
+
+	if last := response.Header().Get("X-TAXII-Date-Added-Last"); last != changed.Format(time.RFC3339Nano) {
+		t.Errorf("date added of revoked indicator expected as deletion time, got %s", last)
+	}
+
+	// next token points to the last object of page
+	if !envelope.More || envelope.Next != blacklistEntities.EncodeDeltaCursor(changed, service.hosts[1].UUID) {
+		t.Fatalf("unexpected next token: %+v", envelope)
+	}
+
+	response, _ = get(url.Values{"added_after": {addedAfter.Format(time.RFC3339)}, "next": {envelope.Next}})
+	if response.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", response.Code, response.Body.String())
+	}
+
+	if filter = service.filters[1]; !filter.UpdatedAfter.Equal(changed) || filter.UpdatedAfterUUID != service.hosts[1].UUID || filter.Offset != 0 {
+		t.Errorf("next page not selected by cursor: %+v", filter)
+	}
+
+	// first page without added_after contains only active hosts
+	get(url.Values{})
+
+	if filter = service.filters[2]; filter.IsActive != nil || filter.UpdatedAfter != nil {
+		t.Errorf("unexpected hosts filter: %+v", filter)
+	}
+
+	// next token without host UUID is not accepted
+	for _, next := range []string{"10", blacklistEntities.EncodeDeltaCursor(changed, pgtype.UUID{})} {
+		if response, _ = get(url.Values{"next": {next}}); response.Code != http.StatusBadRequest {
+			t.Errorf("got status %d of next token '%s'", response.Code, next)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"domain_threat_intelligence_api/api/rest"
 	"domain_threat_intelligence_api/cmd/core/repos"
 	"domain_threat_intelligence_api/cmd/core/services"
+	"domain_threat_intelligence_api/cmd/integrations/misp"
 	"domain_threat_intelligence_api/cmd/integrations/naumen"
 	"domain_threat_intelligence_api/cmd/mail"
 	"domain_threat_intelligence_api/configs"
@@ -57,9 +58,27 @@ func StartApp(staticCfg configs.StaticConfig, dynamicCfg *configs.DynamicConfigP
 	domainServices.SMTPService = mail.NewSMTPClient(dynamicCfg, dynamicUpdateChan)
 
 	// creating repositories and services
-	domainServices.BlacklistService = services.NewBlackListsServiceImpl(repos.NewBlacklistsRepoImpl(dbConn), domainServices.ServiceDeskService)
+	blacklistsRepo := repos.NewBlacklistsRepoImpl(dbConn)
+	domainServices.BlacklistService = services.NewBlackListsServiceImpl(blacklistsRepo, domainServices.ServiceDeskService)
+	domainServices.BlacklistImportFeedsService = services.NewBlacklistImportFeedsServiceImpl(repos.NewBlacklistImportFeedsRepoImpl(dbConn), domainServices.BlacklistService)
+	domainServices.BlacklistExportFeedsService = services.NewBlacklistExportFeedsServiceImpl(repos.NewBlacklistExportFeedsRepoImpl(dbConn), blacklistsRepo)
 	domainServices.SystemStateService = services.NewSystemStateServiceImpl(dynamicCfg)
 
+	domainServices.MISPService = misp.NewSynchronizer(misp.NewClient(dynamicCfg), domainServices.BlacklistService)
+
+	interrupted, err := domainServices.BlacklistService.FailInterruptedImportJobs()
+	if err != nil {
+		return err
+	}
+
+	if interrupted > 0 {
+		slog.Warn(fmt.Sprintf("%d import jobs interrupted by previous run marked as failed", interrupted))
+	}
+
+	go domainServices.BlacklistImportFeedsService.StartScheduler(time.Minute)
+	go domainServices.MISPService.StartScheduler(time.Minute)
+	go domainServices.BlacklistService.StartExpirer(time.Minute)
+
 	usersRepo := repos.NewUsersRepoImpl(dbConn)
 	domainServices.AuthService = services.NewAuthServiceImpl(usersRepo, domainServices.SMTPService, "salt", staticCfg.WebServer.Security.Domain, staticCfg.WebServer.Security.AllowedOrigins[0])
 	domainServices.UsersService = services.NewUsersServiceImpl(usersRepo, domainServices.AuthService)
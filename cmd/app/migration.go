@@ -6,6 +6,8 @@ import (
 	"domain_threat_intelligence_api/cmd/core/entities/scanEntities"
 	"domain_threat_intelligence_api/cmd/core/entities/serviceDeskEntities"
 	"domain_threat_intelligence_api/cmd/core/entities/userEntities"
+	"fmt"
+	"github.com/jackc/pgtype"
 	"gorm.io/gorm"
 	"log/slog"
 )
@@ -13,14 +15,38 @@ import (
 func runMigrations(database *gorm.DB) error {
 	slog.Info("running migrations...")
 
-	err := database.AutoMigrate(
+	// hosts saved before canonical form was introduced are detected before original value columns are created
+	canonicalTables := pendingCanonicalHostTables(database)
+
+	// sightings of existing hosts are created once, when sightings table is created
+	sightingsPending := !database.Migrator().HasTable(blacklistEntities.BlacklistSighting{})
+
+	// duplicates must be merged before unique indexes by host value are created
+	err := mergeDuplicateHosts(database)
+	if err != nil {
+		return err
+	}
+
+	err = database.AutoMigrate(
 		serviceDeskEntities.ServiceDeskTicket{},
-		blacklistEntities.BlacklistImportEvent{},
 		blacklistEntities.BlacklistSource{},
+		blacklistEntities.BlacklistImportFeed{},
+		blacklistEntities.ImportFeedTAXIIBookmark{},
+		blacklistEntities.BlacklistImportEvent{},
+		blacklistEntities.BlacklistImportError{},
+		blacklistEntities.BlacklistImportProfile{},
+		blacklistEntities.BlacklistTTLPolicy{},
+		blacklistEntities.AllowlistEntry{},
+		blacklistEntities.BlacklistTag{},
+		blacklistEntities.BlacklistSighting{},
+		blacklistEntities.BlacklistExportFeed{},
+		blacklistEntities.BlacklistExportFeedToken{},
+		blacklistEntities.BlacklistExportFeedAccess{},
 		blacklistEntities.BlacklistedDomain{},
 		blacklistEntities.BlacklistedIP{},
 		blacklistEntities.BlacklistedURL{},
 		blacklistEntities.BlacklistedEmail{},
+		blacklistEntities.BlacklistHostRevision{},
 		networkEntities.NetworkNodeType{},
 		networkEntities.NetworkNode{},
 		networkEntities.NetworkNodeScan{},
@@ -34,12 +60,30 @@ func runMigrations(database *gorm.DB) error {
 		return err
 	}
 
+	err = migrateBlacklistIndexes(database)
+	if err != nil {
+		return err
+	}
+
+	err = migrateCanonicalHosts(database, canonicalTables)
+	if err != nil {
+		return err
+	}
+
 	// populating dictionary tables
 	err = migrateBlacklistSources(database)
 	if err != nil {
 		return err
 	}
 
+	// sightings reference sources, so they are migrated after sources
+	if sightingsPending {
+		err = migrateSightings(database)
+		if err != nil {
+			return err
+		}
+	}
+
 	err = migrateUserRoles(database)
 	if err != nil {
 		return err
@@ -71,6 +115,274 @@ func migrateBlacklistSources(database *gorm.DB) error {
 	return nil
 }
 
+// migrateBlacklistIndexes creates indexes not supported by model tags. GiST index is used by network containment lookups.
+func migrateBlacklistIndexes(database *gorm.DB) error {
+	err := database.Exec("CREATE INDEX IF NOT EXISTS idx_ip_address_gist ON blacklisted_ips USING gist (ip_address inet_ops)").Error
+	if err != nil {
+		slog.Error("error creating blacklist indexes: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// canonicalHostTables defines tables of hosts saved in canonical form: table, host type, value column, unique value
+// column, original value column and unique index by value
+var canonicalHostTables = [][6]string{
+	{"blacklisted_urls", "url", "url", "md5", "original_url", "idx_url_host"},
+	{"blacklisted_domains", "domain", "urn", "urn", "original_urn", "idx_domain_host"},
+}
+
+// canonicalHostsBatchSize limits number of hosts converted to canonical form in single query
+const canonicalHostsBatchSize = 10000
+
+// pendingCanonicalHostTables returns tables of hosts saved before canonical form was introduced, which do not have
+// original value column yet
+func pendingCanonicalHostTables(database *gorm.DB) [][6]string {
+	var pending [][6]string
+
+	for _, t := range canonicalHostTables {
+		if database.Migrator().HasTable(t[0]) && !database.Migrator().HasColumn(t[0], t[4]) {
+			pending = append(pending, t)
+		}
+	}
+
+	return pending
+}
+
+// migrateCanonicalHosts converts URLs and domains saved before canonical form was introduced. Value provided by source
+// is kept as original one, hosts which become same host are merged like hosts saved once for every source.
+func migrateCanonicalHosts(database *gorm.DB, tables [][6]string) error {
+	for _, t := range tables {
+		table, type_, column, unique, original, index := t[0], t[1], t[2], t[3], t[4], t[5]
+
+		err := database.Transaction(func(tx *gorm.DB) error {
+			err := tx.Exec("CREATE TEMPORARY TABLE canonical_hosts (uuid uuid PRIMARY KEY, value text NOT NULL, hash text NOT NULL) ON COMMIT DROP").Error
+			if err != nil {
+				return err
+			}
+
+			var last pgtype.UUID
+			for {
+				var hosts []struct {
+					UUID  pgtype.UUID
+					Value string
+				}
+
+				query := tx.Table(table).Select(fmt.Sprintf("uuid, %s AS value", column)).Order("uuid").Limit(canonicalHostsBatchSize)
+				if last.Status == pgtype.Present {
+					query = query.Where("uuid > ?", last)
+				}
+
+				err = query.Find(&hosts).Error
+				if err != nil {
+					return err
+				}
+
+				if len(hosts) == 0 {
+					break
+				}
+
+				var converted = make([]map[string]interface{}, 0, len(hosts))
+				for _, h := range hosts {
+					value, hash := canonicalHost(type_, h.Value)
+					converted = append(converted, map[string]interface{}{"uuid": h.UUID, "value": value, "hash": hash})
+				}
+
+				err = tx.Table("canonical_hosts").CreateInBatches(&converted, 1000).Error
+				if err != nil {
+					return err
+				}
+
+				last = hosts[len(hosts)-1].UUID
+			}
+
+			// converted hosts may collide with each other, so unique index is created again after they are merged
+			err = tx.Exec(fmt.Sprintf("DROP INDEX IF EXISTS %s", index)).Error
+			if err != nil {
+				return err
+			}
+
+			assignments := fmt.Sprintf("%[1]s = %[2]s.%[1]s, %[3]s = c.value", original, table, column)
+			if unique != column {
+				assignments += fmt.Sprintf(", %s = c.hash", unique)
+			}
+
+			err = tx.Exec(fmt.Sprintf("UPDATE %s SET %s FROM canonical_hosts AS c WHERE %[1]s.uuid = c.uuid", table, assignments)).Error
+			if err != nil {
+				return err
+			}
+
+			err = mergeHostDuplicates(tx, table, type_, unique)
+			if err != nil {
+				return err
+			}
+
+			return tx.Exec(fmt.Sprintf("CREATE UNIQUE INDEX %s ON %s (%s)", index, table, unique)).Error
+		})
+
+		if err != nil {
+			slog.Error(fmt.Sprintf("error converting %s to canonical form: %s", table, err.Error()))
+			return err
+		}
+	}
+
+	return nil
+}
+
+// canonicalHost returns canonical form of host value and its unique value: URL hash for URLs, same value for domains.
+// Invalid domains are kept as they are.
+func canonicalHost(type_, value string) (string, string) {
+	if type_ == "url" {
+		canonical := blacklistEntities.NormalizeURL(value)
+		return canonical, blacklistEntities.URLHash(canonical)
+	}
+
+	canonical, err := blacklistEntities.CanonicalDomain(value)
+	if err != nil {
+		return value, value
+	}
+
+	return canonical, canonical
+}
+
+// duplicateHostTables defines tables of hosts saved once for every source before sightings were introduced:
+// table, host type, unique host value column, previous unique index by value and source
+var duplicateHostTables = [][4]string{
+	{"blacklisted_ips", "ip", "ip_address", "idx_ip"},
+	{"blacklisted_domains", "domain", "urn", "idx_domain"},
+	{"blacklisted_urls", "url", "md5", "idx_url"},
+	{"blacklisted_emails", "email", "email", "idx_email"},
+}
+
+// mergeDuplicateHosts merges hosts saved once for every source into single host. The active host created first is kept,
+// all sources of merged hosts are saved as its sightings, tags are moved to it and lifetime is extended. Runs only
+// while unique index by value and source exists.
+func mergeDuplicateHosts(database *gorm.DB) error {
+	for _, t := range duplicateHostTables {
+		table, type_, column, index := t[0], t[1], t[2], t[3]
+
+		if !database.Migrator().HasIndex(table, index) {
+			continue
+		}
+
+		// sightings are saved while hosts are merged, so their table is created before other tables
+		err := database.AutoMigrate(blacklistEntities.BlacklistSighting{})
+		if err != nil {
+			slog.Error("error migrating sightings schema: " + err.Error())
+			return err
+		}
+
+		err = database.Transaction(func(tx *gorm.DB) error {
+			err := mergeHostDuplicates(tx, table, type_, column)
+			if err != nil {
+				return err
+			}
+
+			return tx.Migrator().DropIndex(table, index)
+		})
+
+		if err != nil {
+			slog.Error(fmt.Sprintf("error merging duplicates of %s: %s", table, err.Error()))
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mergeHostDuplicates merges hosts with same value into single host. The active host created first is kept, sources
+// of merged hosts are saved as its sightings, sightings, tags and revisions of merged hosts are moved to it and
+// lifetime is extended.
+func mergeHostDuplicates(tx *gorm.DB, table, type_, column string) error {
+	// every duplicate is mapped to kept host
+	err := tx.Exec(fmt.Sprintf("CREATE TEMPORARY TABLE host_duplicates ON COMMIT DROP AS "+
+		"SELECT uuid, keeper FROM (SELECT uuid, "+
+		"first_value(uuid) OVER (PARTITION BY %[2]s ORDER BY deleted_at IS NOT NULL, created_at, uuid) AS keeper, "+
+		"count(*) OVER (PARTITION BY %[2]s) AS count FROM %[1]s) AS ranked WHERE count > 1", table, column)).Error
+	if err != nil {
+		return err
+	}
+
+	// sightings of same source are merged, so every kept host is updated once by every source
+	err = tx.Exec("INSERT INTO blacklist_sightings (host_id, host_type, source_id, import_event_id, description, first_seen_at, last_seen_at) "+
+		"SELECT d.keeper, ?, s.source_id, max(s.import_event_id), max(s.description), min(s.first_seen_at), max(s.last_seen_at) "+
+		"FROM blacklist_sightings AS s JOIN host_duplicates AS d ON d.uuid = s.host_id WHERE s.host_type = ? AND d.uuid <> d.keeper "+
+		"GROUP BY d.keeper, s.source_id "+
+		"ON CONFLICT (host_id, host_type, source_id) DO UPDATE SET "+
+		"first_seen_at = LEAST(blacklist_sightings.first_seen_at, excluded.first_seen_at), "+
+		"last_seen_at = GREATEST(blacklist_sightings.last_seen_at, excluded.last_seen_at), "+
+		"import_event_id = COALESCE(excluded.import_event_id, blacklist_sightings.import_event_id)", type_, type_).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Exec("DELETE FROM blacklist_sightings WHERE host_type = ? AND host_id IN (SELECT uuid FROM host_duplicates WHERE uuid <> keeper)", type_).Error
+	if err != nil {
+		return err
+	}
+
+	// deleted duplicates are not sightings any more, unless all hosts are deleted
+	err = tx.Exec(fmt.Sprintf("INSERT INTO blacklist_sightings (host_id, host_type, source_id, import_event_id, description, first_seen_at, last_seen_at) "+
+		"SELECT d.keeper, ?, COALESCE(h.source_id, ?), h.import_event_id, h.description, "+
+		"COALESCE(h.discovered_at, h.created_at, now()), COALESCE(h.updated_at, h.created_at, now()) "+
+		"FROM %s AS h JOIN host_duplicates AS d ON d.uuid = h.uuid WHERE h.deleted_at IS NULL OR d.uuid = d.keeper "+
+		"ON CONFLICT DO NOTHING", table), type_, blacklistEntities.SourceUnknown).Error
+	if err != nil {
+		return err
+	}
+
+	if tx.Migrator().HasColumn(table, "valid_until") {
+		err = tx.Exec(fmt.Sprintf("UPDATE %[1]s SET valid_until = merged.valid_until FROM "+
+			"(SELECT d.keeper, CASE WHEN bool_or(h.valid_until IS NULL) THEN NULL ELSE max(h.valid_until) END AS valid_until "+
+			"FROM %[1]s AS h JOIN host_duplicates AS d ON d.uuid = h.uuid WHERE h.deleted_at IS NULL GROUP BY d.keeper) AS merged "+
+			"WHERE %[1]s.uuid = merged.keeper", table)).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	tagsTable := fmt.Sprintf("blacklisted_%s_tags", type_)
+	if tx.Migrator().HasTable(tagsTable) {
+		err = tx.Exec(fmt.Sprintf("INSERT INTO %s (host_uuid, tag_id) "+
+			"SELECT d.keeper, t.tag_id FROM %[1]s AS t JOIN host_duplicates AS d ON d.uuid = t.host_uuid WHERE d.uuid <> d.keeper "+
+			"ON CONFLICT DO NOTHING", tagsTable)).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	if tx.Migrator().HasTable("blacklist_host_revisions") {
+		err = tx.Exec("UPDATE blacklist_host_revisions SET host_id = d.keeper FROM host_duplicates AS d "+
+			"WHERE blacklist_host_revisions.host_type = ? AND blacklist_host_revisions.host_id = d.uuid AND d.uuid <> d.keeper", type_).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE uuid IN (SELECT uuid FROM host_duplicates WHERE uuid <> keeper)", table)).Error
+}
+
+// migrateSightings creates sightings of hosts saved before sightings were introduced from their sources. Runs once,
+// when sightings table is created.
+func migrateSightings(database *gorm.DB) error {
+	for _, t := range duplicateHostTables {
+		table, type_ := t[0], t[1]
+
+		err := database.Exec(fmt.Sprintf("INSERT INTO blacklist_sightings (host_id, host_type, source_id, import_event_id, description, first_seen_at, last_seen_at) "+
+			"SELECT h.uuid, ?, COALESCE(h.source_id, ?), h.import_event_id, h.description, "+
+			"COALESCE(h.discovered_at, h.created_at, now()), COALESCE(h.updated_at, h.created_at, now()) FROM %s AS h "+
+			"WHERE NOT EXISTS (SELECT 1 FROM blacklist_sightings AS s WHERE s.host_type = ? AND s.host_id = h.uuid)", table), type_, blacklistEntities.SourceUnknown, type_).Error
+
+		if err != nil {
+			slog.Error("error migrating sightings: " + err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
+
 func migrateUserRoles(database *gorm.DB) error {
 	for _, r := range userEntities.DefaultUserPermissions {
 		err := database.
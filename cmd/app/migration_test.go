@@ -0,0 +1,359 @@
+package app
+
+import (
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"fmt"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testDB returns database defined by TEST_POSTGRES_DSN with empty schema, which is dropped after test.
+// Test is skipped if database is not defined.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if len(dsn) == 0 {
+		t.Skip("TEST_POSTGRES_DSN is not defined")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// migrations run own transactions, so schema is isolated by search path of single connection
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sqlDB.SetMaxOpenConns(1)
+
+	schema := fmt.Sprintf("migration_test_%d", time.Now().UnixNano())
+	for _, query := range []string{"CREATE SCHEMA " + schema, "SET search_path TO " + schema} {
+		if err = db.Exec(query).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Cleanup(func() {
+		db.Exec("DROP SCHEMA " + schema + " CASCADE")
+		sqlDB.Close()
+	})
+
+	return db
+}
+
+// legacyHostsDB returns database migrated by current schema, where domains are unique by value and source and
+// sightings table does not exist, as they were saved before sightings were introduced
+func legacyHostsDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db := testDB(t)
+
+	if err := runMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range []string{
+		"DROP INDEX idx_domain_host",
+		"CREATE UNIQUE INDEX idx_domain ON blacklisted_domains (urn, source_id)",
+		"DROP TABLE blacklist_sightings",
+	} {
+		if err := db.Exec(query).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return db
+}
+
+// domainSightings returns source IDs of sightings by domain
+func domainSightings(t *testing.T, db *gorm.DB) map[string][]uint64 {
+	t.Helper()
+
+	var rows []struct {
+		URN      string
+		SourceID uint64
+	}
+
+	err := db.Raw("SELECT d.urn, s.source_id FROM blacklist_sightings AS s JOIN blacklisted_domains AS d ON d.uuid = s.host_id " +
+		"WHERE s.host_type = 'domain' ORDER BY d.urn, s.source_id").Scan(&rows).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sightings = make(map[string][]uint64)
+	for _, r := range rows {
+		sightings[r.URN] = append(sightings[r.URN], r.SourceID)
+	}
+
+	return sightings
+}
+
+func TestMergeDuplicateHosts(t *testing.T) {
+	db := legacyHostsDB(t)
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	later, latest := created.AddDate(0, 1, 0), created.AddDate(0, 2, 0)
+
+	tag := blacklistEntities.BlacklistTag{Name: "APT28", Kind: blacklistEntities.TagKindActor}
+
+	domains := []blacklistEntities.BlacklistedDomain{
+		// deleted host created first is not kept, and its source is not a sighting
+		{URN: "evil.com", SourceID: blacklistEntities.SourceFinCERT, CreatedAt: created, DeletedAt: gorm.DeletedAt{Time: later, Valid: true}},
+		{URN: "evil.com", SourceID: blacklistEntities.SourceKaspersky, CreatedAt: created.Add(time.Hour), ValidUntil: &later},
+		{URN: "evil.com", SourceID: blacklistEntities.SourceDrWeb, CreatedAt: created.Add(2 * time.Hour), ValidUntil: &latest, Tags: []blacklistEntities.BlacklistTag{tag}},
+		{URN: "other.com", SourceID: blacklistEntities.SourceManual, CreatedAt: created},
+	}
+
+	if err := db.Create(&domains).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+
+	var merged []blacklistEntities.BlacklistedDomain
+	if err := db.Unscoped().Preload("Tags").Order("urn ASC").Find(&merged).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(merged) != 2 || merged[0].UUID != domains[1].UUID || merged[1].UUID != domains[3].UUID {
+		t.Fatalf("unexpected hosts kept: %+v", merged)
+	}
+
+	// tags are moved to kept host, its lifetime is extended to the latest one
+	if len(merged[0].Tags) != 1 || merged[0].Tags[0].Name != tag.Name {
+		t.Errorf("tags not moved to kept host: %+v", merged[0].Tags)
+	}
+
+	if merged[0].ValidUntil == nil || !merged[0].ValidUntil.Equal(latest) {
+		t.Errorf("got valid until %v, want %s", merged[0].ValidUntil, latest)
+	}
+
+	want := map[string][]uint64{
+		"evil.com":  {blacklistEntities.SourceKaspersky, blacklistEntities.SourceDrWeb},
+		"other.com": {blacklistEntities.SourceManual},
+	}
+
+	if got := domainSightings(t, db); !reflect.DeepEqual(got, want) {
+		t.Errorf("got sightings %v, want %v", got, want)
+	}
+
+	if db.Migrator().HasIndex("blacklisted_domains", "idx_domain") {
+		t.Error("unique index by value and source not dropped")
+	}
+
+	// migrations run again change nothing
+	if err := runMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := domainSightings(t, db); !reflect.DeepEqual(got, want) {
+		t.Errorf("got sightings %v after second migration, want %v", got, want)
+	}
+}
+
+func TestMergeDuplicateHostsPreservesSightings(t *testing.T) {
+	db := legacyHostsDB(t)
+
+	firstSeen := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	domains := []blacklistEntities.BlacklistedDomain{
+		{URN: "evil.com", SourceID: blacklistEntities.SourceKaspersky, Description: "phishing", DiscoveredAt: firstSeen},
+		{URN: "evil.com", SourceID: blacklistEntities.SourceDrWeb, Description: "malware"},
+	}
+
+	if err := db.Create(&domains).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+
+	var sightings []blacklistEntities.BlacklistSighting
+	if err := db.Order("source_id ASC").Find(&sightings).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// descriptions and discovery dates of every source are kept in its sighting
+	if len(sightings) != 2 {
+		t.Fatalf("expected 2 sightings, got %+v", sightings)
+	}
+
+	if s := sightings[0]; s.SourceID != blacklistEntities.SourceKaspersky || s.Description != "phishing" || !s.FirstSeenAt.Equal(firstSeen) {
+		t.Errorf("unexpected sighting: %+v", s)
+	}
+
+	if s := sightings[1]; s.SourceID != blacklistEntities.SourceDrWeb || s.Description != "malware" || s.HostID != domains[0].UUID {
+		t.Errorf("unexpected sighting: %+v", s)
+	}
+}
+
+func TestMigrateSightings(t *testing.T) {
+	db := testDB(t)
+
+	if err := runMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+
+	// hosts saved before sightings were introduced have no sightings
+	domain := blacklistEntities.BlacklistedDomain{URN: "evil.com", SourceID: blacklistEntities.SourceKaspersky}
+	if err := db.Create(&domain).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := migrateSightings(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string][]uint64{"evil.com": {blacklistEntities.SourceKaspersky}}
+	if got := domainSightings(t, db); !reflect.DeepEqual(got, want) {
+		t.Errorf("got sightings %v, want %v", got, want)
+	}
+
+	// sightings are created once with sightings table, so migrations run again do not create them
+	other := blacklistEntities.BlacklistedDomain{URN: "other.com", SourceID: blacklistEntities.SourceManual}
+	if err := db.Create(&other).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := domainSightings(t, db); !reflect.DeepEqual(got, want) {
+		t.Errorf("got sightings %v after second migration, want %v", got, want)
+	}
+}
+
+// legacyCanonicalDB returns database migrated by current schema with URLs and domains saved as they were before
+// canonical form was introduced: without original values and unique indexes by canonical value
+func legacyCanonicalDB(t *testing.T, hosts ...interface{}) *gorm.DB {
+	t.Helper()
+
+	db := testDB(t)
+
+	if err := runMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, h := range hosts {
+		if err := db.Create(h).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, query := range []string{
+		"DROP INDEX idx_url_host",
+		"DROP INDEX idx_domain_host",
+		"ALTER TABLE blacklisted_urls DROP COLUMN original_url",
+		"ALTER TABLE blacklisted_domains DROP COLUMN original_urn",
+	} {
+		if err := db.Exec(query).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return db
+}
+
+func TestMigrateCanonicalHosts(t *testing.T) {
+	firstSeen := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// domains have sightings of their sources, URLs were saved before sightings were introduced
+	domains := []blacklistEntities.BlacklistedDomain{
+		{URN: "Evil.COM.", SourceID: blacklistEntities.SourceKaspersky, CreatedAt: created, Sightings: []blacklistEntities.BlacklistSighting{
+			blacklistEntities.NewBlacklistSighting(blacklistEntities.SourceKaspersky, nil, "phishing", firstSeen),
+		}},
+		{URN: "evil.com", SourceID: blacklistEntities.SourceDrWeb, CreatedAt: created.Add(time.Hour), Sightings: []blacklistEntities.BlacklistSighting{
+			blacklistEntities.NewBlacklistSighting(blacklistEntities.SourceDrWeb, nil, "malware", created),
+		}},
+	}
+
+	urls := []blacklistEntities.BlacklistedURL{
+		{URL: "HTTP://Evil.com:80/a", MD5: blacklistEntities.URLHash("HTTP://Evil.com:80/a"), SourceID: blacklistEntities.SourceKaspersky, CreatedAt: created},
+		{URL: "http://evil.com/a", MD5: blacklistEntities.URLHash("http://evil.com/a"), SourceID: blacklistEntities.SourceDrWeb, CreatedAt: created.Add(time.Hour)},
+	}
+
+	db := legacyCanonicalDB(t, &domains, &urls)
+
+	// migrations run again change nothing
+	for i := 0; i < 2; i++ {
+		if err := runMigrations(db); err != nil {
+			t.Fatal(err)
+		}
+
+		var merged []blacklistEntities.BlacklistedDomain
+		if err := db.Preload("Sightings", func(db *gorm.DB) *gorm.DB { return db.Order("source_id") }).Find(&merged).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		// host created first is kept with canonical value, value provided by its source is kept as original one
+		if len(merged) != 1 || merged[0].UUID != domains[0].UUID || merged[0].URN != "evil.com" || merged[0].OriginalURN != "Evil.COM." {
+			t.Fatalf("unexpected domains: %+v", merged)
+		}
+
+		// sightings of merged hosts are moved to kept host with their descriptions and dates
+		sightings := merged[0].Sightings
+		if len(sightings) != 2 || sightings[0].Description != "phishing" || !sightings[0].FirstSeenAt.Equal(firstSeen) ||
+			sightings[1].SourceID != blacklistEntities.SourceDrWeb || sightings[1].Description != "malware" {
+			t.Errorf("unexpected sightings: %+v", sightings)
+		}
+
+		var mergedURLs []blacklistEntities.BlacklistedURL
+		if err := db.Preload("Sightings", func(db *gorm.DB) *gorm.DB { return db.Order("source_id") }).Find(&mergedURLs).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		if len(mergedURLs) != 1 {
+			t.Fatalf("unexpected URLs: %+v", mergedURLs)
+		}
+
+		url := mergedURLs[0]
+		if url.UUID != urls[0].UUID || url.URL != "http://evil.com/a" || url.MD5 != blacklistEntities.URLHash("http://evil.com/a") || url.OriginalURL != "HTTP://Evil.com:80/a" {
+			t.Errorf("unexpected URL: %+v", url)
+		}
+
+		// sources of hosts without sightings become sightings
+		if len(url.Sightings) != 2 || url.Sightings[0].SourceID != blacklistEntities.SourceKaspersky || url.Sightings[1].SourceID != blacklistEntities.SourceDrWeb {
+			t.Errorf("unexpected URL sightings: %+v", url.Sightings)
+		}
+
+		for _, index := range [][2]string{{"blacklisted_urls", "idx_url_host"}, {"blacklisted_domains", "idx_domain_host"}} {
+			if !db.Migrator().HasIndex(index[0], index[1]) {
+				t.Errorf("unique index %s not created again", index[1])
+			}
+		}
+	}
+}
+
+func TestCanonicalHost(t *testing.T) {
+	tests := []struct {
+		type_      string
+		value      string
+		want       string
+		wantUnique string
+	}{
+		{type_: "url", value: "HTTP://Evil.com:80/a", want: "http://evil.com/a", wantUnique: blacklistEntities.URLHash("http://evil.com/a")},
+		{type_: "domain", value: "Evil.COM.", want: "evil.com", wantUnique: "evil.com"},
+		{type_: "domain", value: "not a domain!", want: "not a domain!", wantUnique: "not a domain!"},
+	}
+
+	for _, tt := range tests {
+		if got, unique := canonicalHost(tt.type_, tt.value); got != tt.want || unique != tt.wantUnique {
+			t.Errorf("canonicalHost(%s, %s) = %s, %s, want %s, %s", tt.type_, tt.value, got, unique, tt.want, tt.wantUnique)
+		}
+	}
+}
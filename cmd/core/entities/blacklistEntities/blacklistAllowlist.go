@@ -0,0 +1,225 @@
+package blacklistEntities
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jackc/pgtype"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// AllowlistEntry defines host, which must never be blacklisted, like own domains, CDNs or public DNS resolvers.
+// Hosts matching allowlist are rejected by all importers and save requests.
+type AllowlistEntry struct {
+	ID uint64 `json:"ID" gorm:"primaryKey"`
+
+	// Type defines how value is matched: ip (address or CIDR), domain or url (pattern, * matches any characters)
+	Type  string `json:"Type" gorm:"column:type;size:16;not null;uniqueIndex:idx_allowlist"`
+	Value string `json:"Value" gorm:"column:value;not null;uniqueIndex:idx_allowlist"`
+	// IncludeSubdomains allows domain and all its subdomains
+	IncludeSubdomains bool   `json:"IncludeSubdomains" gorm:"column:include_subdomains;not null;default:false"`
+	Description       string `json:"Description" gorm:"column:description;size:512"`
+
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+}
+
+// Normalize validates entry and converts its value to canonical form, same as blacklisted hosts are saved
+func (e AllowlistEntry) Normalize() (AllowlistEntry, error) {
+	switch e.Type {
+	case "ip":
+		ip, err := ParseInet(e.Value)
+		if err != nil {
+			return AllowlistEntry{}, err
+		}
+
+		e.Value = ip.IPNet.String()
+		e.IncludeSubdomains = false
+	case "domain":
+		domain, includeSubdomains, err := ParseDomainPattern(e.Value)
+		if err != nil {
+			return AllowlistEntry{}, err
+		}
+
+		e.Value = domain
+		e.IncludeSubdomains = e.IncludeSubdomains || includeSubdomains
+	case "url":
+		e.Value = NormalizeURL(e.Value)
+		e.IncludeSubdomains = false
+
+		if len(strings.Trim(e.Value, "*/")) == 0 {
+			return AllowlistEntry{}, errors.New("URL pattern matches all URLs")
+		}
+	default:
+		return AllowlistEntry{}, errors.New("type must be one of: ip, domain, url")
+	}
+
+	return e, nil
+}
+
+// Reason describes why host was rejected, used in import error log
+func (e AllowlistEntry) Reason() string {
+	if e.IncludeSubdomains {
+		return fmt.Sprintf("allowlisted by entry #%d (*.%s)", e.ID, e.Value)
+	}
+
+	return fmt.Sprintf("allowlisted by entry #%d (%s)", e.ID, e.Value)
+}
+
+// URLPattern returns regular expression matching canonical URLs by URL pattern. Patterns without scheme match URLs
+// with any scheme. Expression is compatible with PostgreSQL, so it is also used to find conflicting URLs.
+func URLPattern(pattern string) string {
+	expr := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, `.*`)
+
+	if !strings.Contains(pattern, "://") {
+		expr = `([a-z][a-z0-9+.-]*://)?` + expr
+	}
+
+	return "^" + expr + "$"
+}
+
+// URLHostPattern returns regular expression matching URLs with defined host, and its subdomains if includeSubdomains set
+func URLHostPattern(host string, includeSubdomains bool) string {
+	expr := `^([a-z][a-z0-9+.-]*://)?([^/@]*@)?`
+	if includeSubdomains {
+		expr += `([^/@:?#]*\.)?`
+	}
+
+	return expr + regexp.QuoteMeta(host) + `([/:?#]|$)`
+}
+
+// Allowlist matches hosts with allowlist entries
+type Allowlist struct {
+	networks []allowedNetwork
+	domains  []AllowlistEntry
+	urls     []allowedURL
+}
+
+type allowedNetwork struct {
+	network *net.IPNet
+	entry   AllowlistEntry
+}
+
+type allowedURL struct {
+	pattern *regexp.Regexp
+	entry   AllowlistEntry
+}
+
+// NewAllowlist prepares entries for matching, entries with invalid values are ignored
+func NewAllowlist(entries []AllowlistEntry) *Allowlist {
+	a := &Allowlist{}
+
+	for _, e := range entries {
+		switch e.Type {
+		case "ip":
+			ip, err := ParseInet(e.Value)
+			if err == nil {
+				a.networks = append(a.networks, allowedNetwork{network: ip.IPNet, entry: e})
+			}
+		case "domain":
+			a.domains = append(a.domains, e)
+		case "url":
+			pattern, err := regexp.Compile(URLPattern(e.Value))
+			if err == nil {
+				a.urls = append(a.urls, allowedURL{pattern: pattern, entry: e})
+			}
+		}
+	}
+
+	return a
+}
+
+// MatchIP checks if IP address or network overlaps with allowlisted network, so blacklisting it would block allowed host
+func (a *Allowlist) MatchIP(ip pgtype.Inet) (AllowlistEntry, bool) {
+	if ip.IPNet == nil {
+		return AllowlistEntry{}, false
+	}
+
+	for _, n := range a.networks {
+		if n.network.Contains(ip.IPNet.IP) || ip.IPNet.Contains(n.network.IP) {
+			return n.entry, true
+		}
+	}
+
+	return AllowlistEntry{}, false
+}
+
+// MatchDomain checks if domain is allowlisted itself or by parent domain with all subdomains. If domain is blacklisted
+// with all subdomains, it also matches allowlisted subdomains.
+func (a *Allowlist) MatchDomain(domain string, includeSubdomains bool) (AllowlistEntry, bool) {
+	domain, err := CanonicalDomain(domain)
+	if err != nil {
+		return AllowlistEntry{}, false
+	}
+
+	for _, e := range a.domains {
+		if e.Value == domain ||
+			(e.IncludeSubdomains && strings.HasSuffix(domain, "."+e.Value)) ||
+			(includeSubdomains && strings.HasSuffix(e.Value, "."+domain)) {
+			return e, true
+		}
+	}
+
+	return AllowlistEntry{}, false
+}
+
+// MatchURL checks if URL matches allowlisted URL pattern, or its host is allowlisted
+func (a *Allowlist) MatchURL(value string) (AllowlistEntry, bool) {
+	canonical := NormalizeURL(value)
+
+	for _, u := range a.urls {
+		if u.pattern.MatchString(canonical) {
+			return u.entry, true
+		}
+	}
+
+	raw := canonical
+	if !strings.Contains(raw, "://") {
+		raw = "//" + raw
+	}
+
+	parsedURL, err := url.Parse(raw)
+	if err != nil || len(parsedURL.Hostname()) == 0 {
+		return AllowlistEntry{}, false
+	}
+
+	if ip, err := ParseInet(parsedURL.Hostname()); err == nil {
+		return a.MatchIP(ip)
+	}
+
+	return a.MatchDomain(parsedURL.Hostname(), false)
+}
+
+// MatchEmail checks if domain of email address is allowlisted
+func (a *Allowlist) MatchEmail(email string) (AllowlistEntry, bool) {
+	i := strings.LastIndex(email, "@")
+	if i == -1 {
+		return AllowlistEntry{}, false
+	}
+
+	return a.MatchDomain(email[i+1:], false)
+}
+
+// MatchHost checks if blacklisted host of any type is allowlisted
+func (a *Allowlist) MatchHost(h BlacklistedHost) (AllowlistEntry, bool) {
+	switch h.Type {
+	case "ip":
+		ip, err := ParseInet(h.Host)
+		if err != nil {
+			return AllowlistEntry{}, false
+		}
+
+		return a.MatchIP(ip)
+	case "domain":
+		return a.MatchDomain(h.Host, h.IncludeSubdomains)
+	case "url":
+		return a.MatchURL(h.Host)
+	case "email":
+		return a.MatchEmail(h.Host)
+	}
+
+	return AllowlistEntry{}, false
+}
@@ -0,0 +1,141 @@
+package blacklistEntities
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestAllowlistEntryNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   AllowlistEntry
+		want    AllowlistEntry
+		wantErr bool
+	}{
+		{name: "address", entry: AllowlistEntry{Type: "ip", Value: "8.8.8.8"}, want: AllowlistEntry{Type: "ip", Value: "8.8.8.8/32"}},
+		{name: "network", entry: AllowlistEntry{Type: "ip", Value: "10.1.2.3/8"}, want: AllowlistEntry{Type: "ip", Value: "10.0.0.0/8"}},
+		{name: "domain", entry: AllowlistEntry{Type: "domain", Value: "Example.COM."}, want: AllowlistEntry{Type: "domain", Value: "example.com"}},
+		{name: "wildcard domain", entry: AllowlistEntry{Type: "domain", Value: "*.example.com"}, want: AllowlistEntry{Type: "domain", Value: "example.com", IncludeSubdomains: true}},
+		{name: "url pattern", entry: AllowlistEntry{Type: "url", Value: "HTTPS://Example.com/*", IncludeSubdomains: true}, want: AllowlistEntry{Type: "url", Value: "https://example.com/*"}},
+		{name: "pattern matching all urls", entry: AllowlistEntry{Type: "url", Value: "*"}, wantErr: true},
+		{name: "invalid address", entry: AllowlistEntry{Type: "ip", Value: "10.0.0.256"}, wantErr: true},
+		{name: "unknown type", entry: AllowlistEntry{Type: "email", Value: "a@example.com"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.entry.Normalize()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Normalize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowlistMatch(t *testing.T) {
+	allowlist := NewAllowlist([]AllowlistEntry{
+		{ID: 1, Type: "ip", Value: "8.8.8.0/24"},
+		{ID: 2, Type: "domain", Value: "example.com"},
+		{ID: 3, Type: "domain", Value: "cdn.net", IncludeSubdomains: true},
+		{ID: 4, Type: "url", Value: "evil.org/allowed/*"},
+		{ID: 5, Type: "ip", Value: "not an address"},
+		{ID: 6, Type: "domain", Value: "api.service.io"},
+	})
+
+	mustInet := func(value string) AllowlistEntry {
+		ip, err := ParseInet(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entry, _ := allowlist.MatchIP(ip)
+		return entry
+	}
+
+	tests := []struct {
+		name   string
+		got    AllowlistEntry
+		wantID uint64
+	}{
+		{name: "address in network", got: mustInet("8.8.8.8"), wantID: 1},
+		{name: "network containing allowlisted one", got: mustInet("8.0.0.0/8"), wantID: 1},
+		{name: "other address", got: mustInet("8.8.9.1")},
+		{name: "domain", got: first(allowlist.MatchDomain("EXAMPLE.com", false)), wantID: 2},
+		{name: "subdomain of domain without subdomains", got: first(allowlist.MatchDomain("www.example.com", false))},
+		{name: "subdomain", got: first(allowlist.MatchDomain("img.cdn.net", false)), wantID: 3},
+		{name: "wildcard covering allowlisted domain", got: first(allowlist.MatchDomain("service.io", true)), wantID: 6},
+		{name: "domain without subdomains", got: first(allowlist.MatchDomain("service.io", false))},
+		{name: "url pattern", got: first(allowlist.MatchURL("http://evil.org/allowed/a")), wantID: 4},
+		{name: "url not matching pattern", got: first(allowlist.MatchURL("http://evil.org/a"))},
+		{name: "url of allowlisted host", got: first(allowlist.MatchURL("https://user@img.cdn.net:8443/a")), wantID: 3},
+		{name: "url of allowlisted address", got: first(allowlist.MatchURL("http://8.8.8.8/a")), wantID: 1},
+		{name: "email", got: first(allowlist.MatchEmail("user@Example.com")), wantID: 2},
+		{name: "invalid email", got: first(allowlist.MatchEmail("example.com"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got.ID != tt.wantID {
+				t.Errorf("matched entry #%d, want #%d", tt.got.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestURLPatterns(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{pattern: URLPattern("example.com/*"), value: "https://example.com/a", want: true},
+		{pattern: URLPattern("example.com/*"), value: "https://example.com.evil.org/a"},
+		{pattern: URLPattern("https://example.com/a"), value: "http://example.com/a"},
+		{pattern: URLPattern("example.com/a.b"), value: "example.com/aXb"},
+		{pattern: URLHostPattern("example.com", false), value: "http://user@example.com:8080/a", want: true},
+		{pattern: URLHostPattern("example.com", false), value: "http://www.example.com/a"},
+		{pattern: URLHostPattern("example.com", true), value: "http://www.example.com/a", want: true},
+		{pattern: URLHostPattern("example.com", true), value: "http://badexample.com/a"},
+	}
+
+	for _, tt := range tests {
+		if got := regexp.MustCompile(tt.pattern).MatchString(tt.value); got != tt.want {
+			t.Errorf("%s matching %s = %v, want %v", tt.pattern, tt.value, got, tt.want)
+		}
+	}
+}
+
+// first returns matched entry, dropping match flag
+func first(entry AllowlistEntry, _ bool) AllowlistEntry {
+	return entry
+}
+
+func TestAllowlistMatchHost(t *testing.T) {
+	allowlist := NewAllowlist([]AllowlistEntry{
+		{ID: 1, Type: "ip", Value: "8.8.8.0/24"},
+		{ID: 2, Type: "domain", Value: "example.com", IncludeSubdomains: true},
+	})
+
+	tests := []struct {
+		host   BlacklistedHost
+		wantID uint64
+	}{
+		{host: BlacklistedHost{Type: "ip", Host: "8.8.8.8/32"}, wantID: 1},
+		{host: BlacklistedHost{Type: "domain", Host: "www.example.com"}, wantID: 2},
+		{host: BlacklistedHost{Type: "url", Host: "http://example.com/a"}, wantID: 2},
+		{host: BlacklistedHost{Type: "email", Host: "user@example.com"}, wantID: 2},
+		{host: BlacklistedHost{Type: "domain", Host: "evil.com"}},
+		{host: BlacklistedHost{Type: "ip", Host: "malformed"}},
+		{host: BlacklistedHost{Type: "hash", Host: "example.com"}},
+	}
+
+	for _, tt := range tests {
+		if entry, _ := allowlist.MatchHost(tt.host); entry.ID != tt.wantID {
+			t.Errorf("%s %s matched entry #%d, want #%d", tt.host.Type, tt.host.Host, entry.ID, tt.wantID)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package blacklistEntities
+
+// BlacklistCheckResult describes if single checked value is blacklisted and lists all matching blacklist entries
+type BlacklistCheckResult struct {
+	Value string `json:"Value"`
+	// Type is detected type of checked value: ip, domain, url or email. Empty if type not detected.
+	Type string `json:"Type"`
+
+	IsBlacklisted bool                  `json:"IsBlacklisted"`
+	Matches       []BlacklistCheckMatch `json:"Matches"`
+
+	Error string `json:"Error,omitempty"`
+}
+
+// BlacklistCheckMatch describes single blacklist entry matching checked value and how it was matched
+type BlacklistCheckMatch struct {
+	MatchType CheckMatchType `json:"MatchType"`
+
+	BlacklistedHost
+}
+
+type CheckMatchType string
+
+const (
+	CheckMatchExact        CheckMatchType = "exact"         // same value is blacklisted
+	CheckMatchNetwork      CheckMatchType = "network"       // IP address is covered by blacklisted network
+	CheckMatchParentDomain CheckMatchType = "parent-domain" // parent domain is blacklisted without subdomains
+	CheckMatchWildcard     CheckMatchType = "wildcard"      // parent domain is blacklisted with all subdomains
+	CheckMatchURLHost      CheckMatchType = "url-host"      // host of URL is blacklisted
+	CheckMatchEmailDomain  CheckMatchType = "email-domain"  // domain of email address is blacklisted
+)
@@ -0,0 +1,97 @@
+package blacklistEntities
+
+import (
+	"encoding/base64"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgtype"
+	"strings"
+	"time"
+)
+
+// BlacklistDeltaFilter selects hosts changed since cursor or timestamp. If both are not defined, all hosts are returned.
+// Limit defines max number of hosts in single delta, default limit is used if not defined.
+type BlacklistDeltaFilter struct {
+	Cursor    string     `json:"Cursor" form:"cursor"`
+	Since     *time.Time `json:"Since" form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	SourceIDs []uint64   `json:"SourceId" form:"source_id[]" binding:"dive"`
+	Limit     int        `json:"Limit" form:"limit" binding:"min=0"`
+}
+
+// BlacklistDelta contains hosts added, updated and removed since cursor. Cursor should be used in next delta request.
+// More is set if delta was cut by limit, so next delta should be requested immediately.
+type BlacklistDelta struct {
+	Cursor string     `json:"Cursor"`
+	Since  *time.Time `json:"Since"`
+	More   bool       `json:"More"`
+
+	Added   []BlacklistedHost `json:"Added"`
+	Updated []BlacklistedHost `json:"Updated"`
+	Removed []BlacklistedHost `json:"Removed"`
+}
+
+// DeltaChange defines how host was changed since cursor
+type DeltaChange string
+
+const (
+	DeltaChangeAdded   DeltaChange = "added"
+	DeltaChangeUpdated DeltaChange = "updated"
+	DeltaChangeRemoved DeltaChange = "removed"
+)
+
+// ChangeSince returns how host was changed since defined time
+func (h *BlacklistedHost) ChangeSince(since *time.Time) DeltaChange {
+	if h.DeletedAt.Valid {
+		return DeltaChangeRemoved
+	} else if since == nil || h.CreatedAt.After(*since) {
+		return DeltaChangeAdded
+	}
+
+	return DeltaChangeUpdated
+}
+
+// ChangedAt returns time of the latest host change, including deletion
+func (h *BlacklistedHost) ChangedAt() time.Time {
+	if h.DeletedAt.Valid && h.DeletedAt.Time.After(h.UpdatedAt) {
+		return h.DeletedAt.Time
+	}
+
+	return h.UpdatedAt
+}
+
+// EncodeDeltaCursor returns opaque cursor pointing to change of defined host. Host UUID orders hosts changed at the
+// same time, if it is not defined, cursor points to all changes made before defined time.
+func EncodeDeltaCursor(t time.Time, id pgtype.UUID) string {
+	value := t.UTC().Format(time.RFC3339Nano)
+	if id.Status == pgtype.Present {
+		value += " " + uuid.UUID(id.Bytes).String()
+	}
+
+	return base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+// DecodeDeltaCursor returns time and host UUID cursor points to
+func DecodeDeltaCursor(cursor string) (time.Time, pgtype.UUID, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, pgtype.UUID{}, errors.New("delta cursor malformed")
+	}
+
+	value, id, hasID := strings.Cut(string(b), " ")
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, pgtype.UUID{}, errors.New("delta cursor malformed")
+	}
+
+	if !hasID {
+		return t, pgtype.UUID{}, nil
+	}
+
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, pgtype.UUID{}, errors.New("delta cursor malformed")
+	}
+
+	return t, pgtype.UUID{Bytes: parsed, Status: pgtype.Present}, nil
+}
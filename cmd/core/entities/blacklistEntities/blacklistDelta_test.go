@@ -0,0 +1,62 @@
+package blacklistEntities
+
+import (
+	"encoding/base64"
+	"github.com/jackc/pgtype"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestBlacklistedHostChangeSince(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	deleted := gorm.DeletedAt{Time: since.Add(time.Hour), Valid: true}
+
+	tests := []struct {
+		name  string
+		host  BlacklistedHost
+		since *time.Time
+		want  DeltaChange
+	}{
+		{name: "first delta", host: BlacklistedHost{CreatedAt: since.Add(-time.Hour)}, want: DeltaChangeAdded},
+		{name: "created after cursor", host: BlacklistedHost{CreatedAt: since.Add(time.Hour)}, since: &since, want: DeltaChangeAdded},
+		{name: "created before cursor", host: BlacklistedHost{CreatedAt: since.Add(-time.Hour), UpdatedAt: since.Add(time.Hour)}, since: &since, want: DeltaChangeUpdated},
+		{name: "deleted", host: BlacklistedHost{CreatedAt: since.Add(time.Minute), DeletedAt: deleted}, since: &since, want: DeltaChangeRemoved},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.host.ChangeSince(tt.since); got != tt.want {
+				t.Errorf("ChangeSince() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	host := BlacklistedHost{UpdatedAt: since, DeletedAt: deleted}
+	if !host.ChangedAt().Equal(deleted.Time) {
+		t.Errorf("deletion time expected as time of the latest change, got %s", host.ChangedAt())
+	}
+}
+
+func TestDeltaCursor(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 30, 0, 123456789, time.FixedZone("UTC+3", 3*60*60))
+	id := pgtype.UUID{Bytes: [16]byte{1, 2, 3}, Status: pgtype.Present}
+
+	for _, want := range []pgtype.UUID{id, {}} {
+		got, gotID, err := DecodeDeltaCursor(EncodeDeltaCursor(now, want))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !got.Equal(now) || gotID != want {
+			t.Errorf("got %s %v, want %s %v", got, gotID, now, want)
+		}
+	}
+
+	malformed := base64.RawURLEncoding.EncodeToString([]byte(now.Format(time.RFC3339Nano) + " not-uuid"))
+	for _, cursor := range []string{"not base64!", EncodeDeltaCursor(time.Time{}, pgtype.UUID{})[:4], malformed} {
+		if _, _, err := DecodeDeltaCursor(cursor); err == nil {
+			t.Errorf("expected error of cursor '%s'", cursor)
+		}
+	}
+}
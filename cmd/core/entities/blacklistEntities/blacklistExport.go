@@ -0,0 +1,34 @@
+package blacklistEntities
+
+// ExportFormat defines format of blacklist export, used by network devices and DNS servers
+type ExportFormat string
+
+const (
+	ExportFormatText     ExportFormat = "txt"      // all hosts, one per line
+	ExportFormatHosts    ExportFormat = "hosts"    // hosts file, domains resolved to 0.0.0.0
+	ExportFormatRPZ      ExportFormat = "rpz"      // BIND/Unbound response policy zone
+	ExportFormatSquid    ExportFormat = "squid"    // Squid dstdomain ACL file
+	ExportFormatIPTables ExportFormat = "iptables" // ipset restore file, used with iptables
+	ExportFormatNFTables ExportFormat = "nftables" // nftables set definitions
+	ExportFormatSuricata ExportFormat = "suricata" // Suricata IP reputation list
+	ExportFormatSnort    ExportFormat = "snort"    // Snort IP reputation blacklist
+)
+
+// formats of delta export
+const (
+	ExportFormatJSON ExportFormat = "json"
+	ExportFormatCSV  ExportFormat = "csv"
+	ExportFormatSTIX ExportFormat = "stix"
+)
+
+// ExportFormats lists all supported blacklist list export formats
+var ExportFormats = []ExportFormat{
+	ExportFormatText,
+	ExportFormatHosts,
+	ExportFormatRPZ,
+	ExportFormatSquid,
+	ExportFormatIPTables,
+	ExportFormatNFTables,
+	ExportFormatSuricata,
+	ExportFormatSnort,
+}
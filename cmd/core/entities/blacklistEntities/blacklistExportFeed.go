@@ -0,0 +1,99 @@
+package blacklistEntities
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"time"
+)
+
+// BlacklistExportFeed defines named public feed with saved filter and format, pulled by network devices with feed tokens
+type BlacklistExportFeed struct {
+	ID uint64 `json:"ID" gorm:"primaryKey"`
+
+	Name        string       `json:"Name" gorm:"column:name;size:128;not null;unique"`
+	Description string       `json:"Description" gorm:"column:description;size:512"`
+	Format      ExportFormat `json:"Format" gorm:"column:format;size:16;not null"`
+	IsEnabled   bool         `json:"IsEnabled" gorm:"column:is_enabled;default:true"`
+
+	Filter datatypes.JSONType[BlacklistExportFilter] `json:"Filter" gorm:"column:filter"`
+
+	// Tokens contains all tokens issued to feed consumers
+	Tokens []BlacklistExportFeedToken `json:"Tokens,omitempty" gorm:"foreignKey:FeedID"`
+
+	CreatedAt time.Time      `json:"CreatedAt"`
+	UpdatedAt time.Time      `json:"UpdatedAt"`
+	DeletedAt gorm.DeletedAt `json:"DeletedAt,omitempty" gorm:"index"`
+}
+
+// BlacklistExportFeedToken is long-lived token issued to single feed consumer. Only token hash is stored.
+type BlacklistExportFeedToken struct {
+	ID uint64 `json:"ID" gorm:"primaryKey"`
+
+	Feed   *BlacklistExportFeed `json:"Feed,omitempty" gorm:"foreignKey:FeedID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	FeedID uint64               `json:"FeedID" gorm:"column:feed_id;not null;index"`
+
+	// Consumer describes device or system that uses token
+	Consumer string `json:"Consumer" gorm:"column:consumer;size:128;not null"`
+
+	// Token is returned only once, right after token is issued
+	Token     string `json:"Token,omitempty" gorm:"-"`
+	TokenHash string `json:"-" gorm:"column:token_hash;size:64;not null;uniqueIndex"`
+
+	ExpiresAt    *time.Time `json:"ExpiresAt" gorm:"column:expires_at"`
+	LastAccessAt *time.Time `json:"LastAccessAt" gorm:"column:last_access_at"`
+
+	CreatedAt time.Time      `json:"CreatedAt"`
+	UpdatedAt time.Time      `json:"UpdatedAt"`
+	DeletedAt gorm.DeletedAt `json:"DeletedAt,omitempty" gorm:"index"`
+}
+
+// BlacklistExportFeedAccess describes single feed request made with feed token
+type BlacklistExportFeedAccess struct {
+	ID uint64 `json:"ID" gorm:"primaryKey"`
+
+	Token   *BlacklistExportFeedToken `json:"Token,omitempty" gorm:"foreignKey:TokenID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	TokenID uint64                    `json:"TokenID" gorm:"column:token_id;not null;index"`
+	FeedID  uint64                    `json:"FeedID" gorm:"column:feed_id;not null;index"`
+
+	RemoteAddr string `json:"RemoteAddr" gorm:"column:remote_addr;size:64"`
+	UserAgent  string `json:"UserAgent" gorm:"column:user_agent;size:512"`
+	StatusCode int    `json:"StatusCode" gorm:"column:status_code"`
+
+	CreatedAt time.Time `json:"CreatedAt" gorm:"index"`
+}
+
+// BlacklistExportFeedAccessFilter is used to select feed access log
+type BlacklistExportFeedAccessFilter struct {
+	Offset  int    `json:"Offset" form:"offset"`
+	Limit   int    `json:"Limit" form:"limit" binding:"required"`
+	FeedID  uint64 `json:"FeedID" form:"feed_id"`
+	TokenID uint64 `json:"TokenID" form:"token_id"`
+}
+
+// IsValid checks if token is not expired at defined time
+func (t *BlacklistExportFeedToken) IsValid(now time.Time) bool {
+	return t.ExpiresAt == nil || t.ExpiresAt.After(now)
+}
+
+// GenerateExportFeedToken returns new random feed token and its hash
+func GenerateExportFeedToken() (string, string, error) {
+	b := make([]byte, 32)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", "", err
+	}
+
+	token := hex.EncodeToString(b)
+
+	return token, HashExportFeedToken(token), nil
+}
+
+// HashExportFeedToken returns hash of feed token, which is stored in database
+func HashExportFeedToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
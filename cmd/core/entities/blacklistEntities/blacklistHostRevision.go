@@ -0,0 +1,96 @@
+package blacklistEntities
+
+import (
+	"domain_threat_intelligence_api/cmd/core/entities/userEntities"
+	"github.com/jackc/pgtype"
+	"gorm.io/datatypes"
+	"slices"
+	"time"
+)
+
+// BlacklistHostRevision records single change of blacklisted host: creation, update, deletion or restore.
+// Revisions are never updated, so they show who changed host, when, and what host looked like before and after change.
+type BlacklistHostRevision struct {
+	ID uint64 `json:"ID" gorm:"primaryKey"`
+
+	// HostID and HostType reference blacklisted host of any type: ip, domain, url or email
+	HostID   pgtype.UUID `json:"HostID" gorm:"column:host_id;type:uuid;not null;index:idx_revision_host"`
+	HostType string      `json:"HostType" gorm:"column:host_type;size:16;not null;index:idx_revision_host"`
+
+	// Action is one of: create, update, delete, restore
+	Action string `json:"Action" gorm:"column:action;size:16;not null"`
+	// Reason describes changes made automatically, like expiration
+	Reason string `json:"Reason,omitempty" gorm:"column:reason"`
+
+	// User defines user who changed host, not defined for automatic changes
+	User   *userEntities.PlatformUser `json:"User,omitempty" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	UserID *uint64                    `json:"UserID" gorm:"column:user_id;index"`
+
+	// ImportEvent describes import session which changed host
+	ImportEvent   *BlacklistImportEvent `json:"ImportEvent,omitempty" gorm:"foreignKey:ImportEventID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	ImportEventID *uint64               `json:"ImportEventID" gorm:"column:import_event_id;index"`
+
+	// Before and After contain host state before and after change, Before is empty for created hosts
+	Before datatypes.JSONType[*BlacklistedHost] `json:"Before" gorm:"column:before;type:jsonb"`
+	After  datatypes.JSONType[*BlacklistedHost] `json:"After" gorm:"column:after;type:jsonb"`
+
+	CreatedAt time.Time `json:"CreatedAt" gorm:"column:created_at;index"`
+}
+
+const (
+	RevisionActionCreate  = "create"
+	RevisionActionUpdate  = "update"
+	RevisionActionDelete  = "delete"
+	RevisionActionRestore = "restore"
+)
+
+// RevisionReasonExpired is reason of deletion of hosts expired by lifetime
+const RevisionReasonExpired = "expired"
+
+// NewBlacklistHostRevision compares host states before and after save or delete and creates revision describing change.
+// Returns false if host was not changed. Before is nil for hosts not saved before.
+func NewBlacklistHostRevision(before *BlacklistedHost, after BlacklistedHost) (BlacklistHostRevision, bool) {
+	var action string
+
+	switch {
+	case before == nil:
+		action = RevisionActionCreate
+	case before.DeletedAt.Valid && !after.DeletedAt.Valid:
+		action = RevisionActionRestore
+	case !before.DeletedAt.Valid && after.DeletedAt.Valid:
+		action = RevisionActionDelete
+	case !before.SameState(after):
+		action = RevisionActionUpdate
+	default:
+		return BlacklistHostRevision{}, false
+	}
+
+	// snapshots keep host attributes only, linked entities are loaded separately
+	after.Source, after.ImportEvent, after.Tags, after.Sightings = nil, nil, nil, nil
+	if before != nil {
+		snapshot := *before
+		snapshot.Source, snapshot.ImportEvent, snapshot.Tags, snapshot.Sightings = nil, nil, nil, nil
+		before = &snapshot
+	}
+
+	return BlacklistHostRevision{
+		HostID:   after.UUID,
+		HostType: after.Type,
+		Action:   action,
+		Before:   datatypes.NewJSONType(before),
+		After:    datatypes.NewJSONType(&after),
+	}, true
+}
+
+// SameState reports whether hosts have same value, description, threat attributes and deletion state. Lifetime and
+// dates are not compared, so hosts imported again without changes are not revised on every import.
+func (h BlacklistedHost) SameState(other BlacklistedHost) bool {
+	return h.Host == other.Host &&
+		h.Description == other.Description &&
+		h.IncludeSubdomains == other.IncludeSubdomains &&
+		h.Confidence == other.Confidence &&
+		h.Severity == other.Severity &&
+		slices.Equal(h.Categories, other.Categories) &&
+		h.KillChainPhase == other.KillChainPhase &&
+		h.DeletedAt.Valid == other.DeletedAt.Valid
+}
@@ -0,0 +1,56 @@
+package blacklistEntities
+
+import (
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestNewBlacklistHostRevision(t *testing.T) {
+	host := BlacklistedHost{Type: "domain", Host: "evil.com", Description: "phishing", Source: &BlacklistSource{Name: "Kaspersky"}}
+
+	changed := host
+	changed.Description = "malware"
+
+	extended := host
+	validUntil := time.Now().AddDate(0, 1, 0)
+	extended.ValidUntil, extended.UpdatedAt = &validUntil, time.Now()
+
+	deleted := host
+	deleted.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+
+	tests := []struct {
+		name       string
+		before     *BlacklistedHost
+		after      BlacklistedHost
+		wantAction string
+	}{
+		{name: "created", after: host, wantAction: RevisionActionCreate},
+		{name: "updated", before: &host, after: changed, wantAction: RevisionActionUpdate},
+		{name: "deleted", before: &host, after: deleted, wantAction: RevisionActionDelete},
+		{name: "restored", before: &deleted, after: host, wantAction: RevisionActionRestore},
+		{name: "imported again without changes", before: &host, after: extended},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revision, ok := NewBlacklistHostRevision(tt.before, tt.after)
+			if ok != (len(tt.wantAction) > 0) || revision.Action != tt.wantAction {
+				t.Fatalf("got action %q (%v), want %q", revision.Action, ok, tt.wantAction)
+			}
+
+			if !ok {
+				return
+			}
+
+			if (tt.before == nil) != (revision.Before.Data() == nil) || revision.After.Data().Host != tt.after.Host {
+				t.Errorf("unexpected snapshots: %+v, %+v", revision.Before.Data(), revision.After.Data())
+			}
+
+			// snapshots keep host attributes only, hosts are not changed
+			if revision.After.Data().Source != nil || tt.after.Source == nil {
+				t.Errorf("linked source kept in snapshot or removed from host")
+			}
+		})
+	}
+}
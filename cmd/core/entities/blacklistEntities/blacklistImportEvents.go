@@ -14,6 +14,19 @@ type BlacklistImportEvent struct {
 	Type       string                                          `json:"Type" gorm:"column:type"`
 	IsComplete bool                                            `json:"IsComplete" gorm:"column:is_complete"`
 
+	// IsCancelled defines if import job was cancelled before all hosts were saved. Hosts saved before cancellation are kept.
+	IsCancelled bool `json:"IsCancelled" gorm:"column:is_cancelled"`
+	// Error contains reason why import job failed
+	Error string `json:"Error,omitempty" gorm:"column:error"`
+
+	// Source defines source all imported hosts were attributed to, if source was defined on import
+	Source   *BlacklistSource `json:"Source,omitempty" gorm:"foreignKey:SourceID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	SourceID *uint64          `json:"SourceID" gorm:"column:source_id"`
+
+	// Feed defines remote import feed, if import event was created by scheduled feed run
+	Feed   *BlacklistImportFeed `json:"Feed,omitempty" gorm:"foreignKey:FeedID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	FeedID *uint64              `json:"FeedID" gorm:"column:feed_id"`
+
 	// CreatedBy defines import event creator identity
 	CreatedBy   *userEntities.PlatformUser `json:"CreatedBy"`
 	CreatedByID *uint64                    `json:"CreatedByID" gorm:"column:created_by_id"`
@@ -37,6 +50,65 @@ type BlacklistImportEventSummary struct {
 		Domains int64 `json:"Domains"`
 		Emails  int64 `json:"Emails"`
 	} `json:"New"`
+	// Progress is updated by import job after every saved batch of hosts
+	Progress struct {
+		Total     int64 `json:"Total"`
+		Processed int64 `json:"Processed"`
+	} `json:"Progress"`
 	Skipped int64 `json:"Skipped"`
 	Errored int   `json:"Errored"`
+	// Allowlisted counts hosts rejected by allowlist
+	Allowlisted int64 `json:"Allowlisted"`
 }
+
+// BlacklistImportError describes single value, which could not be parsed or saved on import
+type BlacklistImportError struct {
+	ID uint64 `json:"ID,omitempty" gorm:"primaryKey"`
+
+	ImportEvent   *BlacklistImportEvent `json:"ImportEvent,omitempty" gorm:"foreignKey:ImportEventID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	ImportEventID uint64                `json:"ImportEventID,omitempty" gorm:"column:import_event_id;index"`
+
+	// Location defines position of value in imported data, like row number or bundle and object index
+	Location string `json:"Location" gorm:"column:location"`
+	// Type defines indicator type, if it was defined in imported data
+	Type   string `json:"Type" gorm:"column:type"`
+	Value  string `json:"Value" gorm:"column:value"`
+	Reason string `json:"Reason" gorm:"column:reason"`
+
+	CreatedAt time.Time `json:"CreatedAt,omitempty"`
+}
+
+// BlacklistImportJob describes import running in background. Only data matching job type is used.
+type BlacklistImportJob struct {
+	Type         string
+	SourceID     uint64
+	ExtractAll   bool
+	DiscoveredAt time.Time
+
+	STIX2Bundles []STIX2Bundle
+	CSV          [][]string
+	MISPEvents   []MISPEvent
+
+	// CSVProfile describes CSV columns layout, FinCERT layout is used if not defined
+	CSVProfile *BlacklistImportProfile
+
+	// Values contains plain list of hosts of any type, used by txt and text imports
+	Values []string
+
+	// PreviewID defines import preview to commit, used by preview import
+	PreviewID string
+
+	// CreatedByID defines user who started import, import preview can be committed only by user who created it
+	CreatedByID *uint64
+	// FeedID defines import feed which started import, if any
+	FeedID *uint64
+}
+
+const (
+	ImportJobSTIX    = "stix"
+	ImportJobCSV     = "csv"
+	ImportJobMISP    = "misp"
+	ImportJobTxt     = "txt"
+	ImportJobText    = "text"
+	ImportJobPreview = "preview"
+)
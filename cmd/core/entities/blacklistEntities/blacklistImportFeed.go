@@ -0,0 +1,88 @@
+package blacklistEntities
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// BlacklistImportFeed defines remote indicators feed, which is pulled periodically and imported into blacklists
+type BlacklistImportFeed struct {
+	ID uint64 `json:"ID" gorm:"primaryKey"`
+
+	Name        string           `json:"Name" gorm:"column:name;size:128;not null;unique"`
+	Description string           `json:"Description" gorm:"column:description;size:512"`
+	URL         string           `json:"URL" gorm:"column:url;size:1024;not null"`
+	Format      ImportFeedFormat `json:"Format" gorm:"column:format;size:16;not null"`
+
+	// PollingInterval defines delay between feed runs in minutes
+	PollingInterval uint64 `json:"PollingInterval" gorm:"column:polling_interval;not null;default:60"`
+	IsEnabled       bool   `json:"IsEnabled" gorm:"column:is_enabled;default:true"`
+	ExtractAll      bool   `json:"ExtractAll" gorm:"column:extract_all;default:false"`
+
+	// TAXIICollectionID limits TAXII feed to single collection, if not defined all readable collections are pulled
+	TAXIICollectionID string `json:"TAXIICollectionID,omitempty" gorm:"column:taxii_collection_id;size:128"`
+
+	// Credentials used to access feed. If login defined, basic auth is used, if token defined, bearer auth is used
+	AuthLogin    string `json:"AuthLogin,omitempty" gorm:"column:auth_login;size:128"`
+	AuthPassword string `json:"AuthPassword,omitempty" gorm:"column:auth_password;size:256"`
+	AuthToken    string `json:"AuthToken,omitempty" gorm:"column:auth_token;size:1024"`
+
+	// Source defines which source all indicators from this feed will be attributed to
+	Source   *BlacklistSource `json:"Source,omitempty" gorm:"foreignKey:SourceID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	SourceID uint64           `json:"SourceID" gorm:"column:source_id;not null"`
+
+	// LastImportEventID describes latest import session created by this feed
+	LastImportEventID *uint64 `json:"LastImportEventID" gorm:"column:last_import_event_id"`
+
+	LastRunAt *time.Time `json:"LastRunAt" gorm:"column:last_run_at"`
+	LastError string     `json:"LastError" gorm:"column:last_error;size:1024"`
+
+	CreatedAt time.Time      `json:"CreatedAt"`
+	UpdatedAt time.Time      `json:"UpdatedAt"`
+	DeletedAt gorm.DeletedAt `json:"DeletedAt,omitempty" gorm:"index"`
+}
+
+type ImportFeedFormat string
+
+const (
+	ImportFeedFormatSTIX ImportFeedFormat = "stix"
+	ImportFeedFormatCSV  ImportFeedFormat = "csv"
+	ImportFeedFormatText ImportFeedFormat = "txt"
+
+	// ImportFeedFormatTAXII defines TAXII 2.1 server feed, feed URL must point to server discovery or API root
+	ImportFeedFormatTAXII ImportFeedFormat = "taxii"
+)
+
+// IsDue checks if feed should be pulled at defined time
+func (f *BlacklistImportFeed) IsDue(now time.Time) bool {
+	if !f.IsEnabled {
+		return false
+	}
+
+	if f.LastRunAt == nil {
+		return true
+	}
+
+	return f.LastRunAt.Add(time.Duration(f.PollingInterval) * time.Minute).Before(now)
+}
+
+// HideCredentials removes secrets from feed, used before sending feed to clients
+func (f *BlacklistImportFeed) HideCredentials() {
+	f.AuthPassword = ""
+	f.AuthToken = ""
+}
+
+// ImportFeedTAXIIBookmark stores date of the last object imported from TAXII collection, used as added_after value on next run
+type ImportFeedTAXIIBookmark struct {
+	ID uint64 `json:"ID" gorm:"primaryKey"`
+
+	Feed   *BlacklistImportFeed `json:"Feed,omitempty" gorm:"foreignKey:FeedID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	FeedID uint64               `json:"FeedID" gorm:"column:feed_id;not null;uniqueIndex:idx_taxii_bookmark"`
+
+	// CollectionURL defines collection by its API root and ID
+	CollectionURL string `json:"CollectionURL" gorm:"column:collection_url;size:1024;not null;uniqueIndex:idx_taxii_bookmark"`
+	AddedAfter    string `json:"AddedAfter" gorm:"column:added_after;size:64"`
+
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+}
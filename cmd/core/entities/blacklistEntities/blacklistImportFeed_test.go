@@ -0,0 +1,33 @@
+package blacklistEntities
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBlacklistImportFeedIsDue(t *testing.T) {
+	now := time.Now()
+	before := func(d time.Duration) *time.Time {
+		t := now.Add(-d)
+		return &t
+	}
+
+	tests := []struct {
+		name string
+		feed BlacklistImportFeed
+		want bool
+	}{
+		{name: "never run", feed: BlacklistImportFeed{IsEnabled: true, PollingInterval: 60}, want: true},
+		{name: "interval passed", feed: BlacklistImportFeed{IsEnabled: true, PollingInterval: 60, LastRunAt: before(61 * time.Minute)}, want: true},
+		{name: "interval not passed", feed: BlacklistImportFeed{IsEnabled: true, PollingInterval: 60, LastRunAt: before(59 * time.Minute)}, want: false},
+		{name: "disabled", feed: BlacklistImportFeed{PollingInterval: 60}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.feed.IsDue(now); got != tt.want {
+				t.Errorf("IsDue() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package blacklistEntities
+
+import "time"
+
+// BlacklistImportPreview describes result of import dry-run: which hosts would be added, which already exist and
+// which values are invalid. Preview is kept for limited time and can be committed by its ID without uploading file again.
+type BlacklistImportPreview struct {
+	ID       string  `json:"ID"`
+	Type     string  `json:"Type"`
+	SourceID *uint64 `json:"SourceID"`
+
+	Summary BlacklistImportPreviewSummary `json:"Summary"`
+
+	// New contains hosts not yet blacklisted by their source
+	New []BlacklistImportPreviewHost `json:"New"`
+	// Existing contains hosts already blacklisted by their source, which would only be updated
+	Existing []BlacklistImportPreviewHost `json:"Existing"`
+	// Invalid contains values that can not be imported
+	Invalid []BlacklistImportError `json:"Invalid"`
+	// Allowlisted contains hosts rejected by allowlist
+	Allowlisted []BlacklistImportError `json:"Allowlisted"`
+
+	// CreatedByID defines user who created preview, only this user can retrieve and commit it
+	CreatedByID *uint64 `json:"CreatedByID"`
+
+	CreatedAt time.Time `json:"CreatedAt"`
+	ExpiresAt time.Time `json:"ExpiresAt"`
+}
+
+type BlacklistImportPreviewSummary struct {
+	New      BlacklistImportPreviewCount `json:"New"`
+	Existing BlacklistImportPreviewCount `json:"Existing"`
+	Invalid  int64                       `json:"Invalid"`
+	Skipped  int64                       `json:"Skipped"`
+	// Allowlisted counts hosts rejected by allowlist
+	Allowlisted int64 `json:"Allowlisted"`
+}
+
+type BlacklistImportPreviewCount struct {
+	Total   int64 `json:"Total"`
+	IPs     int64 `json:"IPs"`
+	URLs    int64 `json:"URLs"`
+	Domains int64 `json:"Domains"`
+	Emails  int64 `json:"Emails"`
+}
+
+// Add counts single host of defined type
+func (c *BlacklistImportPreviewCount) Add(type_ string) {
+	switch type_ {
+	case "ip":
+		c.IPs++
+	case "url":
+		c.URLs++
+	case "domain":
+		c.Domains++
+	case "email":
+		c.Emails++
+	}
+
+	c.Total++
+}
+
+type BlacklistImportPreviewHost struct {
+	Host     string `json:"Host"`
+	Type     string `json:"Type"`
+	SourceID uint64 `json:"SourceID"`
+
+	// ExistingSourceIDs lists all sources, which already blacklisted the host
+	ExistingSourceIDs []uint64 `json:"ExistingSourceIDs"`
+}
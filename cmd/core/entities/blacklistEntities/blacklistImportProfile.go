@@ -0,0 +1,179 @@
+package blacklistEntities
+
+import (
+	"encoding/csv"
+	"errors"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// BlacklistImportProfile describes layout of CSV file: column names, delimiter, date layout and how type and source
+// columns values are mapped, so CSV files of different origin can be imported without code changes
+type BlacklistImportProfile struct {
+	ID uint64 `json:"ID" gorm:"primaryKey"`
+
+	Name        string `json:"Name" gorm:"column:name;size:128;not null;unique"`
+	Description string `json:"Description" gorm:"column:description;size:512"`
+
+	// Delimiter defines single character used to separate columns
+	Delimiter string `json:"Delimiter" gorm:"column:delimiter;size:4;not null;default:','"`
+
+	// Column names, as they are defined in file header. Only value column is required.
+	TypeColumn         string `json:"TypeColumn" gorm:"column:type_column;size:128"`
+	ValueColumn        string `json:"ValueColumn" gorm:"column:value_column;size:128;not null"`
+	SourceColumn       string `json:"SourceColumn" gorm:"column:source_column;size:128"`
+	DiscoveredAtColumn string `json:"DiscoveredAtColumn" gorm:"column:discovered_at_column;size:128"`
+	DescriptionColumn  string `json:"DescriptionColumn" gorm:"column:description_column;size:128"`
+
+	// Threat attribute columns. Category column can contain several categories separated by comma, semicolon or pipe,
+	// severity column can contain severity name or level from 1 (low) to 4 (critical).
+	ConfidenceColumn     string `json:"ConfidenceColumn" gorm:"column:confidence_column;size:128"`
+	SeverityColumn       string `json:"SeverityColumn" gorm:"column:severity_column;size:128"`
+	CategoryColumn       string `json:"CategoryColumn" gorm:"column:category_column;size:128"`
+	KillChainPhaseColumn string `json:"KillChainPhaseColumn" gorm:"column:kill_chain_phase_column;size:128"`
+
+	// DateLayout defines Go time layout of discovery date column, e.g. 2006-01-02
+	DateLayout string `json:"DateLayout" gorm:"column:date_layout;size:64"`
+
+	// DefaultType is used if type column is not defined or empty. If not defined, type is detected from value.
+	DefaultType string `json:"DefaultType" gorm:"column:default_type;size:16"`
+
+	// TypeMapping maps type column values to host types (ip, domain, url, email), e.g. "IPv4" -> "ip".
+	// Keys are case-insensitive, values of unknown types are skipped.
+	TypeMapping datatypes.JSONType[map[string]string] `json:"TypeMapping" gorm:"column:type_mapping"`
+
+	// SourceMapping maps source column values to blacklist source IDs, e.g. "Vendor-Kaspersky" -> 3
+	SourceMapping datatypes.JSONType[map[string]uint64] `json:"SourceMapping" gorm:"column:source_mapping"`
+	// DefaultSourceID is used if source column is not defined or its value not mapped, unknown source used if not defined
+	DefaultSourceID uint64 `json:"DefaultSourceID" gorm:"column:default_source_id"`
+
+	CreatedAt time.Time      `json:"CreatedAt"`
+	UpdatedAt time.Time      `json:"UpdatedAt"`
+	DeletedAt gorm.DeletedAt `json:"DeletedAt,omitempty" gorm:"index"`
+}
+
+// DefaultImportProfile returns profile of FinCERT CSV files, used if no profile selected on import
+func DefaultImportProfile() BlacklistImportProfile {
+	return BlacklistImportProfile{
+		Name:               "FinCERT",
+		Delimiter:          ",",
+		TypeColumn:         "Type_IOC",
+		ValueColumn:        "Value",
+		SourceColumn:       "Source",
+		DiscoveredAtColumn: "First Seen",
+		DescriptionColumn:  "Comment",
+		DateLayout:         "02.01.2006",
+		TypeMapping: datatypes.NewJSONType(map[string]string{
+			"ip-addres":  "ip",
+			"ip-address": "ip",
+		}),
+		SourceMapping: datatypes.NewJSONType(map[string]uint64{
+			"Vendor-Kaspersky": SourceKaspersky,
+			"Vendor-DRWEB":     SourceDrWeb,
+			"FinCERT":          SourceFinCERT,
+			"Vendor":           SourceFinCERT,
+		}),
+		DefaultSourceID: SourceUnknown,
+	}
+}
+
+// Validate checks if profile can be used to read CSV files
+func (p *BlacklistImportProfile) Validate() error {
+	if len(p.Name) == 0 {
+		return errors.New("profile name not defined")
+	}
+
+	if len(strings.TrimSpace(p.ValueColumn)) == 0 {
+		return errors.New("value column not defined")
+	}
+
+	if utf8.RuneCountInString(p.Delimiter) > 1 {
+		return errors.New("delimiter must be a single character")
+	}
+
+	if p.Delimiter == "\"" || p.Delimiter == "\n" || p.Delimiter == "\r" {
+		return errors.New("delimiter not supported")
+	}
+
+	// layout without any Go time elements, like dd.mm.yyyy, is formatted to itself
+	if len(p.DateLayout) > 0 && time.Now().Format(p.DateLayout) == p.DateLayout {
+		return errors.New("date layout must be defined in Go time layout format, e.g. 02.01.2006")
+	}
+
+	switch p.DefaultType {
+	case "", "ip", "domain", "url", "email":
+	default:
+		return errors.New("default type must be one of ip, domain, url or email")
+	}
+
+	for k, v := range p.TypeMapping.Data() {
+		switch v {
+		case "ip", "domain", "url", "email":
+		default:
+			return errors.New("type '" + k + "' mapped to unknown host type '" + v + "'")
+		}
+	}
+
+	return nil
+}
+
+// NewCSVReader returns CSV reader using profile delimiter
+func (p *BlacklistImportProfile) NewCSVReader(r io.Reader) *csv.Reader {
+	reader := csv.NewReader(r)
+
+	if d, _ := utf8.DecodeRuneInString(p.Delimiter); d != utf8.RuneError {
+		reader.Comma = d
+	}
+
+	// other tools often export rows with missing trailing columns
+	reader.FieldsPerRecord = -1
+
+	return reader
+}
+
+// HostType maps type column value to host type. Returns empty string if type is unknown.
+func (p *BlacklistImportProfile) HostType(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	for k, v := range p.TypeMapping.Data() {
+		if strings.ToLower(k) == value {
+			return v
+		}
+	}
+
+	switch value {
+	case "ip", "domain", "url", "email":
+		return value
+	}
+
+	return ""
+}
+
+// ThreatAttributes parses threat attribute columns values, unknown values are ignored
+func (p *BlacklistImportProfile) ThreatAttributes(confidence, severity, categories, killChainPhase string) ThreatAttributes {
+	return ThreatAttributes{
+		Confidence: ParseConfidence(confidence),
+		Severity:   ParseSeverity(severity),
+		Categories: ParseThreatCategories(strings.FieldsFunc(categories, func(r rune) bool {
+			return r == ',' || r == ';' || r == '|'
+		})),
+		KillChainPhase: ParseKillChainPhase(killChainPhase),
+	}
+}
+
+// Source maps source column value to blacklist source ID
+func (p *BlacklistImportProfile) Source(value string) uint64 {
+	if id, ok := p.SourceMapping.Data()[strings.TrimSpace(value)]; ok && id != 0 {
+		return id
+	}
+
+	if p.DefaultSourceID != 0 {
+		return p.DefaultSourceID
+	}
+
+	return SourceUnknown
+}
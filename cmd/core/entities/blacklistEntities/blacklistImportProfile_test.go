@@ -0,0 +1,73 @@
+package blacklistEntities
+
+import (
+	"gorm.io/datatypes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBlacklistImportProfileValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(p *BlacklistImportProfile)
+		wantErr bool
+	}{
+		{name: "default profile", modify: func(p *BlacklistImportProfile) {}},
+		{name: "no name", modify: func(p *BlacklistImportProfile) { p.Name = "" }, wantErr: true},
+		{name: "no value column", modify: func(p *BlacklistImportProfile) { p.ValueColumn = " " }, wantErr: true},
+		{name: "tab delimiter", modify: func(p *BlacklistImportProfile) { p.Delimiter = "\t" }},
+		{name: "long delimiter", modify: func(p *BlacklistImportProfile) { p.Delimiter = ";;" }, wantErr: true},
+		{name: "quote delimiter", modify: func(p *BlacklistImportProfile) { p.Delimiter = "\"" }, wantErr: true},
+		{name: "date layout not in Go format", modify: func(p *BlacklistImportProfile) { p.DateLayout = "dd.mm.yyyy" }, wantErr: true},
+		{name: "unknown default type", modify: func(p *BlacklistImportProfile) { p.DefaultType = "hash" }, wantErr: true},
+		{
+			name: "unknown mapped type",
+			modify: func(p *BlacklistImportProfile) {
+				p.TypeMapping = datatypes.NewJSONType(map[string]string{"sha256": "hash"})
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := DefaultImportProfile()
+			tt.modify(&p)
+
+			if err := p.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBlacklistImportProfileMapping(t *testing.T) {
+	p := DefaultImportProfile()
+
+	for value, want := range map[string]string{"IP-Address": "ip", " domain ": "domain", "URL": "url", "sha256": ""} {
+		if got := p.HostType(value); got != want {
+			t.Errorf("HostType(%s) = %s, want %s", value, got, want)
+		}
+	}
+
+	for value, want := range map[string]uint64{"Vendor-DRWEB": SourceDrWeb, "Vendor": SourceFinCERT, "other": SourceUnknown} {
+		if got := p.Source(value); got != want {
+			t.Errorf("Source(%s) = %d, want %d", value, got, want)
+		}
+	}
+}
+
+func TestBlacklistImportProfileNewCSVReader(t *testing.T) {
+	p := DefaultImportProfile()
+	p.Delimiter = ";"
+
+	lines, err := p.NewCSVReader(strings.NewReader("Value;Comment\n10.0.0.1\n")).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := [][]string{{"Value", "Comment"}, {"10.0.0.1"}}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("got %v, want %v", lines, want)
+	}
+}
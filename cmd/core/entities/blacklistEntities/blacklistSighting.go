@@ -0,0 +1,86 @@
+package blacklistEntities
+
+import (
+	"github.com/jackc/pgtype"
+	"time"
+)
+
+// BlacklistSighting records that blacklisted host was reported by source. Host is saved once for all sources,
+// every source which reported it has single sighting, so hosts reported by several sources are corroborated.
+type BlacklistSighting struct {
+	ID uint64 `json:"ID" gorm:"primaryKey"`
+
+	// HostID and HostType reference blacklisted host of any type: ip, domain, url or email
+	HostID   pgtype.UUID `json:"HostID" gorm:"column:host_id;type:uuid;not null;uniqueIndex:idx_sighting"`
+	HostType string      `json:"HostType" gorm:"column:host_type;size:16;not null;uniqueIndex:idx_sighting"`
+
+	// Source defines source which reported host
+	Source   *BlacklistSource `json:"Source,omitempty" gorm:"foreignKey:SourceID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	SourceID uint64           `json:"SourceID" gorm:"column:source_id;not null;uniqueIndex:idx_sighting;index"`
+
+	// ImportEvent describes the latest import session where host was reported by source
+	ImportEvent   *BlacklistImportEvent `json:"ImportEvent,omitempty" gorm:"foreignKey:ImportEventID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	ImportEventID *uint64               `json:"ImportEventID" gorm:"column:import_event_id;index"`
+
+	// Description is provided by source, descriptions of different sources are kept separately
+	Description string `json:"Description" gorm:"column:description"`
+
+	// FirstSeenAt is date of discovery provided by source, LastSeenAt is date when host was reported by source last time
+	FirstSeenAt time.Time `json:"FirstSeenAt" gorm:"column:first_seen_at;not null"`
+	LastSeenAt  time.Time `json:"LastSeenAt" gorm:"column:last_seen_at;not null"`
+}
+
+// NewBlacklistSighting creates sighting of host saved from source. Host is seen at discovery date, if it is defined.
+func NewBlacklistSighting(sourceID uint64, importEventID *uint64, description string, discoveredAt time.Time) BlacklistSighting {
+	now := time.Now()
+
+	firstSeenAt := discoveredAt
+	if firstSeenAt.IsZero() || firstSeenAt.After(now) {
+		firstSeenAt = now
+	}
+
+	return BlacklistSighting{
+		SourceID:      sourceID,
+		ImportEventID: importEventID,
+		Description:   description,
+		FirstSeenAt:   firstSeenAt,
+		LastSeenAt:    now,
+	}
+}
+
+// MergeSightings adds sightings to list, sightings of same source are merged: the earliest first seen date,
+// the latest last seen date and import event, and the latest defined description are kept
+func MergeSightings(sightings []BlacklistSighting, added ...BlacklistSighting) []BlacklistSighting {
+	for _, a := range added {
+		i := -1
+		for j, s := range sightings {
+			if s.SourceID == a.SourceID {
+				i = j
+				break
+			}
+		}
+
+		if i == -1 {
+			sightings = append(sightings, a)
+			continue
+		}
+
+		if a.FirstSeenAt.Before(sightings[i].FirstSeenAt) {
+			sightings[i].FirstSeenAt = a.FirstSeenAt
+		}
+
+		if a.LastSeenAt.After(sightings[i].LastSeenAt) {
+			sightings[i].LastSeenAt = a.LastSeenAt
+		}
+
+		if a.ImportEventID != nil {
+			sightings[i].ImportEventID = a.ImportEventID
+		}
+
+		if len(a.Description) > 0 {
+			sightings[i].Description = a.Description
+		}
+	}
+
+	return sightings
+}
@@ -0,0 +1,59 @@
+package blacklistEntities
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewBlacklistSighting(t *testing.T) {
+	discoveredAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s := NewBlacklistSighting(SourceKaspersky, nil, "c2", discoveredAt)
+	if s.SourceID != SourceKaspersky || s.Description != "c2" || !s.FirstSeenAt.Equal(discoveredAt) || time.Since(s.LastSeenAt) > time.Minute {
+		t.Errorf("unexpected sighting: %+v", s)
+	}
+
+	// hosts without discovery date or discovered in the future are seen now
+	for _, d := range []time.Time{{}, time.Now().AddDate(1, 0, 0)} {
+		if s = NewBlacklistSighting(SourceKaspersky, nil, "", d); time.Since(s.FirstSeenAt) > time.Minute {
+			t.Errorf("sighting of host discovered at %s first seen at %s", d, s.FirstSeenAt)
+		}
+	}
+}
+
+func TestMergeSightings(t *testing.T) {
+	jan, feb, mar := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	event := uint64(7)
+
+	sightings := []BlacklistSighting{{SourceID: SourceKaspersky, Description: "phishing", FirstSeenAt: feb, LastSeenAt: feb}}
+
+	sightings = MergeSightings(sightings,
+		BlacklistSighting{SourceID: SourceKaspersky, ImportEventID: &event, FirstSeenAt: jan, LastSeenAt: mar},
+		BlacklistSighting{SourceID: SourceDrWeb, Description: "malware", FirstSeenAt: mar, LastSeenAt: mar},
+	)
+
+	if len(sightings) != 2 {
+		t.Fatalf("expected sightings of 2 sources, got %+v", sightings)
+	}
+
+	// sightings of same source are merged, description is kept if new one is not defined
+	if s := sightings[0]; !s.FirstSeenAt.Equal(jan) || !s.LastSeenAt.Equal(mar) || s.ImportEventID != &event || s.Description != "phishing" {
+		t.Errorf("sightings not merged: %+v", s)
+	}
+
+	if s := sightings[1]; s.SourceID != SourceDrWeb || s.Description != "malware" {
+		t.Errorf("unexpected sighting: %+v", s)
+	}
+}
+
+func TestBlacklistedHostSourceNames(t *testing.T) {
+	host := BlacklistedHost{Source: &BlacklistSource{Name: "Kaspersky"}}
+	if names := host.SourceNames(); len(names) != 1 || names[0] != "Kaspersky" {
+		t.Errorf("expected first source without sightings, got %v", names)
+	}
+
+	host.Sightings = []BlacklistSighting{{Source: &BlacklistSource{Name: "Kaspersky"}}, {Source: &BlacklistSource{Name: "DrWEB"}}}
+	if names := host.SourceNames(); len(names) != 2 || names[1] != "DrWEB" {
+		t.Errorf("expected sources of all sightings, got %v", names)
+	}
+}
@@ -0,0 +1,80 @@
+package blacklistEntities
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// BlacklistTTLPolicy defines lifetime of blacklisted hosts by source and host type. Lifetime is set on every save,
+// so hosts imported again get their lifetime extended.
+type BlacklistTTLPolicy struct {
+	ID uint64 `json:"ID" gorm:"primaryKey"`
+
+	// SourceID limits policy to hosts of single source, 0 applies policy to hosts of all sources
+	SourceID uint64 `json:"SourceID" gorm:"column:source_id;not null;default:0;uniqueIndex:idx_ttl_policy"`
+	// Type limits policy to single host type: ip, domain, url or email. Empty type applies policy to all types.
+	Type string `json:"Type" gorm:"column:type;size:16;not null;default:'';uniqueIndex:idx_ttl_policy"`
+
+	TTLDays     uint64 `json:"TTLDays" gorm:"column:ttl_days;not null"`
+	Description string `json:"Description" gorm:"column:description;size:512"`
+
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+}
+
+func (p BlacklistTTLPolicy) Validate() error {
+	if p.TTLDays == 0 {
+		return errors.New("TTL must be at least 1 day")
+	}
+
+	if len(p.Type) > 0 && !slices.Contains([]string{"ip", "domain", "url", "email"}, p.Type) {
+		return errors.New("type must be one of: ip, domain, url, email")
+	}
+
+	return nil
+}
+
+// matches checks if policy applies to host and returns its priority. Policy of single source is preferred over
+// policy of single type, policies for all sources and types have the lowest priority.
+func (p BlacklistTTLPolicy) matches(sourceID uint64, type_ string) (int, bool) {
+	if (p.SourceID != 0 && p.SourceID != sourceID) || (len(p.Type) > 0 && p.Type != type_) {
+		return 0, false
+	}
+
+	priority := 0
+	if p.SourceID != 0 {
+		priority += 2
+	}
+
+	if len(p.Type) > 0 {
+		priority += 1
+	}
+
+	return priority, true
+}
+
+type BlacklistTTLPolicies []BlacklistTTLPolicy
+
+// ValidUntil returns expiration date of host by the most specific matching policy, nil if no policy matches
+func (policies BlacklistTTLPolicies) ValidUntil(sourceID uint64, type_ string, now time.Time) *time.Time {
+	var matched *BlacklistTTLPolicy
+	var matchedPriority int
+
+	for i, p := range policies {
+		priority, ok := p.matches(sourceID, type_)
+		if !ok || (matched != nil && priority <= matchedPriority) {
+			continue
+		}
+
+		matched = &policies[i]
+		matchedPriority = priority
+	}
+
+	if matched == nil {
+		return nil
+	}
+
+	validUntil := now.AddDate(0, 0, int(matched.TTLDays))
+	return &validUntil
+}
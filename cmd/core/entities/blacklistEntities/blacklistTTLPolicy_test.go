@@ -0,0 +1,60 @@
+package blacklistEntities
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBlacklistTTLPoliciesValidUntil(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	policies := BlacklistTTLPolicies{
+		{TTLDays: 90},
+		{Type: "url", TTLDays: 30},
+		{SourceID: SourceKaspersky, TTLDays: 14},
+		{SourceID: SourceKaspersky, Type: "url", TTLDays: 7},
+	}
+
+	tests := []struct {
+		name     string
+		policies BlacklistTTLPolicies
+		sourceID uint64
+		type_    string
+		wantDays int
+	}{
+		{name: "policy of all hosts", policies: policies, sourceID: SourceDrWeb, type_: "ip", wantDays: 90},
+		{name: "policy of type", policies: policies, sourceID: SourceDrWeb, type_: "url", wantDays: 30},
+		{name: "policy of source preferred over type", policies: policies[:3], sourceID: SourceKaspersky, type_: "url", wantDays: 14},
+		{name: "policy of source and type", policies: policies, sourceID: SourceKaspersky, type_: "url", wantDays: 7},
+		{name: "no matching policy", policies: policies[1:2], sourceID: SourceDrWeb, type_: "ip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policies.ValidUntil(tt.sourceID, tt.type_, now)
+
+			if tt.wantDays == 0 {
+				if got != nil {
+					t.Errorf("expected host without expiration date, got %s", got)
+				}
+				return
+			}
+
+			if want := now.AddDate(0, 0, tt.wantDays); got == nil || !got.Equal(want) {
+				t.Errorf("got %v, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestBlacklistTTLPolicyValidate(t *testing.T) {
+	for _, p := range []BlacklistTTLPolicy{{TTLDays: 0}, {TTLDays: 1, Type: "hash"}} {
+		if err := p.Validate(); err == nil {
+			t.Errorf("expected error of policy %+v", p)
+		}
+	}
+
+	if err := (BlacklistTTLPolicy{TTLDays: 1, Type: "ip"}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,137 @@
+package blacklistEntities
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// BlacklistTag groups blacklisted hosts by campaign, incident or threat actor. Tags are linked to hosts of all types.
+type BlacklistTag struct {
+	ID uint64 `json:"ID" gorm:"primaryKey"`
+
+	Name string `json:"Name" gorm:"column:name;size:256;not null;uniqueIndex:idx_tag"`
+	// Kind is one of: campaign, incident, actor. Empty kind is used for all other tags, like TLP markings.
+	Kind        string `json:"Kind" gorm:"column:kind;size:16;not null;default:'';uniqueIndex:idx_tag"`
+	Description string `json:"Description" gorm:"column:description;size:512"`
+
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+}
+
+// BlacklistTagCount contains tag with number of active hosts tagged by it
+type BlacklistTagCount struct {
+	BlacklistTag
+
+	Count int64 `json:"Count" gorm:"column:count"`
+}
+
+const (
+	TagKindCampaign = "campaign"
+	TagKindIncident = "incident"
+	TagKindActor    = "actor"
+)
+
+var TagKinds = []string{TagKindCampaign, TagKindIncident, TagKindActor}
+
+func (t BlacklistTag) Validate() error {
+	if len(strings.TrimSpace(t.Name)) == 0 {
+		return errors.New("tag name not defined")
+	}
+
+	switch t.Kind {
+	case "", TagKindCampaign, TagKindIncident, TagKindActor:
+	default:
+		return errors.New("kind must be one of: campaign, incident, actor")
+	}
+
+	return nil
+}
+
+// Key identifies tag by kind and name, same as unique index, so tags parsed from imported data can be mapped to saved tags
+func (t BlacklistTag) Key() string {
+	return t.Kind + "/" + t.Name
+}
+
+// Label returns tag as STIX label or MISP tag, kind is written as prefix, e.g. actor:APT28
+func (t BlacklistTag) Label() string {
+	if len(t.Kind) == 0 {
+		return t.Name
+	}
+
+	return t.Kind + ":" + t.Name
+}
+
+// tagGalaxyKinds maps MISP galaxies to tag kinds
+var tagGalaxyKinds = map[string]string{
+	"threat-actor":             TagKindActor,
+	"microsoft-activity-group": TagKindActor,
+	"campaign":                 TagKindCampaign,
+}
+
+// ParseTag converts STIX label or MISP tag to tag. Tags like actor:APT28, campaign:"Operation X" or
+// misp-galaxy:threat-actor="APT 28" get kind by prefix. MISP attribute metadata, like misp:type="url", is not a tag.
+func ParseTag(value string) (BlacklistTag, bool) {
+	value = strings.TrimSpace(value)
+
+	if len(value) == 0 || strings.HasPrefix(value, "misp:") {
+		return BlacklistTag{}, false
+	}
+
+	prefix, name, found := strings.Cut(value, ":")
+	if !found {
+		return BlacklistTag{Name: value}, true
+	}
+
+	var kind string
+	switch {
+	case strings.HasPrefix(prefix, "misp-galaxy"):
+		galaxy, galaxyName, ok := strings.Cut(name, "=")
+		if !ok {
+			return BlacklistTag{Name: value}, true
+		}
+
+		kind, name = tagGalaxyKinds[galaxy], galaxyName
+	case prefix == TagKindActor || prefix == TagKindCampaign || prefix == TagKindIncident:
+		kind = prefix
+	default:
+		return BlacklistTag{Name: value}, true
+	}
+
+	name = strings.TrimSpace(strings.Trim(name, "\""))
+	if len(name) == 0 {
+		return BlacklistTag{}, false
+	}
+
+	return BlacklistTag{Name: name, Kind: kind}, true
+}
+
+// ParseTags converts values to unique tags, values which are not tags are ignored
+func ParseTags(values []string) []BlacklistTag {
+	var tags []BlacklistTag
+
+	for _, v := range values {
+		if tag, ok := ParseTag(v); ok {
+			tags = append(tags, tag)
+		}
+	}
+
+	return UniqueTags(tags)
+}
+
+// UniqueTags returns tags without duplicates by kind and name
+func UniqueTags(tags []BlacklistTag) []BlacklistTag {
+	var result []BlacklistTag
+	var unique = make(map[string]bool)
+
+	for _, t := range tags {
+		if unique[t.Key()] {
+			continue
+		}
+
+		unique[t.Key()] = true
+		result = append(result, t)
+	}
+
+	return result
+}
@@ -0,0 +1,61 @@
+package blacklistEntities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   BlacklistTag
+		wantOk bool
+	}{
+		{value: "tlp:amber", want: BlacklistTag{Name: "tlp:amber"}, wantOk: true},
+		{value: " Emotet ", want: BlacklistTag{Name: "Emotet"}, wantOk: true},
+		{value: "actor:APT28", want: BlacklistTag{Name: "APT28", Kind: TagKindActor}, wantOk: true},
+		{value: `campaign:"Operation X"`, want: BlacklistTag{Name: "Operation X", Kind: TagKindCampaign}, wantOk: true},
+		{value: `misp-galaxy:threat-actor="APT 28"`, want: BlacklistTag{Name: "APT 28", Kind: TagKindActor}, wantOk: true},
+		{value: `misp-galaxy:tool="Mimikatz"`, want: BlacklistTag{Name: "Mimikatz"}, wantOk: true},
+		{value: `misp:type="url"`},
+		{value: `actor:""`},
+		{value: "  "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := ParseTag(tt.value)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("got %+v (%v), want %+v (%v)", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	got := ParseTags([]string{"actor:APT28", `misp-galaxy:threat-actor="APT28"`, "APT28", `misp:category="Network activity"`})
+	want := []BlacklistTag{{Name: "APT28", Kind: TagKindActor}, {Name: "APT28"}}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// label is parsed back to the same tag
+	for _, tag := range want {
+		if parsed, _ := ParseTag(tag.Label()); parsed != tag {
+			t.Errorf("tag %+v parsed from label %s as %+v", tag, tag.Label(), parsed)
+		}
+	}
+}
+
+func TestBlacklistTagValidate(t *testing.T) {
+	for _, tag := range []BlacklistTag{{Name: " "}, {Name: "APT28", Kind: "group"}} {
+		if err := tag.Validate(); err == nil {
+			t.Errorf("expected error of tag %+v", tag)
+		}
+	}
+
+	if err := (BlacklistTag{Name: "APT28", Kind: TagKindActor}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
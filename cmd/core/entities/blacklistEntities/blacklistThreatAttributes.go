@@ -0,0 +1,178 @@
+package blacklistEntities
+
+import (
+	"gorm.io/datatypes"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ThreatAttributes classifies blacklisted host: how confident source is, how dangerous host is and what kind of threat it is.
+// Attributes are embedded into all host types.
+type ThreatAttributes struct {
+	// Confidence is score in range 0-100, same as in STIX. 0 means confidence is not defined.
+	Confidence int `json:"Confidence" gorm:"column:confidence;not null;default:0"`
+	// Severity is one of: low, medium, high, critical. Empty if not defined.
+	Severity string `json:"Severity" gorm:"column:severity;size:16;not null;default:''"`
+	// Categories contains threat categories, like phishing or c2
+	Categories datatypes.JSONSlice[string] `json:"Categories" gorm:"column:categories;type:jsonb;not null;default:'[]'"`
+	// KillChainPhase is phase name of Lockheed Martin kill chain or MITRE ATT&CK tactic, like delivery or command-and-control
+	KillChainPhase string `json:"KillChainPhase" gorm:"column:kill_chain_phase;size:64;not null;default:''"`
+}
+
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// Severities contains all severities from the lowest to the highest
+var Severities = []string{SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+const (
+	ThreatCategoryPhishing            = "phishing"
+	ThreatCategoryC2                  = "c2"
+	ThreatCategoryMalwareDistribution = "malware-distribution"
+	ThreatCategoryScanning            = "scanning"
+	ThreatCategoryBruteforce          = "bruteforce"
+	ThreatCategorySpam                = "spam"
+	ThreatCategoryBotnet              = "botnet"
+	ThreatCategoryFraud               = "fraud"
+	ThreatCategoryAnonymization       = "anonymization"
+	ThreatCategoryCompromised         = "compromised"
+)
+
+var ThreatCategories = []string{
+	ThreatCategoryPhishing,
+	ThreatCategoryC2,
+	ThreatCategoryMalwareDistribution,
+	ThreatCategoryScanning,
+	ThreatCategoryBruteforce,
+	ThreatCategorySpam,
+	ThreatCategoryBotnet,
+	ThreatCategoryFraud,
+	ThreatCategoryAnonymization,
+	ThreatCategoryCompromised,
+}
+
+// threatCategoryAliases maps names used by vendors and STIX indicator types to threat categories
+var threatCategoryAliases = map[string]string{
+	"phish":               ThreatCategoryPhishing,
+	"phishing-site":       ThreatCategoryPhishing,
+	"c&c":                 ThreatCategoryC2,
+	"cnc":                 ThreatCategoryC2,
+	"c2-server":           ThreatCategoryC2,
+	"command-and-control": ThreatCategoryC2,
+	"malware":             ThreatCategoryMalwareDistribution,
+	"malware-download":    ThreatCategoryMalwareDistribution,
+	"malware-hosting":     ThreatCategoryMalwareDistribution,
+	"payload-delivery":    ThreatCategoryMalwareDistribution,
+	"scan":                ThreatCategoryScanning,
+	"scanner":             ThreatCategoryScanning,
+	"brute-force":         ThreatCategoryBruteforce,
+	"spammer":             ThreatCategorySpam,
+	"bot":                 ThreatCategoryBotnet,
+	"fraudulent":          ThreatCategoryFraud,
+	"tor":                 ThreatCategoryAnonymization,
+	"proxy":               ThreatCategoryAnonymization,
+	"vpn":                 ThreatCategoryAnonymization,
+	"compromised-host":    ThreatCategoryCompromised,
+}
+
+// normalizeLabel converts label to lowercase words separated by dashes, e.g. "Malware Distribution" to malware-distribution
+func normalizeLabel(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	return strings.Join(strings.FieldsFunc(value, func(r rune) bool {
+		return r == ' ' || r == '_' || r == '-'
+	}), "-")
+}
+
+// ParseSeverity converts severity name or level from 1 (low) to 4 (critical) to severity. Returns empty string if unknown.
+func ParseSeverity(value string) string {
+	value = normalizeLabel(value)
+
+	if level, err := strconv.Atoi(value); err == nil && level >= 1 && level <= len(Severities) {
+		return Severities[level-1]
+	}
+
+	switch value {
+	case "info", "informational":
+		return SeverityLow
+	case "moderate":
+		return SeverityMedium
+	}
+
+	if slices.Contains(Severities, value) {
+		return value
+	}
+
+	return ""
+}
+
+// SeveritiesFrom returns severities same or higher than defined one, used to filter hosts by minimal severity.
+// Returns nil if severity is unknown.
+func SeveritiesFrom(severity string) []string {
+	i := slices.Index(Severities, ParseSeverity(severity))
+	if i == -1 {
+		return nil
+	}
+
+	return Severities[i:]
+}
+
+// ParseThreatCategory converts category name, alias or STIX label to threat category. Returns false if category is unknown.
+func ParseThreatCategory(value string) (string, bool) {
+	value = normalizeLabel(value)
+
+	if slices.Contains(ThreatCategories, value) {
+		return value, true
+	}
+
+	category, ok := threatCategoryAliases[value]
+	return category, ok
+}
+
+// ParseThreatCategories converts values to unique threat categories, unknown values are ignored
+func ParseThreatCategories(values []string) []string {
+	var categories = []string{}
+
+	for _, v := range values {
+		category, ok := ParseThreatCategory(v)
+		if ok && !slices.Contains(categories, category) {
+			categories = append(categories, category)
+		}
+	}
+
+	return categories
+}
+
+// ParseConfidence parses confidence score, values out of range 0-100 are clamped. Returns 0 if value is not a number.
+func ParseConfidence(value string) int {
+	confidence, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(value), "%"))
+	if err != nil {
+		return 0
+	}
+
+	return clampConfidence(confidence)
+}
+
+func clampConfidence(confidence int) int {
+	return min(max(confidence, 0), 100)
+}
+
+// ParseKillChainPhase converts phase name to canonical form, e.g. "Command and Control" to command-and-control
+func ParseKillChainPhase(value string) string {
+	return normalizeLabel(value)
+}
+
+// Normalize clamps confidence, drops unknown severity and categories and converts kill chain phase to canonical form
+func (t ThreatAttributes) Normalize() ThreatAttributes {
+	t.Confidence = clampConfidence(t.Confidence)
+	t.Severity = ParseSeverity(t.Severity)
+	t.Categories = ParseThreatCategories(t.Categories)
+	t.KillChainPhase = ParseKillChainPhase(t.KillChainPhase)
+
+	return t
+}
@@ -0,0 +1,78 @@
+package blacklistEntities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSeverity(t *testing.T) {
+	tests := map[string]string{
+		"High":          SeverityHigh,
+		" critical ":    SeverityCritical,
+		"1":             SeverityLow,
+		"4":             SeverityCritical,
+		"5":             "",
+		"Informational": SeverityLow,
+		"moderate":      SeverityMedium,
+		"unknown":       "",
+	}
+
+	for value, want := range tests {
+		if got := ParseSeverity(value); got != want {
+			t.Errorf("ParseSeverity(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestSeveritiesFrom(t *testing.T) {
+	if got := SeveritiesFrom("high"); !reflect.DeepEqual(got, []string{SeverityHigh, SeverityCritical}) {
+		t.Errorf("got %v", got)
+	}
+
+	if got := SeveritiesFrom("unknown"); got != nil {
+		t.Errorf("expected no severities, got %v", got)
+	}
+}
+
+func TestParseThreatCategories(t *testing.T) {
+	got := ParseThreatCategories([]string{"Phishing", "C&C", "command and control", "Malware_Hosting", "unknown", " tor "})
+	want := []string{ThreatCategoryPhishing, ThreatCategoryC2, ThreatCategoryMalwareDistribution, ThreatCategoryAnonymization}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got = ParseThreatCategories(nil); got == nil || len(got) != 0 {
+		t.Errorf("expected empty categories, got %#v", got)
+	}
+}
+
+func TestParseConfidence(t *testing.T) {
+	tests := map[string]int{"85": 85, " 70% ": 70, "150": 100, "-5": 0, "high": 0}
+
+	for value, want := range tests {
+		if got := ParseConfidence(value); got != want {
+			t.Errorf("ParseConfidence(%q) = %d, want %d", value, got, want)
+		}
+	}
+}
+
+func TestThreatAttributesNormalize(t *testing.T) {
+	got := ThreatAttributes{
+		Confidence:     120,
+		Severity:       "3",
+		Categories:     []string{"spammer", "spam", "other"},
+		KillChainPhase: "Command and Control",
+	}.Normalize()
+
+	want := ThreatAttributes{
+		Confidence:     100,
+		Severity:       SeverityHigh,
+		Categories:     []string{ThreatCategorySpam},
+		KillChainPhase: "command-and-control",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -1,29 +1,133 @@
 package blacklistEntities
 
 import (
+	"errors"
+	"fmt"
 	"github.com/jackc/pgtype"
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
 	"gorm.io/gorm"
+	"strings"
 	"time"
 )
 
 type BlacklistedDomain struct {
 	UUID pgtype.UUID `json:"UUID" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
 
-	URN         string `json:"URN" gorm:"column:urn;not_null;uniqueIndex:idx_domain"`
+	URN string `json:"URN" gorm:"column:urn;not_null;uniqueIndex:idx_domain_host"`
+	// OriginalURN is domain as it was provided by source, URN contains its canonical form
+	OriginalURN string `json:"OriginalURN" gorm:"column:original_urn"`
 	Description string `json:"Description" gorm:"column:description"`
 
-	// Defines source from where blacklisted host was added
+	// IncludeSubdomains defines that domain and all its subdomains are blacklisted, written as *.example.com
+	IncludeSubdomains bool `json:"IncludeSubdomains" gorm:"column:include_subdomains;not null;default:false"`
+
+	// ThreatAttributes define confidence, severity and categories of threat
+	ThreatAttributes `gorm:"embedded"`
+
+	// Tags group hosts by campaign, incident or threat actor
+	Tags []BlacklistTag `json:"Tags,omitempty" gorm:"many2many:blacklisted_domain_tags;joinForeignKey:HostUUID;joinReferences:TagID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+
+	// Sightings list all sources which reported host, the earliest first
+	Sightings []BlacklistSighting `json:"Sightings,omitempty" gorm:"polymorphic:Host;polymorphicValue:domain"`
+
+	// Defines source from where blacklisted host was added first, all sources are listed in sightings
 	Source   *BlacklistSource `json:"Source,omitempty" gorm:"foreignKey:SourceID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
-	SourceID uint64           `json:"SourceID" gorm:"uniqueIndex:idx_domain"`
+	SourceID uint64           `json:"SourceID"`
 
-	// ImportEvent describes import session from where blacklisted host was added
+	// ImportEvent describes import session from where blacklisted host was added first
 	ImportEvent   *BlacklistImportEvent `json:"ImportEvent,omitempty" gorm:"foreignKey:ImportEventID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 	ImportEventID *uint64               `json:"ImportEventID" gorm:"column:import_event_id"`
 
 	// DiscoveredAt sets date of discovery, provided by source or inserted automatically on create
 	DiscoveredAt time.Time `json:"DiscoveredAt" gorm:"autoCreateTime"`
+	// ValidUntil sets date when host expires and is deleted automatically, hosts without date never expire
+	ValidUntil *time.Time `json:"ValidUntil" gorm:"column:valid_until;index"`
 
 	CreatedAt time.Time      `json:"CreatedAt"`
 	UpdatedAt time.Time      `json:"UpdatedAt"`
 	DeletedAt gorm.DeletedAt `json:"DeletedAt,omitempty" gorm:"index"`
 }
+
+// ParentDomains returns domain with all its parent domains up to registrable domain, e.g. for a.b.example.co.uk
+// returns a.b.example.co.uk, b.example.co.uk and example.co.uk. Public suffixes are never returned as parents.
+func ParentDomains(domain string) []string {
+	domain, err := CanonicalDomain(domain)
+	if err != nil {
+		return []string{domain}
+	}
+
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return []string{domain}
+	}
+
+	var domains []string
+	for d := domain; ; {
+		domains = append(domains, d)
+
+		i := strings.Index(d, ".")
+		if d == registrable || i == -1 {
+			break
+		}
+
+		d = d[i+1:]
+	}
+
+	return domains
+}
+
+// ParseDomainPattern parses domain or wildcard domain pattern, like *.example.com or .example.com, which matches domain
+// and all its subdomains. Public suffixes, like com or co.uk, are rejected, so whole zones are not blacklisted by mistake.
+func ParseDomainPattern(value string) (string, bool, error) {
+	domain := strings.TrimSuffix(strings.TrimSpace(value), ".")
+
+	includeSubdomains := false
+	for _, prefix := range []string{"*.", "."} {
+		if strings.HasPrefix(domain, prefix) {
+			domain = strings.TrimPrefix(domain, prefix)
+			includeSubdomains = true
+			break
+		}
+	}
+
+	if len(domain) == 0 || strings.ContainsAny(domain, "* /") {
+		return "", false, errors.New("invalid domain pattern")
+	}
+
+	domain, err := CanonicalDomain(domain)
+	if err != nil {
+		return "", false, err
+	}
+
+	if IsPublicSuffix(domain) {
+		return "", false, fmt.Errorf("domain '%s' is public suffix", domain)
+	}
+
+	return domain, includeSubdomains, nil
+}
+
+// IsPublicSuffix checks if domain is listed public suffix, like com, co.uk or github.io.
+// Single label names not listed as top level domains, like localhost, are not treated as public suffixes.
+func IsPublicSuffix(domain string) bool {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	suffix, icann := publicsuffix.PublicSuffix(domain)
+
+	return suffix == domain && (icann || strings.Contains(domain, "."))
+}
+
+// idnaProfile converts internationalized domains to punycode. Underscores are allowed, as they are used in malicious domains.
+var idnaProfile = idna.New(idna.MapForLookup(), idna.StrictDomainName(false), idna.Transitional(false))
+
+// CanonicalDomain converts domain to canonical form: lowercase, without trailing dot, internationalized labels in punycode
+func CanonicalDomain(value string) (string, error) {
+	domain := strings.TrimSuffix(strings.TrimSpace(value), ".")
+
+	ascii, err := idnaProfile.ToASCII(domain)
+	if err != nil {
+		return strings.ToLower(domain), fmt.Errorf("invalid domain '%s': %s", value, err.Error())
+	}
+
+	return strings.ToLower(ascii), nil
+}
@@ -0,0 +1,95 @@
+package blacklistEntities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParentDomains(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{value: "evil.com", want: []string{"evil.com"}},
+		{value: "A.b.Evil.com.", want: []string{"a.b.evil.com", "b.evil.com", "evil.com"}},
+		{value: "a.example.co.uk", want: []string{"a.example.co.uk", "example.co.uk"}},
+		{value: "co.uk", want: []string{"co.uk"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := ParentDomains(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParentDomains(%s) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDomainPattern(t *testing.T) {
+	tests := []struct {
+		name                  string
+		value                 string
+		want                  string
+		wantIncludeSubdomains bool
+		wantErr               bool
+	}{
+		{name: "domain", value: "Evil.COM.", want: "evil.com"},
+		{name: "internationalized", value: "*.пример.рф", want: "xn--e1afmkfd.xn--p1ai", wantIncludeSubdomains: true},
+		{name: "wildcard", value: "*.evil.com", want: "evil.com", wantIncludeSubdomains: true},
+		{name: "leading dot", value: " .evil.co.uk ", want: "evil.co.uk", wantIncludeSubdomains: true},
+		{name: "underscore", value: "_dmarc.evil.com", want: "_dmarc.evil.com"},
+		{name: "single label", value: "localhost", want: "localhost"},
+		{name: "public suffix", value: "*.com", wantErr: true},
+		{name: "multi label public suffix", value: "co.uk", wantErr: true},
+		{name: "private public suffix", value: "*.github.io", wantErr: true},
+		{name: "wildcard inside", value: "evil.*.com", wantErr: true},
+		{name: "only wildcard", value: "*.", wantErr: true},
+		{name: "path", value: "evil.com/a", wantErr: true},
+		{name: "empty", value: " ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, includeSubdomains, err := ParseDomainPattern(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %s", got)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want || includeSubdomains != tt.wantIncludeSubdomains {
+				t.Errorf("ParseDomainPattern(%s) = %s, %t, want %s, %t", tt.value, got, includeSubdomains, tt.want, tt.wantIncludeSubdomains)
+			}
+		})
+	}
+}
+
+func TestCanonicalDomain(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: " Evil.COM. ", want: "evil.com"},
+		{value: "ПРИМЕР.рф", want: "xn--e1afmkfd.xn--p1ai"},
+		{value: "xn--e1afmkfd.xn--p1ai", want: "xn--e1afmkfd.xn--p1ai"},
+		{value: "_dmarc.evil.com", want: "_dmarc.evil.com"},
+		{value: "xn--a.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := CanonicalDomain(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CanonicalDomain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("CanonicalDomain(%s) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
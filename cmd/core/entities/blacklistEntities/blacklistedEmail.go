@@ -9,19 +9,30 @@ import (
 type BlacklistedEmail struct {
 	UUID pgtype.UUID `json:"UUID" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
 
-	Email       string `json:"Email" gorm:"column:email;not_null;uniqueIndex:idx_email"`
+	Email       string `json:"Email" gorm:"column:email;not_null;uniqueIndex:idx_email_host"`
 	Description string `json:"Description" gorm:"column:description"`
 
-	// Defines source from where blacklisted host was added
+	// ThreatAttributes define confidence, severity and categories of threat
+	ThreatAttributes `gorm:"embedded"`
+
+	// Tags group hosts by campaign, incident or threat actor
+	Tags []BlacklistTag `json:"Tags,omitempty" gorm:"many2many:blacklisted_email_tags;joinForeignKey:HostUUID;joinReferences:TagID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+
+	// Sightings list all sources which reported host, the earliest first
+	Sightings []BlacklistSighting `json:"Sightings,omitempty" gorm:"polymorphic:Host;polymorphicValue:email"`
+
+	// Defines source from where blacklisted host was added first, all sources are listed in sightings
 	Source   *BlacklistSource `json:"Source,omitempty" gorm:"foreignKey:SourceID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
-	SourceID uint64           `json:"SourceID" gorm:"uniqueIndex:idx_email"`
+	SourceID uint64           `json:"SourceID"`
 
-	// ImportEvent describes import session from where blacklisted host was added
+	// ImportEvent describes import session from where blacklisted host was added first
 	ImportEvent   *BlacklistImportEvent `json:"ImportEvent,omitempty" gorm:"foreignKey:ImportEventID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 	ImportEventID *uint64               `json:"ImportEventID" gorm:"column:import_event_id"`
 
 	// DiscoveredAt sets date of discovery, provided by source or inserted automatically on create
 	DiscoveredAt time.Time `json:"DiscoveredAt" gorm:"autoCreateTime"`
+	// ValidUntil sets date when host expires and is deleted automatically, hosts without date never expire
+	ValidUntil *time.Time `json:"ValidUntil" gorm:"column:valid_until;index"`
 
 	CreatedAt time.Time      `json:"CreatedAt"`
 	UpdatedAt time.Time      `json:"UpdatedAt"`
@@ -1,6 +1,9 @@
 package blacklistEntities
 
-import "time"
+import (
+	"github.com/jackc/pgtype"
+	"time"
+)
 
 type BlacklistSearchFilter struct {
 	Offset           int        `json:"Offset" form:"offset"`
@@ -13,6 +16,27 @@ type BlacklistSearchFilter struct {
 	DiscoveredAfter  *time.Time `json:"DiscoveredAfter" form:"discovered_after" time_format:"2006-01-02"`
 	DiscoveredBefore *time.Time `json:"DiscoveredBefore" form:"discovered_before" time_format:"2006-01-02"`
 	SearchString     string     `json:"SearchString" form:"search_string"`
+	// ExpiresBefore selects hosts expiring before defined date, used to find hosts expiring soon
+	ExpiresBefore *time.Time `json:"ExpiresBefore" form:"expires_before" time_format:"2006-01-02"`
+	// Domain selects domains matching defined domain exactly or covering it as parent domain with all subdomains
+	Domain string `json:"Domain" form:"domain"`
+
+	// Threat attributes: minimal confidence and severity, any of categories and kill chain phase
+	MinConfidence  int      `json:"MinConfidence" form:"min_confidence" binding:"min=0,max=100"`
+	MinSeverity    string   `json:"MinSeverity" form:"min_severity" binding:"omitempty,oneof=low medium high critical"`
+	Categories     []string `json:"Categories" form:"category[]"`
+	KillChainPhase string   `json:"KillChainPhase" form:"kill_chain_phase"`
+	// TagIDs selects hosts tagged by any of defined tags
+	TagIDs []uint64 `json:"TagIDs" form:"tag_id[]" binding:"dive"`
+
+	// UpdatedAfter selects hosts updated or deleted after defined time. If UpdatedAfterUUID defined, hosts changed
+	// exactly at defined time are selected too, if their UUID is greater.
+	// UpdatedAfter and SortByUpdated are used to page through hosts in order they were added, updated or deleted
+	UpdatedAfter     *time.Time  `json:"-" form:"-"`
+	UpdatedAfterUUID pgtype.UUID `json:"-" form:"-"`
+	// UpdatedBefore selects hosts updated or deleted not later than defined time
+	UpdatedBefore *time.Time `json:"-" form:"-"`
+	SortByUpdated bool       `json:"-" form:"-"`
 }
 
 type BlacklistExportFilter struct {
@@ -24,12 +48,54 @@ type BlacklistExportFilter struct {
 	CreatedBefore    *time.Time `json:"CreatedBefore" form:"created_before" time_format:"2006-01-02"`
 	DiscoveredAfter  *time.Time `json:"DiscoveredAfter" form:"discovered_after" time_format:"2006-01-02"`
 	DiscoveredBefore *time.Time `json:"DiscoveredBefore" form:"discovered_before" time_format:"2006-01-02"`
+
+	// Threat attributes: minimal confidence and severity, any of categories and kill chain phase
+	MinConfidence  int      `json:"MinConfidence" form:"min_confidence" binding:"min=0,max=100"`
+	MinSeverity    string   `json:"MinSeverity" form:"min_severity" binding:"omitempty,oneof=low medium high critical"`
+	Categories     []string `json:"Categories" form:"category[]"`
+	KillChainPhase string   `json:"KillChainPhase" form:"kill_chain_phase"`
+	// TagIDs selects hosts tagged by any of defined tags
+	TagIDs []uint64 `json:"TagIDs" form:"tag_id[]" binding:"dive"`
+}
+
+// ToSearchFilter converts export filter to search filter without limits.
+// OnlyNew selects hosts created in last 2 hours, same as HostStatusNew.
+func (f BlacklistExportFilter) ToSearchFilter() BlacklistSearchFilter {
+	filter := BlacklistSearchFilter{
+		SourceIDs:        f.SourceIDs,
+		ImportEventID:    f.ImportEventID,
+		IsActive:         f.IsActive,
+		CreatedAfter:     f.CreatedAfter,
+		CreatedBefore:    f.CreatedBefore,
+		DiscoveredAfter:  f.DiscoveredAfter,
+		DiscoveredBefore: f.DiscoveredBefore,
+		MinConfidence:    f.MinConfidence,
+		MinSeverity:      f.MinSeverity,
+		Categories:       f.Categories,
+		KillChainPhase:   f.KillChainPhase,
+		TagIDs:           f.TagIDs,
+	}
+
+	if f.OnlyNew != nil && *f.OnlyNew {
+		threshold := time.Now().Add(-2 * time.Hour)
+		if filter.CreatedAfter == nil || filter.CreatedAfter.Before(threshold) {
+			filter.CreatedAfter = &threshold
+		}
+	}
+
+	return filter
+}
+
+type BlacklistImportErrorFilter struct {
+	Offset int `json:"Offset" form:"offset"`
+	Limit  int `json:"Limit" form:"limit" binding:"required"`
 }
 
 type BlacklistImportEventFilter struct {
 	Offset        int        `json:"Offset" form:"offset"`
 	Limit         int        `json:"Limit" form:"limit" binding:"required"`
 	Type          string     `json:"Type" form:"type"`
+	FeedID        uint64     `json:"FeedID" form:"feed_id"`
 	CreatedAfter  *time.Time `json:"CreatedAfter" form:"created_after" time_format:"2006-01-02"`
 	CreatedBefore *time.Time `json:"CreatedBefore" form:"created_before" time_format:"2006-01-02"`
 }
@@ -3,6 +3,8 @@ package blacklistEntities
 import (
 	"github.com/jackc/pgtype"
 	"gorm.io/gorm"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -14,7 +16,19 @@ type BlacklistedHost struct {
 	Description string     `json:"Description" gorm:"column:description"`
 	Status      HostStatus `json:"Status" gorm:"-"`
 
-	// Source defines source from where blacklisted host was added
+	// IncludeSubdomains is set for domains blacklisted with all subdomains
+	IncludeSubdomains bool `json:"IncludeSubdomains,omitempty" gorm:"column:include_subdomains"`
+
+	// ThreatAttributes define confidence, severity and categories of threat
+	ThreatAttributes `gorm:"embedded"`
+
+	// Tags group hosts by campaign, incident or threat actor, loaded separately from host
+	Tags []BlacklistTag `json:"Tags,omitempty" gorm:"-"`
+
+	// Sightings list all sources which reported host, loaded separately from host
+	Sightings []BlacklistSighting `json:"Sightings,omitempty" gorm:"-"`
+
+	// Source defines source from where blacklisted host was added first
 	Source   *BlacklistSource `json:"Source,omitempty"`
 	SourceID uint64           `json:"SourceID" gorm:"column:source_id"`
 
@@ -24,12 +38,31 @@ type BlacklistedHost struct {
 
 	// DiscoveredAt sets date of discovery, provided by source or inserted automatically on create
 	DiscoveredAt time.Time `json:"DiscoveredAt" gorm:"autoCreateTime"`
+	// ValidUntil sets date when host expires, hosts without date never expire
+	ValidUntil *time.Time `json:"ValidUntil" gorm:"column:valid_until"`
 
 	CreatedAt time.Time      `json:"CreatedAt" gorm:"column:created_at"`
 	UpdatedAt time.Time      `json:"UpdatedAt" gorm:"column:updated_at"`
 	DeletedAt gorm.DeletedAt `json:"DeletedAt,omitempty" gorm:"column:deleted_at"`
 }
 
+// SourceNames returns names of all sources which reported host. Returns first source if sightings are not loaded.
+func (h BlacklistedHost) SourceNames() []string {
+	var names []string
+
+	for _, s := range h.Sightings {
+		if s.Source != nil {
+			names = append(names, s.Source.Name)
+		}
+	}
+
+	if len(names) == 0 && h.Source != nil {
+		names = append(names, h.Source.Name)
+	}
+
+	return names
+}
+
 type HostStatus string
 
 const (
@@ -47,8 +80,14 @@ func (h *BlacklistedHost) FromIP(ip BlacklistedIP) {
 
 	h.Source = ip.Source
 	h.SourceID = ip.SourceID
+	h.ImportEventID = ip.ImportEventID
 
 	h.Description = ip.Description
+	h.ThreatAttributes = ip.ThreatAttributes
+	h.Tags = ip.Tags
+	h.Sightings = ip.Sightings
+	h.DiscoveredAt = ip.DiscoveredAt
+	h.ValidUntil = ip.ValidUntil
 	h.CreatedAt = ip.CreatedAt
 	h.UpdatedAt = ip.UpdatedAt
 	h.DeletedAt = ip.DeletedAt
@@ -60,11 +99,18 @@ func (h *BlacklistedHost) FromDomain(ip BlacklistedDomain) {
 	h.Host = ip.URN
 	h.UUID = ip.UUID
 	h.Type = "domain"
+	h.IncludeSubdomains = ip.IncludeSubdomains
 
 	h.Source = ip.Source
 	h.SourceID = ip.SourceID
+	h.ImportEventID = ip.ImportEventID
 
 	h.Description = ip.Description
+	h.ThreatAttributes = ip.ThreatAttributes
+	h.Tags = ip.Tags
+	h.Sightings = ip.Sightings
+	h.DiscoveredAt = ip.DiscoveredAt
+	h.ValidUntil = ip.ValidUntil
 	h.CreatedAt = ip.CreatedAt
 	h.UpdatedAt = ip.UpdatedAt
 	h.DeletedAt = ip.DeletedAt
@@ -79,11 +125,71 @@ func (h *BlacklistedHost) FromURL(ip BlacklistedURL) {
 
 	h.Source = ip.Source
 	h.SourceID = ip.SourceID
+	h.ImportEventID = ip.ImportEventID
 
 	h.Description = ip.Description
+	h.ThreatAttributes = ip.ThreatAttributes
+	h.Tags = ip.Tags
+	h.Sightings = ip.Sightings
+	h.DiscoveredAt = ip.DiscoveredAt
+	h.ValidUntil = ip.ValidUntil
 	h.CreatedAt = ip.CreatedAt
 	h.UpdatedAt = ip.UpdatedAt
 	h.DeletedAt = ip.DeletedAt
 
 	h.Status = HostStatusDefault
 }
+
+func (h *BlacklistedHost) FromEmail(email BlacklistedEmail) {
+	h.Host = email.Email
+	h.UUID = email.UUID
+	h.Type = "email"
+
+	h.Source = email.Source
+	h.SourceID = email.SourceID
+	h.ImportEventID = email.ImportEventID
+
+	h.Description = email.Description
+	h.ThreatAttributes = email.ThreatAttributes
+	h.Tags = email.Tags
+	h.Sightings = email.Sightings
+	h.DiscoveredAt = email.DiscoveredAt
+	h.ValidUntil = email.ValidUntil
+	h.CreatedAt = email.CreatedAt
+	h.UpdatedAt = email.UpdatedAt
+	h.DeletedAt = email.DeletedAt
+
+	h.Status = HostStatusDefault
+}
+
+var domainRegex = regexp.MustCompile(`^([a-zA-Z0-9_]([a-zA-Z0-9-_]{0,61}[a-zA-Z0-9])?\.)+[a-zA-Z][a-zA-Z0-9-]{0,61}[a-zA-Z0-9]\.?$`)
+
+// DetectHostType returns type of blacklisted host by its value: ip, url, domain or email.
+// Returns empty string if type can not be detected.
+func DetectHostType(value string) string {
+	value = strings.TrimSpace(value)
+
+	if len(value) == 0 {
+		return ""
+	}
+
+	// IPv4 and IPv6 addresses and networks
+	if _, err := ParseInet(value); err == nil {
+		return "ip"
+	}
+
+	if strings.Contains(value, "://") || strings.Contains(value, "/") {
+		return "url"
+	}
+
+	if i := strings.LastIndex(value, "@"); i > 0 && domainRegex.MatchString(value[i+1:]) {
+		return "email"
+	}
+
+	// wildcard domain patterns are detected as domains
+	if domainRegex.MatchString(strings.TrimPrefix(value, "*.")) {
+		return "domain"
+	}
+
+	return ""
+}
@@ -0,0 +1,28 @@
+package blacklistEntities
+
+import "testing"
+
+func TestDetectHostType(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "10.0.0.1", want: "ip"},
+		{value: " 2001:db8::1 ", want: "ip"},
+		{value: "10.0.0.0/24", want: "ip"},
+		{value: "http://evil.com/a", want: "url"},
+		{value: "evil.com/a", want: "url"},
+		{value: "user@evil.com", want: "email"},
+		{value: "sub.evil.com", want: "domain"},
+		{value: "not a host", want: ""},
+		{value: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := DetectHostType(tt.value); got != tt.want {
+				t.Errorf("DetectHostType(%s) = '%s', want '%s'", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,38 +1,125 @@
 package blacklistEntities
 
 import (
+	"fmt"
 	"github.com/jackc/pgtype"
 	"gorm.io/gorm"
+	"net"
 	"regexp"
+	"strings"
 	"time"
 )
 
 type BlacklistedIP struct {
 	UUID pgtype.UUID `json:"UUID" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
 
-	IPAddress   pgtype.Inet `json:"IPAddress" gorm:"column:ip_address;type:inet;not_null;uniqueIndex:idx_ip"`
+	IPAddress   pgtype.Inet `json:"IPAddress" gorm:"column:ip_address;type:inet;not_null;uniqueIndex:idx_ip_host"`
 	Description string      `json:"Description" gorm:"column:description"`
 
-	// Defines source from where blacklisted host was added
+	// ThreatAttributes define confidence, severity and categories of threat
+	ThreatAttributes `gorm:"embedded"`
+
+	// Tags group hosts by campaign, incident or threat actor
+	Tags []BlacklistTag `json:"Tags,omitempty" gorm:"many2many:blacklisted_ip_tags;joinForeignKey:HostUUID;joinReferences:TagID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+
+	// Sightings list all sources which reported host, the earliest first
+	Sightings []BlacklistSighting `json:"Sightings,omitempty" gorm:"polymorphic:Host;polymorphicValue:ip"`
+
+	// Defines source from where blacklisted host was added first, all sources are listed in sightings
 	Source   *BlacklistSource `json:"Source,omitempty" gorm:"foreignKey:SourceID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
-	SourceID uint64           `json:"SourceID" gorm:"uniqueIndex:idx_ip"`
+	SourceID uint64           `json:"SourceID"`
 
-	// ImportEvent describes import session from where blacklisted host was added
+	// ImportEvent describes import session from where blacklisted host was added first
 	ImportEvent   *BlacklistImportEvent `json:"ImportEvent,omitempty" gorm:"foreignKey:ImportEventID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 	ImportEventID *uint64               `json:"ImportEventID" gorm:"column:import_event_id"`
 
 	// DiscoveredAt sets date of discovery, provided by source or inserted automatically on create
 	DiscoveredAt time.Time `json:"DiscoveredAt" gorm:"autoCreateTime"`
+	// ValidUntil sets date when host expires and is deleted automatically, hosts without date never expire
+	ValidUntil *time.Time `json:"ValidUntil" gorm:"column:valid_until;index"`
 
 	CreatedAt time.Time      `json:"CreatedAt"`
 	UpdatedAt time.Time      `json:"UpdatedAt"`
 	DeletedAt gorm.DeletedAt `json:"DeletedAt,omitempty" gorm:"index"`
 }
 
+var (
+	patternIPv4Regex = regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)(?:/(?:3[0-2]|[12]?\d))?\b`)
+	patternIPv6Regex = regexp.MustCompile(`(?i)(?:[0-9a-f]{0,4}:){2,7}[0-9a-f]{0,4}(?:/(?:12[0-8]|1[01]\d|[1-9]?\d))?`)
+)
+
+// ExtractIPFromPattern returns first IPv4 or IPv6 address or network found in input, like STIX pattern.
+// Returns empty string if no valid address found.
 func ExtractIPFromPattern(input string) string {
-	numBlock := "(25[0-5]|2[0-4][0-9]|1[0-9][0-9]|[1-9]?[0-9])"
-	regexPattern := numBlock + "\\." + numBlock + "\\." + numBlock + "\\." + numBlock
+	var result string
+	var resultIndex = -1
+
+	for _, re := range []*regexp.Regexp{patternIPv4Regex, patternIPv6Regex} {
+		for _, loc := range re.FindAllStringIndex(input, -1) {
+			if resultIndex != -1 && loc[0] >= resultIndex {
+				break
+			}
+
+			// IPv4-mapped IPv6 address is matched only partially, it is found by IPv4 pattern instead
+			if loc[1] < len(input) && input[loc[1]] == '.' {
+				continue
+			}
+
+			value := input[loc[0]:loc[1]]
+			if _, err := ParseInet(value); err == nil {
+				result, resultIndex = value, loc[0]
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// ParseInet parses IPv4 or IPv6 address or network in CIDR notation. Host bits of networks are cleared,
+// IPv4-mapped IPv6 addresses are converted to IPv4. Address can be enclosed in brackets, as in URLs.
+func ParseInet(value string) (pgtype.Inet, error) {
+	value = strings.TrimSpace(value)
+	value = strings.Replace(strings.TrimPrefix(value, "["), "]", "", 1)
+
+	var network *net.IPNet
+
+	if strings.Contains(value, "/") {
+		ip, n, err := net.ParseCIDR(value)
+		if err != nil {
+			return pgtype.Inet{}, fmt.Errorf("'%s' is not valid IP network", value)
+		}
+
+		ones, bits := n.Mask.Size()
+		if ip4 := ip.To4(); ip4 != nil && bits == 128 {
+			if ones < 96 {
+				return pgtype.Inet{}, fmt.Errorf("'%s' is not valid IP network", value)
+			}
+
+			ones, bits = ones-96, 32
+		}
+
+		network = &net.IPNet{IP: n.IP, Mask: net.CIDRMask(ones, bits)}
+		if bits == 32 {
+			network.IP = n.IP.To4().Mask(network.Mask)
+		}
+	} else {
+		// zone index is not stored
+		if i := strings.Index(value, "%"); i != -1 {
+			value = value[:i]
+		}
+
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return pgtype.Inet{}, fmt.Errorf("'%s' is not valid IP address", value)
+		}
+
+		if ip4 := ip.To4(); ip4 != nil {
+			network = &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+		} else {
+			network = &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+		}
+	}
 
-	regEx := regexp.MustCompile(regexPattern)
-	return regEx.FindString(input)
+	return pgtype.Inet{IPNet: network, Status: pgtype.Present}, nil
 }
@@ -0,0 +1,44 @@
+package blacklistEntities
+
+import "testing"
+
+func TestParseInet(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "ipv4 address", value: " 10.0.0.1 ", want: "10.0.0.1/32"},
+		{name: "ipv4 network", value: "10.0.0.0/24", want: "10.0.0.0/24"},
+		{name: "ipv4 network with host bits", value: "10.0.0.17/24", want: "10.0.0.0/24"},
+		{name: "ipv6 address", value: "2001:DB8::1", want: "2001:db8::1/128"},
+		{name: "ipv6 network with host bits", value: "2001:db8::1/32", want: "2001:db8::/32"},
+		{name: "ipv6 address in brackets", value: "[2001:db8::1]", want: "2001:db8::1/128"},
+		{name: "ipv6 address with zone", value: "fe80::1%eth0", want: "fe80::1/128"},
+		{name: "ipv4-mapped address", value: "::ffff:10.0.0.1", want: "10.0.0.1/32"},
+		{name: "ipv4-mapped network", value: "::ffff:10.0.0.0/120", want: "10.0.0.0/24"},
+		{name: "ipv4-mapped network too wide", value: "::ffff:10.0.0.0/64", wantErr: true},
+		{name: "invalid address", value: "10.0.0.256", wantErr: true},
+		{name: "invalid network", value: "10.0.0.0/33", wantErr: true},
+		{name: "domain", value: "evil.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseInet(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %s", got.IPNet)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.IPNet.String() != tt.want {
+				t.Errorf("ParseInet(%s) = %s, want %s", tt.value, got.IPNet, tt.want)
+			}
+		})
+	}
+}
@@ -1,30 +1,162 @@
 package blacklistEntities
 
 import (
+	"crypto/md5"
+	"encoding/hex"
 	"github.com/jackc/pgtype"
 	"gorm.io/gorm"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type BlacklistedURL struct {
 	UUID pgtype.UUID `json:"UUID" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
 
-	URL         string `json:"URL" gorm:"column:url;not_null"`
-	MD5         string `json:"MD5" gorm:"column:md5;not_null;uniqueIndex:idx_url"`
+	URL string `json:"URL" gorm:"column:url;not_null"`
+	// OriginalURL is URL as it was provided by source, URL contains its canonical form
+	OriginalURL string `json:"OriginalURL" gorm:"column:original_url"`
+	MD5         string `json:"MD5" gorm:"column:md5;not_null;uniqueIndex:idx_url_host"`
 	Description string `json:"Description" gorm:"column:description"`
 
-	// Defines source from where blacklisted host was added
+	// ThreatAttributes define confidence, severity and categories of threat
+	ThreatAttributes `gorm:"embedded"`
+
+	// Tags group hosts by campaign, incident or threat actor
+	Tags []BlacklistTag `json:"Tags,omitempty" gorm:"many2many:blacklisted_url_tags;joinForeignKey:HostUUID;joinReferences:TagID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+
+	// Sightings list all sources which reported host, the earliest first
+	Sightings []BlacklistSighting `json:"Sightings,omitempty" gorm:"polymorphic:Host;polymorphicValue:url"`
+
+	// Defines source from where blacklisted host was added first, all sources are listed in sightings
 	Source   *BlacklistSource `json:"Source,omitempty" gorm:"foreignKey:SourceID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
-	SourceID uint64           `json:"SourceID" gorm:"uniqueIndex:idx_url"`
+	SourceID uint64           `json:"SourceID"`
 
-	// ImportEvent describes import session from where blacklisted host was added
+	// ImportEvent describes import session from where blacklisted host was added first
 	ImportEvent   *BlacklistImportEvent `json:"ImportEvent,omitempty" gorm:"foreignKey:ImportEventID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 	ImportEventID *uint64               `json:"ImportEventID" gorm:"column:import_event_id"`
 
 	// DiscoveredAt sets date of discovery, provided by source or inserted automatically on create
 	DiscoveredAt time.Time `json:"DiscoveredAt" gorm:"autoCreateTime"`
+	// ValidUntil sets date when host expires and is deleted automatically, hosts without date never expire
+	ValidUntil *time.Time `json:"ValidUntil" gorm:"column:valid_until;index"`
 
 	CreatedAt time.Time      `json:"CreatedAt"`
 	UpdatedAt time.Time      `json:"UpdatedAt"`
 	DeletedAt gorm.DeletedAt `json:"DeletedAt,omitempty" gorm:"index"`
 }
+
+// URLHash returns MD5 hash of URL, used as unique key of blacklisted URL
+func URLHash(value string) string {
+	hash := md5.Sum([]byte(value))
+	return hex.EncodeToString(hash[:])
+}
+
+// defaultPorts contains ports removed from canonical URLs
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ftp":   "21",
+	"ws":    "80",
+	"wss":   "443",
+}
+
+// NormalizeURL converts URL to canonical form: scheme and host are lowercased, internationalized host is converted to
+// punycode, trailing dot of host, default port, fragment and trailing slash of path are removed, percent-encoding is
+// normalized. URLs without scheme are normalized and returned without scheme. Value is returned unchanged if it is not
+// valid URL.
+func NormalizeURL(value string) string {
+	value = strings.TrimSpace(value)
+
+	// host with port, like evil.com:8080/a, is parsed as scheme
+	schemeless := false
+	if !strings.Contains(value, "://") {
+		u, err := url.Parse(value)
+		schemeless = err != nil || len(u.Scheme) == 0 || strings.Contains(u.Scheme, ".")
+	}
+
+	raw := value
+	if schemeless {
+		raw = "//" + strings.TrimPrefix(value, "//")
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || len(u.Host) == 0 {
+		return value
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	host, port := u.Hostname(), u.Port()
+	if h, err := CanonicalDomain(host); err == nil {
+		host = h
+	}
+
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+
+	u.Host = host
+	if len(port) > 0 {
+		u.Host += ":" + port
+	}
+
+	path := normalizeEscapes(u.EscapedPath())
+	if len(path) == 0 {
+		path = "/"
+	} else if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	u.Path, err = url.PathUnescape(path)
+	if err != nil {
+		return value
+	}
+
+	u.RawPath = path
+	u.RawQuery = normalizeEscapes(u.RawQuery)
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	if schemeless {
+		return strings.TrimPrefix(u.String(), "//")
+	}
+
+	return u.String()
+}
+
+// normalizeEscapes decodes percent-encoded unreserved characters and uppercases hex digits of other escapes,
+// so %7e and ~, %2f and %2F are written same way
+func normalizeEscapes(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			c, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err == nil {
+				if isUnreserved(byte(c)) {
+					b.WriteByte(byte(c))
+				} else {
+					b.WriteString("%" + strings.ToUpper(s[i+1:i+3]))
+				}
+
+				i += 2
+				continue
+			}
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+// isUnreserved checks if character is allowed in URL without encoding, see RFC 3986
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~'
+}
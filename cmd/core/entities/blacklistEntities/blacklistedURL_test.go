@@ -0,0 +1,34 @@
+package blacklistEntities
+
+import "testing"
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "already canonical", value: "http://evil.com/a", want: "http://evil.com/a"},
+		{name: "scheme and host case", value: "HTTP://EVIL.com/Path", want: "http://evil.com/Path"},
+		{name: "trailing slash and fragment", value: "https://evil.com/a/#login", want: "https://evil.com/a"},
+		{name: "root path", value: "https://evil.com", want: "https://evil.com/"},
+		{name: "default port", value: "https://evil.com:443/a", want: "https://evil.com/a"},
+		{name: "custom port", value: "http://evil.com:8080/a", want: "http://evil.com:8080/a"},
+		{name: "trailing dot of host", value: "http://evil.com./a", want: "http://evil.com/a"},
+		{name: "internationalized host", value: "http://пример.рф/a", want: "http://xn--e1afmkfd.xn--p1ai/a"},
+		{name: "unreserved escapes", value: "http://evil.com/%7euser/%2fa", want: "http://evil.com/~user/%2Fa"},
+		{name: "query kept", value: "http://evil.com/a?id=%2f1", want: "http://evil.com/a?id=%2F1"},
+		{name: "ipv6 host", value: "http://[2001:DB8::1]:80/a", want: "http://[2001:db8::1]/a"},
+		{name: "without scheme", value: "EVIL.com/a/", want: "evil.com/a"},
+		{name: "without scheme with port", value: "evil.com:8080/a", want: "evil.com:8080/a"},
+		{name: "not url", value: "not a url", want: "not a url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeURL(tt.value); got != tt.want {
+				t.Errorf("NormalizeURL(%s) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
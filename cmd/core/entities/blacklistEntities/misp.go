@@ -0,0 +1,327 @@
+package blacklistEntities
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MISPEvent represents MISP event with its attributes and objects
+// reference: https://www.misp-project.org/datamodels/
+type MISPEvent struct {
+	ID        string      `json:"id,omitempty"`
+	UUID      string      `json:"uuid,omitempty"`
+	Info      string      `json:"info"`
+	Date      string      `json:"date,omitempty"`
+	Timestamp json.Number `json:"timestamp,omitempty"`
+
+	Distribution  string `json:"distribution,omitempty"`
+	ThreatLevelID string `json:"threat_level_id,omitempty"`
+	Analysis      string `json:"analysis,omitempty"`
+
+	Orgc *MISPOrganisation `json:"Orgc,omitempty"`
+
+	Tag       []MISPTag       `json:"Tag,omitempty"`
+	Attribute []MISPAttribute `json:"Attribute,omitempty"`
+	Object    []MISPObject    `json:"Object,omitempty"`
+}
+
+type MISPAttribute struct {
+	ID        string      `json:"id,omitempty"`
+	UUID      string      `json:"uuid,omitempty"`
+	EventID   string      `json:"event_id,omitempty"`
+	Type      string      `json:"type"`
+	Category  string      `json:"category,omitempty"`
+	Value     string      `json:"value"`
+	Comment   string      `json:"comment,omitempty"`
+	ToIDs     MISPBool    `json:"to_ids"`
+	Deleted   MISPBool    `json:"deleted,omitempty"`
+	Timestamp json.Number `json:"timestamp,omitempty"`
+
+	Tag []MISPTag `json:"Tag,omitempty"`
+}
+
+type MISPObject struct {
+	Name      string          `json:"name"`
+	Attribute []MISPAttribute `json:"Attribute,omitempty"`
+}
+
+type MISPTag struct {
+	Name string `json:"name"`
+}
+
+type MISPOrganisation struct {
+	Name string `json:"name"`
+}
+
+// MISPBool is boolean, which MISP can export as true/false, "1"/"0" or 1/0
+type MISPBool bool
+
+func (b *MISPBool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), "\"") {
+	case "true", "1":
+		*b = true
+	case "false", "0", "", "null":
+		*b = false
+	default:
+		return fmt.Errorf("misp boolean value '%s' malformed", string(data))
+	}
+
+	return nil
+}
+
+// MISPAttributeTypes lists attribute types that can be imported as blacklisted hosts
+var MISPAttributeTypes = []string{
+	"ip-src",
+	"ip-dst",
+	"domain",
+	"hostname",
+	"url",
+	"email-src",
+	"email-dst",
+	"domain|ip",
+}
+
+// ParseMISPEvents parses MISP JSON export. Single event ({"Event": {...}}), list of events
+// and restSearch response ({"response": [{"Event": {...}}]}) are supported.
+func ParseMISPEvents(data []byte) ([]MISPEvent, error) {
+	type wrapped struct {
+		Event *MISPEvent `json:"Event"`
+	}
+
+	var single wrapped
+	if err := json.Unmarshal(data, &single); err == nil && single.Event != nil {
+		return []MISPEvent{*single.Event}, nil
+	}
+
+	var list []wrapped
+	if err := json.Unmarshal(data, &list); err != nil {
+		var response struct {
+			Response []wrapped `json:"response"`
+		}
+
+		if err = json.Unmarshal(data, &response); err != nil {
+			return nil, errors.New("misp events not found: " + err.Error())
+		}
+
+		list = response.Response
+	}
+
+	var events []MISPEvent
+	for _, w := range list {
+		if w.Event != nil {
+			events = append(events, *w.Event)
+		}
+	}
+
+	if len(events) == 0 {
+		return nil, errors.New("misp events not found")
+	}
+
+	return events, nil
+}
+
+// AllAttributes returns event attributes together with attributes of all event objects
+func (e *MISPEvent) AllAttributes() []MISPAttribute {
+	attributes := make([]MISPAttribute, 0, len(e.Attribute))
+	attributes = append(attributes, e.Attribute...)
+
+	for _, o := range e.Object {
+		attributes = append(attributes, o.Attribute...)
+	}
+
+	return attributes
+}
+
+// EventTime returns event timestamp, or event date if timestamp not defined
+func (e *MISPEvent) EventTime() time.Time {
+	if t := parseMISPTimestamp(e.Timestamp); !t.IsZero() {
+		return t
+	}
+
+	t, _ := time.Parse(time.DateOnly, e.Date)
+	return t
+}
+
+// Describe returns description of attribute with event info, comment and all event and attribute tags
+func (a *MISPAttribute) Describe(event MISPEvent) string {
+	var parts []string
+
+	if len(event.Info) > 0 {
+		parts = append(parts, "MISP: "+event.Info)
+	}
+
+	if len(a.Comment) > 0 {
+		parts = append(parts, a.Comment)
+	}
+
+	var tags []string
+	for _, t := range event.Tag {
+		tags = append(tags, t.Name)
+	}
+
+	for _, t := range a.Tag {
+		tags = append(tags, t.Name)
+	}
+
+	if len(tags) > 0 {
+		parts = append(parts, "tags: "+strings.Join(tags, ", "))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// Tags returns tags of attribute and its event. MISP galaxy tags, like misp-galaxy:threat-actor="APT 28", get tag kind.
+func (a *MISPAttribute) Tags(event MISPEvent) []BlacklistTag {
+	var names []string
+	for _, t := range event.Tag {
+		names = append(names, t.Name)
+	}
+
+	for _, t := range a.Tag {
+		names = append(names, t.Name)
+	}
+
+	return ParseTags(names)
+}
+
+// ToBlacklisted converts attribute into blacklisted hosts. Only attributes with types from MISPAttributeTypes are supported.
+// Composite domain|ip attributes are converted into domain and IP.
+func (a *MISPAttribute) ToBlacklisted(event MISPEvent, extractAll bool, sourceID uint64) (*BlacklistedIP, *BlacklistedDomain, *BlacklistedURL, *BlacklistedEmail, error) {
+	var ip_ *BlacklistedIP
+	var domain_ *BlacklistedDomain
+	var url_ *BlacklistedURL
+	var email_ *BlacklistedEmail
+
+	description := a.Describe(event)
+
+	discoveredAt := parseMISPTimestamp(a.Timestamp)
+	if discoveredAt.IsZero() {
+		discoveredAt = event.EventTime()
+	}
+
+	newIP := func(value string) (*BlacklistedIP, error) {
+		ipAddress, err := ParseInet(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return &BlacklistedIP{
+			IPAddress:    ipAddress,
+			Description:  description,
+			SourceID:     sourceID,
+			DiscoveredAt: discoveredAt,
+		}, nil
+	}
+
+	newDomain := func(value string) *BlacklistedDomain {
+		return &BlacklistedDomain{
+			URN:          strings.ToLower(strings.TrimSuffix(strings.TrimSpace(value), ".")),
+			Description:  description,
+			SourceID:     sourceID,
+			DiscoveredAt: discoveredAt,
+		}
+	}
+
+	var err error
+
+	switch a.Type {
+	case "ip-src", "ip-dst":
+		ip_, err = newIP(a.Value)
+	case "domain", "hostname":
+		domain_ = newDomain(a.Value)
+	case "domain|ip":
+		domain, ip, found := strings.Cut(a.Value, "|")
+		if !found {
+			return nil, nil, nil, nil, fmt.Errorf("composite value '%s' malformed", a.Value)
+		}
+
+		domain_ = newDomain(domain)
+		ip_, err = newIP(ip)
+	case "email-src", "email-dst":
+		email_ = &BlacklistedEmail{
+			Email:        strings.TrimSpace(a.Value),
+			Description:  description,
+			SourceID:     sourceID,
+			DiscoveredAt: discoveredAt,
+		}
+	case "url":
+		url_ = &BlacklistedURL{
+			URL:          strings.TrimSpace(a.Value),
+			Description:  description,
+			SourceID:     sourceID,
+			DiscoveredAt: discoveredAt,
+		}
+
+		if !extractAll {
+			break
+		}
+
+		u := url_.URL
+		if !strings.Contains(u, "//") {
+			u = "//" + u
+		}
+
+		parsed, parseErr := url.Parse(u)
+		if parseErr != nil || len(parsed.Hostname()) == 0 {
+			break
+		}
+
+		if DetectHostType(parsed.Hostname()) == "ip" {
+			ip_, err = newIP(parsed.Hostname())
+		} else {
+			domain_ = newDomain(parsed.Hostname())
+		}
+	default:
+		return nil, nil, nil, nil, fmt.Errorf("attribute type '%s' not supported", a.Type)
+	}
+
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	return ip_, domain_, url_, email_, nil
+}
+
+// NewMISPAttribute creates IDS attribute from blacklisted host
+func NewMISPAttribute(h BlacklistedHost) (MISPAttribute, error) {
+	attribute := MISPAttribute{
+		Category: "Network activity",
+		Value:    h.Host,
+		Comment:  h.Description,
+		ToIDs:    true,
+	}
+
+	switch h.Type {
+	case "ip":
+		attribute.Type = "ip-dst"
+	case "domain":
+		attribute.Type = "domain"
+	case "url":
+		attribute.Type = "url"
+	case "email":
+		attribute.Type = "email-src"
+		attribute.Category = "Payload delivery"
+	default:
+		return MISPAttribute{}, fmt.Errorf("host type '%s' not supported", h.Type)
+	}
+
+	for _, t := range h.Tags {
+		attribute.Tag = append(attribute.Tag, MISPTag{Name: t.Label()})
+	}
+
+	return attribute, nil
+}
+
+func parseMISPTimestamp(timestamp json.Number) time.Time {
+	seconds, err := strconv.ParseInt(timestamp.String(), 10, 64)
+	if err != nil || seconds == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(seconds, 0)
+}
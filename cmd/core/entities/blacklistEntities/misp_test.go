@@ -0,0 +1,95 @@
+package blacklistEntities
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseMISPEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "single event", data: `{"Event": {"info": "a"}}`, want: []string{"a"}},
+		{name: "list of events", data: `[{"Event": {"info": "a"}}, {"Event": {"info": "b"}}]`, want: []string{"a", "b"}},
+		{name: "restSearch response", data: `{"response": [{"Event": {"info": "a"}}]}`, want: []string{"a"}},
+		{name: "no events", data: `{"response": []}`, wantErr: true},
+		{name: "malformed", data: `not json`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := ParseMISPEvents([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMISPEvents() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if len(events) != len(tt.want) {
+				t.Fatalf("got %d events, want %d", len(events), len(tt.want))
+			}
+
+			for i, e := range events {
+				if e.Info != tt.want[i] {
+					t.Errorf("got event '%s', want '%s'", e.Info, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMISPBoolUnmarshalJSON(t *testing.T) {
+	for data, want := range map[string]bool{`true`: true, `"1"`: true, `1`: true, `false`: false, `"0"`: false, `null`: false} {
+		var b MISPBool
+		if err := json.Unmarshal([]byte(data), &b); err != nil || bool(b) != want {
+			t.Errorf("%s: got %v (%v), want %v", data, b, err, want)
+		}
+	}
+
+	var b MISPBool
+	if err := json.Unmarshal([]byte(`"yes"`), &b); err == nil {
+		t.Error("expected error of malformed boolean")
+	}
+}
+
+func TestMISPAttributeToBlacklisted(t *testing.T) {
+	event := MISPEvent{Date: "2024-01-01"}
+
+	a := MISPAttribute{Type: "url", Value: "http://10.0.0.1/a"}
+
+	ip, domain, url_, _, err := a.ToBlacklisted(event, true, SourceManual)
+	if err != nil || ip == nil || domain != nil || url_ == nil {
+		t.Fatalf("url host not extracted: %v %v %v %v", ip, domain, url_, err)
+	}
+
+	if ip.IPAddress.IPNet.String() != "10.0.0.1/32" || ip.DiscoveredAt != event.EventTime() || ip.SourceID != SourceManual {
+		t.Errorf("unexpected ip: %+v", ip)
+	}
+
+	for _, a = range []MISPAttribute{{Type: "domain|ip", Value: "evil.com"}, {Type: "sha256", Value: "abc"}, {Type: "ip-src", Value: "evil.com"}} {
+		if _, _, _, _, err = a.ToBlacklisted(event, false, SourceManual); err == nil {
+			t.Errorf("expected error of attribute %+v", a)
+		}
+	}
+}
+
+func TestMISPAttributeTags(t *testing.T) {
+	event := MISPEvent{Tag: []MISPTag{{Name: `misp-galaxy:threat-actor="APT28"`}, {Name: "tlp:amber"}}}
+	a := MISPAttribute{Type: "domain", Value: "evil.com", Tag: []MISPTag{{Name: "tlp:amber"}, {Name: `misp:category="Network activity"`}}}
+
+	want := []BlacklistTag{{Name: "APT28", Kind: TagKindActor}, {Name: "tlp:amber"}}
+	if got := a.Tags(event); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	exported, err := NewMISPAttribute(BlacklistedHost{Type: "domain", Host: "evil.com", Tags: want})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := exported.Tags(MISPEvent{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("exported tags parsed as %+v, want %+v", got, want)
+	}
+}
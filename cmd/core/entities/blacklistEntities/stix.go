@@ -3,8 +3,9 @@ package blacklistEntities
 import (
 	"errors"
 	"fmt"
-	"github.com/jackc/pgtype"
+	"github.com/google/uuid"
 	"log/slog"
+	"net"
 	"net/url"
 	"regexp"
 	"slices"
@@ -27,24 +28,44 @@ type STIX2Object struct {
 	Id            string     `json:"id"`
 	Created       time.Time  `json:"created"`
 	CreatedByRef  string     `json:"created_by_ref,omitempty"`
-	ValidFrom     time.Time  `json:"valid_from,omitempty"`
+	ValidFrom     *time.Time `json:"valid_from,omitempty"`
 	Modified      time.Time  `json:"modified"`
 	Published     *time.Time `json:"published,omitempty"`
-	Revoked       *time.Time `json:"revoked,omitempty"`
-	Name          string     `json:"name"`
-	IdentityClass string     `json:"identity_class"`
+	Revoked       *bool      `json:"revoked,omitempty"`
+	Name          string     `json:"name,omitempty"`
+	IdentityClass string     `json:"identity_class,omitempty"`
 
-	Description    string `json:"description,omitempty"`
-	Pattern        string `json:"pattern,omitempty"`
-	PatternType    string `json:"pattern_type,omitempty"`
-	PatternVersion string `json:"pattern_version,omitempty"`
+	Description    string   `json:"description,omitempty"`
+	IndicatorTypes []string `json:"indicator_types,omitempty"`
+	Pattern        string   `json:"pattern,omitempty"`
+	PatternType    string   `json:"pattern_type,omitempty"`
+	PatternVersion string   `json:"pattern_version,omitempty"`
 
-	KillChainPhases []struct {
-		KillChainName string `json:"kill_chain_name"`
-		PhaseName     string `json:"phase_name"`
-	} `json:"kill_chain_phases"`
+	KillChainPhases []STIX2KillChainPhase `json:"kill_chain_phases,omitempty"`
 
-	Labels []string `json:"labels"`
+	Labels     []string `json:"labels,omitempty"`
+	Confidence *int     `json:"confidence,omitempty"`
+
+	SightingOfRef    string     `json:"sighting_of_ref,omitempty"`
+	WhereSightedRefs []string   `json:"where_sighted_refs,omitempty"`
+	FirstSeen        *time.Time `json:"first_seen,omitempty"`
+	LastSeen         *time.Time `json:"last_seen,omitempty"`
+}
+
+type STIX2KillChainPhase struct {
+	KillChainName string `json:"kill_chain_name"`
+	PhaseName     string `json:"phase_name"`
+}
+
+// lockheedMartinKillChain contains phases of Lockheed Martin kill chain, other phases are exported as MITRE ATT&CK tactics
+var lockheedMartinKillChain = []string{
+	"reconnaissance",
+	"weaponization",
+	"delivery",
+	"exploitation",
+	"installation",
+	"command-and-control",
+	"actions-on-objectives",
 }
 
 var FilteredTypes = []string{
@@ -57,20 +78,18 @@ var FilteredLabels = []string{
 }
 
 func (s *STIX2Object) ToBlackListedIP(sourceID uint64) (BlacklistedIP, error) {
-	var ipAddress = pgtype.Inet{}
+	if !strings.Contains(s.Pattern, "network-traffic:dst_ref.type = 'ipv4-addr'") && !strings.Contains(s.Pattern, "network-traffic:dst_ref.type = 'ipv6-addr'") {
+		return BlacklistedIP{}, errors.New("IP address not found")
+	}
 
-	if !strings.Contains(s.Pattern, "network-traffic:dst_ref.type = 'ipv4-addr'") {
-		return BlacklistedIP{}, errors.New("IPv4 not found")
-	} else {
-		dstValue := ExtractIPFromPattern(s.Pattern)
-		if len(dstValue) == 0 {
-			return BlacklistedIP{}, errors.New("IPv4 not found")
-		}
+	dstValue := ExtractIPFromPattern(s.Pattern)
+	if len(dstValue) == 0 {
+		return BlacklistedIP{}, errors.New("IP address not found")
+	}
 
-		err := ipAddress.Set(dstValue)
-		if err != nil {
-			return BlacklistedIP{}, err
-		}
+	ipAddress, err := ParseInet(dstValue)
+	if err != nil {
+		return BlacklistedIP{}, err
 	}
 
 	return BlacklistedIP{
@@ -101,17 +120,16 @@ func (s *STIX2Object) ToBlacklisted(extractAll bool) (*BlacklistedIP, *Blacklist
 	}
 
 	if slices.Contains(s.Labels, "misp:type=\"ip-src\"") {
-		value := ExtractIPFromPattern(s.Pattern)
+		ipAddress, err := ParseInet(ExtractIPFromPattern(s.Pattern))
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+
 		ip_ = &BlacklistedIP{
-			IPAddress:    pgtype.Inet{},
+			IPAddress:    ipAddress,
 			Description:  s.Description,
 			SourceID:     sourceID,
-			DiscoveredAt: s.ValidFrom,
-		}
-
-		err := ip_.IPAddress.Set(value)
-		if err != nil {
-			return nil, nil, nil, nil, err
+			DiscoveredAt: s.validFrom(),
 		}
 
 		if !extractAll {
@@ -126,16 +144,16 @@ func (s *STIX2Object) ToBlacklisted(extractAll bool) (*BlacklistedIP, *Blacklist
 	if extractAll {
 		dstValue := ExtractIPFromPattern(s.Pattern)
 		if len(dstValue) != 0 {
+			ipAddress, err := ParseInet(dstValue)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+
 			ip_ = &BlacklistedIP{
-				IPAddress:    pgtype.Inet{},
+				IPAddress:    ipAddress,
 				Description:  s.Description,
 				SourceID:     sourceID,
-				DiscoveredAt: s.ValidFrom,
-			}
-
-			err := ip_.IPAddress.Set(dstValue)
-			if err != nil {
-				return nil, nil, nil, nil, err
+				DiscoveredAt: s.validFrom(),
 			}
 		}
 	}
@@ -145,7 +163,7 @@ func (s *STIX2Object) ToBlacklisted(extractAll bool) (*BlacklistedIP, *Blacklist
 			URL:          extractValueFromPattern(s.Pattern),
 			Description:  s.Description,
 			SourceID:     sourceID,
-			DiscoveredAt: s.ValidFrom,
+			DiscoveredAt: s.validFrom(),
 		}
 
 		if !extractAll {
@@ -168,7 +186,7 @@ func (s *STIX2Object) ToBlacklisted(extractAll bool) (*BlacklistedIP, *Blacklist
 					URN:          domain.Hostname(),
 					Description:  s.Description,
 					SourceID:     sourceID,
-					DiscoveredAt: s.ValidFrom,
+					DiscoveredAt: s.validFrom(),
 				}
 			} else {
 				slog.Warn(fmt.Sprintf("failed to parse hostname from URL '%s', error: %s", url_.URL, err.Error()))
@@ -181,7 +199,7 @@ func (s *STIX2Object) ToBlacklisted(extractAll bool) (*BlacklistedIP, *Blacklist
 			Email:        extractValueFromPattern(s.Pattern),
 			Description:  s.Description,
 			SourceID:     sourceID,
-			DiscoveredAt: s.ValidFrom,
+			DiscoveredAt: s.validFrom(),
 		}
 
 		if !extractAll {
@@ -189,9 +207,267 @@ func (s *STIX2Object) ToBlacklisted(extractAll bool) (*BlacklistedIP, *Blacklist
 		}
 	}
 
+	// indicators without MISP labels are parsed by STIX pattern object types
+	if ip_ == nil && domain_ == nil && url_ == nil && email_ == nil {
+		return s.fromPatternObjects(sourceID)
+	}
+
+	return ip_, domain_, url_, email_, nil
+}
+
+// stixPatternRegex matches simple comparison expressions in STIX patterns, like [ipv4-addr:value = '1.1.1.1'].
+// Networks can also be compared with ISSUBSET operator, like [ipv4-addr:value ISSUBSET '10.0.0.0/8'].
+var stixPatternRegex = regexp.MustCompile(`(ipv4-addr|ipv6-addr|domain-name|url|email-addr):value\s*(?:=|ISSUBSET)\s*'([^']+)'`)
+
+// fromPatternObjects extracts first value of every supported type from STIX pattern
+func (s *STIX2Object) fromPatternObjects(sourceID uint64) (*BlacklistedIP, *BlacklistedDomain, *BlacklistedURL, *BlacklistedEmail, error) {
+	var ip_ *BlacklistedIP
+	var domain_ *BlacklistedDomain
+	var url_ *BlacklistedURL
+	var email_ *BlacklistedEmail
+
+	if len(s.PatternType) > 0 && s.PatternType != "stix" {
+		return nil, nil, nil, nil, fmt.Errorf("pattern type '%s' not supported", s.PatternType)
+	}
+
+	for _, match := range stixPatternRegex.FindAllStringSubmatch(s.Pattern, -1) {
+		value := match[2]
+
+		switch match[1] {
+		case "ipv4-addr", "ipv6-addr":
+			if ip_ != nil {
+				continue
+			}
+
+			ipAddress, err := ParseInet(value)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+
+			ip_ = &BlacklistedIP{
+				IPAddress:    ipAddress,
+				Description:  s.Description,
+				SourceID:     sourceID,
+				DiscoveredAt: s.validFrom(),
+			}
+		case "domain-name":
+			if domain_ == nil {
+				domain_ = &BlacklistedDomain{
+					URN:          value,
+					Description:  s.Description,
+					SourceID:     sourceID,
+					DiscoveredAt: s.validFrom(),
+				}
+			}
+		case "url":
+			if url_ == nil {
+				url_ = &BlacklistedURL{
+					URL:          value,
+					Description:  s.Description,
+					SourceID:     sourceID,
+					DiscoveredAt: s.validFrom(),
+				}
+			}
+		case "email-addr":
+			if email_ == nil {
+				email_ = &BlacklistedEmail{
+					Email:        value,
+					Description:  s.Description,
+					SourceID:     sourceID,
+					DiscoveredAt: s.validFrom(),
+				}
+			}
+		}
+	}
+
 	return ip_, domain_, url_, email_, nil
 }
 
+// ThreatAttributes returns confidence, threat categories and kill chain phase of indicator. Categories are parsed from
+// indicator types and labels, MISP labels like misp:category="Payload delivery" are parsed by their value.
+func (s *STIX2Object) ThreatAttributes() ThreatAttributes {
+	var attributes ThreatAttributes
+
+	if s.Confidence != nil {
+		attributes.Confidence = *s.Confidence
+	}
+
+	var labels = slices.Clone(s.IndicatorTypes)
+	for _, l := range s.Labels {
+		if i := strings.Index(l, "=\""); i != -1 {
+			l = strings.Trim(l[i+1:], "\"")
+		}
+
+		labels = append(labels, l)
+	}
+
+	attributes.Categories = ParseThreatCategories(labels)
+
+	if len(s.KillChainPhases) > 0 {
+		attributes.KillChainPhase = s.KillChainPhases[0].PhaseName
+	}
+
+	return attributes.Normalize()
+}
+
+// Tags returns tags from indicator labels. Labels parsed as threat categories and MISP metadata are not tags.
+func (s *STIX2Object) Tags() []BlacklistTag {
+	var labels []string
+	for _, l := range s.Labels {
+		if _, ok := ParseThreatCategory(l); !ok {
+			labels = append(labels, l)
+		}
+	}
+
+	return ParseTags(labels)
+}
+
+// validFrom returns time from which indicator is valid, or zero time if not defined
+func (s *STIX2Object) validFrom() time.Time {
+	if s.ValidFrom == nil {
+		return time.Time{}
+	}
+
+	return *s.ValidFrom
+}
+
+// NewSTIX2Indicator creates STIX 2.1 indicator object from blacklisted host. Indicator ID is derived from host UUID.
+func NewSTIX2Indicator(h BlacklistedHost) (STIX2Object, error) {
+	var pattern string
+
+	value := strings.NewReplacer("\\", "\\\\", "'", "\\'").Replace(h.Host)
+
+	switch h.Type {
+	case "ip":
+		addr := h.Host
+		if ip, _, err := net.ParseCIDR(h.Host); err == nil {
+			addr = ip.String()
+		}
+
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return STIX2Object{}, fmt.Errorf("ip address '%s' malformed", h.Host)
+		} else if ip.To4() != nil {
+			pattern = fmt.Sprintf("[ipv4-addr:value = '%s']", value)
+		} else {
+			pattern = fmt.Sprintf("[ipv6-addr:value = '%s']", value)
+		}
+	case "domain":
+		pattern = fmt.Sprintf("[domain-name:value = '%s']", value)
+		if h.IncludeSubdomains {
+			pattern = fmt.Sprintf("[domain-name:value = '%s' OR domain-name:value LIKE '%%.%s']", value, value)
+		}
+	case "url":
+		pattern = fmt.Sprintf("[url:value = '%s']", value)
+	case "email":
+		pattern = fmt.Sprintf("[email-addr:value = '%s']", value)
+	default:
+		return STIX2Object{}, fmt.Errorf("host type '%s' not supported", h.Type)
+	}
+
+	validFrom := h.DiscoveredAt
+	if validFrom.IsZero() {
+		validFrom = h.CreatedAt
+	}
+
+	indicator := STIX2Object{
+		Type:           "indicator",
+		SpecVersion:    "2.1",
+		Id:             "indicator--" + uuid.UUID(h.UUID.Bytes).String(),
+		Created:        h.CreatedAt.UTC(),
+		Modified:       h.ChangedAt().UTC(),
+		ValidFrom:      &validFrom,
+		Name:           h.Host,
+		Description:    h.Description,
+		IndicatorTypes: []string{"malicious-activity"},
+		Pattern:        pattern,
+		PatternType:    "stix",
+		PatternVersion: "2.1",
+	}
+
+	if h.SourceID != 0 {
+		indicator.CreatedByRef = STIX2IdentityID(h.SourceID)
+	}
+
+	if h.Confidence > 0 {
+		confidence := h.Confidence
+		indicator.Confidence = &confidence
+	}
+
+	// tags are exported as labels after categories, so they are parsed back as tags
+	indicator.Labels = slices.Clone(h.Categories)
+	for _, t := range h.Tags {
+		indicator.Labels = append(indicator.Labels, t.Label())
+	}
+
+	if len(h.KillChainPhase) > 0 {
+		killChainName := "mitre-attack"
+		if slices.Contains(lockheedMartinKillChain, h.KillChainPhase) {
+			killChainName = "lockheed-martin-cyber-kill-chain"
+		}
+
+		indicator.KillChainPhases = []STIX2KillChainPhase{{KillChainName: killChainName, PhaseName: h.KillChainPhase}}
+	}
+
+	// soft deleted hosts are exported as revoked indicators
+	if h.DeletedAt.Valid {
+		revoked := true
+		indicator.Revoked = &revoked
+	}
+
+	return indicator, nil
+}
+
+// NewSTIX2Sightings creates STIX 2.1 sighting object for every source which reported blacklisted host. Sightings
+// reference indicator created by NewSTIX2Indicator and identity of source.
+func NewSTIX2Sightings(h BlacklistedHost) []STIX2Object {
+	var sightings []STIX2Object
+
+	for _, s := range h.Sightings {
+		firstSeen, lastSeen := s.FirstSeenAt.UTC(), s.LastSeenAt.UTC()
+
+		sightings = append(sightings, STIX2Object{
+			Type:             "sighting",
+			SpecVersion:      "2.1",
+			Id:               "sighting--" + uuid.NewSHA1(uuid.UUID(h.UUID.Bytes), []byte(fmt.Sprintf("blacklist-source-%d", s.SourceID))).String(),
+			Created:          firstSeen,
+			Modified:         lastSeen,
+			CreatedByRef:     STIX2IdentityID(s.SourceID),
+			Description:      s.Description,
+			SightingOfRef:    "indicator--" + uuid.UUID(h.UUID.Bytes).String(),
+			WhereSightedRefs: []string{STIX2IdentityID(s.SourceID)},
+			FirstSeen:        &firstSeen,
+			LastSeen:         &lastSeen,
+		})
+	}
+
+	return sightings
+}
+
+// NewSTIX2Identity creates STIX 2.1 identity object from blacklist source, referenced by indicators from this source
+func NewSTIX2Identity(source BlacklistSource) STIX2Object {
+	return STIX2Object{
+		Type:          "identity",
+		SpecVersion:   "2.1",
+		Id:            STIX2IdentityID(source.ID),
+		Created:       source.CreatedAt.UTC(),
+		Modified:      source.UpdatedAt.UTC(),
+		Name:          source.Name,
+		Description:   source.Description,
+		IdentityClass: "organization",
+	}
+}
+
+// STIX2IdentityID returns STIX identity ID of blacklist source. ID is always the same for defined source.
+func STIX2IdentityID(sourceID uint64) string {
+	return "identity--" + SourceUUID(sourceID)
+}
+
+// SourceUUID returns UUID derived from blacklist source ID, used in STIX and TAXII identifiers
+func SourceUUID(sourceID uint64) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("blacklist-source-%d", sourceID))).String()
+}
+
 // type, spec_version, id, created, modified
 // need to eject types in [identity, indicator]
 
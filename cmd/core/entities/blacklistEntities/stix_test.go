@@ -0,0 +1,215 @@
+package blacklistEntities
+
+import (
+	"github.com/jackc/pgtype"
+	"gorm.io/gorm"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewSTIX2Indicator(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		host        BlacklistedHost
+		wantPattern string
+		wantErr     bool
+	}{
+		{name: "ipv4 address", host: BlacklistedHost{Type: "ip", Host: "10.0.0.1"}, wantPattern: "[ipv4-addr:value = '10.0.0.1']"},
+		{name: "ipv4 network", host: BlacklistedHost{Type: "ip", Host: "10.0.0.0/24"}, wantPattern: "[ipv4-addr:value = '10.0.0.0/24']"},
+		{name: "ipv6 address", host: BlacklistedHost{Type: "ip", Host: "2001:db8::1"}, wantPattern: "[ipv6-addr:value = '2001:db8::1']"},
+		{name: "domain", host: BlacklistedHost{Type: "domain", Host: "evil.com"}, wantPattern: "[domain-name:value = 'evil.com']"},
+		{name: "url with quotes", host: BlacklistedHost{Type: "url", Host: `http://evil.com/a'b\c`}, wantPattern: `[url:value = 'http://evil.com/a\'b\\c']`},
+		{name: "email", host: BlacklistedHost{Type: "email", Host: "user@evil.com"}, wantPattern: "[email-addr:value = 'user@evil.com']"},
+		{name: "malformed ip", host: BlacklistedHost{Type: "ip", Host: "10.0.0.256"}, wantErr: true},
+		{name: "unknown type", host: BlacklistedHost{Type: "hash", Host: "e3b0c442"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.host.UUID = pgtype.UUID{Bytes: [16]byte{1}, Status: pgtype.Present}
+			tt.host.CreatedAt = created
+
+			got, err := NewSTIX2Indicator(tt.host)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", got)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Pattern != tt.wantPattern || got.PatternType != "stix" || got.Type != "indicator" {
+				t.Errorf("got pattern %s of type %s, want %s", got.Pattern, got.PatternType, tt.wantPattern)
+			}
+
+			if got.Id != "indicator--01000000-0000-0000-0000-000000000000" {
+				t.Errorf("indicator id not derived from host uuid: %s", got.Id)
+			}
+
+			if got.ValidFrom == nil || !got.ValidFrom.Equal(created) {
+				t.Errorf("indicator valid from creation time expected if discovery time not defined: %v", got.ValidFrom)
+			}
+		})
+	}
+}
+
+func TestNewSTIX2IndicatorSourceAndRevocation(t *testing.T) {
+	host := BlacklistedHost{Type: "domain", Host: "evil.com", SourceID: SourceKaspersky}
+
+	active, err := NewSTIX2Indicator(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if active.CreatedByRef != STIX2IdentityID(SourceKaspersky) || active.Revoked != nil {
+		t.Errorf("unexpected active indicator: %+v", active)
+	}
+
+	if identity := NewSTIX2Identity(DefaultSources[2]); identity.Id != active.CreatedByRef {
+		t.Errorf("indicator does not reference source identity %s: %s", identity.Id, active.CreatedByRef)
+	}
+
+	host.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+
+	revoked, err := NewSTIX2Indicator(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// revocation is the latest indicator modification
+	if revoked.Revoked == nil || !*revoked.Revoked || !revoked.Modified.Equal(host.DeletedAt.Time.UTC()) {
+		t.Errorf("deleted host not exported as revoked indicator: %+v", revoked)
+	}
+}
+
+func TestNewSTIX2IndicatorImportedBack(t *testing.T) {
+	for _, host := range []BlacklistedHost{
+		{Type: "ip", Host: "10.0.0.1"},
+		{Type: "domain", Host: "evil.com"},
+		{Type: "url", Host: "http://evil.com/a"},
+		{Type: "email", Host: "user@evil.com"},
+	} {
+		t.Run(host.Type, func(t *testing.T) {
+			indicator, err := NewSTIX2Indicator(host)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ip, domain, url, email, err := indicator.ToBlacklisted(false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got string
+			switch {
+			case ip != nil:
+				got = ip.IPAddress.IPNet.IP.String()
+			case domain != nil:
+				got = domain.URN
+			case url != nil:
+				got = url.URL
+			case email != nil:
+				got = email.Email
+			}
+
+			if got != host.Host {
+				t.Errorf("got %s imported back, want %s", got, host.Host)
+			}
+		})
+	}
+}
+
+func TestSTIX2ThreatAttributes(t *testing.T) {
+	confidence := 85
+
+	object := STIX2Object{
+		IndicatorTypes:  []string{"malicious-activity", "phishing"},
+		Labels:          []string{`misp:category="Payload delivery"`, "c2"},
+		Confidence:      &confidence,
+		KillChainPhases: []STIX2KillChainPhase{{KillChainName: "lockheed-martin-cyber-kill-chain", PhaseName: "Delivery"}},
+	}
+
+	want := ThreatAttributes{
+		Confidence:     85,
+		Categories:     []string{ThreatCategoryPhishing, ThreatCategoryMalwareDistribution, ThreatCategoryC2},
+		KillChainPhase: "delivery",
+	}
+
+	got := object.ThreatAttributes()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// exported indicator keeps the same attributes
+	indicator, err := NewSTIX2Indicator(BlacklistedHost{Type: "domain", Host: "evil.com", ThreatAttributes: got})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exported := indicator.ThreatAttributes(); !reflect.DeepEqual(exported, want) {
+		t.Errorf("got %+v, want %+v", exported, want)
+	}
+
+	if phases := indicator.KillChainPhases; len(phases) != 1 || phases[0].KillChainName != "lockheed-martin-cyber-kill-chain" {
+		t.Errorf("unexpected kill chain phases: %+v", phases)
+	}
+
+	indicator, _ = NewSTIX2Indicator(BlacklistedHost{Type: "domain", Host: "evil.com", ThreatAttributes: ThreatAttributes{KillChainPhase: "exfiltration"}})
+	if phases := indicator.KillChainPhases; len(phases) != 1 || phases[0].KillChainName != "mitre-attack" {
+		t.Errorf("unexpected kill chain phases: %+v", phases)
+	}
+}
+
+func TestSTIX2Tags(t *testing.T) {
+	host := BlacklistedHost{
+		Type:             "domain",
+		Host:             "evil.com",
+		ThreatAttributes: ThreatAttributes{Categories: []string{ThreatCategoryPhishing}},
+		Tags:             []BlacklistTag{{Name: "APT28", Kind: TagKindActor}, {Name: "tlp:amber"}},
+	}
+
+	indicator, err := NewSTIX2Indicator(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"phishing", "actor:APT28", "tlp:amber"}; !reflect.DeepEqual(indicator.Labels, want) {
+		t.Errorf("got labels %v, want %v", indicator.Labels, want)
+	}
+
+	// categories are not parsed as tags
+	if got := indicator.Tags(); !reflect.DeepEqual(got, host.Tags) {
+		t.Errorf("got tags %+v, want %+v", got, host.Tags)
+	}
+}
+
+func TestNewSTIX2Sightings(t *testing.T) {
+	seen := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	host := BlacklistedHost{
+		UUID: pgtype.UUID{Bytes: [16]byte{1}, Status: pgtype.Present},
+		Sightings: []BlacklistSighting{
+			{SourceID: SourceKaspersky, FirstSeenAt: seen, LastSeenAt: seen},
+			{SourceID: SourceDrWeb, FirstSeenAt: seen, LastSeenAt: seen},
+		},
+	}
+
+	sightings := NewSTIX2Sightings(host)
+	if len(sightings) != 2 {
+		t.Fatalf("expected sighting of every source, got %+v", sightings)
+	}
+
+	s := sightings[0]
+	if s.Type != "sighting" || s.SightingOfRef != "indicator--01000000-0000-0000-0000-000000000000" || s.WhereSightedRefs[0] != STIX2IdentityID(SourceKaspersky) {
+		t.Errorf("unexpected sighting: %+v", s)
+	}
+
+	// identifiers are stable and unique for every source
+	if again := NewSTIX2Sightings(host); again[0].Id != s.Id || sightings[1].Id == s.Id {
+		t.Errorf("unexpected sighting identifiers: %s, %s, %s", s.Id, again[0].Id, sightings[1].Id)
+	}
+}
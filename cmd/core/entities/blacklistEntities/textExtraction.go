@@ -0,0 +1,213 @@
+package blacklistEntities
+
+import (
+	"golang.org/x/net/publicsuffix"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// TextIndicators contains deduplicated indicators extracted from free text
+type TextIndicators struct {
+	IPs      []string `json:"IPs"`
+	Networks []string `json:"Networks"`
+	Domains  []string `json:"Domains"`
+	URLs     []string `json:"URLs"`
+	Emails   []string `json:"Emails"`
+}
+
+// Count returns total number of extracted indicators
+func (i TextIndicators) Count() int {
+	return len(i.IPs) + len(i.Networks) + len(i.Domains) + len(i.URLs) + len(i.Emails)
+}
+
+// Values returns all extracted indicators as single list
+func (i TextIndicators) Values() []string {
+	values := make([]string, 0, i.Count())
+
+	values = append(values, i.IPs...)
+	values = append(values, i.Networks...)
+	values = append(values, i.Domains...)
+	values = append(values, i.URLs...)
+	values = append(values, i.Emails...)
+
+	return values
+}
+
+var (
+	// defangReplacer replaces common defanged forms, like example[.]com or user[at]example.com
+	defangReplacer = strings.NewReplacer(
+		"[.]", ".", "(.)", ".", "{.}", ".", "[dot]", ".", "(dot)", ".", "{dot}", ".",
+		"[:]", ":", "[://]", "://",
+		"[@]", "@", "[at]", "@", "(at)", "@", "{at}", "@",
+		"[/]", "/",
+	)
+	defangSchemeRegex = regexp.MustCompile(`(?i)\b(?:h[x*]{2}ps?|fxp)://`)
+
+	textURLRegex    = regexp.MustCompile(`(?i)\b(?:https?|ftp)://[^\s<>"'\x60]+`)
+	textEmailRegex  = regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9-]{0,61}[a-z0-9]\b`)
+	textIPv4Regex   = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}(?:/\d{1,2})?\b`)
+	textIPv6Regex   = regexp.MustCompile(`(?i)(?:[0-9a-f]{0,4}:){2,7}[0-9a-f]{0,4}(?:/\d{1,3})?`)
+	textDomainRegex = regexp.MustCompile(`(?i)\b(?:[a-z0-9_](?:[a-z0-9-_]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9-]{0,61}[a-z0-9]\b`)
+)
+
+// RefangText restores defanged indicators, like hxxp://example[.]com, into their original form
+func RefangText(text string) string {
+	text = defangReplacer.Replace(text)
+
+	return defangSchemeRegex.ReplaceAllStringFunc(text, func(s string) string {
+		lower := strings.ToLower(s)
+
+		switch {
+		case strings.HasPrefix(lower, "fxp"):
+			return "ftp://"
+		case strings.HasSuffix(lower, "s://"):
+			return "https://"
+		default:
+			return "http://"
+		}
+	})
+}
+
+// ExtractIndicatorsFromText extracts IPv4/IPv6 addresses, CIDRs, domains, URLs and emails from arbitrary text.
+// Defanged indicators are refanged. Hosts of extracted URLs and emails are not extracted as separate domains.
+// Domains are extracted only if they have known public suffix, so file names like report.pdf are skipped.
+func ExtractIndicatorsFromText(text string) TextIndicators {
+	var result TextIndicators
+
+	text = RefangText(text)
+
+	// every extracted value is cut from text, so it is not matched again by less specific pattern
+	cut := func(re *regexp.Regexp, handle func(value string) bool) {
+		text = re.ReplaceAllStringFunc(text, func(match string) string {
+			if handle(match) {
+				return " "
+			}
+
+			return match
+		})
+	}
+
+	cut(textURLRegex, func(value string) bool {
+		value = trimURL(value)
+		result.URLs = append(result.URLs, value)
+		return true
+	})
+
+	cut(textEmailRegex, func(value string) bool {
+		result.Emails = append(result.Emails, value)
+		return true
+	})
+
+	addIP := func(value string) bool {
+		if strings.Contains(value, "/") {
+			_, network, err := net.ParseCIDR(value)
+			if err != nil {
+				return false
+			}
+
+			result.Networks = append(result.Networks, network.String())
+			return true
+		}
+
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return false
+		}
+
+		result.IPs = append(result.IPs, ip.String())
+		return true
+	}
+
+	cut(textIPv4Regex, addIP)
+
+	// IPv6 pattern also matches parts of words, like d:: of std::string, so match must not be surrounded by them
+	for _, loc := range textIPv6Regex.FindAllStringIndex(text, -1) {
+		value := text[loc[0]:loc[1]]
+
+		if isWordBoundary(text, loc[0]-1) && isWordBoundary(text, loc[1]) && isIPv6(value) && addIP(value) {
+			text = text[:loc[0]] + strings.Repeat(" ", len(value)) + text[loc[1]:]
+		}
+	}
+
+	cut(textDomainRegex, func(value string) bool {
+		value = strings.ToLower(value)
+
+		if _, icann := publicsuffix.PublicSuffix(value); !icann {
+			return false
+		}
+
+		result.Domains = append(result.Domains, value)
+		return true
+	})
+
+	result.IPs = uniqueValues(result.IPs)
+	result.Networks = uniqueValues(result.Networks)
+	result.Domains = uniqueValues(result.Domains)
+	result.URLs = uniqueValues(result.URLs)
+	result.Emails = uniqueValues(result.Emails)
+
+	return result
+}
+
+// trimURL removes punctuation following URL in text. Closing bracket is removed only if URL has no matching opening
+// bracket, so URL in parentheses is trimmed, but https://example.com/a(b) is kept intact.
+func trimURL(value string) string {
+	brackets := map[byte]string{')': "(", ']': "[", '}': "{"}
+
+	for len(value) > 0 {
+		last := value[len(value)-1]
+
+		switch last {
+		case '.', ',', ';', ':', '!', '?', '\'', '"':
+		case ')', ']', '}':
+			if strings.Count(value, brackets[last]) >= strings.Count(value, string(last)) {
+				return value
+			}
+		default:
+			return value
+		}
+
+		value = value[:len(value)-1]
+	}
+
+	return value
+}
+
+// isWordBoundary checks if byte at index of text can not continue IPv6 address, indexes out of text are boundaries
+func isWordBoundary(text string, index int) bool {
+	if index < 0 || index >= len(text) {
+		return true
+	}
+
+	c := text[index]
+
+	return !(c == ':' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z')
+}
+
+// isIPv6 checks if value is IPv6 address or network written with at least 3 groups or starting with group, so
+// short colon-separated tokens like ::ba are not treated as addresses. Unspecified and loopback addresses are rejected.
+func isIPv6(value string) bool {
+	address, _, _ := strings.Cut(value, "/")
+
+	var groups int
+	for _, g := range strings.Split(address, ":") {
+		if len(g) > 0 {
+			groups++
+		}
+	}
+
+	if groups < 3 && strings.HasPrefix(address, ":") {
+		return false
+	}
+
+	ip := net.ParseIP(address)
+
+	return ip != nil && !ip.IsUnspecified() && !ip.IsLoopback()
+}
+
+func uniqueValues(values []string) []string {
+	slices.Sort(values)
+	return slices.Compact(values)
+}
@@ -0,0 +1,93 @@
+package blacklistEntities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractIndicatorsFromText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want TextIndicators
+	}{
+		{
+			name: "defanged indicators",
+			text: "C2 at hxxps://evil[.]com/login and 10.0.0[.]1, mail from admin[at]phish[.]net",
+			want: TextIndicators{IPs: []string{"10.0.0.1"}, URLs: []string{"https://evil.com/login"}, Emails: []string{"admin@phish.net"}},
+		},
+		{
+			name: "networks and duplicates",
+			text: "ranges 192.168.1.0/24, 192.168.1.0/24 and 2001:db8::/32",
+			want: TextIndicators{Networks: []string{"192.168.1.0/24", "2001:db8::/32"}},
+		},
+		{
+			name: "ipv6 addresses",
+			text: "hosts fe80::1 and 2001:db8:0:0:1:0:0:1.",
+			want: TextIndicators{IPs: []string{"2001:db8::1:0:0:1", "fe80::1"}},
+		},
+		{
+			name: "code is not ipv6",
+			text: "std::string and Foo::Bar, see a::b::c",
+			want: TextIndicators{},
+		},
+		{
+			name: "unspecified and loopback ipv6",
+			text: "listen on :: and ::1",
+			want: TextIndicators{},
+		},
+		{
+			name: "url in parentheses",
+			text: "see (https://evil.com/path), or https://evil.com/a(b).",
+			want: TextIndicators{URLs: []string{"https://evil.com/a(b)", "https://evil.com/path"}},
+		},
+		{
+			name: "domains with public suffix only",
+			text: "Evil.COM dropped report.pdf to cdn.example.org",
+			want: TextIndicators{Domains: []string{"cdn.example.org", "evil.com"}},
+		},
+		{
+			name: "url and email hosts are not domains",
+			text: "https://evil.com/ and user@phish.net",
+			want: TextIndicators{URLs: []string{"https://evil.com/"}, Emails: []string{"user@phish.net"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractIndicatorsFromText(tt.text)
+
+			// empty and nil lists are equal for comparison
+			for _, list := range []*[]string{&got.IPs, &got.Networks, &got.Domains, &got.URLs, &got.Emails} {
+				if len(*list) == 0 {
+					*list = nil
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimURL(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "https://evil.com/a.", want: "https://evil.com/a"},
+		{value: "https://evil.com/a),", want: "https://evil.com/a"},
+		{value: "https://evil.com/a(b)", want: "https://evil.com/a(b)"},
+		{value: "https://evil.com/a(b)).", want: "https://evil.com/a(b)"},
+		{value: "https://evil.com/a[1]]", want: "https://evil.com/a[1]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := trimURL(tt.value); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
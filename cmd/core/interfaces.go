@@ -11,33 +11,94 @@ import (
 
 type IBlacklistsService interface {
 	RetrieveIPsByFilter(blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedIP, error)
-	SaveIPs([]blacklistEntities.BlacklistedIP) (int64, error)
-	DeleteIP(uuid pgtype.UUID) (int64, error)
+	// RetrieveIPsContaining returns blacklisted addresses and networks covering defined IP address or network
+	RetrieveIPsContaining(value string) ([]blacklistEntities.BlacklistedIP, error)
+	SaveIPs(hosts []blacklistEntities.BlacklistedIP, userID *uint64) (int64, error)
+	DeleteIP(uuid pgtype.UUID, userID *uint64) (int64, error)
 
 	RetrieveDomainsByFilter(blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedDomain, error)
-	SaveDomains([]blacklistEntities.BlacklistedDomain) (int64, error)
-	DeleteDomain(uuid pgtype.UUID) (int64, error)
+	SaveDomains(hosts []blacklistEntities.BlacklistedDomain, userID *uint64) (int64, error)
+	DeleteDomain(uuid pgtype.UUID, userID *uint64) (int64, error)
 
 	RetrieveURLsByFilter(blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedURL, error)
-	SaveURLs([]blacklistEntities.BlacklistedURL) (int64, error)
-	DeleteURL(uuid pgtype.UUID) (int64, error)
+	SaveURLs(hosts []blacklistEntities.BlacklistedURL, userID *uint64) (int64, error)
+	DeleteURL(uuid pgtype.UUID, userID *uint64) (int64, error)
 
 	RetrieveEmailsByFilter(blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedEmail, error)
-	SaveEmails([]blacklistEntities.BlacklistedEmail) (int64, error)
-	DeleteEmail(uuid pgtype.UUID) (int64, error)
+	SaveEmails(hosts []blacklistEntities.BlacklistedEmail, userID *uint64) (int64, error)
+	DeleteEmail(uuid pgtype.UUID, userID *uint64) (int64, error)
 
 	SaveImportEvent(event blacklistEntities.BlacklistImportEvent) (blacklistEntities.BlacklistImportEvent, error)
 	RetrieveImportEventsByFilter(filter blacklistEntities.BlacklistImportEventFilter) ([]blacklistEntities.BlacklistImportEvent, error)
 	RetrieveImportEvent(id uint64) (blacklistEntities.BlacklistImportEvent, error)
 	DeleteImportEvent(id uint64) (int64, error)
+	RetrieveImportErrors(eventID uint64, filter blacklistEntities.BlacklistImportErrorFilter) ([]blacklistEntities.BlacklistImportError, error)
+	ExportImportErrorsToCSV(eventID uint64) ([]byte, error)
+
+	RetrieveAllImportProfiles() ([]blacklistEntities.BlacklistImportProfile, error)
+	RetrieveImportProfile(id uint64) (blacklistEntities.BlacklistImportProfile, error)
+	SaveImportProfile(profile blacklistEntities.BlacklistImportProfile) (blacklistEntities.BlacklistImportProfile, error)
+	DeleteImportProfile(id uint64) (int64, error)
+
+	RetrieveAllTTLPolicies() ([]blacklistEntities.BlacklistTTLPolicy, error)
+	SaveTTLPolicy(policy blacklistEntities.BlacklistTTLPolicy) (blacklistEntities.BlacklistTTLPolicy, error)
+	DeleteTTLPolicy(id uint64) (int64, error)
+
+	RetrieveAllowlist() ([]blacklistEntities.AllowlistEntry, error)
+	// SaveAllowlistEntry creates or updates allowlist entry and returns blacklisted hosts conflicting with it
+	SaveAllowlistEntry(entry blacklistEntities.AllowlistEntry) (blacklistEntities.AllowlistEntry, []blacklistEntities.BlacklistedHost, error)
+	DeleteAllowlistEntry(id uint64) (int64, error)
+	// RetrieveAllowlistConflicts returns blacklisted hosts matching allowlist entry, so they can be reviewed and deleted
+	RetrieveAllowlistConflicts(id uint64) ([]blacklistEntities.BlacklistedHost, error)
+
+	RetrieveAllTags() ([]blacklistEntities.BlacklistTag, error)
+	SaveTag(tag blacklistEntities.BlacklistTag) (blacklistEntities.BlacklistTag, error)
+	DeleteTag(id uint64) (int64, error)
+	// TagHosts links hosts with tags, hosts are defined by type and UUID
+	TagHosts(tagIDs []uint64, hosts []blacklistEntities.BlacklistedHost) (int64, error)
+	// UntagHosts unlinks hosts from tags, hosts are defined by type and UUID
+	UntagHosts(tagIDs []uint64, hosts []blacklistEntities.BlacklistedHost) (int64, error)
+	// RetrieveTagStatistics returns all tags with number of active hosts tagged by them
+	RetrieveTagStatistics() ([]blacklistEntities.BlacklistTagCount, error)
+
+	// RetrieveHostHistory returns all revisions of host of any type, the latest first
+	RetrieveHostHistory(uuid pgtype.UUID) ([]blacklistEntities.BlacklistHostRevision, error)
+	// RestoreHost makes soft deleted host of any type active again
+	RestoreHost(uuid pgtype.UUID, userID *uint64) (int64, error)
+
+	// ExpireHosts deletes all hosts with expiration date in the past, returns number of deleted hosts
+	ExpireHosts() (int64, error)
+	// StartExpirer starts deleting expired hosts with defined interval
+	StartExpirer(interval time.Duration)
 
 	RetrieveHostsByFilter(blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedHost, error)
 
-	ImportFromSTIX2(bundles []blacklistEntities.STIX2Bundle, extractAll bool) (blacklistEntities.BlacklistImportEvent, error)
-	ImportFromCSV(data [][]string, discoveredAt time.Time, extractAll bool) (blacklistEntities.BlacklistImportEvent, error)
+	// CheckValues detects type of every value and returns all blacklist entries matching it
+	CheckValues(values []string) ([]blacklistEntities.BlacklistCheckResult, error)
+
+	ImportFromSTIX2(bundles []blacklistEntities.STIX2Bundle, extractAll bool, sourceID uint64) (blacklistEntities.BlacklistImportEvent, error)
+	ImportFromCSV(data [][]string, discoveredAt time.Time, extractAll bool, sourceID uint64) (blacklistEntities.BlacklistImportEvent, error)
+	// StartImportJob saves imported hosts in background, returned import event is updated with progress
+	StartImportJob(job blacklistEntities.BlacklistImportJob) (blacklistEntities.BlacklistImportEvent, error)
+	CancelImportJob(eventID uint64) error
+	// FailInterruptedImportJobs marks import jobs left incomplete by previous application run as failed
+	FailInterruptedImportJobs() (int64, error)
+
+	// Import previews are available only to user who created them
+	PreviewImportFromSTIX2(bundles []blacklistEntities.STIX2Bundle, extractAll bool, sourceID uint64, userID *uint64) (blacklistEntities.BlacklistImportPreview, error)
+	PreviewImportFromCSV(data [][]string, profile blacklistEntities.BlacklistImportProfile, discoveredAt time.Time, extractAll bool, sourceID uint64, userID *uint64) (blacklistEntities.BlacklistImportPreview, error)
+	RetrieveImportPreview(id string, userID *uint64) (blacklistEntities.BlacklistImportPreview, error)
+	ImportFromMISP(events []blacklistEntities.MISPEvent, extractAll bool, sourceID uint64) (blacklistEntities.BlacklistImportEvent, error)
 
 	ExportToJSON(blacklistEntities.BlacklistSearchFilter) ([]byte, error)
 	ExportToCSV(blacklistEntities.BlacklistSearchFilter) ([]byte, error)
+	ExportToSTIX(blacklistEntities.BlacklistSearchFilter) ([]byte, error)
+	ExportToXLSX(blacklistEntities.BlacklistSearchFilter) ([]byte, error)
+	ExportToFormat(format blacklistEntities.ExportFormat, filter blacklistEntities.BlacklistExportFilter) ([]byte, error)
+
+	// ExportDelta returns hosts added, updated and removed since cursor in JSON, CSV or STIX format, cursor for next request
+	// and flag set if delta was cut by limit
+	ExportDelta(format blacklistEntities.ExportFormat, filter blacklistEntities.BlacklistDeltaFilter) ([]byte, string, bool, error)
 	ExportToNaumen(filter blacklistEntities.BlacklistSearchFilter) (serviceDeskEntities.ServiceDeskTicket, error)
 
 	RetrieveTotalStatistics() (ips int64, urls int64, domains int64, emails int64)
@@ -49,27 +110,76 @@ type IBlacklistsService interface {
 
 type IBlacklistsRepo interface {
 	SelectIPsByFilter(blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedIP, error)
-	SaveIPs([]blacklistEntities.BlacklistedIP) (int64, error)
-	DeleteIP(uuid pgtype.UUID) (int64, error)
+	SelectIPsContaining(addr string) ([]blacklistEntities.BlacklistedIP, error)
+	SaveIPs(hosts []blacklistEntities.BlacklistedIP, userID *uint64) (int64, error)
+	DeleteIP(uuid pgtype.UUID, userID *uint64) (int64, error)
 
 	SelectDomainsByFilter(blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedDomain, error)
-	SaveDomains([]blacklistEntities.BlacklistedDomain) (int64, error)
-	DeleteDomain(uuid pgtype.UUID) (int64, error)
+	SaveDomains(hosts []blacklistEntities.BlacklistedDomain, userID *uint64) (int64, error)
+	DeleteDomain(uuid pgtype.UUID, userID *uint64) (int64, error)
 
 	SelectURLsByFilter(blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedURL, error)
-	SaveURLs([]blacklistEntities.BlacklistedURL) (int64, error)
-	DeleteURL(uuid pgtype.UUID) (int64, error)
+	SaveURLs(hosts []blacklistEntities.BlacklistedURL, userID *uint64) (int64, error)
+	DeleteURL(uuid pgtype.UUID, userID *uint64) (int64, error)
 
 	SelectEmailsByFilter(blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedEmail, error)
-	SaveEmails([]blacklistEntities.BlacklistedEmail) (int64, error)
-	DeleteEmail(uuid pgtype.UUID) (int64, error)
+	SaveEmails(hosts []blacklistEntities.BlacklistedEmail, userID *uint64) (int64, error)
+	DeleteEmail(uuid pgtype.UUID, userID *uint64) (int64, error)
 
 	SaveImportEvent(event blacklistEntities.BlacklistImportEvent) (blacklistEntities.BlacklistImportEvent, error)
 	SelectImportEventsByFilter(filter blacklistEntities.BlacklistImportEventFilter) ([]blacklistEntities.BlacklistImportEvent, error)
 	SelectImportEvent(id uint64) (blacklistEntities.BlacklistImportEvent, error)
 	DeleteImportEvent(id uint64) (int64, error)
+	// FailIncompleteImportEvents marks all incomplete import events as complete with error reason
+	FailIncompleteImportEvents(reason string) (int64, error)
+	SaveImportErrors(importErrors []blacklistEntities.BlacklistImportError) error
+	SelectImportErrors(eventID uint64, filter blacklistEntities.BlacklistImportErrorFilter) ([]blacklistEntities.BlacklistImportError, error)
+
+	SelectAllImportProfiles() ([]blacklistEntities.BlacklistImportProfile, error)
+	SelectImportProfile(id uint64) (blacklistEntities.BlacklistImportProfile, error)
+	SaveImportProfile(profile blacklistEntities.BlacklistImportProfile) (blacklistEntities.BlacklistImportProfile, error)
+	DeleteImportProfile(id uint64) (int64, error)
+
+	SelectAllTTLPolicies() ([]blacklistEntities.BlacklistTTLPolicy, error)
+	SaveTTLPolicy(policy blacklistEntities.BlacklistTTLPolicy) (blacklistEntities.BlacklistTTLPolicy, error)
+	DeleteTTLPolicy(id uint64) (int64, error)
+
+	SelectAllowlist() ([]blacklistEntities.AllowlistEntry, error)
+	SelectAllowlistEntry(id uint64) (blacklistEntities.AllowlistEntry, error)
+	SaveAllowlistEntry(entry blacklistEntities.AllowlistEntry) (blacklistEntities.AllowlistEntry, error)
+	DeleteAllowlistEntry(id uint64) (int64, error)
+	// SelectAllowlistConflicts returns active blacklisted hosts matching allowlist entry
+	SelectAllowlistConflicts(entry blacklistEntities.AllowlistEntry) ([]blacklistEntities.BlacklistedHost, error)
+
+	SelectAllTags() ([]blacklistEntities.BlacklistTag, error)
+	SaveTag(tag blacklistEntities.BlacklistTag) (blacklistEntities.BlacklistTag, error)
+	// SaveTagsByKey creates missing tags by kind and name and returns all tags with IDs
+	SaveTagsByKey(tags []blacklistEntities.BlacklistTag) ([]blacklistEntities.BlacklistTag, error)
+	DeleteTag(id uint64) (int64, error)
+	SaveHostTags(type_ string, uuids []pgtype.UUID, tagIDs []uint64) (int64, error)
+	DeleteHostTags(type_ string, uuids []pgtype.UUID, tagIDs []uint64) (int64, error)
+	// SelectHostsTags returns tags of hosts of single type by host UUID
+	SelectHostsTags(type_ string, uuids []pgtype.UUID) (map[[16]byte][]blacklistEntities.BlacklistTag, error)
+	CountTagStatistics() ([]blacklistEntities.BlacklistTagCount, error)
+
+	// SelectHostsSightings returns sightings of hosts of single type by host UUID
+	SelectHostsSightings(type_ string, uuids []pgtype.UUID) (map[[16]byte][]blacklistEntities.BlacklistSighting, error)
+
+	// DeleteExpiredHosts soft deletes all hosts expired before defined time
+	DeleteExpiredHosts(now time.Time) (int64, error)
+
+	// SelectHost returns host of any type by UUID, including deleted hosts
+	SelectHost(uuid pgtype.UUID) (blacklistEntities.BlacklistedHost, error)
+	RestoreHost(type_ string, uuid pgtype.UUID, validUntil *time.Time, userID *uint64) (int64, error)
+	SelectHostRevisions(uuid pgtype.UUID) ([]blacklistEntities.BlacklistHostRevision, error)
 
 	SelectHostsUnionByFilter(filter blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedHost, error)
+	SelectHostsByValues(type_ string, values []string) ([]blacklistEntities.BlacklistedHost, error)
+
+	SelectIPsContainingAny(addrs []string) ([]blacklistEntities.BlacklistedIP, error)
+	SelectDomainsByURNs(urns []string) ([]blacklistEntities.BlacklistedDomain, error)
+	SelectURLsByMD5(hashes []string) ([]blacklistEntities.BlacklistedURL, error)
+	SelectEmailsByValues(emails []string) ([]blacklistEntities.BlacklistedEmail, error)
 
 	CountStatistics() (ips int64, urls int64, domains int64, emails int64)
 	SelectByCreationDateStatistics(startDate, endDate time.Time) ([]blacklistEntities.BlacklistedByDate, error)
@@ -78,6 +188,66 @@ type IBlacklistsRepo interface {
 	SelectAllSources() ([]blacklistEntities.BlacklistSource, error)
 }
 
+// IBlacklistImportFeedsService manages remote indicator feeds, which are pulled and imported on schedule
+type IBlacklistImportFeedsService interface {
+	RetrieveAllFeeds() ([]blacklistEntities.BlacklistImportFeed, error)
+	RetrieveFeed(id uint64) (blacklistEntities.BlacklistImportFeed, error)
+	SaveFeed(feed blacklistEntities.BlacklistImportFeed) (blacklistEntities.BlacklistImportFeed, error)
+	DeleteFeed(id uint64) (int64, error)
+
+	// RunFeed pulls feed immediately and starts import job, returned import event is updated with progress
+	RunFeed(id uint64) (blacklistEntities.BlacklistImportEvent, error)
+
+	// StartScheduler starts checking all feeds with defined interval, runs every feed that is due
+	StartScheduler(interval time.Duration)
+}
+
+type IBlacklistImportFeedsRepo interface {
+	SelectAllFeeds() ([]blacklistEntities.BlacklistImportFeed, error)
+	SelectFeed(id uint64) (blacklistEntities.BlacklistImportFeed, error)
+	SaveFeed(feed blacklistEntities.BlacklistImportFeed) (blacklistEntities.BlacklistImportFeed, error)
+	DeleteFeed(id uint64) (int64, error)
+
+	SelectTAXIIBookmarks(feedID uint64) ([]blacklistEntities.ImportFeedTAXIIBookmark, error)
+	SaveTAXIIBookmark(bookmark blacklistEntities.ImportFeedTAXIIBookmark) error
+}
+
+// IBlacklistExportFeedsService manages public export feeds, which are pulled by network devices with per-consumer tokens
+type IBlacklistExportFeedsService interface {
+	RetrieveAllFeeds() ([]blacklistEntities.BlacklistExportFeed, error)
+	RetrieveFeed(id uint64) (blacklistEntities.BlacklistExportFeed, error)
+	SaveFeed(feed blacklistEntities.BlacklistExportFeed) (blacklistEntities.BlacklistExportFeed, error)
+	DeleteFeed(id uint64) (int64, error)
+
+	// IssueToken creates new consumer token for feed, plain token value is returned only once
+	IssueToken(feedID uint64, consumer string, expiresAt *time.Time) (blacklistEntities.BlacklistExportFeedToken, error)
+	RevokeToken(id uint64) (int64, error)
+
+	// RetrieveFeedByToken returns valid token with its feed, returns error if token not found, expired or feed disabled
+	RetrieveFeedByToken(token string) (blacklistEntities.BlacklistExportFeedToken, error)
+
+	// RenderFeed returns rendered feed contents, its ETag and time of last modification of feed or its hosts
+	RenderFeed(feed blacklistEntities.BlacklistExportFeed) (data []byte, etag string, lastModified time.Time, err error)
+
+	LogAccess(token blacklistEntities.BlacklistExportFeedToken, remoteAddr, userAgent string, statusCode int)
+	RetrieveAccessLog(filter blacklistEntities.BlacklistExportFeedAccessFilter) ([]blacklistEntities.BlacklistExportFeedAccess, error)
+}
+
+type IBlacklistExportFeedsRepo interface {
+	SelectAllFeeds() ([]blacklistEntities.BlacklistExportFeed, error)
+	SelectFeed(id uint64) (blacklistEntities.BlacklistExportFeed, error)
+	SaveFeed(feed blacklistEntities.BlacklistExportFeed) (blacklistEntities.BlacklistExportFeed, error)
+	DeleteFeed(id uint64) (int64, error)
+
+	SaveToken(token blacklistEntities.BlacklistExportFeedToken) (blacklistEntities.BlacklistExportFeedToken, error)
+	DeleteToken(id uint64) (int64, error)
+	SelectTokenByHash(hash string) (blacklistEntities.BlacklistExportFeedToken, error)
+	UpdateTokenAccess(id uint64, accessedAt time.Time) error
+
+	SaveAccess(access blacklistEntities.BlacklistExportFeedAccess) error
+	SelectAccessByFilter(filter blacklistEntities.BlacklistExportFeedAccessFilter) ([]blacklistEntities.BlacklistExportFeedAccess, error)
+}
+
 type IUsersService interface {
 	// SaveUser updates only existing entities.PlatformUser, returns error if user doesn't exist, ID must be defined.
 	// This method doesn't update user password, use ResetPassword or ChangePassword
@@ -144,6 +314,7 @@ type ISystemStateService interface {
 	UpdateSMTPConfig(enabled, SSL, UseAuth bool, host, user, from, password string, port int) error
 	UpdateNSDCredentials(enabled bool, host, clientKey string, clientID, clientGroupID uint64) error
 	UpdateNSDBlacklistServiceConfig(id, slm uint64, callType string, types []string) error
+	UpdateMISPConfig(enabled, verifyTLS bool, host, key string, syncInterval, sourceID uint64) error
 }
 
 type IServiceDeskService interface {
@@ -156,6 +327,20 @@ type IServiceDeskService interface {
 	SendBlacklistedHosts([]blacklistEntities.BlacklistedHost) (ticket serviceDeskEntities.ServiceDeskTicket, err error)
 }
 
+// IMISPService synchronizes blacklists with MISP instance
+type IMISPService interface {
+	IsAvailable() bool
+
+	// Sync pulls events changed since last synchronization and imports their IDS attributes
+	Sync() (blacklistEntities.BlacklistImportEvent, error)
+
+	// PushHosts creates MISP event containing all hosts selected by filter
+	PushHosts(filter blacklistEntities.BlacklistSearchFilter) (blacklistEntities.MISPEvent, error)
+
+	// StartScheduler starts checking with defined interval if synchronization is due
+	StartScheduler(interval time.Duration)
+}
+
 type ISMTPService interface {
 	SendMessage(to, cc, bcc []string, subject, body string) error
 }
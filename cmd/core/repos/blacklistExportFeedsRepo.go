@@ -0,0 +1,114 @@
+package repos
+
+import (
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"gorm.io/gorm"
+	"time"
+)
+
+type BlacklistExportFeedsRepoImpl struct {
+	*gorm.DB
+}
+
+func NewBlacklistExportFeedsRepoImpl(DB *gorm.DB) *BlacklistExportFeedsRepoImpl {
+	return &BlacklistExportFeedsRepoImpl{DB: DB}
+}
+
+func (r *BlacklistExportFeedsRepoImpl) SelectAllFeeds() ([]blacklistEntities.BlacklistExportFeed, error) {
+	var feeds []blacklistEntities.BlacklistExportFeed
+
+	err := r.Preload("Tokens").Order("ID ASC").Find(&feeds).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return feeds, nil
+}
+
+func (r *BlacklistExportFeedsRepoImpl) SelectFeed(id uint64) (blacklistEntities.BlacklistExportFeed, error) {
+	feed := blacklistEntities.BlacklistExportFeed{}
+
+	err := r.Preload("Tokens").Find(&feed, id).Error
+	if err != nil {
+		return blacklistEntities.BlacklistExportFeed{}, err
+	}
+
+	return feed, nil
+}
+
+func (r *BlacklistExportFeedsRepoImpl) SaveFeed(feed blacklistEntities.BlacklistExportFeed) (blacklistEntities.BlacklistExportFeed, error) {
+	err := r.Omit("Tokens").Save(&feed).Error
+	if err != nil {
+		return blacklistEntities.BlacklistExportFeed{}, err
+	}
+
+	return feed, nil
+}
+
+func (r *BlacklistExportFeedsRepoImpl) DeleteFeed(id uint64) (int64, error) {
+	query := r.Delete(&blacklistEntities.BlacklistExportFeed{
+		ID: id,
+	})
+
+	return query.RowsAffected, query.Error
+}
+
+func (r *BlacklistExportFeedsRepoImpl) SaveToken(token blacklistEntities.BlacklistExportFeedToken) (blacklistEntities.BlacklistExportFeedToken, error) {
+	err := r.Omit("Feed").Save(&token).Error
+	if err != nil {
+		return blacklistEntities.BlacklistExportFeedToken{}, err
+	}
+
+	return token, nil
+}
+
+func (r *BlacklistExportFeedsRepoImpl) DeleteToken(id uint64) (int64, error) {
+	query := r.Delete(&blacklistEntities.BlacklistExportFeedToken{
+		ID: id,
+	})
+
+	return query.RowsAffected, query.Error
+}
+
+func (r *BlacklistExportFeedsRepoImpl) SelectTokenByHash(hash string) (blacklistEntities.BlacklistExportFeedToken, error) {
+	token := blacklistEntities.BlacklistExportFeedToken{}
+
+	err := r.Preload("Feed").Where("token_hash = ?", hash).Find(&token).Error
+	if err != nil {
+		return blacklistEntities.BlacklistExportFeedToken{}, err
+	}
+
+	return token, nil
+}
+
+func (r *BlacklistExportFeedsRepoImpl) UpdateTokenAccess(id uint64, accessedAt time.Time) error {
+	return r.Model(&blacklistEntities.BlacklistExportFeedToken{}).Where("id = ?", id).UpdateColumn("last_access_at", accessedAt).Error
+}
+
+func (r *BlacklistExportFeedsRepoImpl) SaveAccess(access blacklistEntities.BlacklistExportFeedAccess) error {
+	return r.Omit("Token").Create(&access).Error
+}
+
+func (r *BlacklistExportFeedsRepoImpl) SelectAccessByFilter(filter blacklistEntities.BlacklistExportFeedAccessFilter) ([]blacklistEntities.BlacklistExportFeedAccess, error) {
+	query := r.Model(&blacklistEntities.BlacklistExportFeedAccess{})
+
+	if filter.FeedID > 0 {
+		query = query.Where("feed_id = ?", filter.FeedID)
+	}
+
+	if filter.TokenID > 0 {
+		query = query.Where("token_id = ?", filter.TokenID)
+	}
+
+	if filter.Limit != 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var result []blacklistEntities.BlacklistExportFeedAccess
+	err := query.Offset(filter.Offset).Order("created_at DESC, ID DESC").Find(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
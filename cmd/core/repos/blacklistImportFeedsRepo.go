@@ -0,0 +1,74 @@
+package repos
+
+import (
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type BlacklistImportFeedsRepoImpl struct {
+	*gorm.DB
+}
+
+func NewBlacklistImportFeedsRepoImpl(DB *gorm.DB) *BlacklistImportFeedsRepoImpl {
+	return &BlacklistImportFeedsRepoImpl{DB: DB}
+}
+
+func (r *BlacklistImportFeedsRepoImpl) SelectAllFeeds() ([]blacklistEntities.BlacklistImportFeed, error) {
+	var feeds []blacklistEntities.BlacklistImportFeed
+
+	err := r.Preload("Source").Order("ID ASC").Find(&feeds).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return feeds, nil
+}
+
+func (r *BlacklistImportFeedsRepoImpl) SelectFeed(id uint64) (blacklistEntities.BlacklistImportFeed, error) {
+	feed := blacklistEntities.BlacklistImportFeed{}
+
+	err := r.Preload("Source").Find(&feed, id).Error
+	if err != nil {
+		return blacklistEntities.BlacklistImportFeed{}, err
+	}
+
+	return feed, nil
+}
+
+func (r *BlacklistImportFeedsRepoImpl) SaveFeed(feed blacklistEntities.BlacklistImportFeed) (blacklistEntities.BlacklistImportFeed, error) {
+	err := r.Omit("Source").Save(&feed).Error
+	if err != nil {
+		return blacklistEntities.BlacklistImportFeed{}, err
+	}
+
+	return feed, nil
+}
+
+func (r *BlacklistImportFeedsRepoImpl) DeleteFeed(id uint64) (int64, error) {
+	query := r.Delete(&blacklistEntities.BlacklistImportFeed{
+		ID: id,
+	})
+
+	return query.RowsAffected, query.Error
+}
+
+func (r *BlacklistImportFeedsRepoImpl) SelectTAXIIBookmarks(feedID uint64) ([]blacklistEntities.ImportFeedTAXIIBookmark, error) {
+	var bookmarks []blacklistEntities.ImportFeedTAXIIBookmark
+
+	err := r.Where("feed_id = ?", feedID).Find(&bookmarks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return bookmarks, nil
+}
+
+// SaveTAXIIBookmark creates collection bookmark or updates its value if bookmark for collection already exists
+func (r *BlacklistImportFeedsRepoImpl) SaveTAXIIBookmark(bookmark blacklistEntities.ImportFeedTAXIIBookmark) error {
+	return r.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "feed_id"}, {Name: "collection_url"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"added_after": bookmark.AddedAfter, "updated_at": time.Now()}),
+	}).Create(&bookmark).Error
+}
@@ -2,11 +2,12 @@ package repos
 
 import (
 	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"encoding/json"
+	"fmt"
 	"github.com/jackc/pgtype"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
-	"log/slog"
-	"net"
+	"strings"
 	"time"
 )
 
@@ -37,16 +38,20 @@ func (r *BlacklistsRepoImpl) SelectURLsByFilter(filter blacklistEntities.Blackli
 		query = query.Where("discovered_at < ?", filter.DiscoveredBefore)
 	}
 
-	if len(filter.SearchString) > 0 {
-		query = query.Where("URL LIKE ?", "%"+filter.SearchString+"%")
+	if filter.ExpiresBefore != nil {
+		query = query.Where("valid_until < ?", filter.ExpiresBefore)
 	}
 
+	query = whereThreatAttributes(query, filter)
+	query = whereTagged(query, "url", filter.TagIDs)
+	query = whereSighted(query, "url", filter.SourceIDs)
+
 	if filter.ImportEventID > 0 {
 		query = query.Where("import_event_id = ?", filter.ImportEventID)
 	}
 
-	if len(filter.SourceIDs) > 0 {
-		query = query.Where("source_id IN ?", filter.SourceIDs)
+	if len(filter.SearchString) > 0 {
+		query = query.Where("URL LIKE ?", "%"+filter.SearchString+"%")
 	}
 
 	if filter.Limit != 0 {
@@ -54,26 +59,19 @@ func (r *BlacklistsRepoImpl) SelectURLsByFilter(filter blacklistEntities.Blackli
 	}
 
 	var result []blacklistEntities.BlacklistedURL
-	err := query.Preload("Source").Offset(filter.Offset).Order("created_at DESC, updated_at DESC, UUID DESC").Find(&result).Error
+	err := preloadSightings(query.Preload("Source").Preload("Tags")).Offset(filter.Offset).Order("created_at DESC, updated_at DESC, UUID DESC").Find(&result).Error
 
 	return result, err
 }
 
-func (r *BlacklistsRepoImpl) SaveURLs(urls []blacklistEntities.BlacklistedURL) (int64, error) {
-	query := r.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "md5"}, {Name: "source_id"}},
-		DoUpdates: clause.Assignments(map[string]interface{}{"updated_at": time.Now(), "deleted_at": nil}),
-	}).CreateInBatches(&urls, 100)
+func (r *BlacklistsRepoImpl) SaveURLs(urls []blacklistEntities.BlacklistedURL, userID *uint64) (int64, error) {
+	assignments := withThreatAttributesOnConflict("blacklisted_urls", map[string]interface{}{"updated_at": time.Now(), "deleted_at": nil, "valid_until": validUntilOnConflict("blacklisted_urls")})
 
-	return query.RowsAffected, query.Error
+	return saveHosts(r.DB, "url", "md5", urls, func(v blacklistEntities.BlacklistedURL) string { return v.MD5 }, (*blacklistEntities.BlacklistedHost).FromURL, assignments, userID)
 }
 
-func (r *BlacklistsRepoImpl) DeleteURL(uuid pgtype.UUID) (int64, error) {
-	query := r.Delete(&blacklistEntities.BlacklistedURL{
-		UUID: uuid,
-	})
-
-	return query.RowsAffected, query.Error
+func (r *BlacklistsRepoImpl) DeleteURL(uuid pgtype.UUID, userID *uint64) (int64, error) {
+	return r.deleteHosts("url", []pgtype.UUID{uuid}, userID, "")
 }
 
 func NewBlacklistsRepoImpl(DB *gorm.DB) *BlacklistsRepoImpl {
@@ -103,45 +101,95 @@ func (r *BlacklistsRepoImpl) SelectIPsByFilter(filter blacklistEntities.Blacklis
 		query = query.Where("discovered_at < ?", filter.DiscoveredBefore)
 	}
 
-	if len(filter.SearchString) > 0 {
-		query = query.Where("ip_address <<= ?", filter.SearchString)
+	if filter.ExpiresBefore != nil {
+		query = query.Where("valid_until < ?", filter.ExpiresBefore)
 	}
 
-	if len(filter.SourceIDs) > 0 {
-		query = query.Where("source_id IN ?", filter.SourceIDs)
-	}
+	query = whereThreatAttributes(query, filter)
+	query = whereTagged(query, "ip", filter.TagIDs)
+	query = whereSighted(query, "ip", filter.SourceIDs)
 
 	if filter.ImportEventID > 0 {
 		query = query.Where("import_event_id = ?", filter.ImportEventID)
 	}
 
+	// search string is IP address or network, both networks inside it and networks covering it are selected
+	if len(filter.SearchString) > 0 {
+		query = query.Where("(ip_address <<= ? OR ip_address >>= ?)", filter.SearchString, filter.SearchString)
+	}
+
 	if filter.Limit != 0 {
 		query = query.Limit(filter.Limit)
 	}
 
 	var result []blacklistEntities.BlacklistedIP
-	err := query.Preload("Source").Offset(filter.Offset).Order("created_at DESC, updated_at DESC, UUID DESC").Find(&result).Error
+	err := preloadSightings(query.Preload("Source").Preload("Tags")).Offset(filter.Offset).Order("created_at DESC, updated_at DESC, UUID DESC").Find(&result).Error
 
 	return result, err
 }
 
-// SaveIPs saves ip records to database. If ip with specific source not presented, creates one.
-// If defined combination already in database, updates it and makes it active.
-func (r *BlacklistsRepoImpl) SaveIPs(ips []blacklistEntities.BlacklistedIP) (int64, error) {
-	query := r.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "ip_address"}, {Name: "source_id"}},
-		DoUpdates: clause.Assignments(map[string]interface{}{"updated_at": time.Now(), "deleted_at": nil}),
-	}).CreateInBatches(&ips, 100)
+// SelectIPsContaining selects active IP addresses and networks that contain or are equal to defined address,
+// the most specific networks first
+func (r *BlacklistsRepoImpl) SelectIPsContaining(addr string) ([]blacklistEntities.BlacklistedIP, error) {
+	var result []blacklistEntities.BlacklistedIP
 
-	return query.RowsAffected, query.Error
+	err := preloadSightings(r.Preload("Source")).Where("ip_address >>= ?", addr).Order("masklen(ip_address) DESC, created_at DESC").Find(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
-func (r *BlacklistsRepoImpl) DeleteIP(uuid pgtype.UUID) (int64, error) {
-	query := r.Delete(&blacklistEntities.BlacklistedIP{
-		UUID: uuid,
-	})
+// SelectIPsContainingAny selects active IP addresses and networks that contain or are equal to any of defined addresses
+func (r *BlacklistsRepoImpl) SelectIPsContainingAny(addrs []string) ([]blacklistEntities.BlacklistedIP, error) {
+	var result []blacklistEntities.BlacklistedIP
 
-	return query.RowsAffected, query.Error
+	query, err := whereIPContainsAny(preloadSightings(r.Preload("Source")), addrs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = query.Order("masklen(ip_address) DESC, created_at DESC").Find(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// whereIPContainsAny selects IP addresses and networks containing or equal to any of defined addresses. Addresses are
+// bound as single inet array, so any number of addresses takes one placeholder.
+func whereIPContainsAny(query *gorm.DB, addrs []string) (*gorm.DB, error) {
+	var inets = make([]pgtype.Inet, 0, len(addrs))
+	for _, a := range addrs {
+		ip, err := blacklistEntities.ParseInet(a)
+		if err != nil {
+			return nil, err
+		}
+
+		inets = append(inets, ip)
+	}
+
+	var array pgtype.InetArray
+	err := array.Set(inets)
+	if err != nil {
+		return nil, err
+	}
+
+	return query.Where("ip_address >>= ANY (CAST(? AS inet[]))", array), nil
+}
+
+// SaveIPs saves ip records to database. If ip not presented, creates one. If ip already in database,
+// updates it and makes it active. Sources of ip are saved as sightings.
+func (r *BlacklistsRepoImpl) SaveIPs(ips []blacklistEntities.BlacklistedIP, userID *uint64) (int64, error) {
+	assignments := withThreatAttributesOnConflict("blacklisted_ips", map[string]interface{}{"updated_at": time.Now(), "deleted_at": nil, "valid_until": validUntilOnConflict("blacklisted_ips")})
+
+	return saveHosts(r.DB, "ip", "ip_address", ips, func(v blacklistEntities.BlacklistedIP) string { return v.IPAddress.IPNet.String() }, (*blacklistEntities.BlacklistedHost).FromIP, assignments, userID)
+}
+
+func (r *BlacklistsRepoImpl) DeleteIP(uuid pgtype.UUID, userID *uint64) (int64, error) {
+	return r.deleteHosts("ip", []pgtype.UUID{uuid}, userID, "")
 }
 
 func (r *BlacklistsRepoImpl) SelectDomainsByFilter(filter blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedDomain, error) {
@@ -167,45 +215,53 @@ func (r *BlacklistsRepoImpl) SelectDomainsByFilter(filter blacklistEntities.Blac
 		query = query.Where("discovered_at < ?", filter.DiscoveredBefore)
 	}
 
-	if len(filter.SearchString) > 0 {
-		query = query.Where("URN LIKE ?", "%"+filter.SearchString+"%")
+	if filter.ExpiresBefore != nil {
+		query = query.Where("valid_until < ?", filter.ExpiresBefore)
 	}
 
-	if len(filter.SourceIDs) > 0 {
-		query = query.Where("source_id IN ?", filter.SourceIDs)
-	}
+	query = whereThreatAttributes(query, filter)
+	query = whereTagged(query, "domain", filter.TagIDs)
+	query = whereSighted(query, "domain", filter.SourceIDs)
 
 	if filter.ImportEventID > 0 {
 		query = query.Where("import_event_id = ?", filter.ImportEventID)
 	}
 
+	if len(filter.SearchString) > 0 {
+		query = query.Where("URN LIKE ?", "%"+filter.SearchString+"%")
+	}
+
+	if len(filter.Domain) > 0 {
+		query = whereDomainCovers(query, filter.Domain)
+	}
+
 	if filter.Limit != 0 {
 		query = query.Limit(filter.Limit)
 	}
 
 	var result []blacklistEntities.BlacklistedDomain
-	err := query.Preload("Source").Offset(filter.Offset).Order("created_at DESC, updated_at DESC, UUID DESC").Find(&result).Error
+	err := preloadSightings(query.Preload("Source").Preload("Tags")).Offset(filter.Offset).Order("created_at DESC, updated_at DESC, UUID DESC").Find(&result).Error
 
 	return result, err
 }
 
-// SaveDomains saves domain records to database. If domain with specific source not presented, creates one.
-// If defined combination already in database, updates it and makes it active.
-func (r *BlacklistsRepoImpl) SaveDomains(domains []blacklistEntities.BlacklistedDomain) (int64, error) {
-	query := r.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "urn"}, {Name: "source_id"}},
-		DoUpdates: clause.Assignments(map[string]interface{}{"updated_at": time.Now(), "deleted_at": nil}),
-	}).CreateInBatches(&domains, 100)
+// SaveDomains saves domain records to database. If domain not presented, creates one. If domain already in database,
+// updates it and makes it active. Sources of domain are saved as sightings.
+func (r *BlacklistsRepoImpl) SaveDomains(domains []blacklistEntities.BlacklistedDomain, userID *uint64) (int64, error) {
+	assignments := withThreatAttributesOnConflict("blacklisted_domains", map[string]interface{}{"updated_at": time.Now(), "deleted_at": nil, "valid_until": validUntilOnConflict("blacklisted_domains"), "include_subdomains": gorm.Expr("excluded.include_subdomains")})
 
-	return query.RowsAffected, query.Error
+	return saveHosts(r.DB, "domain", "urn", domains, func(v blacklistEntities.BlacklistedDomain) string { return v.URN }, (*blacklistEntities.BlacklistedHost).FromDomain, assignments, userID)
 }
 
-func (r *BlacklistsRepoImpl) DeleteDomain(uuid pgtype.UUID) (int64, error) {
-	query := r.Delete(&blacklistEntities.BlacklistedDomain{
-		UUID: uuid,
-	})
+// whereDomainCovers selects domains equal to defined domain, or its parent domains blacklisted with all subdomains
+func whereDomainCovers(query *gorm.DB, domain string) *gorm.DB {
+	domains := blacklistEntities.ParentDomains(strings.TrimPrefix(domain, "*."))
 
-	return query.RowsAffected, query.Error
+	return query.Where("(urn = ? OR (include_subdomains AND urn IN ?))", domains[0], domains[1:])
+}
+
+func (r *BlacklistsRepoImpl) DeleteDomain(uuid pgtype.UUID, userID *uint64) (int64, error) {
+	return r.deleteHosts("domain", []pgtype.UUID{uuid}, userID, "")
 }
 
 func (r *BlacklistsRepoImpl) SelectEmailsByFilter(filter blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedEmail, error) {
@@ -231,43 +287,40 @@ func (r *BlacklistsRepoImpl) SelectEmailsByFilter(filter blacklistEntities.Black
 		query = query.Where("discovered_at < ?", filter.DiscoveredBefore)
 	}
 
-	if len(filter.SearchString) > 0 {
-		query = query.Where("URN LIKE ?", "%"+filter.SearchString+"%")
+	if filter.ExpiresBefore != nil {
+		query = query.Where("valid_until < ?", filter.ExpiresBefore)
 	}
 
-	if len(filter.SourceIDs) > 0 {
-		query = query.Where("source_id IN ?", filter.SourceIDs)
-	}
+	query = whereThreatAttributes(query, filter)
+	query = whereTagged(query, "email", filter.TagIDs)
+	query = whereSighted(query, "email", filter.SourceIDs)
 
 	if filter.ImportEventID > 0 {
 		query = query.Where("import_event_id = ?", filter.ImportEventID)
 	}
 
+	if len(filter.SearchString) > 0 {
+		query = query.Where("URN LIKE ?", "%"+filter.SearchString+"%")
+	}
+
 	if filter.Limit != 0 {
 		query = query.Limit(filter.Limit)
 	}
 
 	var result []blacklistEntities.BlacklistedEmail
-	err := query.Preload("Source").Offset(filter.Offset).Order("created_at DESC, updated_at DESC, UUID DESC").Find(&result).Error
+	err := preloadSightings(query.Preload("Source").Preload("Tags")).Offset(filter.Offset).Order("created_at DESC, updated_at DESC, UUID DESC").Find(&result).Error
 
 	return result, err
 }
 
-func (r *BlacklistsRepoImpl) SaveEmails(emails []blacklistEntities.BlacklistedEmail) (int64, error) {
-	query := r.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "email"}, {Name: "source_id"}},
-		DoUpdates: clause.Assignments(map[string]interface{}{"updated_at": time.Now(), "deleted_at": nil}),
-	}).CreateInBatches(&emails, 100)
+func (r *BlacklistsRepoImpl) SaveEmails(emails []blacklistEntities.BlacklistedEmail, userID *uint64) (int64, error) {
+	assignments := withThreatAttributesOnConflict("blacklisted_emails", map[string]interface{}{"updated_at": time.Now(), "deleted_at": nil, "valid_until": validUntilOnConflict("blacklisted_emails")})
 
-	return query.RowsAffected, query.Error
+	return saveHosts(r.DB, "email", "email", emails, func(v blacklistEntities.BlacklistedEmail) string { return v.Email }, (*blacklistEntities.BlacklistedHost).FromEmail, assignments, userID)
 }
 
-func (r *BlacklistsRepoImpl) DeleteEmail(uuid pgtype.UUID) (int64, error) {
-	query := r.Delete(&blacklistEntities.BlacklistedEmail{
-		UUID: uuid,
-	})
-
-	return query.RowsAffected, query.Error
+func (r *BlacklistsRepoImpl) DeleteEmail(uuid pgtype.UUID, userID *uint64) (int64, error) {
+	return r.deleteHosts("email", []pgtype.UUID{uuid}, userID, "")
 }
 
 func (r *BlacklistsRepoImpl) SaveImportEvent(event blacklistEntities.BlacklistImportEvent) (blacklistEntities.BlacklistImportEvent, error) {
@@ -294,6 +347,10 @@ func (r *BlacklistsRepoImpl) SelectImportEventsByFilter(filter blacklistEntities
 		query = query.Where("type = ?", filter.Type)
 	}
 
+	if filter.FeedID > 0 {
+		query = query.Where("feed_id = ?", filter.FeedID)
+	}
+
 	if filter.Limit != 0 {
 		query = query.Limit(filter.Limit)
 	}
@@ -323,6 +380,770 @@ func (r *BlacklistsRepoImpl) DeleteImportEvent(id uint64) (int64, error) {
 	return query.RowsAffected, query.Error
 }
 
+func (r *BlacklistsRepoImpl) FailIncompleteImportEvents(reason string) (int64, error) {
+	query := r.Model(&blacklistEntities.BlacklistImportEvent{}).
+		Where("is_complete = ?", false).
+		Updates(map[string]interface{}{"is_complete": true, "error": reason})
+
+	return query.RowsAffected, query.Error
+}
+
+func (r *BlacklistsRepoImpl) SaveImportErrors(importErrors []blacklistEntities.BlacklistImportError) error {
+	return r.Omit("ImportEvent").CreateInBatches(&importErrors, 100).Error
+}
+
+func (r *BlacklistsRepoImpl) SelectImportErrors(eventID uint64, filter blacklistEntities.BlacklistImportErrorFilter) ([]blacklistEntities.BlacklistImportError, error) {
+	query := r.Model(&blacklistEntities.BlacklistImportError{}).Where("import_event_id = ?", eventID)
+
+	if filter.Limit != 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var result []blacklistEntities.BlacklistImportError
+	err := query.Offset(filter.Offset).Order("ID ASC").Find(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *BlacklistsRepoImpl) SelectAllImportProfiles() ([]blacklistEntities.BlacklistImportProfile, error) {
+	var profiles []blacklistEntities.BlacklistImportProfile
+
+	err := r.Order("ID ASC").Find(&profiles).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return profiles, nil
+}
+
+func (r *BlacklistsRepoImpl) SelectImportProfile(id uint64) (blacklistEntities.BlacklistImportProfile, error) {
+	profile := blacklistEntities.BlacklistImportProfile{}
+
+	err := r.Find(&profile, id).Error
+	if err != nil {
+		return blacklistEntities.BlacklistImportProfile{}, err
+	}
+
+	return profile, nil
+}
+
+func (r *BlacklistsRepoImpl) SaveImportProfile(profile blacklistEntities.BlacklistImportProfile) (blacklistEntities.BlacklistImportProfile, error) {
+	err := r.Save(&profile).Error
+	if err != nil {
+		return blacklistEntities.BlacklistImportProfile{}, err
+	}
+
+	return profile, nil
+}
+
+func (r *BlacklistsRepoImpl) DeleteImportProfile(id uint64) (int64, error) {
+	query := r.Delete(&blacklistEntities.BlacklistImportProfile{
+		ID: id,
+	})
+
+	return query.RowsAffected, query.Error
+}
+
+func (r *BlacklistsRepoImpl) SelectAllTTLPolicies() ([]blacklistEntities.BlacklistTTLPolicy, error) {
+	var policies []blacklistEntities.BlacklistTTLPolicy
+
+	err := r.Order("ID ASC").Find(&policies).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+func (r *BlacklistsRepoImpl) SaveTTLPolicy(policy blacklistEntities.BlacklistTTLPolicy) (blacklistEntities.BlacklistTTLPolicy, error) {
+	err := r.Save(&policy).Error
+	if err != nil {
+		return blacklistEntities.BlacklistTTLPolicy{}, err
+	}
+
+	return policy, nil
+}
+
+func (r *BlacklistsRepoImpl) DeleteTTLPolicy(id uint64) (int64, error) {
+	query := r.Delete(&blacklistEntities.BlacklistTTLPolicy{
+		ID: id,
+	})
+
+	return query.RowsAffected, query.Error
+}
+
+func (r *BlacklistsRepoImpl) SelectAllowlist() ([]blacklistEntities.AllowlistEntry, error) {
+	var entries []blacklistEntities.AllowlistEntry
+
+	err := r.Order("ID ASC").Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (r *BlacklistsRepoImpl) SelectAllowlistEntry(id uint64) (blacklistEntities.AllowlistEntry, error) {
+	entry := blacklistEntities.AllowlistEntry{}
+
+	err := r.Find(&entry, id).Error
+	if err != nil {
+		return blacklistEntities.AllowlistEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func (r *BlacklistsRepoImpl) SaveAllowlistEntry(entry blacklistEntities.AllowlistEntry) (blacklistEntities.AllowlistEntry, error) {
+	err := r.Save(&entry).Error
+	if err != nil {
+		return blacklistEntities.AllowlistEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func (r *BlacklistsRepoImpl) DeleteAllowlistEntry(id uint64) (int64, error) {
+	query := r.Delete(&blacklistEntities.AllowlistEntry{
+		ID: id,
+	})
+
+	return query.RowsAffected, query.Error
+}
+
+// SelectAllowlistConflicts selects active hosts matching allowlist entry: IPs overlapping allowlisted network,
+// domains, URLs and emails with allowlisted domain, URLs matching allowlisted URL pattern
+func (r *BlacklistsRepoImpl) SelectAllowlistConflicts(entry blacklistEntities.AllowlistEntry) ([]blacklistEntities.BlacklistedHost, error) {
+	var hosts []blacklistEntities.BlacklistedHost
+
+	var ips []blacklistEntities.BlacklistedIP
+	var domains []blacklistEntities.BlacklistedDomain
+	var urls []blacklistEntities.BlacklistedURL
+	var emails []blacklistEntities.BlacklistedEmail
+
+	var err error
+
+	switch entry.Type {
+	case "ip":
+		err = r.Preload("Source").Where("ip_address && ?", entry.Value).Find(&ips).Error
+
+		// URLs are matched only by single allowlisted address
+		ip, parseErr := blacklistEntities.ParseInet(entry.Value)
+		if err == nil && parseErr == nil {
+			if ones, bits := ip.IPNet.Mask.Size(); ones == bits {
+				host := ip.IPNet.IP.String()
+				if ip.IPNet.IP.To4() == nil {
+					host = "[" + host + "]"
+				}
+
+				err = r.Preload("Source").Where("url ~* ?", blacklistEntities.URLHostPattern(host, false)).Find(&urls).Error
+			}
+		}
+	case "domain":
+		// wildcard domains covering allowlisted domain also conflict
+		domainQuery := whereDomainCovers(r.Preload("Source"), entry.Value)
+		if entry.IncludeSubdomains {
+			domainQuery = domainQuery.Or("urn LIKE ?", "%."+entry.Value)
+		}
+
+		err = domainQuery.Find(&domains).Error
+		if err == nil {
+			err = r.Preload("Source").Where("url ~* ?", blacklistEntities.URLHostPattern(entry.Value, entry.IncludeSubdomains)).Find(&urls).Error
+		}
+
+		if err == nil {
+			emailQuery := r.Preload("Source").Where("lower(email) LIKE ?", "%@"+entry.Value)
+			if entry.IncludeSubdomains {
+				emailQuery = emailQuery.Or("lower(email) LIKE ?", "%@%."+entry.Value)
+			}
+
+			err = emailQuery.Find(&emails).Error
+		}
+	case "url":
+		err = r.Preload("Source").Where("url ~* ?", blacklistEntities.URLPattern(entry.Value)).Find(&urls).Error
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range ips {
+		var h blacklistEntities.BlacklistedHost
+		h.FromIP(v)
+		hosts = append(hosts, h)
+	}
+
+	for _, v := range domains {
+		var h blacklistEntities.BlacklistedHost
+		h.FromDomain(v)
+		hosts = append(hosts, h)
+	}
+
+	for _, v := range urls {
+		var h blacklistEntities.BlacklistedHost
+		h.FromURL(v)
+		hosts = append(hosts, h)
+	}
+
+	for _, v := range emails {
+		var h blacklistEntities.BlacklistedHost
+		h.FromEmail(v)
+		hosts = append(hosts, h)
+	}
+
+	return hosts, nil
+}
+
+func (r *BlacklistsRepoImpl) SelectAllTags() ([]blacklistEntities.BlacklistTag, error) {
+	var tags []blacklistEntities.BlacklistTag
+
+	err := r.Order("kind ASC, name ASC").Find(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (r *BlacklistsRepoImpl) SaveTag(tag blacklistEntities.BlacklistTag) (blacklistEntities.BlacklistTag, error) {
+	err := r.Save(&tag).Error
+	if err != nil {
+		return blacklistEntities.BlacklistTag{}, err
+	}
+
+	return tag, nil
+}
+
+// SaveTagsByKey creates tags not saved yet and returns all tags with IDs. Tags are matched by kind and name,
+// descriptions of existing tags are not changed.
+func (r *BlacklistsRepoImpl) SaveTagsByKey(tags []blacklistEntities.BlacklistTag) ([]blacklistEntities.BlacklistTag, error) {
+	err := r.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}, {Name: "kind"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"updated_at": time.Now()}),
+	}).CreateInBatches(&tags, 100).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (r *BlacklistsRepoImpl) DeleteTag(id uint64) (int64, error) {
+	query := r.Delete(&blacklistEntities.BlacklistTag{
+		ID: id,
+	})
+
+	return query.RowsAffected, query.Error
+}
+
+// SaveHostTags links hosts of single type with tags, existing links are skipped
+func (r *BlacklistsRepoImpl) SaveHostTags(type_ string, uuids []pgtype.UUID, tagIDs []uint64) (int64, error) {
+	table, ok := tagJoinTables[type_]
+	if !ok {
+		return 0, fmt.Errorf("host type '%s' not supported", type_)
+	}
+
+	var links []map[string]interface{}
+	for _, uuid := range uuids {
+		for _, id := range tagIDs {
+			links = append(links, map[string]interface{}{"host_uuid": uuid, "tag_id": id})
+		}
+	}
+
+	if len(links) == 0 {
+		return 0, nil
+	}
+
+	query := r.Table(table).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(links, 100)
+
+	return query.RowsAffected, query.Error
+}
+
+// DeleteHostTags unlinks hosts of single type from tags
+func (r *BlacklistsRepoImpl) DeleteHostTags(type_ string, uuids []pgtype.UUID, tagIDs []uint64) (int64, error) {
+	table, ok := tagJoinTables[type_]
+	if !ok {
+		return 0, fmt.Errorf("host type '%s' not supported", type_)
+	}
+
+	query := r.Exec(fmt.Sprintf("DELETE FROM %s WHERE host_uuid IN ? AND tag_id IN ?", table), uuids, tagIDs)
+
+	return query.RowsAffected, query.Error
+}
+
+// SelectHostsTags returns tags of hosts of single type by host UUID
+func (r *BlacklistsRepoImpl) SelectHostsTags(type_ string, uuids []pgtype.UUID) (map[[16]byte][]blacklistEntities.BlacklistTag, error) {
+	table, ok := tagJoinTables[type_]
+	if !ok {
+		return nil, fmt.Errorf("host type '%s' not supported", type_)
+	}
+
+	var links []struct {
+		HostUUID pgtype.UUID `gorm:"column:host_uuid"`
+
+		blacklistEntities.BlacklistTag
+	}
+
+	err := r.Table(table).
+		Select("host_uuid, blacklist_tags.*").
+		Joins("JOIN blacklist_tags ON blacklist_tags.id = tag_id").
+		Where("host_uuid IN ?", uuids).
+		Order("blacklist_tags.kind ASC, blacklist_tags.name ASC").
+		Scan(&links).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	var tags = make(map[[16]byte][]blacklistEntities.BlacklistTag)
+	for _, l := range links {
+		tags[l.HostUUID.Bytes] = append(tags[l.HostUUID.Bytes], l.BlacklistTag)
+	}
+
+	return tags, nil
+}
+
+// SelectHostsSightings returns sightings of hosts of single type by host UUID, the earliest first
+func (r *BlacklistsRepoImpl) SelectHostsSightings(type_ string, uuids []pgtype.UUID) (map[[16]byte][]blacklistEntities.BlacklistSighting, error) {
+	var found []blacklistEntities.BlacklistSighting
+
+	err := r.Preload("Source").
+		Where("host_type = ? AND host_id IN ?", type_, uuids).
+		Order("first_seen_at ASC, id ASC").
+		Find(&found).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	var sightings = make(map[[16]byte][]blacklistEntities.BlacklistSighting)
+	for _, s := range found {
+		sightings[s.HostID.Bytes] = append(sightings[s.HostID.Bytes], s)
+	}
+
+	return sightings, nil
+}
+
+// CountTagStatistics returns all tags with number of active hosts of all types tagged by them
+func (r *BlacklistsRepoImpl) CountTagStatistics() ([]blacklistEntities.BlacklistTagCount, error) {
+	var counts []blacklistEntities.BlacklistTagCount
+
+	var linked []string
+	for _, t := range [][2]string{{"ip", "blacklisted_ips"}, {"domain", "blacklisted_domains"}, {"url", "blacklisted_urls"}, {"email", "blacklisted_emails"}} {
+		linked = append(linked, fmt.Sprintf("SELECT tag_id FROM %[1]s JOIN %[2]s ON %[2]s.uuid = %[1]s.host_uuid WHERE %[2]s.deleted_at IS NULL", tagJoinTables[t[0]], t[1]))
+	}
+
+	err := r.Raw(fmt.Sprintf(
+		"SELECT blacklist_tags.*, COALESCE(linked.count, 0) AS count FROM blacklist_tags "+
+			"LEFT JOIN (SELECT tag_id, count(*) AS count FROM (%s) AS hosts GROUP BY tag_id) AS linked ON linked.tag_id = blacklist_tags.id "+
+			"ORDER BY count DESC, blacklist_tags.name ASC",
+		strings.Join(linked, " UNION ALL "),
+	)).Scan(&counts).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// expiredHostsBatchSize limits number of expired hosts deleted in single transaction
+const expiredHostsBatchSize = 1000
+
+// DeleteExpiredHosts soft deletes hosts of all types expired before defined time, deletion of every host is revised
+func (r *BlacklistsRepoImpl) DeleteExpiredHosts(now time.Time) (int64, error) {
+	var total int64
+
+	for _, type_ := range []string{"ip", "url", "domain", "email"} {
+		var uuids []pgtype.UUID
+
+		err := r.Model(hostModels[type_]).Where("valid_until <= ?", now).Pluck("uuid", &uuids).Error
+		if err != nil {
+			return total, err
+		}
+
+		// hosts saved again after selection are extended, so they are not deleted
+		expired := func(db *gorm.DB) *gorm.DB {
+			return db.Where("valid_until <= ?", now)
+		}
+
+		for start := 0; start < len(uuids); start += expiredHostsBatchSize {
+			end := min(start+expiredHostsBatchSize, len(uuids))
+
+			rows, err := r.deleteHosts(type_, uuids[start:end], nil, blacklistEntities.RevisionReasonExpired, expired)
+			if err != nil {
+				return total, err
+			}
+
+			total += rows
+		}
+	}
+
+	return total, nil
+}
+
+// hostModels maps host types to their models
+var hostModels = map[string]any{
+	"ip":     &blacklistEntities.BlacklistedIP{},
+	"domain": &blacklistEntities.BlacklistedDomain{},
+	"url":    &blacklistEntities.BlacklistedURL{},
+	"email":  &blacklistEntities.BlacklistedEmail{},
+}
+
+// deleteHosts soft deletes active hosts of single type and records revisions of deleted hosts
+func (r *BlacklistsRepoImpl) deleteHosts(type_ string, uuids []pgtype.UUID, userID *uint64, reason string, scopes ...func(*gorm.DB) *gorm.DB) (int64, error) {
+	var rows int64
+
+	err := r.Transaction(func(tx *gorm.DB) error {
+		before, err := selectHostsByUUIDs(tx, type_, uuids)
+		if err != nil {
+			return err
+		}
+
+		query := tx.Scopes(scopes...).Where("uuid IN ?", uuids).Delete(hostModels[type_])
+		if query.Error != nil {
+			return query.Error
+		}
+
+		rows = query.RowsAffected
+
+		return saveRevisions(tx, type_, before, uuids, userID, nil, reason)
+	})
+
+	return rows, err
+}
+
+// SelectHost selects host of any type by UUID, including deleted hosts. Returns empty host if not found.
+func (r *BlacklistsRepoImpl) SelectHost(uuid pgtype.UUID) (blacklistEntities.BlacklistedHost, error) {
+	for _, type_ := range []string{"ip", "domain", "url", "email"} {
+		hosts, err := selectHostsByUUIDs(r.DB, type_, []pgtype.UUID{uuid})
+		if err != nil {
+			return blacklistEntities.BlacklistedHost{}, err
+		}
+
+		if len(hosts) > 0 {
+			return hosts[0], nil
+		}
+	}
+
+	return blacklistEntities.BlacklistedHost{}, nil
+}
+
+// RestoreHost makes soft deleted host active again with defined expiration date and records restore revision
+func (r *BlacklistsRepoImpl) RestoreHost(type_ string, uuid pgtype.UUID, validUntil *time.Time, userID *uint64) (int64, error) {
+	var rows int64
+
+	err := r.Transaction(func(tx *gorm.DB) error {
+		uuids := []pgtype.UUID{uuid}
+
+		before, err := selectHostsByUUIDs(tx, type_, uuids)
+		if err != nil {
+			return err
+		}
+
+		query := tx.Unscoped().Model(hostModels[type_]).
+			Where("uuid = ? AND deleted_at IS NOT NULL", uuid).
+			Updates(map[string]interface{}{"deleted_at": nil, "valid_until": validUntil, "updated_at": time.Now()})
+		if query.Error != nil {
+			return query.Error
+		}
+
+		rows = query.RowsAffected
+
+		return saveRevisions(tx, type_, before, uuids, userID, nil, "")
+	})
+
+	return rows, err
+}
+
+// SelectHostRevisions selects all revisions of host with users who made them, the latest first
+func (r *BlacklistsRepoImpl) SelectHostRevisions(uuid pgtype.UUID) ([]blacklistEntities.BlacklistHostRevision, error) {
+	var revisions []blacklistEntities.BlacklistHostRevision
+
+	err := r.Preload("User").Where("host_id = ?", uuid).Order("created_at DESC, id DESC").Find(&revisions).Error
+
+	return revisions, err
+}
+
+// selectHostsByUUIDs selects hosts of single type by UUID, including deleted hosts
+func selectHostsByUUIDs(tx *gorm.DB, type_ string, uuids []pgtype.UUID) ([]blacklistEntities.BlacklistedHost, error) {
+	var hosts []blacklistEntities.BlacklistedHost
+	var err error
+
+	query := tx.Unscoped().Where("uuid IN ?", uuids)
+
+	switch type_ {
+	case "ip":
+		var ips []blacklistEntities.BlacklistedIP
+		err = query.Find(&ips).Error
+		for _, v := range ips {
+			var h blacklistEntities.BlacklistedHost
+			h.FromIP(v)
+			hosts = append(hosts, h)
+		}
+	case "domain":
+		var domains []blacklistEntities.BlacklistedDomain
+		err = query.Find(&domains).Error
+		for _, v := range domains {
+			var h blacklistEntities.BlacklistedHost
+			h.FromDomain(v)
+			hosts = append(hosts, h)
+		}
+	case "url":
+		var urls []blacklistEntities.BlacklistedURL
+		err = query.Find(&urls).Error
+		for _, v := range urls {
+			var h blacklistEntities.BlacklistedHost
+			h.FromURL(v)
+			hosts = append(hosts, h)
+		}
+	case "email":
+		var emails []blacklistEntities.BlacklistedEmail
+		err = query.Find(&emails).Error
+		for _, v := range emails {
+			var h blacklistEntities.BlacklistedHost
+			h.FromEmail(v)
+			hosts = append(hosts, h)
+		}
+	default:
+		err = fmt.Errorf("unknown host type: %s", type_)
+	}
+
+	return hosts, err
+}
+
+// saveHosts saves hosts of single type with their sightings and revisions in single transaction, so host is never saved
+// without source and history. Hosts with same unique value are updated by assignments and made active. Saved hosts are
+// selected by value before save, so revisions describe state before and after save.
+func saveHosts[T any](db *gorm.DB, type_, column string, hosts []T, value func(T) string, toHost func(*blacklistEntities.BlacklistedHost, T), assignments map[string]interface{}, userID *uint64) (int64, error) {
+	var values = make([]string, 0, len(hosts))
+	for _, v := range hosts {
+		values = append(values, value(v))
+	}
+
+	var rows int64
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var saved []T
+		err := tx.Unscoped().Where(column+" IN ?", values).Find(&saved).Error
+		if err != nil {
+			return err
+		}
+
+		query := tx.Omit("Sightings").Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: column}},
+			DoUpdates: clause.Assignments(assignments),
+		}).CreateInBatches(&hosts, 100)
+		if query.Error != nil {
+			return query.Error
+		}
+
+		rows = query.RowsAffected
+
+		var sightings []blacklistEntities.BlacklistSighting
+		var uuids = make([]pgtype.UUID, 0, len(hosts))
+		var importEvents = make(map[[16]byte]*uint64, len(hosts))
+		for _, v := range hosts {
+			var h blacklistEntities.BlacklistedHost
+			toHost(&h, v)
+
+			sightings = append(sightings, hostSightings(type_, h.UUID, h.Sightings)...)
+			uuids = append(uuids, h.UUID)
+			importEvents[h.UUID.Bytes] = h.ImportEventID
+		}
+
+		err = saveSightings(tx, sightings)
+		if err != nil {
+			return err
+		}
+
+		var before = make([]blacklistEntities.BlacklistedHost, len(saved))
+		for i, v := range saved {
+			toHost(&before[i], v)
+		}
+
+		return saveRevisions(tx, type_, before, uuids, userID, importEvents, "")
+	})
+
+	return rows, err
+}
+
+// saveRevisions compares hosts of single type before change with their current state and records revisions of
+// created, updated, deleted and restored hosts. Import events are defined by UUID of host saved by import.
+func saveRevisions(tx *gorm.DB, type_ string, before []blacklistEntities.BlacklistedHost, uuids []pgtype.UUID, userID *uint64, importEvents map[[16]byte]*uint64, reason string) error {
+	after, err := selectHostsByUUIDs(tx, type_, uuids)
+	if err != nil {
+		return err
+	}
+
+	var previous = make(map[[16]byte]*blacklistEntities.BlacklistedHost, len(before))
+	for i := range before {
+		previous[before[i].UUID.Bytes] = &before[i]
+	}
+
+	var revisions []blacklistEntities.BlacklistHostRevision
+	for _, h := range after {
+		revision, changed := blacklistEntities.NewBlacklistHostRevision(previous[h.UUID.Bytes], h)
+		if !changed {
+			continue
+		}
+
+		revision.UserID = userID
+		revision.ImportEventID = importEvents[h.UUID.Bytes]
+		revision.Reason = reason
+
+		revisions = append(revisions, revision)
+	}
+
+	if len(revisions) == 0 {
+		return nil
+	}
+
+	return tx.Omit("User", "ImportEvent").CreateInBatches(&revisions, 100).Error
+}
+
+// validUntilOnConflict extends lifetime of saved host: the latest expiration date is kept. Expired host saved again
+// without expiration date never expires.
+func validUntilOnConflict(table string) clause.Expr {
+	return gorm.Expr(fmt.Sprintf("CASE WHEN excluded.valid_until IS NULL AND %[1]s.valid_until <= now() THEN NULL ELSE GREATEST(excluded.valid_until, %[1]s.valid_until) END", table))
+}
+
+// withThreatAttributesOnConflict adds threat attributes to upsert assignments. Attributes defined by saved host replace
+// existing ones, attributes not defined are kept, so hosts imported again without attributes do not lose them.
+func withThreatAttributesOnConflict(table string, assignments map[string]interface{}) map[string]interface{} {
+	assignments["confidence"] = gorm.Expr(fmt.Sprintf("CASE WHEN excluded.confidence > 0 THEN excluded.confidence ELSE %s.confidence END", table))
+	assignments["severity"] = gorm.Expr(fmt.Sprintf("CASE WHEN excluded.severity <> '' THEN excluded.severity ELSE %s.severity END", table))
+	assignments["categories"] = gorm.Expr(fmt.Sprintf("CASE WHEN excluded.categories <> '[]' THEN excluded.categories ELSE %s.categories END", table))
+	assignments["kill_chain_phase"] = gorm.Expr(fmt.Sprintf("CASE WHEN excluded.kill_chain_phase <> '' THEN excluded.kill_chain_phase ELSE %s.kill_chain_phase END", table))
+
+	return assignments
+}
+
+// tagJoinTables maps host types to tables linking hosts with tags
+var tagJoinTables = map[string]string{
+	"ip":     "blacklisted_ip_tags",
+	"domain": "blacklisted_domain_tags",
+	"url":    "blacklisted_url_tags",
+	"email":  "blacklisted_email_tags",
+}
+
+// changedAt is time of the latest host change. Hosts are soft deleted without updating updated_at,
+// so deletion time is used if it is later.
+const changedAt = "GREATEST(updated_at, deleted_at)"
+
+// whereChanged selects hosts changed after UpdatedAfter (or after host with UpdatedAfterUUID changed at the same time)
+// and not later than UpdatedBefore
+func whereChanged(query *gorm.DB, filter blacklistEntities.BlacklistSearchFilter) *gorm.DB {
+	if filter.UpdatedAfter != nil && filter.UpdatedAfterUUID.Status == pgtype.Present {
+		query = query.Where("("+changedAt+", uuid) > (?, ?)", filter.UpdatedAfter, filter.UpdatedAfterUUID)
+	} else if filter.UpdatedAfter != nil {
+		query = query.Where(changedAt+" > ?", filter.UpdatedAfter)
+	}
+
+	if filter.UpdatedBefore != nil {
+		query = query.Where(changedAt+" <= ?", filter.UpdatedBefore)
+	}
+
+	return query
+}
+
+// whereTagged selects hosts tagged by any of defined tags
+func whereTagged(query *gorm.DB, type_ string, tagIDs []uint64) *gorm.DB {
+	if len(tagIDs) == 0 {
+		return query
+	}
+
+	return query.Where(fmt.Sprintf("uuid IN (SELECT host_uuid FROM %s WHERE tag_id IN ?)", tagJoinTables[type_]), tagIDs)
+}
+
+// whereSighted selects hosts reported by any of defined sources, hosts reported by several sources are selected by any of them
+func whereSighted(query *gorm.DB, type_ string, sourceIDs []uint64) *gorm.DB {
+	if len(sourceIDs) == 0 {
+		return query
+	}
+
+	return query.Where("uuid IN (SELECT host_id FROM blacklist_sightings WHERE host_type = ? AND source_id IN ?)", type_, sourceIDs)
+}
+
+// preloadSightings loads sightings of hosts with their sources, the earliest first
+func preloadSightings(query *gorm.DB) *gorm.DB {
+	return query.Preload("Sightings", func(db *gorm.DB) *gorm.DB {
+		return db.Order("first_seen_at ASC, id ASC")
+	}).Preload("Sightings.Source")
+}
+
+// hostSightings links sightings with saved host
+func hostSightings(type_ string, uuid pgtype.UUID, sightings []blacklistEntities.BlacklistSighting) []blacklistEntities.BlacklistSighting {
+	var result = make([]blacklistEntities.BlacklistSighting, 0, len(sightings))
+
+	for _, s := range sightings {
+		s.HostID = uuid
+		s.HostType = type_
+		result = append(result, s)
+	}
+
+	return result
+}
+
+// saveSightings creates sightings or updates existing sightings of same host and source: first and last seen dates
+// are extended, the latest import event and description are kept
+func saveSightings(tx *gorm.DB, sightings []blacklistEntities.BlacklistSighting) error {
+	if len(sightings) == 0 {
+		return nil
+	}
+
+	return tx.Omit("Source", "ImportEvent").Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "host_id"}, {Name: "host_type"}, {Name: "source_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"first_seen_at":   gorm.Expr("LEAST(blacklist_sightings.first_seen_at, excluded.first_seen_at)"),
+			"last_seen_at":    gorm.Expr("GREATEST(blacklist_sightings.last_seen_at, excluded.last_seen_at)"),
+			"import_event_id": gorm.Expr("COALESCE(excluded.import_event_id, blacklist_sightings.import_event_id)"),
+			"description":     gorm.Expr("CASE WHEN excluded.description <> '' THEN excluded.description ELSE blacklist_sightings.description END"),
+		}),
+	}).CreateInBatches(&sightings, 100).Error
+}
+
+// whereThreatAttributes selects hosts by threat attributes: confidence and severity not lower than defined,
+// any of defined categories and kill chain phase
+func whereThreatAttributes(query *gorm.DB, filter blacklistEntities.BlacklistSearchFilter) *gorm.DB {
+	if filter.MinConfidence > 0 {
+		query = query.Where("confidence >= ?", filter.MinConfidence)
+	}
+
+	if len(filter.MinSeverity) > 0 {
+		query = query.Where("severity IN ?", blacklistEntities.SeveritiesFrom(filter.MinSeverity))
+	}
+
+	if len(filter.Categories) > 0 {
+		var conditions []string
+		var args []interface{}
+
+		for _, c := range filter.Categories {
+			category, ok := blacklistEntities.ParseThreatCategory(c)
+			if !ok {
+				category = c
+			}
+
+			value, _ := json.Marshal([]string{category})
+
+			conditions = append(conditions, "categories @> ?")
+			args = append(args, string(value))
+		}
+
+		query = query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+
+	if len(filter.KillChainPhase) > 0 {
+		query = query.Where("kill_chain_phase = ?", blacklistEntities.ParseKillChainPhase(filter.KillChainPhase))
+	}
+
+	return query
+}
+
 func (r *BlacklistsRepoImpl) CountStatistics() (int64, int64, int64, int64) {
 	var ipCount, urlCount, domainCount, emailCount int64
 
@@ -349,10 +1170,10 @@ func (r *BlacklistsRepoImpl) SelectHostsUnionByFilter(filter blacklistEntities.B
 	var hosts []blacklistEntities.BlacklistedHost
 	var err error
 
-	ipQuery := r.Model(&blacklistEntities.BlacklistedIP{}).Select("uuid, abbrev(ip_address) AS host, 'ip' AS type, description, source_id, import_event_id, discovered_at, created_at, updated_at, deleted_at")
-	urlQuery := r.Model(&blacklistEntities.BlacklistedURL{}).Select("uuid, url AS host, 'url' AS type, description, source_id, import_event_id, discovered_at, created_at, updated_at, deleted_at")
-	domainQuery := r.Model(&blacklistEntities.BlacklistedDomain{}).Select("uuid, urn AS host, 'domain' AS type, description, source_id, import_event_id, discovered_at, created_at, updated_at, deleted_at")
-	emailQuery := r.Model(&blacklistEntities.BlacklistedEmail{}).Select("uuid, email AS host, 'email' AS type, description, source_id, import_event_id, discovered_at, created_at, updated_at, deleted_at")
+	ipQuery := r.Model(&blacklistEntities.BlacklistedIP{}).Select("uuid, abbrev(ip_address) AS host, 'ip' AS type, FALSE AS include_subdomains, description, confidence, severity, categories, kill_chain_phase, source_id, import_event_id, discovered_at, valid_until, created_at, updated_at, deleted_at")
+	urlQuery := r.Model(&blacklistEntities.BlacklistedURL{}).Select("uuid, url AS host, 'url' AS type, FALSE AS include_subdomains, description, confidence, severity, categories, kill_chain_phase, source_id, import_event_id, discovered_at, valid_until, created_at, updated_at, deleted_at")
+	domainQuery := r.Model(&blacklistEntities.BlacklistedDomain{}).Select("uuid, urn AS host, 'domain' AS type, include_subdomains, description, confidence, severity, categories, kill_chain_phase, source_id, import_event_id, discovered_at, valid_until, created_at, updated_at, deleted_at")
+	emailQuery := r.Model(&blacklistEntities.BlacklistedEmail{}).Select("uuid, email AS host, 'email' AS type, FALSE AS include_subdomains, description, confidence, severity, categories, kill_chain_phase, source_id, import_event_id, discovered_at, valid_until, created_at, updated_at, deleted_at")
 
 	if filter.IsActive != nil && *filter.IsActive == false {
 		ipQuery = ipQuery.Unscoped()
@@ -375,34 +1196,70 @@ func (r *BlacklistsRepoImpl) SelectHostsUnionByFilter(filter blacklistEntities.B
 		emailQuery = emailQuery.Where("created_at < ?", filter.CreatedBefore)
 	}
 
-	if len(filter.SearchString) > 0 {
-		var addr string = "0.0.0.0/32"
+	if filter.DiscoveredAfter != nil {
+		ipQuery = ipQuery.Where("discovered_at > ?", filter.DiscoveredAfter)
+		urlQuery = urlQuery.Where("discovered_at > ?", filter.DiscoveredAfter)
+		domainQuery = domainQuery.Where("discovered_at > ?", filter.DiscoveredAfter)
+		emailQuery = emailQuery.Where("discovered_at > ?", filter.DiscoveredAfter)
+	}
+
+	if filter.DiscoveredBefore != nil {
+		ipQuery = ipQuery.Where("discovered_at < ?", filter.DiscoveredBefore)
+		urlQuery = urlQuery.Where("discovered_at < ?", filter.DiscoveredBefore)
+		domainQuery = domainQuery.Where("discovered_at < ?", filter.DiscoveredBefore)
+		emailQuery = emailQuery.Where("discovered_at < ?", filter.DiscoveredBefore)
+	}
 
-		// check if search string is IP or IP with mask
-		_, _, err := net.ParseCIDR(filter.SearchString)
-		ip := net.ParseIP(filter.SearchString)
+	if filter.ExpiresBefore != nil {
+		ipQuery = ipQuery.Where("valid_until < ?", filter.ExpiresBefore)
+		urlQuery = urlQuery.Where("valid_until < ?", filter.ExpiresBefore)
+		domainQuery = domainQuery.Where("valid_until < ?", filter.ExpiresBefore)
+		emailQuery = emailQuery.Where("valid_until < ?", filter.ExpiresBefore)
+	}
+
+	ipQuery = whereThreatAttributes(ipQuery, filter)
+	urlQuery = whereThreatAttributes(urlQuery, filter)
+	domainQuery = whereThreatAttributes(domainQuery, filter)
+	emailQuery = whereThreatAttributes(emailQuery, filter)
+
+	ipQuery = whereTagged(ipQuery, "ip", filter.TagIDs)
+	urlQuery = whereTagged(urlQuery, "url", filter.TagIDs)
+	domainQuery = whereTagged(domainQuery, "domain", filter.TagIDs)
+	emailQuery = whereTagged(emailQuery, "email", filter.TagIDs)
+
+	ipQuery = whereChanged(ipQuery, filter)
+	urlQuery = whereChanged(urlQuery, filter)
+	domainQuery = whereChanged(domainQuery, filter)
+	emailQuery = whereChanged(emailQuery, filter)
 
-		if err != nil && ip == nil {
-			slog.Warn("failed to parse CIDR in multi-search: " + err.Error())
-		} else if err != nil {
-			addr = ip.String() + "/32"
+	if len(filter.SearchString) > 0 {
+		// check if search string is IP or IP with mask, otherwise no IPs are selected
+		ip, err := blacklistEntities.ParseInet(filter.SearchString)
+		if err == nil {
+			addr := ip.IPNet.String()
+			ipQuery = ipQuery.Where("(ip_address <<= ? OR ip_address >>= ?)", addr, addr)
 		} else {
-			addr = filter.SearchString
+			ipQuery = ipQuery.Where("FALSE")
 		}
 
-		ipQuery = ipQuery.Where("ip_address <<= ?", addr)
 		urlQuery = urlQuery.Where("url LIKE ?", "%"+filter.SearchString+"%")
 		domainQuery = domainQuery.Where("urn LIKE ?", "%"+filter.SearchString+"%")
 		emailQuery = emailQuery.Where("email LIKE ?", "%"+filter.SearchString+"%")
 	}
 
-	if len(filter.SourceIDs) > 0 {
-		ipQuery = ipQuery.Where("source_id IN ?", filter.SourceIDs)
-		urlQuery = urlQuery.Where("source_id IN ?", filter.SourceIDs)
-		domainQuery = domainQuery.Where("source_id IN ?", filter.SourceIDs)
-		emailQuery = emailQuery.Where("source_id IN ?", filter.SourceIDs)
+	// domain filter selects only domains
+	if len(filter.Domain) > 0 {
+		ipQuery = ipQuery.Where("FALSE")
+		urlQuery = urlQuery.Where("FALSE")
+		domainQuery = whereDomainCovers(domainQuery, filter.Domain)
+		emailQuery = emailQuery.Where("FALSE")
 	}
 
+	ipQuery = whereSighted(ipQuery, "ip", filter.SourceIDs)
+	urlQuery = whereSighted(urlQuery, "url", filter.SourceIDs)
+	domainQuery = whereSighted(domainQuery, "domain", filter.SourceIDs)
+	emailQuery = whereSighted(emailQuery, "email", filter.SourceIDs)
+
 	if filter.ImportEventID > 0 {
 		ipQuery = ipQuery.Where("import_event_id = ?", filter.ImportEventID)
 		urlQuery = urlQuery.Where("import_event_id = ?", filter.ImportEventID)
@@ -411,6 +1268,10 @@ func (r *BlacklistsRepoImpl) SelectHostsUnionByFilter(filter blacklistEntities.B
 	}
 
 	var query = "? UNION ? UNION ? UNION ? ORDER BY created_at DESC, updated_at DESC, UUID DESC OFFSET ?"
+	if filter.SortByUpdated {
+		// hosts are sorted by change time, same as compared by whereChanged, so they can be paged through by cursor
+		query = "SELECT * FROM (? UNION ? UNION ? UNION ?) AS hosts ORDER BY " + changedAt + " ASC, uuid ASC OFFSET ?"
+	}
 
 	if filter.Limit != 0 {
 		query += " LIMIT ?"
@@ -477,6 +1338,69 @@ func (r *BlacklistsRepoImpl) SelectHostsUnionByFilter(filter blacklistEntities.B
 	return hosts, err
 }
 
+// SelectHostsByValues selects active hosts of defined type by exact values. IP addresses are matched and returned in CIDR notation.
+// Host is returned once for every source which reported it.
+func (r *BlacklistsRepoImpl) SelectHostsByValues(type_ string, values []string) ([]blacklistEntities.BlacklistedHost, error) {
+	var query *gorm.DB
+
+	switch type_ {
+	case "ip":
+		query = r.Model(&blacklistEntities.BlacklistedIP{}).Select("uuid, text(ip_address) AS host, 'ip' AS type, COALESCE(blacklist_sightings.source_id, blacklisted_ips.source_id) AS source_id, confidence, severity, categories, kill_chain_phase").Where("ip_address IN ?", values)
+	case "url":
+		query = r.Model(&blacklistEntities.BlacklistedURL{}).Select("uuid, url AS host, 'url' AS type, COALESCE(blacklist_sightings.source_id, blacklisted_urls.source_id) AS source_id, confidence, severity, categories, kill_chain_phase").Where("url IN ?", values)
+	case "domain":
+		query = r.Model(&blacklistEntities.BlacklistedDomain{}).Select("uuid, urn AS host, 'domain' AS type, COALESCE(blacklist_sightings.source_id, blacklisted_domains.source_id) AS source_id, confidence, severity, categories, kill_chain_phase").Where("urn IN ?", values)
+	case "email":
+		query = r.Model(&blacklistEntities.BlacklistedEmail{}).Select("uuid, email AS host, 'email' AS type, COALESCE(blacklist_sightings.source_id, blacklisted_emails.source_id) AS source_id, confidence, severity, categories, kill_chain_phase").Where("email IN ?", values)
+	default:
+		return nil, fmt.Errorf("host type '%s' not supported", type_)
+	}
+
+	query = query.Joins("LEFT JOIN blacklist_sightings ON blacklist_sightings.host_type = ? AND blacklist_sightings.host_id = uuid", type_)
+
+	var hosts []blacklistEntities.BlacklistedHost
+
+	err := query.Scan(&hosts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return hosts, nil
+}
+
+func (r *BlacklistsRepoImpl) SelectDomainsByURNs(urns []string) ([]blacklistEntities.BlacklistedDomain, error) {
+	var result []blacklistEntities.BlacklistedDomain
+
+	err := preloadSightings(r.Preload("Source")).Where("urn IN ?", urns).Order("created_at DESC").Find(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *BlacklistsRepoImpl) SelectURLsByMD5(hashes []string) ([]blacklistEntities.BlacklistedURL, error) {
+	var result []blacklistEntities.BlacklistedURL
+
+	err := preloadSightings(r.Preload("Source")).Where("md5 IN ?", hashes).Order("created_at DESC").Find(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *BlacklistsRepoImpl) SelectEmailsByValues(emails []string) ([]blacklistEntities.BlacklistedEmail, error) {
+	var result []blacklistEntities.BlacklistedEmail
+
+	err := preloadSightings(r.Preload("Source")).Where("email IN ?", emails).Order("created_at DESC").Find(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
 func (r *BlacklistsRepoImpl) SelectByCreationDateStatistics(startDate, endDate time.Time) ([]blacklistEntities.BlacklistedByDate, error) {
 	var byDate []blacklistEntities.BlacklistedByDate
 
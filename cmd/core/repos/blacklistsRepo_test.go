@@ -0,0 +1,742 @@
+package repos
+
+import (
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"domain_threat_intelligence_api/cmd/core/entities/userEntities"
+	"github.com/jackc/pgtype"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dryRunDB returns database session which builds SQL without connecting to database
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// testDB returns database defined by TEST_POSTGRES_DSN with blacklist tables, test is skipped if it is not defined
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if len(dsn) == 0 {
+		t.Skip("TEST_POSTGRES_DSN is not defined")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.AutoMigrate(
+		userEntities.PlatformUserPermission{},
+		userEntities.PlatformUser{},
+		blacklistEntities.BlacklistSource{},
+		blacklistEntities.BlacklistImportEvent{},
+		blacklistEntities.BlacklistTag{},
+		blacklistEntities.BlacklistSighting{},
+		blacklistEntities.BlacklistedIP{},
+		blacklistEntities.BlacklistedURL{},
+		blacklistEntities.BlacklistedDomain{},
+		blacklistEntities.BlacklistedEmail{},
+		blacklistEntities.BlacklistHostRevision{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range blacklistEntities.DefaultSources {
+		err = db.Where(blacklistEntities.BlacklistSource{ID: s.ID}).FirstOrCreate(&s).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// every test works in own transaction, which is rolled back
+	return db.Begin()
+}
+
+func TestDeleteExpiredHosts(t *testing.T) {
+	db := testDB(t)
+	defer db.Rollback()
+
+	repo := NewBlacklistsRepoImpl(db)
+
+	now := time.Now()
+	expired, valid := now.Add(-time.Hour), now.Add(time.Hour)
+
+	var ips []blacklistEntities.BlacklistedIP
+	for i, validUntil := range []*time.Time{&expired, &valid, nil} {
+		ip, err := blacklistEntities.ParseInet([]string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ips = append(ips, blacklistEntities.BlacklistedIP{IPAddress: ip, SourceID: blacklistEntities.SourceUnknown, ValidUntil: validUntil})
+	}
+
+	_, err := repo.SaveIPs(ips, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := repo.DeleteExpiredHosts(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows != 1 {
+		t.Errorf("expected single expired host deleted, got %d", rows)
+	}
+
+	var active []blacklistEntities.BlacklistedIP
+	if err = db.Find(&active).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(active) != 2 {
+		t.Errorf("expected 2 active hosts, got %d", len(active))
+	}
+
+	// expired host imported again without expiration date becomes active and never expires
+	ips[0].ValidUntil = nil
+
+	_, err = repo.SaveIPs(ips[:1], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var restored blacklistEntities.BlacklistedIP
+	if err = db.Where("ip_address = ?", "10.0.0.1/32").First(&restored).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.ValidUntil != nil {
+		t.Errorf("expected restored host without expiration date, got %s", restored.ValidUntil)
+	}
+}
+
+func TestSaveIPsExtendsValidUntil(t *testing.T) {
+	db := testDB(t)
+	defer db.Rollback()
+
+	repo := NewBlacklistsRepoImpl(db)
+
+	ip, _ := blacklistEntities.ParseInet("10.0.0.1")
+	later, earlier := time.Now().AddDate(0, 0, 30), time.Now().AddDate(0, 0, 7)
+
+	for _, validUntil := range []*time.Time{&later, &earlier} {
+		_, err := repo.SaveIPs([]blacklistEntities.BlacklistedIP{{IPAddress: ip, SourceID: blacklistEntities.SourceUnknown, ValidUntil: validUntil}}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var saved blacklistEntities.BlacklistedIP
+	if err := db.Where("ip_address = ?", "10.0.0.1/32").First(&saved).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// the latest expiration date is kept
+	if saved.ValidUntil == nil || saved.ValidUntil.Sub(later).Abs() > time.Second {
+		t.Errorf("expected host valid until %s, got %v", later, saved.ValidUntil)
+	}
+}
+
+func TestSelectAllowlistConflicts(t *testing.T) {
+	db := testDB(t)
+	defer db.Rollback()
+
+	repo := NewBlacklistsRepoImpl(db)
+	source := blacklistEntities.SourceUnknown
+
+	var ips []blacklistEntities.BlacklistedIP
+	for _, v := range []string{"8.8.8.8", "8.0.0.0/8", "10.0.0.1"} {
+		ip, err := blacklistEntities.ParseInet(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ips = append(ips, blacklistEntities.BlacklistedIP{IPAddress: ip, SourceID: source})
+	}
+
+	if _, err := repo.SaveIPs(ips, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := repo.SaveDomains([]blacklistEntities.BlacklistedDomain{
+		{URN: "example.com", SourceID: source},
+		{URN: "www.example.com", SourceID: source},
+		{URN: "evil.com", SourceID: source},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.SaveURLs([]blacklistEntities.BlacklistedURL{
+		{URL: "http://www.example.com/a", MD5: blacklistEntities.URLHash("http://www.example.com/a"), SourceID: source},
+		{URL: "http://8.8.8.8/a", MD5: blacklistEntities.URLHash("http://8.8.8.8/a"), SourceID: source},
+		{URL: "http://evil.com/a", MD5: blacklistEntities.URLHash("http://evil.com/a"), SourceID: source},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.SaveEmails([]blacklistEntities.BlacklistedEmail{
+		{Email: "user@mail.example.com", SourceID: source},
+		{Email: "user@evil.com", SourceID: source},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		entry blacklistEntities.AllowlistEntry
+		want  []string
+	}{
+		{
+			name:  "address",
+			entry: blacklistEntities.AllowlistEntry{Type: "ip", Value: "8.8.8.8/32"},
+			want:  []string{"ip:8.8.8.8/32", "ip:8.0.0.0/8", "url:http://8.8.8.8/a"},
+		},
+		{
+			name:  "domain",
+			entry: blacklistEntities.AllowlistEntry{Type: "domain", Value: "example.com"},
+			want:  []string{"domain:example.com"},
+		},
+		{
+			name:  "domain with subdomains",
+			entry: blacklistEntities.AllowlistEntry{Type: "domain", Value: "example.com", IncludeSubdomains: true},
+			want:  []string{"domain:example.com", "domain:www.example.com", "url:http://www.example.com/a", "email:user@mail.example.com"},
+		},
+		{
+			name:  "url pattern",
+			entry: blacklistEntities.AllowlistEntry{Type: "url", Value: "*example.com/*"},
+			want:  []string{"url:http://www.example.com/a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hosts, err := repo.SelectAllowlistConflicts(tt.entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, h := range hosts {
+				got = append(got, h.Type+":"+h.Host)
+			}
+
+			// hosts of the same type are returned in any order
+			slices.Sort(got)
+			slices.Sort(tt.want)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectDomainsByThreatAttributes(t *testing.T) {
+	db := testDB(t)
+	defer db.Rollback()
+
+	repo := NewBlacklistsRepoImpl(db)
+	source := blacklistEntities.SourceUnknown
+
+	_, err := repo.SaveDomains([]blacklistEntities.BlacklistedDomain{
+		{URN: "phishing.com", SourceID: source, ThreatAttributes: blacklistEntities.ThreatAttributes{Confidence: 90, Severity: "high", Categories: []string{"phishing"}, KillChainPhase: "delivery"}},
+		{URN: "spam.com", SourceID: source, ThreatAttributes: blacklistEntities.ThreatAttributes{Confidence: 40, Severity: "low", Categories: []string{"spam", "botnet"}}},
+		{URN: "unknown.com", SourceID: source, ThreatAttributes: blacklistEntities.ThreatAttributes{Categories: []string{}}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// host imported again without attributes keeps already known ones
+	_, err = repo.SaveDomains([]blacklistEntities.BlacklistedDomain{{URN: "phishing.com", SourceID: source, ThreatAttributes: blacklistEntities.ThreatAttributes{Categories: []string{}}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter blacklistEntities.BlacklistSearchFilter
+		want   []string
+	}{
+		{name: "min confidence", filter: blacklistEntities.BlacklistSearchFilter{MinConfidence: 50}, want: []string{"phishing.com"}},
+		{name: "min severity", filter: blacklistEntities.BlacklistSearchFilter{MinSeverity: "medium"}, want: []string{"phishing.com"}},
+		{name: "any of categories", filter: blacklistEntities.BlacklistSearchFilter{Categories: []string{"phishing", "bot"}}, want: []string{"phishing.com", "spam.com"}},
+		{name: "kill chain phase", filter: blacklistEntities.BlacklistSearchFilter{KillChainPhase: "Delivery"}, want: []string{"phishing.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domains, err := repo.SelectDomainsByFilter(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, d := range domains {
+				got = append(got, d.URN)
+			}
+
+			slices.Sort(got)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostTags(t *testing.T) {
+	db := testDB(t)
+	defer db.Rollback()
+
+	repo := NewBlacklistsRepoImpl(db)
+	source := blacklistEntities.SourceUnknown
+
+	tags, err := repo.SaveTagsByKey([]blacklistEntities.BlacklistTag{{Name: "APT28", Kind: blacklistEntities.TagKindActor}, {Name: "tlp:amber"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// saving the same tags again returns existing ones
+	again, err := repo.SaveTagsByKey(tags[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if again[0].ID != tags[0].ID {
+		t.Errorf("tag created twice: %d and %d", tags[0].ID, again[0].ID)
+	}
+
+	_, err = repo.SaveDomains([]blacklistEntities.BlacklistedDomain{
+		{URN: "evil.com", SourceID: source},
+		{URN: "other.com", SourceID: source},
+		{URN: "deleted.com", SourceID: source},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var domains []blacklistEntities.BlacklistedDomain
+	if err = db.Order("urn ASC").Find(&domains).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// deleted.com, evil.com, other.com
+	uuids := []pgtype.UUID{domains[0].UUID, domains[1].UUID}
+	if _, err = repo.SaveHostTags("domain", uuids, []uint64{tags[0].ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = repo.SaveHostTags("domain", uuids[1:], []uint64{tags[0].ID, tags[1].ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = repo.DeleteDomain(domains[0].UUID, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	hostTags, err := repo.SelectHostsTags("domain", uuids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := hostTags[domains[1].UUID.Bytes]; len(got) != 2 || got[0].Name != "APT28" {
+		t.Errorf("unexpected tags of evil.com: %+v", got)
+	}
+
+	tagged, err := repo.SelectDomainsByFilter(blacklistEntities.BlacklistSearchFilter{TagIDs: []uint64{tags[0].ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tagged) != 1 || tagged[0].URN != "evil.com" || len(tagged[0].Tags) != 2 {
+		t.Errorf("unexpected tagged domains: %+v", tagged)
+	}
+
+	// deleted hosts are not counted
+	counts, err := repo.CountTagStatistics()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range counts {
+		if (c.ID == tags[0].ID || c.ID == tags[1].ID) && c.Count != 1 {
+			t.Errorf("tag %s counted %d times, want 1", c.Name, c.Count)
+		}
+	}
+
+	if rows, err := repo.DeleteHostTags("domain", uuids[1:], []uint64{tags[1].ID}); err != nil || rows != 1 {
+		t.Errorf("expected single link deleted, got %d: %v", rows, err)
+	}
+}
+
+func TestSaveDomainsWithSightings(t *testing.T) {
+	db := testDB(t)
+	defer db.Rollback()
+
+	repo := NewBlacklistsRepoImpl(db)
+
+	firstSeen := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	saves := []blacklistEntities.BlacklistedDomain{
+		{URN: "evil.com", SourceID: blacklistEntities.SourceKaspersky, Sightings: []blacklistEntities.BlacklistSighting{
+			blacklistEntities.NewBlacklistSighting(blacklistEntities.SourceKaspersky, nil, "phishing", firstSeen),
+		}},
+		{URN: "evil.com", SourceID: blacklistEntities.SourceDrWeb, Sightings: []blacklistEntities.BlacklistSighting{
+			blacklistEntities.NewBlacklistSighting(blacklistEntities.SourceDrWeb, nil, "malware", time.Time{}),
+		}},
+		{URN: "evil.com", SourceID: blacklistEntities.SourceKaspersky, Sightings: []blacklistEntities.BlacklistSighting{
+			blacklistEntities.NewBlacklistSighting(blacklistEntities.SourceKaspersky, nil, "", time.Time{}),
+		}},
+	}
+
+	for _, d := range saves {
+		if _, err := repo.SaveDomains([]blacklistEntities.BlacklistedDomain{d}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	domains, err := repo.SelectDomainsByFilter(blacklistEntities.BlacklistSearchFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// host is saved once with the first source, every source has single sighting
+	if len(domains) != 1 || domains[0].SourceID != blacklistEntities.SourceKaspersky || len(domains[0].Sightings) != 2 {
+		t.Fatalf("unexpected domains: %+v", domains)
+	}
+
+	kaspersky := domains[0].Sightings[0]
+	if kaspersky.SourceID != blacklistEntities.SourceKaspersky || !kaspersky.FirstSeenAt.Equal(firstSeen) || kaspersky.Description != "phishing" {
+		t.Errorf("sighting not merged: %+v", kaspersky)
+	}
+
+	if kaspersky.Source == nil || domains[0].Sightings[1].SourceID != blacklistEntities.SourceDrWeb {
+		t.Errorf("unexpected sightings: %+v", domains[0].Sightings)
+	}
+
+	sightings, err := repo.SelectHostsSightings("domain", []pgtype.UUID{domains[0].UUID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sightings[domains[0].UUID.Bytes]) != 2 {
+		t.Errorf("unexpected sightings: %+v", sightings)
+	}
+}
+
+func TestHostRevisions(t *testing.T) {
+	db := testDB(t)
+	defer db.Rollback()
+
+	repo := NewBlacklistsRepoImpl(db)
+
+	user := userEntities.PlatformUser{FullName: "Analyst", Login: "revisions_test"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	domain := blacklistEntities.BlacklistedDomain{URN: "evil.com", SourceID: blacklistEntities.SourceManual, Description: "phishing"}
+
+	// saved again without changes, host gets no revision
+	for i := 0; i < 2; i++ {
+		if _, err := repo.SaveDomains([]blacklistEntities.BlacklistedDomain{domain}, &user.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	domain.Description = "malware"
+	if _, err := repo.SaveDomains([]blacklistEntities.BlacklistedDomain{domain}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var saved blacklistEntities.BlacklistedDomain
+	if err := db.Where("urn = ?", domain.URN).First(&saved).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.DeleteDomain(saved.UUID, &user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	validUntil := time.Now().Add(time.Hour)
+	if rows, err := repo.RestoreHost("domain", saved.UUID, &validUntil, &user.ID); err != nil || rows != 1 {
+		t.Fatalf("RestoreHost() = %d, %v", rows, err)
+	}
+
+	host, err := repo.SelectHost(saved.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if host.DeletedAt.Valid || host.ValidUntil == nil || host.ValidUntil.Sub(validUntil).Abs() > time.Second {
+		t.Errorf("host not restored: %+v", host)
+	}
+
+	// active host is not restored again
+	if rows, err := repo.RestoreHost("domain", saved.UUID, nil, &user.ID); err != nil || rows != 0 {
+		t.Errorf("RestoreHost() of active host = %d, %v", rows, err)
+	}
+
+	revisions, err := repo.SelectHostRevisions(saved.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actions []string
+	for _, r := range revisions {
+		actions = append(actions, r.Action)
+	}
+
+	// revisions are ordered from the latest
+	want := []string{
+		blacklistEntities.RevisionActionRestore,
+		blacklistEntities.RevisionActionDelete,
+		blacklistEntities.RevisionActionUpdate,
+		blacklistEntities.RevisionActionCreate,
+	}
+
+	if !reflect.DeepEqual(actions, want) {
+		t.Fatalf("got revisions %v, want %v", actions, want)
+	}
+
+	if create := revisions[3]; create.User == nil || create.User.ID != user.ID || create.Before.Data() != nil || create.After.Data().Description != "phishing" {
+		t.Errorf("unexpected create revision: %+v", create)
+	}
+
+	if update := revisions[2]; update.UserID != nil || update.Before.Data().Description != "phishing" || update.After.Data().Description != "malware" {
+		t.Errorf("unexpected update revision: %+v", update)
+	}
+}
+
+func TestDeleteExpiredHostsRevisions(t *testing.T) {
+	db := testDB(t)
+	defer db.Rollback()
+
+	repo := NewBlacklistsRepoImpl(db)
+
+	expired := time.Now().Add(-time.Hour)
+	domain := blacklistEntities.BlacklistedDomain{URN: "evil.com", SourceID: blacklistEntities.SourceManual, ValidUntil: &expired}
+
+	if _, err := repo.SaveDomains([]blacklistEntities.BlacklistedDomain{domain}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.DeleteExpiredHosts(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	var saved blacklistEntities.BlacklistedDomain
+	if err := db.Unscoped().Where("urn = ?", domain.URN).First(&saved).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, err := repo.SelectHostRevisions(saved.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// expiration is revised as automatic deletion
+	if len(revisions) != 2 || revisions[0].Action != blacklistEntities.RevisionActionDelete ||
+		revisions[0].Reason != blacklistEntities.RevisionReasonExpired || revisions[0].UserID != nil {
+		t.Errorf("unexpected revisions: %+v", revisions)
+	}
+}
+
+func TestWhereIPContainsAny(t *testing.T) {
+	tests := []struct {
+		name  string
+		addrs []string
+		want  string
+	}{
+		{name: "single address", addrs: []string{"10.0.0.1/32"}, want: "{10.0.0.1/32}"},
+		{name: "several addresses", addrs: []string{"10.0.0.1/32", "192.168.0.0/16", "2001:db8::1/128"}, want: "{10.0.0.1/32,192.168.0.0/16,2001:db8::1/128}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := whereIPContainsAny(dryRunDB(t).Model(&blacklistEntities.BlacklistedIP{}), tt.addrs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stmt := query.Find(&[]blacklistEntities.BlacklistedIP{}).Statement
+
+			sql := stmt.SQL.String()
+			if !strings.Contains(sql, "ip_address >>= ANY (CAST($1 AS inet[]))") {
+				t.Errorf("unexpected SQL: %s", sql)
+			}
+
+			if len(stmt.Vars) != 1 {
+				t.Fatalf("expected single array variable, got %d: %v", len(stmt.Vars), stmt.Vars)
+			}
+
+			array, ok := stmt.Vars[0].(pgtype.InetArray)
+			if !ok {
+				t.Fatalf("expected inet array, got %T", stmt.Vars[0])
+			}
+
+			value, err := array.Value()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if value != tt.want {
+				t.Errorf("got %v, want %s", value, tt.want)
+			}
+		})
+	}
+}
+
+func TestWhereIPContainsAnyInvalidAddress(t *testing.T) {
+	_, err := whereIPContainsAny(dryRunDB(t), []string{"10.0.0.1", "not an address"})
+	if err == nil {
+		t.Error("expected error for invalid address")
+	}
+}
+
+func TestSelectIPsContainingAny(t *testing.T) {
+	db := testDB(t)
+	defer db.Rollback()
+
+	repo := NewBlacklistsRepoImpl(db)
+
+	var ips []blacklistEntities.BlacklistedIP
+	for _, v := range []string{"10.0.0.0/8", "192.168.1.1"} {
+		ip, err := blacklistEntities.ParseInet(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ips = append(ips, blacklistEntities.BlacklistedIP{IPAddress: ip, SourceID: blacklistEntities.SourceUnknown})
+	}
+
+	_, err := repo.SaveIPs(ips, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := repo.SelectIPsContainingAny([]string{"10.1.2.3/32", "192.168.1.1/32", "172.16.0.1/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(found) != 2 {
+		t.Errorf("expected 2 covering networks, got %d", len(found))
+	}
+}
+
+func TestWhereChanged(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	id := pgtype.UUID{Bytes: [16]byte{1}, Status: pgtype.Present}
+
+	tests := []struct {
+		name   string
+		filter blacklistEntities.BlacklistSearchFilter
+		want   string
+		vars   int
+	}{
+		{name: "after time", filter: blacklistEntities.BlacklistSearchFilter{UpdatedAfter: &at}, want: "GREATEST(updated_at, deleted_at) > $1", vars: 1},
+		{name: "after host", filter: blacklistEntities.BlacklistSearchFilter{UpdatedAfter: &at, UpdatedAfterUUID: id}, want: "(GREATEST(updated_at, deleted_at), uuid) > ($1, $2)", vars: 2},
+		{name: "before time", filter: blacklistEntities.BlacklistSearchFilter{UpdatedBefore: &at}, want: "GREATEST(updated_at, deleted_at) <= $1", vars: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt := whereChanged(dryRunDB(t).Model(&blacklistEntities.BlacklistedDomain{}), tt.filter).Find(&[]blacklistEntities.BlacklistedDomain{}).Statement
+
+			if sql := stmt.SQL.String(); !strings.Contains(sql, tt.want) {
+				t.Errorf("unexpected SQL: %s", sql)
+			}
+
+			if len(stmt.Vars) != tt.vars {
+				t.Errorf("got variables %v, want %d", stmt.Vars, tt.vars)
+			}
+		})
+	}
+}
+
+func TestSelectHostsUnionByChangeCursor(t *testing.T) {
+	db := testDB(t)
+	defer db.Rollback()
+
+	repo := NewBlacklistsRepoImpl(db)
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := repo.SaveDomains([]blacklistEntities.BlacklistedDomain{
+		{URN: "first.com", SourceID: blacklistEntities.SourceUnknown},
+		{URN: "second.com", SourceID: blacklistEntities.SourceUnknown},
+		{URN: "removed.com", SourceID: blacklistEntities.SourceUnknown},
+		{URN: "late.com", SourceID: blacklistEntities.SourceUnknown},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// first and second hosts are changed at the same time, removed host is ordered by deletion time
+	err = db.Exec("UPDATE blacklisted_domains SET updated_at = ? WHERE urn IN ?", at, []string{"first.com", "second.com", "removed.com"}).Error
+	if err == nil {
+		err = db.Exec("UPDATE blacklisted_domains SET deleted_at = ? WHERE urn = ?", at.Add(time.Hour), "removed.com").Error
+	}
+	if err == nil {
+		err = db.Exec("UPDATE blacklisted_domains SET updated_at = ? WHERE urn = ?", at.Add(2*time.Hour), "late.com").Error
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := at.Add(time.Hour)
+	filter := blacklistEntities.BlacklistSearchFilter{
+		Limit:         1,
+		IsActive:      new(bool),
+		UpdatedBefore: &before,
+		SortByUpdated: true,
+	}
+
+	// first page is selected from time, then hosts are paged through by last change time and UUID
+	var got []string
+	for since := at.Add(-time.Second); len(got) < 5; {
+		filter.UpdatedAfter = &since
+
+		hosts, err := repo.SelectHostsUnionByFilter(filter)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(hosts) == 0 {
+			break
+		}
+
+		got = append(got, hosts[0].Host)
+		since, filter.UpdatedAfterUUID = hosts[0].ChangedAt(), hosts[0].UUID
+	}
+
+	// order of hosts changed at the same time depends on generated UUIDs
+	if len(got) > 1 {
+		slices.Sort(got[:2])
+	}
+
+	// late host is changed after defined time
+	if want := []string{"first.com", "second.com", "removed.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"crypto/sha256"
+	"domain_threat_intelligence_api/cmd/core"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+type BlacklistExportFeedsServiceImpl struct {
+	repo           core.IBlacklistExportFeedsRepo
+	blacklistsRepo core.IBlacklistsRepo
+}
+
+func NewBlacklistExportFeedsServiceImpl(repo core.IBlacklistExportFeedsRepo, blacklistsRepo core.IBlacklistsRepo) *BlacklistExportFeedsServiceImpl {
+	return &BlacklistExportFeedsServiceImpl{repo: repo, blacklistsRepo: blacklistsRepo}
+}
+
+func (s *BlacklistExportFeedsServiceImpl) RetrieveAllFeeds() ([]blacklistEntities.BlacklistExportFeed, error) {
+	return s.repo.SelectAllFeeds()
+}
+
+func (s *BlacklistExportFeedsServiceImpl) RetrieveFeed(id uint64) (blacklistEntities.BlacklistExportFeed, error) {
+	return s.repo.SelectFeed(id)
+}
+
+func (s *BlacklistExportFeedsServiceImpl) SaveFeed(feed blacklistEntities.BlacklistExportFeed) (blacklistEntities.BlacklistExportFeed, error) {
+	if len(feed.Name) == 0 {
+		return blacklistEntities.BlacklistExportFeed{}, errors.New("feed name not defined")
+	}
+
+	if !slices.Contains(blacklistEntities.ExportFormats, feed.Format) {
+		return blacklistEntities.BlacklistExportFeed{}, fmt.Errorf("feed format '%s' not supported", feed.Format)
+	}
+
+	if feed.ID != 0 {
+		current, err := s.repo.SelectFeed(feed.ID)
+		if err != nil {
+			return blacklistEntities.BlacklistExportFeed{}, err
+		} else if current.ID == 0 {
+			return blacklistEntities.BlacklistExportFeed{}, errors.New("feed not found")
+		}
+
+		feed.CreatedAt = current.CreatedAt
+	}
+
+	return s.repo.SaveFeed(feed)
+}
+
+func (s *BlacklistExportFeedsServiceImpl) DeleteFeed(id uint64) (int64, error) {
+	return s.repo.DeleteFeed(id)
+}
+
+func (s *BlacklistExportFeedsServiceImpl) IssueToken(feedID uint64, consumer string, expiresAt *time.Time) (blacklistEntities.BlacklistExportFeedToken, error) {
+	if len(consumer) == 0 {
+		return blacklistEntities.BlacklistExportFeedToken{}, errors.New("token consumer not defined")
+	}
+
+	feed, err := s.repo.SelectFeed(feedID)
+	if err != nil {
+		return blacklistEntities.BlacklistExportFeedToken{}, err
+	} else if feed.ID == 0 {
+		return blacklistEntities.BlacklistExportFeedToken{}, errors.New("feed not found")
+	}
+
+	plain, hash, err := blacklistEntities.GenerateExportFeedToken()
+	if err != nil {
+		return blacklistEntities.BlacklistExportFeedToken{}, err
+	}
+
+	token, err := s.repo.SaveToken(blacklistEntities.BlacklistExportFeedToken{
+		FeedID:    feed.ID,
+		Consumer:  consumer,
+		TokenHash: hash,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return blacklistEntities.BlacklistExportFeedToken{}, err
+	}
+
+	token.Token = plain
+
+	return token, nil
+}
+
+func (s *BlacklistExportFeedsServiceImpl) RevokeToken(id uint64) (int64, error) {
+	return s.repo.DeleteToken(id)
+}
+
+func (s *BlacklistExportFeedsServiceImpl) RetrieveFeedByToken(token string) (blacklistEntities.BlacklistExportFeedToken, error) {
+	if len(token) == 0 {
+		return blacklistEntities.BlacklistExportFeedToken{}, errors.New("feed token not defined")
+	}
+
+	feedToken, err := s.repo.SelectTokenByHash(blacklistEntities.HashExportFeedToken(token))
+	if err != nil {
+		return blacklistEntities.BlacklistExportFeedToken{}, err
+	}
+
+	if feedToken.ID == 0 || !feedToken.IsValid(time.Now()) {
+		return blacklistEntities.BlacklistExportFeedToken{}, errors.New("feed token invalid or expired")
+	}
+
+	// feed is not preloaded if it was deleted
+	if feedToken.Feed == nil || !feedToken.Feed.IsEnabled {
+		return feedToken, errors.New("feed not found or disabled")
+	}
+
+	return feedToken, nil
+}
+
+// RenderFeed selects feed hosts and renders them. ETag is calculated from feed settings and hosts state,
+// so it does not change between requests if no hosts were added, updated or removed. Last modification time
+// includes deletion time of hosts matching feed filter.
+func (s *BlacklistExportFeedsServiceImpl) RenderFeed(feed blacklistEntities.BlacklistExportFeed) ([]byte, string, time.Time, error) {
+	render, ok := exportRenderers[feed.Format]
+	if !ok {
+		return nil, "", time.Time{}, fmt.Errorf("feed format '%s' not supported", feed.Format)
+	}
+
+	hosts, err := s.blacklistsRepo.SelectHostsUnionByFilter(renderSearchFilter(feed.Filter.Data()))
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+
+	lastModified := feed.UpdatedAt
+	hash := sha256.New()
+
+	hash.Write([]byte(fmt.Sprintf("%d:%s:%d;", feed.ID, feed.Format, feed.UpdatedAt.UnixNano())))
+
+	for _, h := range hosts {
+		hash.Write([]byte(fmt.Sprintf("%x:%s:%d;", h.UUID.Bytes, h.Host, h.UpdatedAt.UnixNano())))
+
+		if h.UpdatedAt.After(lastModified) {
+			lastModified = h.UpdatedAt
+		}
+	}
+
+	// deleted hosts are not rendered, but their deletion modifies feed, so hosts matching feed filter deleted
+	// after last rendered change are selected too
+	filter := renderSearchFilter(feed.Filter.Data())
+	includeDeleted := false
+	filter.IsActive, filter.UpdatedAfter = &includeDeleted, &lastModified
+
+	changed, err := s.blacklistsRepo.SelectHostsUnionByFilter(filter)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+
+	for _, h := range changed {
+		if h.DeletedAt.Valid && h.DeletedAt.Time.After(lastModified) {
+			lastModified = h.DeletedAt.Time
+		}
+	}
+
+	etag := fmt.Sprintf("\"%s\"", hex.EncodeToString(hash.Sum(nil))[:32])
+
+	return render(hosts), etag, lastModified, nil
+}
+
+// LogAccess saves feed access entry and updates token last access time
+func (s *BlacklistExportFeedsServiceImpl) LogAccess(token blacklistEntities.BlacklistExportFeedToken, remoteAddr, userAgent string, statusCode int) {
+	now := time.Now()
+
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+
+	err := s.repo.SaveAccess(blacklistEntities.BlacklistExportFeedAccess{
+		TokenID:    token.ID,
+		FeedID:     token.FeedID,
+		RemoteAddr: remoteAddr,
+		UserAgent:  strings.ToValidUTF8(userAgent, ""),
+		StatusCode: statusCode,
+		CreatedAt:  now,
+	})
+	if err != nil {
+		slog.Warn("failed to save feed access: " + err.Error())
+	}
+
+	err = s.repo.UpdateTokenAccess(token.ID, now)
+	if err != nil {
+		slog.Warn("failed to update feed token access time: " + err.Error())
+	}
+}
+
+func (s *BlacklistExportFeedsServiceImpl) RetrieveAccessLog(filter blacklistEntities.BlacklistExportFeedAccessFilter) ([]blacklistEntities.BlacklistExportFeedAccess, error) {
+	return s.repo.SelectAccessByFilter(filter)
+}
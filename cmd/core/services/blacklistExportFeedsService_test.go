@@ -0,0 +1,188 @@
+package services
+
+import (
+	"domain_threat_intelligence_api/cmd/core"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"gorm.io/datatypes"
+	"testing"
+	"time"
+)
+
+// fakeExportFeedsRepo keeps export feeds and their tokens in memory
+type fakeExportFeedsRepo struct {
+	core.IBlacklistExportFeedsRepo
+
+	feeds  map[uint64]blacklistEntities.BlacklistExportFeed
+	tokens []blacklistEntities.BlacklistExportFeedToken
+}
+
+func (r *fakeExportFeedsRepo) SelectFeed(id uint64) (blacklistEntities.BlacklistExportFeed, error) {
+	return r.feeds[id], nil
+}
+
+func (r *fakeExportFeedsRepo) SaveToken(token blacklistEntities.BlacklistExportFeedToken) (blacklistEntities.BlacklistExportFeedToken, error) {
+	token.ID = uint64(len(r.tokens) + 1)
+	r.tokens = append(r.tokens, token)
+
+	return token, nil
+}
+
+// SelectTokenByHash preloads token feed, deleted feeds are not preloaded
+func (r *fakeExportFeedsRepo) SelectTokenByHash(hash string) (blacklistEntities.BlacklistExportFeedToken, error) {
+	for _, t := range r.tokens {
+		if t.TokenHash == hash {
+			if feed, ok := r.feeds[t.FeedID]; ok {
+				t.Feed = &feed
+			}
+
+			return t, nil
+		}
+	}
+
+	return blacklistEntities.BlacklistExportFeedToken{}, nil
+}
+
+func TestRetrieveFeedByToken(t *testing.T) {
+	repo := &fakeExportFeedsRepo{feeds: map[uint64]blacklistEntities.BlacklistExportFeed{
+		1: {ID: 1, Name: "enabled", IsEnabled: true},
+		2: {ID: 2, Name: "disabled"},
+	}}
+	s := NewBlacklistExportFeedsServiceImpl(repo, &fakeBlacklistsRepo{})
+
+	expired := time.Now().Add(-time.Minute)
+	valid := time.Now().Add(time.Hour)
+
+	issue := func(feedID uint64, expiresAt *time.Time) string {
+		token, err := s.IssueToken(feedID, "firewall", expiresAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return token.Token
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantFeedID uint64
+		wantErr    bool
+	}{
+		{name: "valid token", token: issue(1, nil), wantFeedID: 1},
+		{name: "token not expired yet", token: issue(1, &valid), wantFeedID: 1},
+		{name: "expired token", token: issue(1, &expired), wantErr: true},
+		{name: "disabled feed", token: issue(2, nil), wantErr: true},
+		{name: "unknown token", token: "0123456789abcdef", wantErr: true},
+		{name: "empty token", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := s.RetrieveFeedByToken(tt.token)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if token.Feed == nil || token.Feed.ID != tt.wantFeedID {
+				t.Errorf("got feed %+v, want #%d", token.Feed, tt.wantFeedID)
+			}
+		})
+	}
+}
+
+func TestIssueToken(t *testing.T) {
+	repo := &fakeExportFeedsRepo{feeds: map[uint64]blacklistEntities.BlacklistExportFeed{1: {ID: 1, IsEnabled: true}}}
+	s := NewBlacklistExportFeedsServiceImpl(repo, &fakeBlacklistsRepo{})
+
+	token, err := s.IssueToken(1, "firewall", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only hash of token is stored
+	if len(token.Token) != 64 || repo.tokens[0].Token != "" || repo.tokens[0].TokenHash != blacklistEntities.HashExportFeedToken(token.Token) {
+		t.Errorf("unexpected issued token: %+v, saved: %+v", token, repo.tokens[0])
+	}
+
+	if _, err = s.IssueToken(1, "", nil); err == nil {
+		t.Error("token issued without consumer")
+	}
+
+	if _, err = s.IssueToken(2, "firewall", nil); err == nil {
+		t.Error("token issued for missing feed")
+	}
+}
+
+func TestRenderFeedETag(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	feed := blacklistEntities.BlacklistExportFeed{ID: 1, Format: blacklistEntities.ExportFormatText, IsEnabled: true, UpdatedAt: created}
+	repo := &fakeBlacklistsRepo{hosts: []blacklistEntities.BlacklistedHost{
+		{Type: "domain", Host: "evil.com", UpdatedAt: created.Add(time.Hour)},
+		{Type: "ip", Host: "10.0.0.1", UpdatedAt: created.Add(2 * time.Hour)},
+	}}
+
+	s := NewBlacklistExportFeedsServiceImpl(&fakeExportFeedsRepo{}, repo)
+
+	data, etag, lastModified, err := s.RenderFeed(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data) == 0 || !lastModified.Equal(created.Add(2*time.Hour)) {
+		t.Errorf("unexpected feed last modification time: %s", lastModified)
+	}
+
+	// ETag does not change while hosts and feed are not changed
+	_, same, _, _ := s.RenderFeed(feed)
+	if same != etag {
+		t.Errorf("etag changed without changes: %s, %s", etag, same)
+	}
+
+	repo.hosts[0].UpdatedAt = created.Add(3 * time.Hour)
+
+	_, updated, lastModified, _ := s.RenderFeed(feed)
+	if updated == etag || !lastModified.Equal(created.Add(3*time.Hour)) {
+		t.Errorf("etag or last modification time not changed by host update: %s, %s", updated, lastModified)
+	}
+
+	feed.Format = blacklistEntities.ExportFormatHosts
+
+	_, reformatted, _, _ := s.RenderFeed(feed)
+	if reformatted == updated {
+		t.Error("etag not changed by feed format")
+	}
+
+	feed.Format = "pdf"
+	if _, _, _, err = s.RenderFeed(feed); err == nil {
+		t.Error("expected error of unsupported format")
+	}
+}
+
+func TestRenderFeedActiveOnly(t *testing.T) {
+	includeDeleted := false
+	feed := blacklistEntities.BlacklistExportFeed{
+		ID: 1, Format: blacklistEntities.ExportFormatSnort, IsEnabled: true,
+		Filter: datatypes.NewJSONType(blacklistEntities.BlacklistExportFilter{IsActive: &includeDeleted}),
+	}
+
+	repo := &fakeBlacklistsRepo{}
+	s := NewBlacklistExportFeedsServiceImpl(&fakeExportFeedsRepo{}, repo)
+
+	if _, _, _, err := s.RenderFeed(feed); err != nil {
+		t.Fatal(err)
+	}
+
+	// rendered hosts are active only, deleted hosts are selected only to find feed modification time
+	if len(repo.filters) != 2 || repo.filters[0].IsActive == nil || !*repo.filters[0].IsActive {
+		t.Fatalf("unexpected hosts filters: %+v", repo.filters)
+	}
+
+	if f := repo.filters[1]; f.IsActive == nil || *f.IsActive || f.UpdatedAfter == nil {
+		t.Errorf("unexpected deleted hosts filter: %+v", f)
+	}
+}
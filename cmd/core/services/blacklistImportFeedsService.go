@@ -0,0 +1,415 @@
+package services
+
+import (
+	"bytes"
+	"domain_threat_intelligence_api/cmd/core"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"domain_threat_intelligence_api/cmd/integrations/taxii"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxFeedSize limits size of a single feed response body
+const maxFeedSize = 256 << 20
+
+// importJobPollInterval defines how often feed import job is checked for completion
+const importJobPollInterval = time.Second
+
+type BlacklistImportFeedsServiceImpl struct {
+	repo       core.IBlacklistImportFeedsRepo
+	blacklists core.IBlacklistsService
+	httpClient *http.Client
+
+	// running contains IDs of feeds that are being pulled right now
+	running   map[uint64]bool
+	runningMu sync.Mutex
+}
+
+func NewBlacklistImportFeedsServiceImpl(repo core.IBlacklistImportFeedsRepo, blacklists core.IBlacklistsService) *BlacklistImportFeedsServiceImpl {
+	return &BlacklistImportFeedsServiceImpl{
+		repo:       repo,
+		blacklists: blacklists,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Minute,
+		},
+		running: make(map[uint64]bool),
+	}
+}
+
+func (s *BlacklistImportFeedsServiceImpl) RetrieveAllFeeds() ([]blacklistEntities.BlacklistImportFeed, error) {
+	feeds, err := s.repo.SelectAllFeeds()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range feeds {
+		feeds[i].HideCredentials()
+	}
+
+	return feeds, nil
+}
+
+func (s *BlacklistImportFeedsServiceImpl) RetrieveFeed(id uint64) (blacklistEntities.BlacklistImportFeed, error) {
+	feed, err := s.repo.SelectFeed(id)
+	if err != nil {
+		return blacklistEntities.BlacklistImportFeed{}, err
+	}
+
+	feed.HideCredentials()
+
+	return feed, nil
+}
+
+// SaveFeed creates new feed or updates existing one. If secrets are not defined on update, keeps existing secrets.
+func (s *BlacklistImportFeedsServiceImpl) SaveFeed(feed blacklistEntities.BlacklistImportFeed) (blacklistEntities.BlacklistImportFeed, error) {
+	if len(feed.Name) == 0 {
+		return blacklistEntities.BlacklistImportFeed{}, errors.New("feed name not defined")
+	}
+
+	u, err := url.ParseRequestURI(feed.URL)
+	if err != nil {
+		return blacklistEntities.BlacklistImportFeed{}, errors.New("feed url malformed: " + err.Error())
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return blacklistEntities.BlacklistImportFeed{}, errors.New("feed url scheme not supported: " + u.Scheme)
+	}
+
+	switch feed.Format {
+	case blacklistEntities.ImportFeedFormatSTIX, blacklistEntities.ImportFeedFormatCSV, blacklistEntities.ImportFeedFormatText, blacklistEntities.ImportFeedFormatTAXII:
+	default:
+		return blacklistEntities.BlacklistImportFeed{}, fmt.Errorf("feed format '%s' not supported", feed.Format)
+	}
+
+	if feed.SourceID == 0 {
+		return blacklistEntities.BlacklistImportFeed{}, errors.New("feed source not defined")
+	}
+
+	if feed.PollingInterval == 0 {
+		feed.PollingInterval = 60
+	}
+
+	if feed.ID != 0 {
+		current, err := s.repo.SelectFeed(feed.ID)
+		if err != nil {
+			return blacklistEntities.BlacklistImportFeed{}, err
+		} else if current.ID == 0 {
+			return blacklistEntities.BlacklistImportFeed{}, errors.New("feed not found")
+		}
+
+		if len(feed.AuthPassword) == 0 {
+			feed.AuthPassword = current.AuthPassword
+		}
+
+		if len(feed.AuthToken) == 0 {
+			feed.AuthToken = current.AuthToken
+		}
+
+		// run state is managed only by feed runs
+		feed.LastRunAt = current.LastRunAt
+		feed.LastError = current.LastError
+		feed.LastImportEventID = current.LastImportEventID
+		feed.CreatedAt = current.CreatedAt
+	}
+
+	feed, err = s.repo.SaveFeed(feed)
+	if err != nil {
+		return blacklistEntities.BlacklistImportFeed{}, err
+	}
+
+	feed.HideCredentials()
+
+	return feed, nil
+}
+
+func (s *BlacklistImportFeedsServiceImpl) DeleteFeed(id uint64) (int64, error) {
+	return s.repo.DeleteFeed(id)
+}
+
+// RunFeed pulls feed and starts import job. Returns incomplete import event, feed stays running until job is complete.
+func (s *BlacklistImportFeedsServiceImpl) RunFeed(id uint64) (blacklistEntities.BlacklistImportEvent, error) {
+	feed, err := s.repo.SelectFeed(id)
+	if err != nil {
+		return blacklistEntities.BlacklistImportEvent{}, err
+	} else if feed.ID == 0 {
+		return blacklistEntities.BlacklistImportEvent{}, errors.New("feed not found")
+	}
+
+	s.runningMu.Lock()
+	if s.running[feed.ID] {
+		s.runningMu.Unlock()
+		return blacklistEntities.BlacklistImportEvent{}, errors.New("feed is already running")
+	}
+
+	s.running[feed.ID] = true
+	s.runningMu.Unlock()
+
+	slog.Info(fmt.Sprintf("running import feed #%d '%s'...", feed.ID, feed.Name))
+
+	var event blacklistEntities.BlacklistImportEvent
+
+	job, bookmarks, err := s.pullFeed(feed)
+	if err == nil {
+		job.FeedID = &feed.ID
+		event, err = s.blacklists.StartImportJob(job)
+	}
+
+	now := time.Now()
+	feed.LastRunAt = &now
+	feed.Source = nil
+
+	if err != nil {
+		s.finishRun(feed, err)
+		return blacklistEntities.BlacklistImportEvent{}, err
+	}
+
+	feed.LastImportEventID = &event.ID
+
+	go s.awaitImport(feed, event.ID, bookmarks)
+
+	return event, nil
+}
+
+// awaitImport waits for feed import job to complete, then moves TAXII bookmarks if import succeeded and saves feed state
+func (s *BlacklistImportFeedsServiceImpl) awaitImport(feed blacklistEntities.BlacklistImportFeed, eventID uint64, bookmarks map[string]string) {
+	var event blacklistEntities.BlacklistImportEvent
+	var err error
+
+	for {
+		event, err = s.blacklists.RetrieveImportEvent(eventID)
+		if err != nil || event.ID == 0 || event.IsComplete {
+			break
+		}
+
+		time.Sleep(importJobPollInterval)
+	}
+
+	if err == nil && event.ID == 0 {
+		err = errors.New("import event not found")
+	} else if err == nil && len(event.Error) > 0 {
+		err = errors.New(event.Error)
+	}
+
+	if err == nil {
+		s.saveTAXIIBookmarks(feed.ID, bookmarks)
+	}
+
+	s.finishRun(feed, err)
+}
+
+// finishRun saves feed state after run and allows feed to be run again
+func (s *BlacklistImportFeedsServiceImpl) finishRun(feed blacklistEntities.BlacklistImportFeed, err error) {
+	defer func() {
+		s.runningMu.Lock()
+		delete(s.running, feed.ID)
+		s.runningMu.Unlock()
+	}()
+
+	if err != nil {
+		slog.Warn(fmt.Sprintf("import feed #%d failed: %s", feed.ID, err.Error()))
+		feed.LastError = err.Error()
+	} else {
+		feed.LastError = ""
+	}
+
+	_, err = s.repo.SaveFeed(feed)
+	if err != nil {
+		slog.Error("failed to save import feed state: " + err.Error())
+	}
+}
+
+func (s *BlacklistImportFeedsServiceImpl) StartScheduler(interval time.Duration) {
+	slog.Info("starting import feeds scheduler...")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		feeds, err := s.repo.SelectAllFeeds()
+		if err != nil {
+			slog.Error("failed to select import feeds: " + err.Error())
+			continue
+		}
+
+		now := time.Now()
+		for _, f := range feeds {
+			if !f.IsDue(now) {
+				continue
+			}
+
+			go func(id uint64) {
+				_, err := s.RunFeed(id)
+				if err != nil {
+					slog.Warn(fmt.Sprintf("scheduled import feed #%d run failed: %s", id, err.Error()))
+				}
+			}(f.ID)
+		}
+	}
+}
+
+// pullFeed downloads feed contents and builds import job by feed format. For TAXII feeds also returns new bookmarks
+// by collection URL, which must be saved only after import succeeded.
+func (s *BlacklistImportFeedsServiceImpl) pullFeed(feed blacklistEntities.BlacklistImportFeed) (blacklistEntities.BlacklistImportJob, map[string]string, error) {
+	if feed.Format == blacklistEntities.ImportFeedFormatTAXII {
+		return s.pullTAXII(feed)
+	}
+
+	body, err := s.pull(feed)
+	if err != nil {
+		return blacklistEntities.BlacklistImportJob{}, nil, err
+	}
+
+	job := blacklistEntities.BlacklistImportJob{
+		SourceID:     feed.SourceID,
+		ExtractAll:   feed.ExtractAll,
+		DiscoveredAt: time.Now(),
+	}
+
+	switch feed.Format {
+	case blacklistEntities.ImportFeedFormatSTIX:
+		var bundle blacklistEntities.STIX2Bundle
+
+		err = json.Unmarshal(body, &bundle)
+		if err != nil {
+			return blacklistEntities.BlacklistImportJob{}, nil, errors.New("failed to decode bundle: " + err.Error())
+		} else if len(bundle.ID) == 0 {
+			return blacklistEntities.BlacklistImportJob{}, nil, errors.New("bundles not found")
+		}
+
+		job.Type, job.STIX2Bundles = blacklistEntities.ImportJobSTIX, []blacklistEntities.STIX2Bundle{bundle}
+	case blacklistEntities.ImportFeedFormatCSV:
+		data, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+		if err != nil {
+			return blacklistEntities.BlacklistImportJob{}, nil, errors.New("failed to read csv: " + err.Error())
+		} else if len(data) == 0 {
+			return blacklistEntities.BlacklistImportJob{}, nil, errors.New("feed is empty")
+		}
+
+		job.Type, job.CSV = blacklistEntities.ImportJobCSV, data
+	case blacklistEntities.ImportFeedFormatText:
+		job.Type, job.Values = blacklistEntities.ImportJobTxt, strings.Split(strings.ReplaceAll(string(body), "\r\n", "\n"), "\n")
+	default:
+		return blacklistEntities.BlacklistImportJob{}, nil, fmt.Errorf("feed format '%s' not supported", feed.Format)
+	}
+
+	return job, nil, nil
+}
+
+func (s *BlacklistImportFeedsServiceImpl) pull(feed blacklistEntities.BlacklistImportFeed) ([]byte, error) {
+	request, err := http.NewRequest(http.MethodGet, feed.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(feed.AuthLogin) > 0 {
+		request.SetBasicAuth(feed.AuthLogin, feed.AuthPassword)
+	} else if len(feed.AuthToken) > 0 {
+		request.Header.Set("Authorization", "Bearer "+feed.AuthToken)
+	}
+
+	response, err := s.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New("feed responded with status: " + response.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(response.Body, maxFeedSize))
+}
+
+// pullTAXII pages through all readable collections of TAXII server, starting from saved bookmarks.
+// All collections are imported by a single import job.
+func (s *BlacklistImportFeedsServiceImpl) pullTAXII(feed blacklistEntities.BlacklistImportFeed) (blacklistEntities.BlacklistImportJob, map[string]string, error) {
+	client := taxii.NewClient(s.httpClient, feed.AuthLogin, feed.AuthPassword, feed.AuthToken)
+
+	// feed URL can point to discovery endpoint or to API root directly
+	var apiRoots []string
+
+	discovery, err := client.Discover(feed.URL)
+	if err == nil && len(discovery.APIRoots) > 0 {
+		apiRoots = discovery.APIRoots
+	} else {
+		apiRoots = []string{feed.URL}
+	}
+
+	savedBookmarks, err := s.repo.SelectTAXIIBookmarks(feed.ID)
+	if err != nil {
+		return blacklistEntities.BlacklistImportJob{}, nil, err
+	}
+
+	var bookmarks = make(map[string]string)
+	for _, b := range savedBookmarks {
+		bookmarks[b.CollectionURL] = b.AddedAfter
+	}
+
+	var bundles []blacklistEntities.STIX2Bundle
+	var newBookmarks = make(map[string]string)
+
+	for _, root := range apiRoots {
+		collections, err := client.Collections(root)
+		if err != nil {
+			return blacklistEntities.BlacklistImportJob{}, nil, fmt.Errorf("failed to get collections from '%s': %s", root, err.Error())
+		}
+
+		for _, collection := range collections {
+			if !collection.CanRead || (len(feed.TAXIICollectionID) > 0 && collection.ID != feed.TAXIICollectionID) {
+				continue
+			}
+
+			collectionURL := strings.TrimSuffix(root, "/") + "/collections/" + collection.ID + "/"
+
+			objects, bookmark, err := client.AllObjects(root, collection.ID, bookmarks[collectionURL], 1000)
+			if err != nil {
+				return blacklistEntities.BlacklistImportJob{}, nil, fmt.Errorf("failed to get objects from collection '%s': %s", collection.ID, err.Error())
+			}
+
+			slog.Info(fmt.Sprintf("pulled %d objects from taxii collection '%s'", len(objects), collection.ID))
+
+			newBookmarks[collectionURL] = bookmark
+
+			if len(objects) > 0 {
+				bundles = append(bundles, blacklistEntities.STIX2Bundle{
+					Type:    "bundle",
+					ID:      "bundle--taxii-" + collection.ID,
+					Objects: objects,
+				})
+			}
+		}
+	}
+
+	return blacklistEntities.BlacklistImportJob{
+		Type:         blacklistEntities.ImportJobSTIX,
+		SourceID:     feed.SourceID,
+		ExtractAll:   feed.ExtractAll,
+		STIX2Bundles: bundles,
+	}, newBookmarks, nil
+}
+
+// saveTAXIIBookmarks saves new TAXII collection bookmarks of feed, failure is only logged
+func (s *BlacklistImportFeedsServiceImpl) saveTAXIIBookmarks(feedID uint64, bookmarks map[string]string) {
+	for collectionURL, addedAfter := range bookmarks {
+		if len(addedAfter) == 0 {
+			continue
+		}
+
+		err := s.repo.SaveTAXIIBookmark(blacklistEntities.ImportFeedTAXIIBookmark{
+			FeedID:        feedID,
+			CollectionURL: collectionURL,
+			AddedAfter:    addedAfter,
+		})
+
+		if err != nil {
+			slog.Error("failed to save taxii bookmark: " + err.Error())
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"domain_threat_intelligence_api/cmd/core"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeBlacklists records started import jobs instead of saving hosts, all jobs are completed at once with defined error
+type fakeBlacklists struct {
+	core.IBlacklistsService
+
+	jobs []blacklistEntities.BlacklistImportJob
+	err  error
+}
+
+func (f *fakeBlacklists) StartImportJob(job blacklistEntities.BlacklistImportJob) (blacklistEntities.BlacklistImportEvent, error) {
+	f.jobs = append(f.jobs, job)
+	return blacklistEntities.BlacklistImportEvent{ID: 1}, nil
+}
+
+func (f *fakeBlacklists) RetrieveImportEvent(id uint64) (blacklistEntities.BlacklistImportEvent, error) {
+	event := blacklistEntities.BlacklistImportEvent{ID: id, IsComplete: true}
+	if f.err != nil {
+		event.Error = f.err.Error()
+	}
+
+	return event, nil
+}
+
+// fakeImportFeedsRepo keeps TAXII bookmarks and saved feed states in memory
+type fakeImportFeedsRepo struct {
+	core.IBlacklistImportFeedsRepo
+
+	bookmarks []blacklistEntities.ImportFeedTAXIIBookmark
+	feeds     []blacklistEntities.BlacklistImportFeed
+}
+
+func (r *fakeImportFeedsRepo) SelectTAXIIBookmarks(feedID uint64) ([]blacklistEntities.ImportFeedTAXIIBookmark, error) {
+	return r.bookmarks, nil
+}
+
+func (r *fakeImportFeedsRepo) SaveTAXIIBookmark(bookmark blacklistEntities.ImportFeedTAXIIBookmark) error {
+	r.bookmarks = append(r.bookmarks, bookmark)
+	return nil
+}
+
+func (r *fakeImportFeedsRepo) SelectFeed(id uint64) (blacklistEntities.BlacklistImportFeed, error) {
+	for _, f := range r.feeds {
+		if f.ID == id {
+			return f, nil
+		}
+	}
+
+	return blacklistEntities.BlacklistImportFeed{}, nil
+}
+
+func (r *fakeImportFeedsRepo) SaveFeed(feed blacklistEntities.BlacklistImportFeed) (blacklistEntities.BlacklistImportFeed, error) {
+	r.feeds = append(r.feeds, feed)
+	return feed, nil
+}
+
+func TestPullFeed(t *testing.T) {
+	tests := []struct {
+		name   string
+		feed   blacklistEntities.BlacklistImportFeed
+		body   string
+		status int
+
+		wantAuth   string
+		wantType   string
+		wantCSV    [][]string
+		wantValues []string
+		wantBundle string
+		wantErr    bool
+	}{
+		{
+			name:       "text with basic auth",
+			feed:       blacklistEntities.BlacklistImportFeed{Format: blacklistEntities.ImportFeedFormatText, AuthLogin: "user", AuthPassword: "secret"},
+			body:       "10.0.0.1\r\nexample.com",
+			status:     http.StatusOK,
+			wantAuth:   "Basic dXNlcjpzZWNyZXQ=",
+			wantType:   blacklistEntities.ImportJobTxt,
+			wantValues: []string{"10.0.0.1", "example.com"},
+		},
+		{
+			name:     "csv with bearer token",
+			feed:     blacklistEntities.BlacklistImportFeed{Format: blacklistEntities.ImportFeedFormatCSV, AuthToken: "token"},
+			body:     "type,value\nip,10.0.0.1\n",
+			status:   http.StatusOK,
+			wantAuth: "Bearer token",
+			wantType: blacklistEntities.ImportJobCSV,
+			wantCSV:  [][]string{{"type", "value"}, {"ip", "10.0.0.1"}},
+		},
+		{
+			name:       "stix without auth",
+			feed:       blacklistEntities.BlacklistImportFeed{Format: blacklistEntities.ImportFeedFormatSTIX},
+			body:       `{"type": "bundle", "id": "bundle--1", "objects": []}`,
+			status:     http.StatusOK,
+			wantType:   blacklistEntities.ImportJobSTIX,
+			wantBundle: "bundle--1",
+		},
+		{
+			name:    "stix without bundle",
+			feed:    blacklistEntities.BlacklistImportFeed{Format: blacklistEntities.ImportFeedFormatSTIX},
+			body:    `{}`,
+			status:  http.StatusOK,
+			wantErr: true,
+		},
+		{
+			name:    "empty csv",
+			feed:    blacklistEntities.BlacklistImportFeed{Format: blacklistEntities.ImportFeedFormatCSV},
+			status:  http.StatusOK,
+			wantErr: true,
+		},
+		{
+			name:     "unauthorized",
+			feed:     blacklistEntities.BlacklistImportFeed{Format: blacklistEntities.ImportFeedFormatText, AuthToken: "expired"},
+			status:   http.StatusUnauthorized,
+			wantAuth: "Bearer expired",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var auth string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth = r.Header.Get("Authorization")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			s := NewBlacklistImportFeedsServiceImpl(&fakeImportFeedsRepo{}, &fakeBlacklists{})
+
+			tt.feed.URL = server.URL
+			job, bookmarks, err := s.pullFeed(tt.feed)
+
+			if auth != tt.wantAuth {
+				t.Errorf("got authorization '%s', want '%s'", auth, tt.wantAuth)
+			}
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if job.Type != tt.wantType || bookmarks != nil {
+				t.Errorf("got job type '%s' with bookmarks %v, want '%s'", job.Type, bookmarks, tt.wantType)
+			}
+
+			if !reflect.DeepEqual(job.CSV, tt.wantCSV) {
+				t.Errorf("got csv %v, want %v", job.CSV, tt.wantCSV)
+			}
+
+			if !reflect.DeepEqual(job.Values, tt.wantValues) {
+				t.Errorf("got values %v, want %v", job.Values, tt.wantValues)
+			}
+
+			if len(tt.wantBundle) > 0 && (len(job.STIX2Bundles) != 1 || job.STIX2Bundles[0].ID != tt.wantBundle) {
+				t.Errorf("got bundles %v, want %s", job.STIX2Bundles, tt.wantBundle)
+			}
+		})
+	}
+}
+
+// newTAXIIServer starts TAXII server with single API root and two collections, only first of them is readable.
+// Objects of readable collection are returned by two pages linked with "next" parameter.
+func newTAXIIServer(t *testing.T, token string, requests *[]string) *httptest.Server {
+	t.Helper()
+
+	indicator := func(id string) blacklistEntities.STIX2Object {
+		return blacklistEntities.STIX2Object{Type: "indicator", Id: id, Pattern: "[ipv4-addr:value = '10.0.0.1']", PatternType: "stix"}
+	}
+
+	write := func(w http.ResponseWriter, v any) {
+		w.Header().Set("Content-Type", "application/taxii+json;version=2.1")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/taxii2/", func(w http.ResponseWriter, r *http.Request) {
+		write(w, map[string]any{"title": "test", "api_roots": []string{"/api1/"}})
+	})
+
+	mux.HandleFunc("/api1/collections/", func(w http.ResponseWriter, r *http.Request) {
+		write(w, map[string]any{"collections": []map[string]any{
+			{"id": "readable", "title": "readable", "can_read": true},
+			{"id": "hidden", "title": "hidden", "can_read": false},
+		}})
+	})
+
+	mux.HandleFunc("/api1/collections/readable/objects/", func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.URL.RawQuery)
+
+		if r.URL.Query().Get("next") == "page-2" {
+			w.Header().Set("X-TAXII-Date-Added-Last", "2024-01-02T00:00:00Z")
+			write(w, map[string]any{"more": false, "objects": []any{indicator("indicator--2")}})
+			return
+		}
+
+		w.Header().Set("X-TAXII-Date-Added-Last", "2024-01-01T00:00:00Z")
+		write(w, map[string]any{"more": true, "next": "page-2", "objects": []any{indicator("indicator--1")}})
+	})
+
+	mux.HandleFunc("/api1/collections/hidden/objects/", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("objects requested from unreadable collection")
+	})
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			write(w, map[string]any{"title": "unauthorized"})
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	}))
+}
+
+func TestPullTAXII(t *testing.T) {
+	var requests []string
+
+	server := newTAXIIServer(t, "token", &requests)
+	defer server.Close()
+
+	collectionURL := server.URL + "/api1/collections/readable/"
+
+	repo := &fakeImportFeedsRepo{bookmarks: []blacklistEntities.ImportFeedTAXIIBookmark{
+		{FeedID: 1, CollectionURL: collectionURL, AddedAfter: "2023-12-31T00:00:00Z"},
+	}}
+
+	s := NewBlacklistImportFeedsServiceImpl(repo, &fakeBlacklists{})
+
+	job, bookmarks, err := s.pullFeed(blacklistEntities.BlacklistImportFeed{
+		ID:        1,
+		URL:       server.URL + "/taxii2/",
+		Format:    blacklistEntities.ImportFeedFormatTAXII,
+		AuthToken: "token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// first page is requested from saved bookmark, second one by next parameter
+	wantRequests := []string{
+		"added_after=2023-12-31T00%3A00%3A00Z&limit=1000",
+		"added_after=2023-12-31T00%3A00%3A00Z&limit=1000&next=page-2",
+	}
+	if !reflect.DeepEqual(requests, wantRequests) {
+		t.Errorf("got requests %v, want %v", requests, wantRequests)
+	}
+
+	if job.Type != blacklistEntities.ImportJobSTIX || len(job.STIX2Bundles) != 1 || len(job.STIX2Bundles[0].Objects) != 2 {
+		t.Fatalf("expected single bundle with objects of both pages, got %+v", job)
+	}
+
+	// bookmarks are saved only after import job is complete
+	if want := map[string]string{collectionURL: "2024-01-02T00:00:00Z"}; !reflect.DeepEqual(bookmarks, want) {
+		t.Errorf("got bookmarks %v, want %v", bookmarks, want)
+	}
+
+	if len(repo.bookmarks) != 1 {
+		t.Errorf("bookmarks saved before import: %v", repo.bookmarks)
+	}
+}
+
+func TestAwaitImport(t *testing.T) {
+	bookmarks := map[string]string{"https://taxii.example.com/api1/collections/readable/": "2024-01-02T00:00:00Z"}
+
+	tests := []struct {
+		name          string
+		err           error
+		wantError     string
+		wantBookmarks int
+	}{
+		{name: "succeeded", wantBookmarks: 1},
+		{name: "failed", err: errors.New("database unavailable"), wantError: "database unavailable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeImportFeedsRepo{}
+			blacklists := &fakeBlacklists{err: tt.err}
+
+			s := NewBlacklistImportFeedsServiceImpl(repo, blacklists)
+			s.running[1] = true
+
+			s.awaitImport(blacklistEntities.BlacklistImportFeed{ID: 1, LastError: "previous error"}, 1, bookmarks)
+
+			if len(repo.bookmarks) != tt.wantBookmarks {
+				t.Errorf("got bookmarks %v, want %d", repo.bookmarks, tt.wantBookmarks)
+			}
+
+			if len(repo.feeds) != 1 || repo.feeds[0].LastError != tt.wantError {
+				t.Errorf("unexpected feed state: %+v", repo.feeds)
+			}
+
+			if s.running[1] {
+				t.Error("feed is still running after import is complete")
+			}
+		})
+	}
+}
+
+func TestRunFeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("10.0.0.1"))
+	}))
+	defer server.Close()
+
+	repo := &fakeImportFeedsRepo{feeds: []blacklistEntities.BlacklistImportFeed{
+		{ID: 1, URL: server.URL, Format: blacklistEntities.ImportFeedFormatText, SourceID: blacklistEntities.SourceDrWeb},
+	}}
+	blacklists := &fakeBlacklists{}
+
+	s := NewBlacklistImportFeedsServiceImpl(repo, blacklists)
+
+	event, err := s.RunFeed(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// import job is linked with feed on start, event is not saved by feed
+	if event.ID != 1 || len(blacklists.jobs) != 1 || blacklists.jobs[0].FeedID == nil || *blacklists.jobs[0].FeedID != 1 {
+		t.Errorf("unexpected import event %+v or started jobs %+v", event, blacklists.jobs)
+	}
+
+	// feed is running until import job is complete
+	for running := true; running; {
+		time.Sleep(time.Millisecond)
+
+		s.runningMu.Lock()
+		running = s.running[1]
+		s.runningMu.Unlock()
+	}
+
+	last := repo.feeds[len(repo.feeds)-1]
+	if last.LastRunAt == nil || last.LastImportEventID == nil || *last.LastImportEventID != 1 || len(last.LastError) > 0 {
+		t.Errorf("unexpected feed state: %+v", last)
+	}
+
+	if _, err = s.RunFeed(2); err == nil {
+		t.Error("expected error of unknown feed")
+	}
+}
+
+func TestPullTAXIIUnauthorized(t *testing.T) {
+	var requests []string
+
+	server := newTAXIIServer(t, "token", &requests)
+	defer server.Close()
+
+	s := NewBlacklistImportFeedsServiceImpl(&fakeImportFeedsRepo{}, &fakeBlacklists{})
+
+	_, _, err := s.pullFeed(blacklistEntities.BlacklistImportFeed{
+		ID:        1,
+		URL:       server.URL + "/taxii2/",
+		Format:    blacklistEntities.ImportFeedFormatTAXII,
+		AuthToken: "wrong",
+	})
+	if err == nil {
+		t.Fatal("expected unauthorized error")
+	}
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"fmt"
+	"log/slog"
+)
+
+func (s *BlackListsServiceImpl) RetrieveAllowlist() ([]blacklistEntities.AllowlistEntry, error) {
+	return s.repo.SelectAllowlist()
+}
+
+// SaveAllowlistEntry creates new allowlist entry or updates existing one. Already blacklisted hosts are not deleted,
+// they are returned as conflicts, so they can be reviewed.
+func (s *BlackListsServiceImpl) SaveAllowlistEntry(entry blacklistEntities.AllowlistEntry) (blacklistEntities.AllowlistEntry, []blacklistEntities.BlacklistedHost, error) {
+	entry, err := entry.Normalize()
+	if err != nil {
+		return blacklistEntities.AllowlistEntry{}, nil, err
+	}
+
+	entry, err = s.repo.SaveAllowlistEntry(entry)
+	if err != nil {
+		return blacklistEntities.AllowlistEntry{}, nil, err
+	}
+
+	// entry is already saved, conflicts can be requested again
+	conflicts, err := s.repo.SelectAllowlistConflicts(entry)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("failed to select conflicts of allowlist entry #%d: %s", entry.ID, err.Error()))
+	}
+
+	return entry, conflicts, nil
+}
+
+func (s *BlackListsServiceImpl) DeleteAllowlistEntry(id uint64) (int64, error) {
+	return s.repo.DeleteAllowlistEntry(id)
+}
+
+// RetrieveAllowlistConflicts returns active hosts matching allowlist entry, empty list if entry not found
+func (s *BlackListsServiceImpl) RetrieveAllowlistConflicts(id uint64) ([]blacklistEntities.BlacklistedHost, error) {
+	entry, err := s.repo.SelectAllowlistEntry(id)
+	if err != nil || entry.ID == 0 {
+		return []blacklistEntities.BlacklistedHost{}, err
+	}
+
+	return s.repo.SelectAllowlistConflicts(entry)
+}
+
+// allowlist returns all allowlist entries prepared for matching
+func (s *BlackListsServiceImpl) allowlist() (*blacklistEntities.Allowlist, error) {
+	entries, err := s.repo.SelectAllowlist()
+	if err != nil {
+		return nil, fmt.Errorf("failed to select allowlist: %s", err.Error())
+	}
+
+	return blacklistEntities.NewAllowlist(entries), nil
+}
+
+// withoutAllowlisted returns new slice of values not matching allowlist
+func withoutAllowlisted[T any](values []T, allowlisted func(T) bool) []T {
+	result := make([]T, 0, len(values))
+
+	for _, v := range values {
+		if !allowlisted(v) {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
+
+// applyAllowlist removes allowlisted hosts from parsed hosts, rejected hosts are kept with matching entry as reason
+func (p *parsedHosts) applyAllowlist(allowlist *blacklistEntities.Allowlist) {
+	reject := func(type_, value string, entry blacklistEntities.AllowlistEntry) {
+		p.allowlisted = append(p.allowlisted, blacklistEntities.BlacklistImportError{
+			Location: "allowlist",
+			Type:     type_,
+			Value:    value,
+			Reason:   entry.Reason(),
+		})
+	}
+
+	for k, v := range p.ips {
+		if entry, ok := allowlist.MatchIP(v.IPAddress); ok {
+			reject("ip", k, entry)
+			delete(p.ips, k)
+		}
+	}
+
+	for k, v := range p.domains {
+		if entry, ok := allowlist.MatchDomain(v.URN, v.IncludeSubdomains); ok {
+			reject("domain", k, entry)
+			delete(p.domains, k)
+		}
+	}
+
+	for k, v := range p.urls {
+		if entry, ok := allowlist.MatchURL(v.URL); ok {
+			reject("url", k, entry)
+			delete(p.urls, k)
+		}
+	}
+
+	for k, v := range p.emails {
+		if entry, ok := allowlist.MatchEmail(v.Email); ok {
+			reject("email", k, entry)
+			delete(p.emails, k)
+		}
+	}
+}
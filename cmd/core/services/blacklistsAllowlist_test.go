@@ -0,0 +1,137 @@
+package services
+
+import (
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSaveAllowlistEntry(t *testing.T) {
+	repo := &fakeBlacklistsRepo{hosts: []blacklistEntities.BlacklistedHost{
+		{Type: "domain", Host: "www.example.com", SourceID: blacklistEntities.SourceKaspersky},
+	}}
+	s := NewBlackListsServiceImpl(repo, nil)
+
+	entry, conflicts, err := s.SaveAllowlistEntry(blacklistEntities.AllowlistEntry{Type: "domain", Value: "*.Example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// entry is saved in canonical form, already blacklisted hosts are returned, not deleted
+	if entry.ID != 1 || entry.Value != "example.com" || !entry.IncludeSubdomains {
+		t.Errorf("unexpected saved entry: %+v", entry)
+	}
+
+	if !reflect.DeepEqual(conflicts, repo.hosts) {
+		t.Errorf("got conflicts %+v, want %+v", conflicts, repo.hosts)
+	}
+
+	if _, _, err = s.SaveAllowlistEntry(blacklistEntities.AllowlistEntry{Type: "url", Value: "*"}); err == nil || len(repo.allowlist) != 1 {
+		t.Error("invalid entry saved")
+	}
+}
+
+func TestSaveAllowlistedHosts(t *testing.T) {
+	repo := &fakeBlacklistsRepo{allowlist: []blacklistEntities.AllowlistEntry{
+		{ID: 1, Type: "ip", Value: "8.8.8.0/24"},
+		{ID: 2, Type: "domain", Value: "example.com", IncludeSubdomains: true},
+	}}
+	s := NewBlackListsServiceImpl(repo, nil)
+
+	var ips []blacklistEntities.BlacklistedIP
+	for _, v := range []string{"8.8.8.8", "10.0.0.1"} {
+		ip, err := blacklistEntities.ParseInet(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ips = append(ips, blacklistEntities.BlacklistedIP{IPAddress: ip, SourceID: blacklistEntities.SourceManual})
+	}
+
+	if _, err := s.SaveIPs(ips, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := s.SaveDomains([]blacklistEntities.BlacklistedDomain{
+		{URN: "www.example.com", SourceID: blacklistEntities.SourceManual},
+		{URN: "evil.com", SourceID: blacklistEntities.SourceManual},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.SaveURLs([]blacklistEntities.BlacklistedURL{
+		{URL: "http://example.com/a", SourceID: blacklistEntities.SourceManual},
+		{URL: "http://evil.com/a", SourceID: blacklistEntities.SourceManual},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.SaveEmails([]blacklistEntities.BlacklistedEmail{
+		{Email: "user@mail.example.com", SourceID: blacklistEntities.SourceManual},
+		{Email: "user@evil.com", SourceID: blacklistEntities.SourceManual},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"domain:evil.com (1)", "email:user@evil.com (1)", "ip:10.0.0.1/32 (1)", "url:http://evil.com/a (1)"}
+	if got := repo.savedHosts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestImportAllowlistedHosts(t *testing.T) {
+	repo := &fakeBlacklistsRepo{allowlist: []blacklistEntities.AllowlistEntry{
+		{ID: 1, Type: "domain", Value: "example.com"},
+	}}
+	s := NewBlackListsServiceImpl(repo, nil)
+
+	data := [][]string{
+		{"Type_IOC", "Value"},
+		{"domain", "example.com"},
+		{"domain", "evil.com"},
+	}
+
+	preview, err := s.PreviewImportFromCSV(data, blacklistEntities.DefaultImportProfile(), time.Now(), false, blacklistEntities.SourceManual, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantRejected := []blacklistEntities.BlacklistImportError{
+		{Location: "allowlist", Type: "domain", Value: "example.com", Reason: "allowlisted by entry #1 (example.com)"},
+	}
+
+	if !reflect.DeepEqual(preview.Allowlisted, wantRejected) || preview.Summary.Allowlisted != 1 || len(preview.New) != 1 {
+		t.Errorf("unexpected preview: %+v", preview)
+	}
+
+	event, err := s.ImportFromCSV(data, time.Now(), false, blacklistEntities.SourceManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary := event.Summary.Data(); summary.Allowlisted != 1 {
+		t.Errorf("unexpected import event summary: %+v", summary)
+	}
+
+	if got := repo.savedHosts(); !reflect.DeepEqual(got, []string{"domain:evil.com (1)"}) {
+		t.Errorf("allowlisted host saved: %v", got)
+	}
+
+	// rejected hosts are kept in import error log
+	rejected, err := s.RetrieveImportErrors(event.ID, blacklistEntities.BlacklistImportErrorFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range rejected {
+		rejected[i].ID, rejected[i].ImportEventID = 0, 0
+	}
+
+	if !reflect.DeepEqual(rejected, wantRejected) {
+		t.Errorf("got %+v, want %+v", rejected, wantRejected)
+	}
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"net/url"
+	"strings"
+)
+
+// checkBatchSize limits number of lookup keys used in single query
+const checkBatchSize = 1000
+
+// checkRef links lookup key to checked value and defines how value is matched by this key
+type checkRef struct {
+	index     int
+	matchType blacklistEntities.CheckMatchType
+}
+
+// checkLookups contains lookup keys of all checked values by host type
+type checkLookups struct {
+	ips     map[string][]checkRef
+	domains map[string][]checkRef
+	hashes  map[string][]checkRef
+	emails  map[string][]checkRef
+}
+
+func (l *checkLookups) add(keys map[string][]checkRef, key string, index int, matchType blacklistEntities.CheckMatchType) {
+	for _, ref := range keys[key] {
+		if ref.index == index {
+			return
+		}
+	}
+
+	keys[key] = append(keys[key], checkRef{index: index, matchType: matchType})
+}
+
+// addHost adds lookup keys of IP address or domain. Domains are looked up with all parent domains.
+func (l *checkLookups) addHost(host string, index int, matchType blacklistEntities.CheckMatchType, parentMatchType blacklistEntities.CheckMatchType) {
+	if ip, err := blacklistEntities.ParseInet(host); err == nil {
+		l.add(l.ips, ip.IPNet.String(), index, matchType)
+		return
+	}
+
+	for i, d := range blacklistEntities.ParentDomains(host) {
+		if i == 0 {
+			l.add(l.domains, d, index, matchType)
+		} else {
+			l.add(l.domains, d, index, parentMatchType)
+		}
+	}
+}
+
+// CheckValues detects type of every value and returns all active blacklist entries matching it. IP addresses are matched
+// by network containment, domains by exact and parent domain match, URLs by hash of raw and normalized URL and by
+// URL host, emails by exact match and by email domain.
+func (s *BlackListsServiceImpl) CheckValues(values []string) ([]blacklistEntities.BlacklistCheckResult, error) {
+	results := make([]blacklistEntities.BlacklistCheckResult, len(values))
+
+	lookups := checkLookups{
+		ips:     make(map[string][]checkRef),
+		domains: make(map[string][]checkRef),
+		hashes:  make(map[string][]checkRef),
+		emails:  make(map[string][]checkRef),
+	}
+
+	for i, v := range values {
+		value := strings.TrimSpace(v)
+		type_ := blacklistEntities.DetectHostType(value)
+
+		results[i] = blacklistEntities.BlacklistCheckResult{
+			Value:   v,
+			Type:    type_,
+			Matches: []blacklistEntities.BlacklistCheckMatch{},
+		}
+
+		switch type_ {
+		case "ip":
+			ip, err := blacklistEntities.ParseInet(value)
+			if err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+
+			lookups.add(lookups.ips, ip.IPNet.String(), i, blacklistEntities.CheckMatchExact)
+		case "domain":
+			lookups.addHost(strings.TrimPrefix(value, "*."), i, blacklistEntities.CheckMatchExact, blacklistEntities.CheckMatchParentDomain)
+		case "url":
+			// URLs are saved in canonical form, raw value is checked for URLs saved before canonicalization
+			candidates := []string{value, blacklistEntities.NormalizeURL(value)}
+			if !strings.Contains(value, "://") {
+				// URLs are often checked without scheme
+				candidates = append(candidates, blacklistEntities.NormalizeURL("http://"+value), blacklistEntities.NormalizeURL("https://"+value))
+			}
+
+			for _, c := range candidates {
+				lookups.add(lookups.hashes, blacklistEntities.URLHash(c), i, blacklistEntities.CheckMatchExact)
+			}
+
+			u := value
+			if !strings.Contains(value, "//") {
+				u = "//" + value
+			}
+
+			parsedURL, err := url.Parse(u)
+			if err == nil && len(parsedURL.Hostname()) > 0 {
+				lookups.addHost(parsedURL.Hostname(), i, blacklistEntities.CheckMatchURLHost, blacklistEntities.CheckMatchURLHost)
+			}
+		case "email":
+			lookups.add(lookups.emails, value, i, blacklistEntities.CheckMatchExact)
+			lookups.add(lookups.emails, strings.ToLower(value), i, blacklistEntities.CheckMatchExact)
+
+			domain := value[strings.LastIndex(value, "@")+1:]
+			lookups.addHost(domain, i, blacklistEntities.CheckMatchEmailDomain, blacklistEntities.CheckMatchEmailDomain)
+		default:
+			results[i].Error = "host type not detected"
+		}
+	}
+
+	addMatch := func(ref checkRef, host blacklistEntities.BlacklistedHost) {
+		for _, m := range results[ref.index].Matches {
+			if m.UUID == host.UUID {
+				return
+			}
+		}
+
+		results[ref.index].IsBlacklisted = true
+		results[ref.index].Matches = append(results[ref.index].Matches, blacklistEntities.BlacklistCheckMatch{
+			MatchType:       ref.matchType,
+			BlacklistedHost: host,
+		})
+	}
+
+	// networks are matched with every checked address they contain
+	err := inBatches(keysOf(lookups.ips), func(addrs []string) error {
+		found, err := s.repo.SelectIPsContainingAny(addrs)
+		if err != nil {
+			return err
+		}
+
+		for _, ip := range found {
+			var host blacklistEntities.BlacklistedHost
+			host.FromIP(ip)
+
+			for _, addr := range addrs {
+				checked, err := blacklistEntities.ParseInet(addr)
+				if err != nil || !ip.IPAddress.IPNet.Contains(checked.IPNet.IP) {
+					continue
+				}
+
+				ones, _ := checked.IPNet.Mask.Size()
+				if foundOnes, _ := ip.IPAddress.IPNet.Mask.Size(); foundOnes > ones {
+					continue // narrower network does not cover checked network
+				}
+
+				for _, ref := range lookups.ips[addr] {
+					if ref.matchType == blacklistEntities.CheckMatchExact && ip.IPAddress.IPNet.String() != addr {
+						ref.matchType = blacklistEntities.CheckMatchNetwork
+					}
+
+					addMatch(ref, host)
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = inBatches(keysOf(lookups.domains), func(urns []string) error {
+		found, err := s.repo.SelectDomainsByURNs(urns)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range found {
+			var host blacklistEntities.BlacklistedHost
+			host.FromDomain(d)
+
+			for _, ref := range lookups.domains[d.URN] {
+				if ref.matchType == blacklistEntities.CheckMatchParentDomain && d.IncludeSubdomains {
+					ref.matchType = blacklistEntities.CheckMatchWildcard
+				}
+
+				addMatch(ref, host)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = inBatches(keysOf(lookups.hashes), func(hashes []string) error {
+		found, err := s.repo.SelectURLsByMD5(hashes)
+		if err != nil {
+			return err
+		}
+
+		for _, u := range found {
+			var host blacklistEntities.BlacklistedHost
+			host.FromURL(u)
+
+			for _, ref := range lookups.hashes[u.MD5] {
+				addMatch(ref, host)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = inBatches(keysOf(lookups.emails), func(emails []string) error {
+		found, err := s.repo.SelectEmailsByValues(emails)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range found {
+			var host blacklistEntities.BlacklistedHost
+			host.FromEmail(e)
+
+			for _, ref := range lookups.emails[e.Email] {
+				addMatch(ref, host)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func keysOf(m map[string][]checkRef) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+// inBatches calls handler for every batch of values, stops on first error
+func inBatches(values []string, handle func(batch []string) error) error {
+	for start := 0; start < len(values); start += checkBatchSize {
+		end := min(start+checkBatchSize, len(values))
+
+		err := handle(values[start:end])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
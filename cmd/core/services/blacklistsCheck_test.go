@@ -0,0 +1,76 @@
+package services
+
+import (
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"reflect"
+	"testing"
+)
+
+func TestCheckValues(t *testing.T) {
+	network, _ := blacklistEntities.ParseInet("10.0.0.0/24")
+	address, _ := blacklistEntities.ParseInet("10.0.0.1")
+
+	repo := &fakeBlacklistsRepo{
+		ips: []blacklistEntities.BlacklistedIP{
+			{UUID: hostUUID(1), IPAddress: network},
+			{UUID: hostUUID(2), IPAddress: address},
+		},
+		domains: []blacklistEntities.BlacklistedDomain{
+			{UUID: hostUUID(3), URN: "evil.com"},
+		},
+		urls: []blacklistEntities.BlacklistedURL{
+			{UUID: hostUUID(4), URL: "http://phish.org/login", MD5: blacklistEntities.URLHash("http://phish.org/login")},
+		},
+		emails: []blacklistEntities.BlacklistedEmail{
+			{UUID: hostUUID(5), Email: "user@spam.net"},
+		},
+	}
+
+	results, err := NewBlackListsServiceImpl(repo, nil).CheckValues([]string{
+		"10.0.0.1",
+		"10.0.0.2",
+		"10.0.1.1",
+		"a.b.evil.com",
+		"HTTP://PHISH.org/login#form",
+		"phish.org/login",
+		"https://evil.com/a",
+		"user@spam.net",
+		"other@evil.com",
+		"not a host",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	match := func(n byte, matchType blacklistEntities.CheckMatchType) string {
+		return string(matchType) + ":" + string('0'+n)
+	}
+
+	want := [][]string{
+		{match(1, blacklistEntities.CheckMatchNetwork), match(2, blacklistEntities.CheckMatchExact)},
+		{match(1, blacklistEntities.CheckMatchNetwork)},
+		nil,
+		{match(3, blacklistEntities.CheckMatchParentDomain)},
+		{match(4, blacklistEntities.CheckMatchExact)},
+		{match(4, blacklistEntities.CheckMatchExact)},
+		{match(3, blacklistEntities.CheckMatchURLHost)},
+		{match(5, blacklistEntities.CheckMatchExact)},
+		{match(3, blacklistEntities.CheckMatchEmailDomain)},
+		nil,
+	}
+
+	for i, r := range results {
+		var got []string
+		for _, m := range r.Matches {
+			got = append(got, match(m.UUID.Bytes[0], m.MatchType))
+		}
+
+		if !reflect.DeepEqual(got, want[i]) || r.IsBlacklisted != (len(want[i]) > 0) {
+			t.Errorf("%s: got matches %v, want %v", r.Value, got, want[i])
+		}
+	}
+
+	if results[9].Error == "" || results[9].Type != "" {
+		t.Errorf("undetected value not reported: %+v", results[9])
+	}
+}
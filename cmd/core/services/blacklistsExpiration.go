@@ -0,0 +1,64 @@
+package services
+
+import (
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+func (s *BlackListsServiceImpl) RetrieveAllTTLPolicies() ([]blacklistEntities.BlacklistTTLPolicy, error) {
+	return s.repo.SelectAllTTLPolicies()
+}
+
+// SaveTTLPolicy creates new TTL policy or updates existing one. New TTL applies to hosts saved after update.
+func (s *BlackListsServiceImpl) SaveTTLPolicy(policy blacklistEntities.BlacklistTTLPolicy) (blacklistEntities.BlacklistTTLPolicy, error) {
+	err := policy.Validate()
+	if err != nil {
+		return blacklistEntities.BlacklistTTLPolicy{}, err
+	}
+
+	return s.repo.SaveTTLPolicy(policy)
+}
+
+func (s *BlackListsServiceImpl) DeleteTTLPolicy(id uint64) (int64, error) {
+	return s.repo.DeleteTTLPolicy(id)
+}
+
+// ExpireHosts soft deletes hosts with expiration date in the past, so they are excluded from exports and feeds
+// and reported as removed by delta export
+func (s *BlackListsServiceImpl) ExpireHosts() (int64, error) {
+	return s.repo.DeleteExpiredHosts(time.Now())
+}
+
+func (s *BlackListsServiceImpl) StartExpirer(interval time.Duration) {
+	slog.Info("starting blacklist hosts expirer...")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// import previews are kept in memory, so expired ones are removed even if no new previews are created
+		s.removeExpiredImportPreviews(time.Now())
+
+		rows, err := s.ExpireHosts()
+		if err != nil {
+			slog.Error("failed to delete expired hosts: " + err.Error())
+			continue
+		}
+
+		if rows > 0 {
+			slog.Info(fmt.Sprintf("%d expired hosts deleted", rows))
+		}
+	}
+}
+
+// ttlPolicies returns all TTL policies, used to set expiration date of saved hosts
+func (s *BlackListsServiceImpl) ttlPolicies() (blacklistEntities.BlacklistTTLPolicies, error) {
+	policies, err := s.repo.SelectAllTTLPolicies()
+	if err != nil {
+		return nil, fmt.Errorf("failed to select TTL policies: %s", err.Error())
+	}
+
+	return policies, nil
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"bytes"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+// hostsRenderer renders blacklisted hosts into file, used by network devices and DNS servers
+type hostsRenderer func(hosts []blacklistEntities.BlacklistedHost) []byte
+
+// exportRenderers contains all renderers by export format. New formats should be registered here.
+var exportRenderers = map[blacklistEntities.ExportFormat]hostsRenderer{
+	blacklistEntities.ExportFormatText:     renderText,
+	blacklistEntities.ExportFormatHosts:    renderHostsFile,
+	blacklistEntities.ExportFormatRPZ:      renderRPZ,
+	blacklistEntities.ExportFormatSquid:    renderSquidACL,
+	blacklistEntities.ExportFormatIPTables: renderIPSet,
+	blacklistEntities.ExportFormatNFTables: renderNFTablesSet,
+	blacklistEntities.ExportFormatSuricata: renderSuricataReputation,
+	blacklistEntities.ExportFormatSnort:    renderSnortReputation,
+}
+
+const exportSetName = "dti_blacklist"
+
+// renderSearchFilter converts export filter to search filter of hosts rendered by network device formats. Devices block
+// every rendered host, so deleted hosts are never selected, whatever export filter defines.
+func renderSearchFilter(filter blacklistEntities.BlacklistExportFilter) blacklistEntities.BlacklistSearchFilter {
+	search := filter.ToSearchFilter()
+
+	isActive := true
+	search.IsActive = &isActive
+
+	return search
+}
+
+// renderText renders all hosts one per line, domains with all subdomains are rendered as *.example.com
+func renderText(hosts []blacklistEntities.BlacklistedHost) []byte {
+	var values []string
+	for _, h := range hosts {
+		if h.IncludeSubdomains {
+			values = append(values, "*."+h.Host)
+		} else {
+			values = append(values, h.Host)
+		}
+	}
+
+	return joinLines(exportHeader("#"), uniqueSorted(values))
+}
+
+// renderHostsFile renders domains as hosts file entries resolving to 0.0.0.0.
+// Hosts file does not support wildcards, so only domain itself is rendered for domains with all subdomains.
+func renderHostsFile(hosts []blacklistEntities.BlacklistedHost) []byte {
+	var lines []string
+	for _, d := range exportDomains(hosts) {
+		lines = append(lines, "0.0.0.0 "+d.name)
+	}
+
+	return joinLines(exportHeader("#"), lines)
+}
+
+// renderRPZ renders domains and IP addresses as response policy zone, returning NXDOMAIN for all of them.
+// Wildcard entry is rendered only for domains blacklisted with all subdomains.
+func renderRPZ(hosts []blacklistEntities.BlacklistedHost) []byte {
+	serial := time.Now().Unix()
+
+	lines := []string{
+		"$TTL 300",
+		fmt.Sprintf("@ IN SOA localhost. root.localhost. (%d 3600 600 86400 300)", serial),
+		"@ IN NS localhost.",
+	}
+
+	for _, d := range exportDomains(hosts) {
+		lines = append(lines, d.name+" CNAME .")
+
+		if d.includeSubdomains {
+			lines = append(lines, "*."+d.name+" CNAME .")
+		}
+	}
+
+	for _, n := range exportNetworks(hosts) {
+		ones, _ := n.Mask.Size()
+
+		if ip := n.IP.To4(); ip != nil {
+			lines = append(lines, fmt.Sprintf("%d.%d.%d.%d.%d.rpz-ip CNAME .", ones, ip[3], ip[2], ip[1], ip[0]))
+		} else {
+			lines = append(lines, fmt.Sprintf("%d.%s.rpz-ip CNAME .", ones, rpzIPv6(n.IP)))
+		}
+	}
+
+	return joinLines(exportHeader(";"), lines)
+}
+
+// rpzIPv6 returns reversed IPv6 address groups, longest run of zero groups is replaced with "zz",
+// e.g. 2001:db8::1 is returned as 1.zz.db8.2001
+func rpzIPv6(ip net.IP) string {
+	compressed := ip.To16().String()
+	compressed = strings.Replace(compressed, "::", ":zz:", 1)
+
+	var groups []string
+	for _, g := range strings.Split(compressed, ":") {
+		if len(g) > 0 {
+			groups = append(groups, g)
+		}
+	}
+
+	slices.Reverse(groups)
+
+	return strings.Join(groups, ".")
+}
+
+// renderSquidACL renders domains as dstdomain ACL file, domains with all subdomains are rendered with leading dot
+func renderSquidACL(hosts []blacklistEntities.BlacklistedHost) []byte {
+	var lines []string
+	for _, d := range exportDomains(hosts) {
+		if d.includeSubdomains {
+			lines = append(lines, "."+d.name)
+		} else {
+			lines = append(lines, d.name)
+		}
+	}
+
+	return joinLines(exportHeader("#"), lines)
+}
+
+// renderIPSet renders IP addresses and networks as ipset restore file, should be used with "ipset restore" and iptables "-m set"
+func renderIPSet(hosts []blacklistEntities.BlacklistedHost) []byte {
+	lines := []string{
+		fmt.Sprintf("create %s hash:net family inet -exist", exportSetName),
+		fmt.Sprintf("create %s_v6 hash:net family inet6 -exist", exportSetName),
+	}
+
+	for _, n := range exportNetworks(hosts) {
+		if n.IP.To4() != nil {
+			lines = append(lines, fmt.Sprintf("add %s %s -exist", exportSetName, formatNetwork(n)))
+		} else {
+			lines = append(lines, fmt.Sprintf("add %s_v6 %s -exist", exportSetName, formatNetwork(n)))
+		}
+	}
+
+	return joinLines(exportHeader("#"), lines)
+}
+
+// renderNFTablesSet renders IP addresses and networks as nftables sets, should be loaded with "nft -f"
+func renderNFTablesSet(hosts []blacklistEntities.BlacklistedHost) []byte {
+	var v4, v6 []string
+	for _, n := range exportNetworks(hosts) {
+		if n.IP.To4() != nil {
+			v4 = append(v4, formatNetwork(n))
+		} else {
+			v6 = append(v6, formatNetwork(n))
+		}
+	}
+
+	set := func(name, type_ string, elements []string) []string {
+		lines := []string{
+			fmt.Sprintf("\tset %s {", name),
+			fmt.Sprintf("\t\ttype %s", type_),
+			"\t\tflags interval",
+			"\t\tauto-merge",
+		}
+
+		if len(elements) > 0 {
+			lines = append(lines, fmt.Sprintf("\t\telements = { %s }", strings.Join(elements, ", ")))
+		}
+
+		return append(lines, "\t}")
+	}
+
+	lines := []string{fmt.Sprintf("table inet %s {", exportSetName)}
+	lines = append(lines, set(exportSetName+"_v4", "ipv4_addr", v4)...)
+	lines = append(lines, set(exportSetName+"_v6", "ipv6_addr", v6)...)
+	lines = append(lines, "}")
+
+	return joinLines(exportHeader("#"), lines)
+}
+
+// renderSuricataReputation renders IP addresses as Suricata iprep file with category 1 and maximum score
+func renderSuricataReputation(hosts []blacklistEntities.BlacklistedHost) []byte {
+	var lines []string
+	for _, n := range exportNetworks(hosts) {
+		lines = append(lines, fmt.Sprintf("%s,1,127", formatNetwork(n)))
+	}
+
+	return joinLines(exportHeader("#"), lines)
+}
+
+// renderSnortReputation renders IP addresses and networks as Snort reputation blacklist
+func renderSnortReputation(hosts []blacklistEntities.BlacklistedHost) []byte {
+	var lines []string
+	for _, n := range exportNetworks(hosts) {
+		lines = append(lines, formatNetwork(n))
+	}
+
+	return joinLines(exportHeader("#"), lines)
+}
+
+// exportDomain is domain rendered by network device formats
+type exportDomain struct {
+	name              string
+	includeSubdomains bool
+}
+
+// exportDomains returns sorted unique domains from domain hosts, URLs are not exported as domains.
+// If domain is blacklisted both with and without subdomains, it is returned with subdomains.
+func exportDomains(hosts []blacklistEntities.BlacklistedHost) []exportDomain {
+	subdomains := make(map[string]bool)
+
+	for _, h := range hosts {
+		if h.Type != "domain" {
+			continue
+		}
+
+		name := strings.ToLower(strings.TrimSuffix(h.Host, "."))
+		subdomains[name] = subdomains[name] || h.IncludeSubdomains
+	}
+
+	var names []string
+	for name := range subdomains {
+		names = append(names, name)
+	}
+
+	var domains []exportDomain
+	for _, name := range uniqueSorted(names) {
+		domains = append(domains, exportDomain{name: name, includeSubdomains: subdomains[name]})
+	}
+
+	return domains
+}
+
+// exportNetworks returns IP networks from ip hosts, single addresses are returned with full mask.
+// Networks are aggregated, so covered networks are removed and adjacent networks are merged.
+func exportNetworks(hosts []blacklistEntities.BlacklistedHost) []*net.IPNet {
+	var networks []*net.IPNet
+	var seen = make(map[string]bool)
+
+	for _, h := range hosts {
+		if h.Type != "ip" {
+			continue
+		}
+
+		var network *net.IPNet
+
+		if _, n, err := net.ParseCIDR(h.Host); err == nil {
+			network = n
+		} else if ip := net.ParseIP(h.Host); ip != nil {
+			if ip4 := ip.To4(); ip4 != nil {
+				network = &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+			} else {
+				network = &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+			}
+		} else {
+			continue
+		}
+
+		if !seen[network.String()] {
+			seen[network.String()] = true
+			networks = append(networks, network)
+		}
+	}
+
+	// wider networks go first, so networks covered by them are removed on aggregation
+	slices.SortFunc(networks, func(a, b *net.IPNet) int {
+		if c := bytes.Compare(a.IP.To16(), b.IP.To16()); c != 0 {
+			return c
+		}
+
+		aOnes, _ := a.Mask.Size()
+		bOnes, _ := b.Mask.Size()
+
+		return aOnes - bOnes
+	})
+
+	return aggregateNetworks(networks)
+}
+
+// aggregateNetworks removes networks covered by other networks and merges adjacent networks of the same size into
+// their parent network, e.g. 10.0.0.0/25 and 10.0.0.128/25 into 10.0.0.0/24. Networks must be sorted by address.
+func aggregateNetworks(networks []*net.IPNet) []*net.IPNet {
+	var result []*net.IPNet
+
+	for _, n := range networks {
+		if len(result) > 0 {
+			last := result[len(result)-1]
+			if last.Contains(n.IP) && len(last.IP) == len(n.IP) {
+				continue
+			}
+		}
+
+		result = append(result, n)
+
+		// merged parent can be adjacent to previous network too
+		for len(result) > 1 {
+			parent := mergeNetworks(result[len(result)-2], result[len(result)-1])
+			if parent == nil {
+				break
+			}
+
+			result = append(result[:len(result)-2], parent)
+		}
+	}
+
+	return result
+}
+
+// mergeNetworks returns parent network of two adjacent networks of the same size, or nil if they can not be merged
+func mergeNetworks(a, b *net.IPNet) *net.IPNet {
+	aOnes, aBits := a.Mask.Size()
+	bOnes, bBits := b.Mask.Size()
+
+	if aOnes != bOnes || aBits != bBits || aOnes == 0 || len(a.IP) != len(b.IP) || a.IP.Equal(b.IP) {
+		return nil
+	}
+
+	mask := net.CIDRMask(aOnes-1, aBits)
+	if !a.IP.Mask(mask).Equal(a.IP) || !b.IP.Mask(mask).Equal(a.IP) {
+		return nil
+	}
+
+	return &net.IPNet{IP: a.IP, Mask: mask}
+}
+
+// formatNetwork returns network in CIDR notation, single addresses are returned without mask
+func formatNetwork(n *net.IPNet) string {
+	if ones, bits := n.Mask.Size(); ones == bits {
+		return n.IP.String()
+	}
+
+	return n.String()
+}
+
+func uniqueSorted(values []string) []string {
+	slices.Sort(values)
+	return slices.Compact(values)
+}
+
+func exportHeader(comment string) []string {
+	return []string{fmt.Sprintf("%s generated by domain threat intelligence api at %s", comment, time.Now().UTC().Format(time.RFC3339))}
+}
+
+func joinLines(header, lines []string) []byte {
+	var buf bytes.Buffer
+
+	for _, l := range append(header, lines...) {
+		buf.WriteString(l)
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes()
+}
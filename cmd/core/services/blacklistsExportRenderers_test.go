@@ -0,0 +1,166 @@
+package services
+
+import (
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportRenderers(t *testing.T) {
+	hosts := []blacklistEntities.BlacklistedHost{
+		{Type: "ip", Host: "10.0.1.0/24"},
+		{Type: "ip", Host: "2001:db8::1"},
+		{Type: "ip", Host: "10.0.0.1"},
+		{Type: "ip", Host: "10.0.0.1"},
+		{Type: "domain", Host: "Evil.COM."},
+		{Type: "domain", Host: "bad.org", IncludeSubdomains: true},
+		{Type: "email", Host: "user@evil.com"},
+		{Type: "url", Host: "http://phishing.net/login"},
+	}
+
+	tests := []struct {
+		format blacklistEntities.ExportFormat
+		// skip defines number of header lines, which are not compared
+		skip int
+		want []string
+	}{
+		{
+			format: blacklistEntities.ExportFormatText,
+			skip:   1,
+			want:   []string{"*.bad.org", "10.0.0.1", "10.0.1.0/24", "2001:db8::1", "Evil.COM.", "http://phishing.net/login", "user@evil.com"},
+		},
+		{
+			format: blacklistEntities.ExportFormatHosts,
+			skip:   1,
+			want:   []string{"0.0.0.0 bad.org", "0.0.0.0 evil.com"},
+		},
+		{
+			format: blacklistEntities.ExportFormatRPZ,
+			skip:   4,
+			want: []string{
+				"bad.org CNAME .", "*.bad.org CNAME .", "evil.com CNAME .",
+				"32.1.0.0.10.rpz-ip CNAME .", "24.0.1.0.10.rpz-ip CNAME .", "128.1.zz.db8.2001.rpz-ip CNAME .",
+			},
+		},
+		{
+			format: blacklistEntities.ExportFormatSquid,
+			skip:   1,
+			want:   []string{".bad.org", "evil.com"},
+		},
+		{
+			format: blacklistEntities.ExportFormatIPTables,
+			skip:   1,
+			want: []string{
+				"create dti_blacklist hash:net family inet -exist",
+				"create dti_blacklist_v6 hash:net family inet6 -exist",
+				"add dti_blacklist 10.0.0.1 -exist",
+				"add dti_blacklist 10.0.1.0/24 -exist",
+				"add dti_blacklist_v6 2001:db8::1 -exist",
+			},
+		},
+		{
+			format: blacklistEntities.ExportFormatNFTables,
+			skip:   1,
+			want: []string{
+				"table inet dti_blacklist {",
+				"\tset dti_blacklist_v4 {", "\t\ttype ipv4_addr", "\t\tflags interval", "\t\tauto-merge", "\t\telements = { 10.0.0.1, 10.0.1.0/24 }", "\t}",
+				"\tset dti_blacklist_v6 {", "\t\ttype ipv6_addr", "\t\tflags interval", "\t\tauto-merge", "\t\telements = { 2001:db8::1 }", "\t}",
+				"}",
+			},
+		},
+		{
+			format: blacklistEntities.ExportFormatSuricata,
+			skip:   1,
+			want:   []string{"10.0.0.1,1,127", "10.0.1.0/24,1,127", "2001:db8::1,1,127"},
+		},
+		{
+			format: blacklistEntities.ExportFormatSnort,
+			skip:   1,
+			want:   []string{"10.0.0.1", "10.0.1.0/24", "2001:db8::1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			render, ok := exportRenderers[tt.format]
+			if !ok {
+				t.Fatalf("renderer of format '%s' not registered", tt.format)
+			}
+
+			lines := strings.Split(strings.TrimSuffix(string(render(hosts)), "\n"), "\n")
+			if !strings.HasPrefix(lines[0], "# generated") && !strings.HasPrefix(lines[0], "; generated") {
+				t.Errorf("file header missing: %s", lines[0])
+			}
+
+			if got := lines[tt.skip:]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportToFormatNotSupported(t *testing.T) {
+	_, err := NewBlackListsServiceImpl(&fakeBlacklistsRepo{}, nil).ExportToFormat("pdf", blacklistEntities.BlacklistExportFilter{})
+	if err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestExportNetworks(t *testing.T) {
+	tests := []struct {
+		name  string
+		hosts []string
+		want  []string
+	}{
+		{name: "single addresses", hosts: []string{"10.0.0.5", "10.0.0.1", "10.0.0.1"}, want: []string{"10.0.0.1/32", "10.0.0.5/32"}},
+		{name: "covered networks removed", hosts: []string{"10.0.0.1", "10.0.0.0/24", "10.0.0.128/25"}, want: []string{"10.0.0.0/24"}},
+		{name: "adjacent networks merged", hosts: []string{"10.0.0.128/25", "10.0.0.0/25"}, want: []string{"10.0.0.0/24"}},
+		{name: "merged parent merged again", hosts: []string{"10.0.0.0/25", "10.0.0.128/25", "10.0.1.0/24"}, want: []string{"10.0.0.0/23"}},
+		{name: "adjacent addresses", hosts: []string{"10.0.0.0", "10.0.0.1", "10.0.0.2"}, want: []string{"10.0.0.0/31", "10.0.0.2/32"}},
+		{name: "networks of different parents not merged", hosts: []string{"10.0.0.128/25", "10.0.1.0/25"}, want: []string{"10.0.0.128/25", "10.0.1.0/25"}},
+		{name: "ipv6 and ipv4 kept apart", hosts: []string{"::a00:0/121", "10.0.0.0/25", "2001:db8::/33", "2001:db8:8000::/33"}, want: []string{"::a00:0/121", "10.0.0.0/25", "2001:db8::/32"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hosts []blacklistEntities.BlacklistedHost
+			for _, h := range tt.hosts {
+				hosts = append(hosts, blacklistEntities.BlacklistedHost{Host: h, Type: "ip"})
+			}
+
+			// hosts of other types are ignored
+			hosts = append(hosts, blacklistEntities.BlacklistedHost{Host: "evil.com", Type: "domain"})
+
+			var got []string
+			for _, n := range exportNetworks(hosts) {
+				got = append(got, n.String())
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportToFormatActiveOnly(t *testing.T) {
+	repo := &fakeBlacklistsRepo{}
+	s := NewBlackListsServiceImpl(repo, nil)
+
+	// deleted hosts are not rendered, even if filter selects them
+	includeDeleted := false
+	filter := blacklistEntities.BlacklistExportFilter{IsActive: &includeDeleted, SourceIDs: []uint64{blacklistEntities.SourceKaspersky}}
+
+	for format := range exportRenderers {
+		if _, err := s.ExportToFormat(format, filter); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, f := range repo.filters {
+		if f.IsActive == nil || !*f.IsActive || !reflect.DeepEqual(f.SourceIDs, filter.SourceIDs) {
+			t.Errorf("unexpected hosts filter: %+v", f)
+		}
+	}
+}
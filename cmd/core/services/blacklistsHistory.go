@@ -0,0 +1,48 @@
+package services
+
+import (
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"errors"
+	"github.com/jackc/pgtype"
+	"time"
+)
+
+func (s *BlackListsServiceImpl) RetrieveHostHistory(uuid pgtype.UUID) ([]blacklistEntities.BlacklistHostRevision, error) {
+	return s.repo.SelectHostRevisions(uuid)
+}
+
+// RestoreHost makes soft deleted host active again. Host deleted after expiration gets new expiration date
+// by TTL policy, so it is not deleted again by expirer. Allowlisted hosts are not restored.
+func (s *BlackListsServiceImpl) RestoreHost(uuid pgtype.UUID, userID *uint64) (int64, error) {
+	host, err := s.repo.SelectHost(uuid)
+	if err != nil {
+		return 0, err
+	} else if len(host.Type) == 0 {
+		return 0, errors.New("host not found")
+	} else if !host.DeletedAt.Valid {
+		return 0, errors.New("host is not deleted")
+	}
+
+	allowlist, err := s.allowlist()
+	if err != nil {
+		return 0, err
+	}
+
+	if entry, ok := allowlist.MatchHost(host); ok {
+		return 0, errors.New("host is " + entry.Reason())
+	}
+
+	now := time.Now()
+
+	validUntil := host.ValidUntil
+	if validUntil != nil && !validUntil.After(now) {
+		policies, err := s.ttlPolicies()
+		if err != nil {
+			return 0, err
+		}
+
+		validUntil = policies.ValidUntil(host.SourceID, host.Type, now)
+	}
+
+	return s.repo.RestoreHost(host.Type, uuid, validUntil, userID)
+}
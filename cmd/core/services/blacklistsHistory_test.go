@@ -0,0 +1,64 @@
+package services
+
+import (
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"github.com/jackc/pgtype"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestRestoreHost(t *testing.T) {
+	now := time.Now()
+	expired, active := now.AddDate(0, 0, -1), now.AddDate(0, 1, 0)
+	deleted := gorm.DeletedAt{Time: now, Valid: true}
+
+	repo := &fakeBlacklistsRepo{
+		hosts: []blacklistEntities.BlacklistedHost{
+			{UUID: hostUUID(1), Type: "domain", Host: "evil.com", SourceID: blacklistEntities.SourceDrWeb, ValidUntil: &expired, DeletedAt: deleted},
+			{UUID: hostUUID(2), Type: "domain", Host: "malware.com", ValidUntil: &active, DeletedAt: deleted},
+			{UUID: hostUUID(3), Type: "domain", Host: "phishing.com"},
+			{UUID: hostUUID(4), Type: "domain", Host: "www.example.com", DeletedAt: deleted},
+		},
+		allowlist: []blacklistEntities.AllowlistEntry{
+			{ID: 1, Type: "domain", Value: "example.com", IncludeSubdomains: true},
+		},
+		ttlPolicies: []blacklistEntities.BlacklistTTLPolicy{
+			{SourceID: blacklistEntities.SourceDrWeb, TTLDays: 7},
+		},
+	}
+	s := NewBlackListsServiceImpl(repo, nil)
+
+	tests := []struct {
+		name    string
+		uuid    pgtype.UUID
+		wantErr bool
+	}{
+		{name: "expired host", uuid: hostUUID(1)},
+		{name: "active host", uuid: hostUUID(2)},
+		{name: "host not deleted", uuid: hostUUID(3), wantErr: true},
+		{name: "allowlisted host", uuid: hostUUID(4), wantErr: true},
+		{name: "unknown host", uuid: hostUUID(5), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.RestoreHost(tt.uuid, nil); (err != nil) != tt.wantErr {
+				t.Fatalf("RestoreHost() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if _, ok := repo.restored[tt.uuid.Bytes]; ok == tt.wantErr {
+				t.Errorf("host restored = %v, want %v", ok, !tt.wantErr)
+			}
+		})
+	}
+
+	// expired host gets lifetime by TTL policy of its source, so it is not deleted by expirer again
+	if validUntil := repo.restored[hostUUID(1).Bytes]; validUntil == nil || validUntil.Sub(now.AddDate(0, 0, 7)).Abs() > time.Minute {
+		t.Errorf("got valid until %v for expired host, want in 7 days", validUntil)
+	}
+
+	if validUntil := repo.restored[hostUUID(2).Bytes]; validUntil == nil || !validUntil.Equal(active) {
+		t.Errorf("got valid until %v for active host, want %s", validUntil, active)
+	}
+}
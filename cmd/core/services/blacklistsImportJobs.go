@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"errors"
+	"fmt"
+	"gorm.io/datatypes"
+	"log/slog"
+)
+
+// importBatchSize defines number of hosts saved at once, import progress is updated after every batch
+const importBatchSize = 1000
+
+// StartImportJob parses imported data, creates import event and saves parsed hosts in background.
+// Returns incomplete import event, which is updated with progress as batches are saved.
+func (s *BlackListsServiceImpl) StartImportJob(job blacklistEntities.BlacklistImportJob) (blacklistEntities.BlacklistImportEvent, error) {
+	event, parsed, err := s.prepareImportJob(job)
+	if err != nil {
+		return blacklistEntities.BlacklistImportEvent{}, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.jobsMu.Lock()
+	s.jobs[event.ID] = cancel
+	s.jobsMu.Unlock()
+
+	go func() {
+		defer func() {
+			s.jobsMu.Lock()
+			delete(s.jobs, event.ID)
+			s.jobsMu.Unlock()
+
+			cancel()
+		}()
+
+		_, err := s.saveParsedHosts(ctx, event, parsed)
+		if err != nil {
+			slog.Error(fmt.Sprintf("import job #%d failed: %s", event.ID, err.Error()))
+
+			// failed event is completed anyway, so it is not polled forever
+			event.IsComplete, event.Error = true, err.Error()
+
+			_, err = s.SaveImportEvent(event)
+			if err != nil {
+				slog.Error(fmt.Sprintf("failed to complete import event #%d: %s", event.ID, err.Error()))
+			}
+		}
+	}()
+
+	return event, nil
+}
+
+// CancelImportJob stops running import job after current batch is saved
+func (s *BlackListsServiceImpl) CancelImportJob(eventID uint64) error {
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+
+	cancel, ok := s.jobs[eventID]
+	if !ok {
+		return errors.New("import job not running")
+	}
+
+	cancel()
+
+	return nil
+}
+
+// FailInterruptedImportJobs marks incomplete import events as failed. Import jobs run in memory, so events left
+// incomplete by previous application run are never completed and must be failed before new jobs are started.
+func (s *BlackListsServiceImpl) FailInterruptedImportJobs() (int64, error) {
+	return s.repo.FailIncompleteImportEvents("import interrupted by application restart")
+}
+
+// runImportJob parses imported data and saves parsed hosts in current goroutine
+func (s *BlackListsServiceImpl) runImportJob(job blacklistEntities.BlacklistImportJob) (blacklistEntities.BlacklistImportEvent, error) {
+	event, parsed, err := s.prepareImportJob(job)
+	if err != nil {
+		return blacklistEntities.BlacklistImportEvent{}, err
+	}
+
+	return s.saveParsedHosts(context.Background(), event, parsed)
+}
+
+// prepareImportJob parses imported data by job type and creates import event
+func (s *BlackListsServiceImpl) prepareImportJob(job blacklistEntities.BlacklistImportJob) (blacklistEntities.BlacklistImportEvent, *parsedHosts, error) {
+	var parsed *parsedHosts
+	var err error
+
+	eventType, sourceID := job.Type, job.SourceID
+
+	switch job.Type {
+	case blacklistEntities.ImportJobSTIX:
+		parsed = parseSTIX2(job.STIX2Bundles, job.ExtractAll, job.SourceID)
+	case blacklistEntities.ImportJobCSV:
+		profile := blacklistEntities.DefaultImportProfile()
+		if job.CSVProfile != nil {
+			profile = *job.CSVProfile
+		}
+
+		parsed, err = parseCSV(job.CSV, profile, job.DiscoveredAt, job.ExtractAll, job.SourceID)
+	case blacklistEntities.ImportJobMISP:
+		parsed = parseMISP(job.MISPEvents, job.ExtractAll, job.SourceID)
+	case blacklistEntities.ImportJobTxt, blacklistEntities.ImportJobText:
+		if len(job.Values) == 0 {
+			return blacklistEntities.BlacklistImportEvent{}, nil, errors.New("no indicators to import found")
+		}
+
+		parsed = parseValues(job.Values, job.DiscoveredAt, job.ExtractAll, job.SourceID)
+	case blacklistEntities.ImportJobPreview:
+		preview, err := s.takeImportPreview(job.PreviewID, job.CreatedByID)
+		if err != nil {
+			return blacklistEntities.BlacklistImportEvent{}, nil, err
+		}
+
+		parsed, eventType, sourceID = preview.parsed, preview.preview.Type, 0
+		if preview.preview.SourceID != nil {
+			sourceID = *preview.preview.SourceID
+		}
+	default:
+		return blacklistEntities.BlacklistImportEvent{}, nil, fmt.Errorf("import type '%s' not supported", job.Type)
+	}
+
+	if err != nil {
+		return blacklistEntities.BlacklistImportEvent{}, nil, err
+	}
+
+	event, err := s.createImportEvent(eventType, sourceID, job.CreatedByID, job.FeedID)
+	if err != nil {
+		return blacklistEntities.BlacklistImportEvent{}, nil, err
+	}
+
+	return event, parsed, nil
+}
+
+// saveParsedHosts saves parsed hosts in batches, updating import event progress after every batch.
+// If context is cancelled, import stops and event is marked as cancelled, already saved hosts are kept.
+func (s *BlackListsServiceImpl) saveParsedHosts(ctx context.Context, event blacklistEntities.BlacklistImportEvent, parsed *parsedHosts) (blacklistEntities.BlacklistImportEvent, error) {
+	allowlist, err := s.allowlist()
+	if err != nil {
+		return event, err
+	}
+
+	parsed.applyAllowlist(allowlist)
+
+	var summary = blacklistEntities.BlacklistImportEventSummary{}
+	summary.Skipped = parsed.skipped + int64(len(parsed.invalid))
+	summary.Allowlisted = int64(len(parsed.allowlisted))
+	summary.Progress.Total = int64(len(parsed.ips) + len(parsed.domains) + len(parsed.urls) + len(parsed.emails))
+
+	var ips = make([]blacklistEntities.BlacklistedIP, 0, len(parsed.ips))
+	for _, v := range parsed.ips {
+		v.ImportEventID = &event.ID
+		linkImportEvent(v.Sightings, &event.ID)
+		ips = append(ips, *v)
+	}
+
+	var urls = make([]blacklistEntities.BlacklistedURL, 0, len(parsed.urls))
+	for _, v := range parsed.urls {
+		v.ImportEventID = &event.ID
+		linkImportEvent(v.Sightings, &event.ID)
+		urls = append(urls, *v)
+	}
+
+	var domains = make([]blacklistEntities.BlacklistedDomain, 0, len(parsed.domains))
+	for _, v := range parsed.domains {
+		v.ImportEventID = &event.ID
+		linkImportEvent(v.Sightings, &event.ID)
+		domains = append(domains, *v)
+	}
+
+	var emails = make([]blacklistEntities.BlacklistedEmail, 0, len(parsed.emails))
+	for _, v := range parsed.emails {
+		v.ImportEventID = &event.ID
+		linkImportEvent(v.Sightings, &event.ID)
+		emails = append(emails, *v)
+	}
+
+	s.saveImportErrors(event.ID, parsed.invalid)
+	s.saveImportErrors(event.ID, parsed.allowlisted)
+
+	// progress is saved after every batch, so it can be polled while import is running
+	progress := func(type_ string, processed int, rows int64, err error) {
+		if err != nil {
+			slog.Warn(fmt.Sprintf("failed to save %d hosts of type %s: %s", processed, type_, err.Error()))
+			summary.Errored += processed
+
+			s.saveImportErrors(event.ID, []blacklistEntities.BlacklistImportError{{
+				Location: fmt.Sprintf("batch of %d hosts", processed),
+				Type:     type_,
+				Reason:   "failed to save: " + err.Error(),
+			}})
+		}
+
+		switch type_ {
+		case "ip":
+			summary.Imported.IPs += rows
+		case "url":
+			summary.Imported.URLs += rows
+		case "domain":
+			summary.Imported.Domains += rows
+		case "email":
+			summary.Imported.Emails += rows
+		}
+
+		summary.Imported.Total += rows
+		summary.Progress.Processed += int64(processed)
+
+		event.Summary = datatypes.NewJSONType(summary)
+
+		_, err = s.SaveImportEvent(event)
+		if err != nil {
+			slog.Warn("failed to save import progress: " + err.Error())
+		}
+	}
+
+	err = saveInBatches(ctx, ips, event.CreatedByID, s.SaveIPs, func(processed int, rows int64, err error) { progress("ip", processed, rows, err) })
+	if err == nil {
+		err = saveInBatches(ctx, urls, event.CreatedByID, s.SaveURLs, func(processed int, rows int64, err error) { progress("url", processed, rows, err) })
+	}
+	if err == nil {
+		err = saveInBatches(ctx, domains, event.CreatedByID, s.SaveDomains, func(processed int, rows int64, err error) { progress("domain", processed, rows, err) })
+	}
+	if err == nil {
+		err = saveInBatches(ctx, emails, event.CreatedByID, s.SaveEmails, func(processed int, rows int64, err error) { progress("email", processed, rows, err) })
+	}
+
+	if err != nil {
+		event.IsCancelled = true
+		event.Error = err.Error()
+	}
+
+	// hosts keep import event ID only if they were created by this import
+	hosts, err := s.RetrieveHostsByFilter(blacklistEntities.BlacklistSearchFilter{
+		ImportEventID: event.ID,
+	})
+
+	if err != nil {
+		slog.Warn("failed to count new hosts: " + err.Error())
+	}
+
+	// count by type
+	for _, h := range hosts {
+		switch h.Type {
+		case "ip":
+			summary.New.IPs++
+		case "url":
+			summary.New.URLs++
+		case "domain":
+			summary.New.Domains++
+		case "email":
+			summary.New.Emails++
+		}
+	}
+
+	summary.New.Total = summary.New.IPs + summary.New.Domains + summary.New.URLs + summary.New.Emails
+
+	// saving import event updates
+	event.Summary = datatypes.NewJSONType(summary)
+	event.IsComplete = true
+
+	return s.SaveImportEvent(event)
+}
+
+// linkImportEvent links sightings of all sources which reported imported host with import event
+func linkImportEvent(sightings []blacklistEntities.BlacklistSighting, eventID *uint64) {
+	for i := range sightings {
+		sightings[i].ImportEventID = eventID
+	}
+}
+
+// saveInBatches saves values by batches on behalf of user and reports every batch. Returns error only if context was cancelled.
+func saveInBatches[T any](ctx context.Context, values []T, userID *uint64, save func([]T, *uint64) (int64, error), report func(processed int, rows int64, err error)) error {
+	for start := 0; start < len(values); start += importBatchSize {
+		if ctx.Err() != nil {
+			return errors.New("import cancelled")
+		}
+
+		end := min(start+importBatchSize, len(values))
+
+		rows, err := save(values[start:end], userID)
+		report(end-start, rows, err)
+	}
+
+	return nil
+}
+
+// saveImportErrors saves import error log, failure is only logged, so it does not stop import
+func (s *BlackListsServiceImpl) saveImportErrors(eventID uint64, importErrors []blacklistEntities.BlacklistImportError) {
+	if len(importErrors) == 0 {
+		return
+	}
+
+	for i := range importErrors {
+		importErrors[i].ImportEventID = eventID
+	}
+
+	err := s.repo.SaveImportErrors(importErrors)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("failed to save %d import errors: %s", len(importErrors), err.Error()))
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSaveInBatches(t *testing.T) {
+	values := make([]int, 2*importBatchSize+1)
+	userID := uint64(7)
+
+	var batches []int
+	save := func(batch []int, savedBy *uint64) (int64, error) {
+		if savedBy != &userID {
+			t.Errorf("batch saved by %v, want user #%d", savedBy, userID)
+		}
+
+		batches = append(batches, len(batch))
+		if len(batches) == 2 {
+			return 0, errors.New("failed")
+		}
+
+		return int64(len(batch)), nil
+	}
+
+	var reported []int64
+	var errored int
+	report := func(processed int, rows int64, err error) {
+		reported = append(reported, rows)
+		if err != nil {
+			errored += processed
+		}
+	}
+
+	// failed batch does not stop saving
+	if err := saveInBatches(context.Background(), values, &userID, save, report); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(batches, []int{importBatchSize, importBatchSize, 1}) || !reflect.DeepEqual(reported, []int64{importBatchSize, 0, 1}) || errored != importBatchSize {
+		t.Errorf("got batches %v, reported %v, errored %d", batches, reported, errored)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	batches = nil
+	if err := saveInBatches(ctx, values, &userID, save, report); err == nil || len(batches) != 0 {
+		t.Errorf("cancelled import saved %d batches", len(batches))
+	}
+}
+
+func TestStartImportJob(t *testing.T) {
+	repo := &fakeBlacklistsRepo{}
+	s := NewBlackListsServiceImpl(repo, nil)
+	feedID := uint64(3)
+
+	event, err := s.StartImportJob(blacklistEntities.BlacklistImportJob{
+		Type:     blacklistEntities.ImportJobTxt,
+		SourceID: blacklistEntities.SourceDrWeb,
+		Values:   []string{"10.0.0.1", "evil.com", "not a host"},
+		FeedID:   &feedID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if event.ID == 0 || event.IsComplete || event.Type != blacklistEntities.ImportJobTxt || event.FeedID != &feedID {
+		t.Errorf("unexpected started import event: %+v", event)
+	}
+
+	// job is removed from running jobs after import event is completed
+	for running := true; running; {
+		time.Sleep(time.Millisecond)
+
+		s.jobsMu.Lock()
+		_, running = s.jobs[event.ID]
+		s.jobsMu.Unlock()
+	}
+
+	if err = s.CancelImportJob(event.ID); err == nil {
+		t.Error("completed import job cancelled")
+	}
+
+	want := []string{"domain:evil.com (4)", "ip:10.0.0.1/32 (4)"}
+	if got := repo.savedHosts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	event = repo.events[event.ID-1]
+	if summary := event.Summary.Data(); !event.IsComplete || summary.Progress.Total != 2 || summary.Progress.Processed != 2 || summary.Skipped != 1 {
+		t.Errorf("unexpected completed import event: %+v", event)
+	}
+}
+
+func TestStartImportJobErrors(t *testing.T) {
+	s := NewBlackListsServiceImpl(&fakeBlacklistsRepo{}, nil)
+
+	jobs := []blacklistEntities.BlacklistImportJob{
+		{Type: "unknown"},
+		{Type: blacklistEntities.ImportJobTxt},
+		{Type: blacklistEntities.ImportJobCSV, CSV: [][]string{{"Host"}}},
+		{Type: blacklistEntities.ImportJobPreview, PreviewID: "not found"},
+	}
+
+	for _, job := range jobs {
+		if _, err := s.StartImportJob(job); err == nil {
+			t.Errorf("expected error of job %+v", job)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"cmp"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"slices"
+	"time"
+)
+
+// importPreviewTTL defines how long import preview can be committed
+const importPreviewTTL = time.Hour
+
+// importPreviewBatchSize limits number of values checked for existence in single query
+const importPreviewBatchSize = 1000
+
+// importPreviewMaxHosts limits number of hosts in single preview, larger files should be imported without dry run
+const importPreviewMaxHosts = 100000
+
+// importPreviewLimit limits number of previews kept in memory, the oldest preview is removed if limit is reached
+const importPreviewLimit = 50
+
+// errImportPreviewNotFound is returned for missing and expired previews, and for previews of other users
+var errImportPreviewNotFound = errors.New("import preview not found or expired")
+
+// importPreview keeps parsed hosts of import dry-run, so they can be saved without parsing file again
+type importPreview struct {
+	preview blacklistEntities.BlacklistImportPreview
+	parsed  *parsedHosts
+}
+
+// PreviewImportFromSTIX2 parses STIX 2.0 bundles same as ImportFromSTIX2, but does not save any hosts
+func (s *BlackListsServiceImpl) PreviewImportFromSTIX2(bundles []blacklistEntities.STIX2Bundle, extractAll bool, sourceID uint64, userID *uint64) (blacklistEntities.BlacklistImportPreview, error) {
+	return s.createImportPreview("stix", sourceID, userID, parseSTIX2(bundles, extractAll, sourceID))
+}
+
+// PreviewImportFromCSV parses CSV file same as CSV import job, but does not save any hosts
+func (s *BlackListsServiceImpl) PreviewImportFromCSV(data [][]string, profile blacklistEntities.BlacklistImportProfile, discoveredAt time.Time, extractAll bool, sourceID uint64, userID *uint64) (blacklistEntities.BlacklistImportPreview, error) {
+	parsed, err := parseCSV(data, profile, discoveredAt, extractAll, sourceID)
+	if err != nil {
+		return blacklistEntities.BlacklistImportPreview{}, err
+	}
+
+	return s.createImportPreview("csv", sourceID, userID, parsed)
+}
+
+func (s *BlackListsServiceImpl) RetrieveImportPreview(id string, userID *uint64) (blacklistEntities.BlacklistImportPreview, error) {
+	s.previewsMu.Lock()
+	defer s.previewsMu.Unlock()
+
+	p, ok := s.previews[id]
+	if !ok || !p.isAvailable(userID, time.Now()) {
+		return blacklistEntities.BlacklistImportPreview{}, errImportPreviewNotFound
+	}
+
+	return p.preview, nil
+}
+
+// takeImportPreview removes import preview from storage, so it can not be committed again.
+// Preview of other user is not removed.
+func (s *BlackListsServiceImpl) takeImportPreview(id string, userID *uint64) (*importPreview, error) {
+	s.previewsMu.Lock()
+	defer s.previewsMu.Unlock()
+
+	p, ok := s.previews[id]
+	if !ok || !p.isAvailable(userID, time.Now()) {
+		return nil, errImportPreviewNotFound
+	}
+
+	delete(s.previews, id)
+
+	return p, nil
+}
+
+// removeExpiredImportPreviews removes expired previews from storage
+func (s *BlackListsServiceImpl) removeExpiredImportPreviews(now time.Time) {
+	s.previewsMu.Lock()
+	defer s.previewsMu.Unlock()
+
+	for id, p := range s.previews {
+		if p.preview.ExpiresAt.Before(now) {
+			delete(s.previews, id)
+		}
+	}
+}
+
+// isAvailable checks if preview is not expired and was created by defined user
+func (p *importPreview) isAvailable(userID *uint64, now time.Time) bool {
+	if p.preview.ExpiresAt.Before(now) {
+		return false
+	}
+
+	createdBy := p.preview.CreatedByID
+	if createdBy == nil || userID == nil {
+		return createdBy == nil && userID == nil
+	}
+
+	return *createdBy == *userID
+}
+
+// createImportPreview classifies parsed hosts as new or existing by their sources and stores preview
+func (s *BlackListsServiceImpl) createImportPreview(type_ string, sourceID uint64, userID *uint64, parsed *parsedHosts) (blacklistEntities.BlacklistImportPreview, error) {
+	now := time.Now()
+
+	if count := len(parsed.ips) + len(parsed.urls) + len(parsed.domains) + len(parsed.emails); count > importPreviewMaxHosts {
+		return blacklistEntities.BlacklistImportPreview{}, fmt.Errorf("preview is limited to %d hosts, %d hosts parsed: import without dry run", importPreviewMaxHosts, count)
+	}
+
+	allowlist, err := s.allowlist()
+	if err != nil {
+		return blacklistEntities.BlacklistImportPreview{}, err
+	}
+
+	parsed.applyAllowlist(allowlist)
+
+	preview := blacklistEntities.BlacklistImportPreview{
+		ID:          uuid.NewString(),
+		Type:        type_,
+		New:         []blacklistEntities.BlacklistImportPreviewHost{},
+		Existing:    []blacklistEntities.BlacklistImportPreviewHost{},
+		Invalid:     parsed.invalid,
+		Allowlisted: parsed.allowlisted,
+		CreatedByID: userID,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(importPreviewTTL),
+	}
+
+	if sourceID != 0 {
+		preview.SourceID = &sourceID
+	}
+
+	if preview.Invalid == nil {
+		preview.Invalid = []blacklistEntities.BlacklistImportError{}
+	}
+
+	if preview.Allowlisted == nil {
+		preview.Allowlisted = []blacklistEntities.BlacklistImportError{}
+	}
+
+	preview.Summary.Invalid = int64(len(parsed.invalid))
+	preview.Summary.Skipped = parsed.skipped
+	preview.Summary.Allowlisted = int64(len(parsed.allowlisted))
+
+	var hosts []blacklistEntities.BlacklistImportPreviewHost
+
+	for k, v := range parsed.ips {
+		hosts = append(hosts, blacklistEntities.BlacklistImportPreviewHost{Host: k, Type: "ip", SourceID: v.SourceID})
+	}
+
+	for k, v := range parsed.urls {
+		hosts = append(hosts, blacklistEntities.BlacklistImportPreviewHost{Host: k, Type: "url", SourceID: v.SourceID})
+	}
+
+	for k, v := range parsed.domains {
+		hosts = append(hosts, blacklistEntities.BlacklistImportPreviewHost{Host: k, Type: "domain", SourceID: v.SourceID})
+	}
+
+	for k, v := range parsed.emails {
+		hosts = append(hosts, blacklistEntities.BlacklistImportPreviewHost{Host: k, Type: "email", SourceID: v.SourceID})
+	}
+
+	existing, err := s.selectExistingSources(hosts)
+	if err != nil {
+		return blacklistEntities.BlacklistImportPreview{}, err
+	}
+
+	for _, h := range hosts {
+		h.ExistingSourceIDs = existing[h.Type+":"+h.Host]
+		if h.ExistingSourceIDs == nil {
+			h.ExistingSourceIDs = []uint64{}
+		}
+
+		if slices.Contains(h.ExistingSourceIDs, h.SourceID) {
+			preview.Existing = append(preview.Existing, h)
+			preview.Summary.Existing.Add(h.Type)
+		} else {
+			preview.New = append(preview.New, h)
+			preview.Summary.New.Add(h.Type)
+		}
+	}
+
+	sortPreviewHosts := func(a, b blacklistEntities.BlacklistImportPreviewHost) int {
+		if c := cmp.Compare(a.Type, b.Type); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.Host, b.Host)
+	}
+
+	slices.SortFunc(preview.New, sortPreviewHosts)
+	slices.SortFunc(preview.Existing, sortPreviewHosts)
+
+	s.removeExpiredImportPreviews(now)
+
+	s.previewsMu.Lock()
+	defer s.previewsMu.Unlock()
+
+	// the oldest previews are removed, so previews kept in memory do not grow beyond limit
+	for len(s.previews) >= importPreviewLimit {
+		var oldest string
+		for id, p := range s.previews {
+			if len(oldest) == 0 || p.preview.CreatedAt.Before(s.previews[oldest].preview.CreatedAt) {
+				oldest = id
+			}
+		}
+
+		delete(s.previews, oldest)
+	}
+
+	s.previews[preview.ID] = &importPreview{preview: preview, parsed: parsed}
+
+	return preview, nil
+}
+
+// selectExistingSources returns sources of already blacklisted hosts mapped by host type and value
+func (s *BlackListsServiceImpl) selectExistingSources(hosts []blacklistEntities.BlacklistImportPreviewHost) (map[string][]uint64, error) {
+	var valuesByType = make(map[string][]string)
+	for _, h := range hosts {
+		valuesByType[h.Type] = append(valuesByType[h.Type], h.Host)
+	}
+
+	var result = make(map[string][]uint64)
+
+	for type_, values := range valuesByType {
+		for start := 0; start < len(values); start += importPreviewBatchSize {
+			end := min(start+importPreviewBatchSize, len(values))
+
+			found, err := s.repo.SelectHostsByValues(type_, values[start:end])
+			if err != nil {
+				return nil, err
+			}
+
+			for _, f := range found {
+				key := f.Type + ":" + f.Host
+				if !slices.Contains(result[key], f.SourceID) {
+					result[key] = append(result[key], f.SourceID)
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
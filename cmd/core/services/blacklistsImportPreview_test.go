@@ -0,0 +1,155 @@
+package services
+
+import (
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPreviewImportFromCSV(t *testing.T) {
+	kaspersky := blacklistEntities.SourceKaspersky
+
+	repo := &fakeBlacklistsRepo{hosts: []blacklistEntities.BlacklistedHost{
+		{Type: "domain", Host: "evil.com", SourceID: kaspersky},
+		{Type: "domain", Host: "other.com", SourceID: blacklistEntities.SourceDrWeb},
+	}}
+
+	s := NewBlackListsServiceImpl(repo, nil)
+
+	preview, err := s.PreviewImportFromCSV([][]string{
+		{"Type_IOC", "Value"},
+		{"domain", "evil.com"},
+		{"domain", "other.com"},
+		{"ip-address", "10.0.0.256"},
+		{"sha256", "e3b0c44298fc1c149afbf4c8996fb924"},
+	}, blacklistEntities.DefaultImportProfile(), time.Now(), false, kaspersky, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// host is existing only if it is already blacklisted by the same source
+	wantNew := []blacklistEntities.BlacklistImportPreviewHost{
+		{Host: "other.com", Type: "domain", SourceID: kaspersky, ExistingSourceIDs: []uint64{blacklistEntities.SourceDrWeb}},
+	}
+	wantExisting := []blacklistEntities.BlacklistImportPreviewHost{
+		{Host: "evil.com", Type: "domain", SourceID: kaspersky, ExistingSourceIDs: []uint64{kaspersky}},
+	}
+
+	if !reflect.DeepEqual(preview.New, wantNew) || !reflect.DeepEqual(preview.Existing, wantExisting) {
+		t.Errorf("got new %+v and existing %+v", preview.New, preview.Existing)
+	}
+
+	if preview.Summary.Invalid != 1 || preview.Summary.Skipped != 1 || len(repo.events) != 0 || len(repo.domains) != 0 {
+		t.Errorf("unexpected preview summary or hosts saved: %+v", preview.Summary)
+	}
+
+	if _, err = s.RetrieveImportPreview(preview.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := s.runImportJob(blacklistEntities.BlacklistImportJob{Type: blacklistEntities.ImportJobPreview, PreviewID: preview.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"domain:evil.com (3)", "domain:other.com (3)"}
+	if got := repo.savedHosts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if summary := event.Summary.Data(); event.Type != "csv" || summary.Imported.Total != 2 || summary.Skipped != 2 {
+		t.Errorf("unexpected import event: %+v", event)
+	}
+
+	if _, err = s.runImportJob(blacklistEntities.BlacklistImportJob{Type: blacklistEntities.ImportJobPreview, PreviewID: preview.ID}); err == nil {
+		t.Error("preview committed twice")
+	}
+}
+
+func TestRetrieveExpiredImportPreview(t *testing.T) {
+	s := NewBlackListsServiceImpl(nil, nil)
+	s.previews["expired"] = &importPreview{preview: blacklistEntities.BlacklistImportPreview{ExpiresAt: time.Now().Add(-time.Minute)}}
+
+	if _, err := s.RetrieveImportPreview("expired", nil); err == nil {
+		t.Error("expired preview retrieved")
+	}
+
+	if _, err := s.runImportJob(blacklistEntities.BlacklistImportJob{Type: blacklistEntities.ImportJobPreview, PreviewID: "expired"}); err == nil {
+		t.Error("expired preview committed")
+	}
+}
+
+func TestImportPreviewAvailability(t *testing.T) {
+	owner, other := uint64(1), uint64(2)
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		createdBy *uint64
+		userID    *uint64
+		expiresAt time.Time
+		want      bool
+	}{
+		{name: "owner", createdBy: &owner, userID: &owner, expiresAt: now.Add(time.Minute), want: true},
+		{name: "other user", createdBy: &owner, userID: &other, expiresAt: now.Add(time.Minute), want: false},
+		{name: "anonymous user", createdBy: &owner, userID: nil, expiresAt: now.Add(time.Minute), want: false},
+		{name: "created anonymously", createdBy: nil, userID: nil, expiresAt: now.Add(time.Minute), want: true},
+		{name: "expired", createdBy: &owner, userID: &owner, expiresAt: now.Add(-time.Minute), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := importPreview{preview: blacklistEntities.BlacklistImportPreview{CreatedByID: tt.createdBy, ExpiresAt: tt.expiresAt}}
+
+			if got := p.isAvailable(tt.userID, now); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTakeImportPreview(t *testing.T) {
+	owner, other := uint64(1), uint64(2)
+
+	s := NewBlackListsServiceImpl(nil, nil)
+	s.previews["preview"] = &importPreview{preview: blacklistEntities.BlacklistImportPreview{
+		ID:          "preview",
+		CreatedByID: &owner,
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}}
+
+	if _, err := s.takeImportPreview("preview", &other); err == nil {
+		t.Fatal("preview committed by other user")
+	}
+
+	if _, err := s.RetrieveImportPreview("preview", &owner); err != nil {
+		t.Fatal("preview of owner removed by other user")
+	}
+
+	if _, err := s.takeImportPreview("preview", &owner); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.takeImportPreview("preview", &owner); err == nil {
+		t.Fatal("preview committed twice")
+	}
+}
+
+func TestRemoveExpiredImportPreviews(t *testing.T) {
+	now := time.Now()
+
+	s := NewBlackListsServiceImpl(nil, nil)
+	s.previews["expired"] = &importPreview{preview: blacklistEntities.BlacklistImportPreview{ExpiresAt: now.Add(-time.Minute)}}
+	s.previews["active"] = &importPreview{preview: blacklistEntities.BlacklistImportPreview{ExpiresAt: now.Add(time.Minute)}}
+
+	s.removeExpiredImportPreviews(now)
+
+	if _, ok := s.previews["expired"]; ok {
+		t.Error("expired preview kept")
+	}
+
+	if _, ok := s.previews["active"]; !ok {
+		t.Error("active preview removed")
+	}
+}
@@ -2,17 +2,17 @@ package services
 
 import (
 	"bytes"
-	"crypto/md5"
+	"context"
 	"domain_threat_intelligence_api/cmd/core"
 	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
 	"domain_threat_intelligence_api/cmd/core/entities/serviceDeskEntities"
+	"domain_threat_intelligence_api/cmd/xlsx"
 	"encoding/csv"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/jackc/pgtype"
-	"gorm.io/datatypes"
 	"log/slog"
 	"net/url"
 	"slices"
@@ -24,554 +24,440 @@ import (
 type BlackListsServiceImpl struct {
 	repo core.IBlacklistsRepo
 	desk core.IServiceDeskService
+
+	// previews keeps import dry-run results until they are committed or expired
+	previews   map[string]*importPreview
+	previewsMu sync.Mutex
+
+	// jobs keeps cancel functions of running import jobs by import event ID
+	jobs   map[uint64]context.CancelFunc
+	jobsMu sync.Mutex
 }
 
 func NewBlackListsServiceImpl(repo core.IBlacklistsRepo, desk core.IServiceDeskService) *BlackListsServiceImpl {
-	return &BlackListsServiceImpl{repo: repo, desk: desk}
+	return &BlackListsServiceImpl{
+		repo:     repo,
+		desk:     desk,
+		previews: make(map[string]*importPreview),
+		jobs:     make(map[uint64]context.CancelFunc),
+	}
 }
 
 func (s *BlackListsServiceImpl) RetrieveURLsByFilter(filter blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedURL, error) {
 	return s.repo.SelectURLsByFilter(filter)
 }
 
-func (s *BlackListsServiceImpl) SaveURLs(urls []blacklistEntities.BlacklistedURL) (int64, error) {
+func (s *BlackListsServiceImpl) SaveURLs(urls []blacklistEntities.BlacklistedURL, userID *uint64) (int64, error) {
 	if len(urls) == 0 {
 		return 0, nil
 	}
 
-	// add hashes to URLs
-	for i, v := range urls {
-		hash := md5.Sum([]byte(v.URL))
-		v.MD5 = hex.EncodeToString(hash[:])
+	// URLs are saved in canonical form and hashed, so same URL written differently is saved once.
+	// Original value is kept as provided by source.
+	var canonical = make([]blacklistEntities.BlacklistedURL, 0, len(urls))
+	var unique = make(map[string]int)
 
-		urls[i] = v
-	}
+	for _, v := range urls {
+		if len(v.OriginalURL) == 0 {
+			v.OriginalURL = v.URL
+		}
 
-	return s.repo.SaveURLs(urls)
-}
+		v.URL = blacklistEntities.NormalizeURL(v.URL)
+		v.MD5 = blacklistEntities.URLHash(v.URL)
+		// every source which reported host is saved as its sighting
+		v.Sightings = blacklistEntities.MergeSightings(v.Sightings, blacklistEntities.NewBlacklistSighting(v.SourceID, v.ImportEventID, v.Description, v.DiscoveredAt))
 
-func (s *BlackListsServiceImpl) DeleteURL(uuid pgtype.UUID) (int64, error) {
-	return s.repo.DeleteURL(uuid)
-}
+		// URL reported by several sources is saved once with all sightings
+		if i, ok := unique[v.MD5]; ok {
+			canonical[i].Sightings = blacklistEntities.MergeSightings(canonical[i].Sightings, v.Sightings...)
+			canonical[i].Tags = append(canonical[i].Tags, v.Tags...)
+			continue
+		}
 
-func (s *BlackListsServiceImpl) RetrieveIPsByFilter(filter blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedIP, error) {
-	return s.repo.SelectIPsByFilter(filter)
-}
+		unique[v.MD5] = len(canonical)
+		canonical = append(canonical, v)
+	}
 
-func (s *BlackListsServiceImpl) SaveIPs(ips []blacklistEntities.BlacklistedIP) (int64, error) {
-	if len(ips) == 0 {
-		return 0, nil
+	policies, err := s.ttlPolicies()
+	if err != nil {
+		return 0, err
 	}
 
-	return s.repo.SaveIPs(ips)
-}
+	// hosts without explicit expiration date expire by TTL policy, unknown threat attributes are dropped
+	now := time.Now()
+	for i, v := range canonical {
+		if v.ValidUntil == nil {
+			canonical[i].ValidUntil = policies.ValidUntil(v.SourceID, "url", now)
+		}
 
-func (s *BlackListsServiceImpl) DeleteIP(uuid pgtype.UUID) (int64, error) {
-	return s.repo.DeleteIP(uuid)
-}
+		canonical[i].ThreatAttributes = v.ThreatAttributes.Normalize()
+	}
 
-func (s *BlackListsServiceImpl) RetrieveDomainsByFilter(filter blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedDomain, error) {
-	return s.repo.SelectDomainsByFilter(filter)
-}
+	allowlist, err := s.allowlist()
+	if err != nil {
+		return 0, err
+	}
 
-func (s *BlackListsServiceImpl) SaveDomains(domains []blacklistEntities.BlacklistedDomain) (int64, error) {
-	if len(domains) == 0 {
+	// allowlisted hosts are never saved
+	canonical = withoutAllowlisted(canonical, func(v blacklistEntities.BlacklistedURL) bool {
+		_, ok := allowlist.MatchURL(v.URL)
+		return ok
+	})
+
+	if len(canonical) == 0 {
 		return 0, nil
 	}
 
-	return s.repo.SaveDomains(domains)
-}
+	// tags parsed from imported data are created if missing
+	tags := make([]*[]blacklistEntities.BlacklistTag, len(canonical))
+	for i := range canonical {
+		tags[i] = &canonical[i].Tags
+	}
 
-func (s *BlackListsServiceImpl) DeleteDomain(uuid pgtype.UUID) (int64, error) {
-	return s.repo.DeleteDomain(uuid)
-}
+	err = s.resolveTags(tags)
+	if err != nil {
+		return 0, err
+	}
 
-func (s *BlackListsServiceImpl) RetrieveHostsByFilter(filter blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedHost, error) {
-	return s.repo.SelectHostsUnionByFilter(filter)
+	return s.repo.SaveURLs(canonical, userID)
 }
 
-func (s *BlackListsServiceImpl) RetrieveEmailsByFilter(filter blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedEmail, error) {
-	return s.repo.SelectEmailsByFilter(filter)
+func (s *BlackListsServiceImpl) DeleteURL(uuid pgtype.UUID, userID *uint64) (int64, error) {
+	return s.repo.DeleteURL(uuid, userID)
 }
 
-func (s *BlackListsServiceImpl) SaveEmails(emails []blacklistEntities.BlacklistedEmail) (int64, error) {
-	return s.repo.SaveEmails(emails)
+func (s *BlackListsServiceImpl) RetrieveIPsByFilter(filter blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedIP, error) {
+	return s.repo.SelectIPsByFilter(filter)
 }
 
-func (s *BlackListsServiceImpl) DeleteEmail(uuid pgtype.UUID) (int64, error) {
-	return s.repo.DeleteEmail(uuid)
-}
+func (s *BlackListsServiceImpl) RetrieveIPsContaining(value string) ([]blacklistEntities.BlacklistedIP, error) {
+	ip, err := blacklistEntities.ParseInet(value)
+	if err != nil {
+		return nil, err
+	}
 
-func (s *BlackListsServiceImpl) SaveImportEvent(event blacklistEntities.BlacklistImportEvent) (blacklistEntities.BlacklistImportEvent, error) {
-	return s.repo.SaveImportEvent(event)
+	return s.repo.SelectIPsContaining(ip.IPNet.String())
 }
 
-func (s *BlackListsServiceImpl) RetrieveImportEventsByFilter(filter blacklistEntities.BlacklistImportEventFilter) ([]blacklistEntities.BlacklistImportEvent, error) {
-	return s.repo.SelectImportEventsByFilter(filter)
-}
+func (s *BlackListsServiceImpl) SaveIPs(ips []blacklistEntities.BlacklistedIP, userID *uint64) (int64, error) {
+	if len(ips) == 0 {
+		return 0, nil
+	}
 
-func (s *BlackListsServiceImpl) RetrieveImportEvent(id uint64) (blacklistEntities.BlacklistImportEvent, error) {
-	return s.repo.SelectImportEvent(id)
-}
+	// IP address reported by several sources is saved once with all sightings
+	var unique = make(map[string]int)
+	var merged = make([]blacklistEntities.BlacklistedIP, 0, len(ips))
 
-func (s *BlackListsServiceImpl) DeleteImportEvent(id uint64) (int64, error) {
-	return s.repo.DeleteImportEvent(id)
-}
+	for _, v := range ips {
+		// every source which reported host is saved as its sighting
+		v.Sightings = blacklistEntities.MergeSightings(v.Sightings, blacklistEntities.NewBlacklistSighting(v.SourceID, v.ImportEventID, v.Description, v.DiscoveredAt))
 
-func (s *BlackListsServiceImpl) ImportFromSTIX2(bundles []blacklistEntities.STIX2Bundle, extractAll bool) (blacklistEntities.BlacklistImportEvent, error) {
-	var ipMap = make(map[string]*blacklistEntities.BlacklistedIP)
-	var domainMap = make(map[string]*blacklistEntities.BlacklistedDomain)
-	var urlMap = make(map[string]*blacklistEntities.BlacklistedURL)
-	var emailMap = make(map[string]*blacklistEntities.BlacklistedEmail)
+		key := v.IPAddress.IPNet.String()
+		if i, ok := unique[key]; ok {
+			merged[i].Sightings = blacklistEntities.MergeSightings(merged[i].Sightings, v.Sightings...)
+			merged[i].Tags = append(merged[i].Tags, v.Tags...)
+			continue
+		}
 
-	var summary = blacklistEntities.BlacklistImportEventSummary{}
-	event, err := s.SaveImportEvent(blacklistEntities.BlacklistImportEvent{
-		Type:       "stix",
-		IsComplete: false,
-		CreatedAt:  time.Now(),
-	})
+		unique[key] = len(merged)
+		merged = append(merged, v)
+	}
 
+	ips = merged
+
+	policies, err := s.ttlPolicies()
 	if err != nil {
-		return blacklistEntities.BlacklistImportEvent{}, err
-	} else if event.ID == 0 {
-		return blacklistEntities.BlacklistImportEvent{}, errors.New("import event was not created")
+		return 0, err
 	}
 
-	for bIndex, b := range bundles {
-		for iIndex, object := range b.Objects {
-			if object.Type != "indicator" { // skip all other object types
-				summary.Skipped++
-				continue
-			}
-
-			i, d, u, e, err := object.ToBlacklisted(extractAll)
-			if err != nil {
-				slog.Error(fmt.Sprintf("error in bundle #%d, value #%d; error: %s", bIndex, iIndex, err.Error()))
-			}
+	// hosts without explicit expiration date expire by TTL policy, unknown threat attributes are dropped
+	now := time.Now()
+	for i, v := range ips {
+		if v.ValidUntil == nil {
+			ips[i].ValidUntil = policies.ValidUntil(v.SourceID, "ip", now)
+		}
 
-			if i != nil {
-				ipMap[i.IPAddress.IPNet.String()] = i
-			}
+		ips[i].ThreatAttributes = v.ThreatAttributes.Normalize()
+	}
 
-			if d != nil {
-				domainMap[d.URN] = d
-			}
+	allowlist, err := s.allowlist()
+	if err != nil {
+		return 0, err
+	}
 
-			if u != nil {
-				urlMap[u.URL] = u
-			}
+	// allowlisted hosts are never saved
+	ips = withoutAllowlisted(ips, func(v blacklistEntities.BlacklistedIP) bool {
+		_, ok := allowlist.MatchIP(v.IPAddress)
+		return ok
+	})
 
-			if e != nil {
-				emailMap[e.Email] = e
-			}
-		}
+	if len(ips) == 0 {
+		return 0, nil
 	}
 
-	// async saving of all host types
-	// 1. insert all imported records with clause
-	wg := sync.WaitGroup{}
-	wg.Add(4)
+	// tags parsed from imported data are created if missing
+	tags := make([]*[]blacklistEntities.BlacklistTag, len(ips))
+	for i := range ips {
+		tags[i] = &ips[i].Tags
+	}
 
-	go func() {
-		var ips = make([]blacklistEntities.BlacklistedIP, 0, len(ipMap))
-		for _, v := range ipMap {
-			v.ImportEventID = &event.ID
-			ips = append(ips, *v)
-		}
+	err = s.resolveTags(tags)
+	if err != nil {
+		return 0, err
+	}
 
-		rows, err := s.SaveIPs(ips)
-		if err != nil {
-			slog.Warn("failed to save IP addresses: " + err.Error())
-			summary.Errored += len(ips)
-		} else {
-			summary.Imported.IPs += rows
-		}
+	return s.repo.SaveIPs(ips, userID)
+}
 
-		wg.Done()
-	}()
+func (s *BlackListsServiceImpl) DeleteIP(uuid pgtype.UUID, userID *uint64) (int64, error) {
+	return s.repo.DeleteIP(uuid, userID)
+}
 
-	go func() {
-		var urls = make([]blacklistEntities.BlacklistedURL, 0, len(urlMap))
-		for _, v := range urlMap {
-			v.ImportEventID = &event.ID
-			urls = append(urls, *v)
-		}
+func (s *BlackListsServiceImpl) RetrieveDomainsByFilter(filter blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedDomain, error) {
+	return s.repo.SelectDomainsByFilter(filter)
+}
 
-		rows, err := s.SaveURLs(urls)
-		if err != nil {
-			slog.Warn("failed to save URLs: " + err.Error())
-			summary.Errored += len(urls)
-		} else {
-			summary.Imported.URLs += rows
-		}
+func (s *BlackListsServiceImpl) SaveDomains(domains []blacklistEntities.BlacklistedDomain, userID *uint64) (int64, error) {
+	if len(domains) == 0 {
+		return 0, nil
+	}
 
-		wg.Done()
-	}()
+	// domains are saved in canonical form, so same domain written differently is saved once.
+	// Original value is kept as provided by source.
+	var canonical = make([]blacklistEntities.BlacklistedDomain, 0, len(domains))
+	var unique = make(map[string]int)
 
-	go func() {
-		var domains = make([]blacklistEntities.BlacklistedDomain, 0, len(domainMap))
-		for _, v := range domainMap {
-			v.ImportEventID = &event.ID
-			domains = append(domains, *v)
+	for _, v := range domains {
+		if len(v.OriginalURN) == 0 {
+			v.OriginalURN = v.URN
 		}
 
-		rows, err := s.SaveDomains(domains)
+		urn, err := blacklistEntities.CanonicalDomain(v.URN)
 		if err != nil {
-			slog.Warn("failed to save domains: " + err.Error())
-			summary.Errored += len(domains)
-		} else {
-			summary.Imported.Domains += rows
+			return 0, err
 		}
 
-		wg.Done()
-	}()
+		v.URN = urn
+		// every source which reported host is saved as its sighting
+		v.Sightings = blacklistEntities.MergeSightings(v.Sightings, blacklistEntities.NewBlacklistSighting(v.SourceID, v.ImportEventID, v.Description, v.DiscoveredAt))
 
-	go func() {
-		var emails = make([]blacklistEntities.BlacklistedEmail, 0, len(emailMap))
-		for _, v := range emailMap {
-			v.ImportEventID = &event.ID
-			emails = append(emails, *v)
+		// domain reported by several sources is saved once with all sightings
+		if i, ok := unique[v.URN]; ok {
+			canonical[i].IncludeSubdomains = canonical[i].IncludeSubdomains || v.IncludeSubdomains
+			canonical[i].Sightings = blacklistEntities.MergeSightings(canonical[i].Sightings, v.Sightings...)
+			canonical[i].Tags = append(canonical[i].Tags, v.Tags...)
+			continue
 		}
 
-		rows, err := s.SaveEmails(emails)
-		if err != nil {
-			slog.Warn("failed to save emails: " + err.Error())
-			summary.Errored += len(emails)
-		} else {
-			summary.Imported.Emails += rows
-		}
+		unique[v.URN] = len(canonical)
+		canonical = append(canonical, v)
+	}
 
-		wg.Done()
-	}()
+	policies, err := s.ttlPolicies()
+	if err != nil {
+		return 0, err
+	}
 
-	wg.Wait()
+	// hosts without explicit expiration date expire by TTL policy, unknown threat attributes are dropped
+	now := time.Now()
+	for i, v := range canonical {
+		if v.ValidUntil == nil {
+			canonical[i].ValidUntil = policies.ValidUntil(v.SourceID, "domain", now)
+		}
 
-	// 2. select all inserted records with event ID
-	summary.Imported.Total = summary.Imported.IPs + summary.Imported.Domains + summary.Imported.URLs + summary.Imported.Emails
-	hosts, err := s.RetrieveHostsByFilter(blacklistEntities.BlacklistSearchFilter{
-		Limit:         int(summary.Imported.Total),
-		ImportEventID: event.ID,
-	})
+		canonical[i].ThreatAttributes = v.ThreatAttributes.Normalize()
+	}
 
+	allowlist, err := s.allowlist()
 	if err != nil {
-		return blacklistEntities.BlacklistImportEvent{}, nil
+		return 0, err
 	}
 
-	// count by type
-	for _, h := range hosts {
-		switch h.Type {
-		case "ip":
-			summary.New.IPs++
-		case "url":
-			summary.New.URLs++
-		case "domain":
-			summary.New.Domains++
-		case "email":
-			summary.New.Emails++
-		}
+	// allowlisted hosts are never saved
+	canonical = withoutAllowlisted(canonical, func(v blacklistEntities.BlacklistedDomain) bool {
+		_, ok := allowlist.MatchDomain(v.URN, v.IncludeSubdomains)
+		return ok
+	})
+
+	if len(canonical) == 0 {
+		return 0, nil
 	}
 
-	summary.New.Total = summary.New.IPs + summary.New.Domains + summary.New.URLs + summary.New.Emails
+	// tags parsed from imported data are created if missing
+	tags := make([]*[]blacklistEntities.BlacklistTag, len(canonical))
+	for i := range canonical {
+		tags[i] = &canonical[i].Tags
+	}
 
-	// saving import event updates
-	event.Summary = datatypes.NewJSONType(summary)
-	event.IsComplete = true
+	err = s.resolveTags(tags)
+	if err != nil {
+		return 0, err
+	}
 
-	return s.SaveImportEvent(event)
+	return s.repo.SaveDomains(canonical, userID)
 }
 
-func (s *BlackListsServiceImpl) ImportFromCSV(data [][]string, discoveredAt time.Time, extractAll bool) (blacklistEntities.BlacklistImportEvent, error) {
-	var ipMap = make(map[string]*blacklistEntities.BlacklistedIP)
-	var domainMap = make(map[string]*blacklistEntities.BlacklistedDomain)
-	var urlMap = make(map[string]*blacklistEntities.BlacklistedURL)
-	var emailMap = make(map[string]*blacklistEntities.BlacklistedEmail)
+func (s *BlackListsServiceImpl) DeleteDomain(uuid pgtype.UUID, userID *uint64) (int64, error) {
+	return s.repo.DeleteDomain(uuid, userID)
+}
 
-	var summary = blacklistEntities.BlacklistImportEventSummary{}
-	event, err := s.SaveImportEvent(blacklistEntities.BlacklistImportEvent{
-		Type:       "csv",
-		IsComplete: false,
-		CreatedAt:  time.Now(),
-	})
+func (s *BlackListsServiceImpl) RetrieveHostsByFilter(filter blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedHost, error) {
+	hosts, err := s.repo.SelectHostsUnionByFilter(filter)
+	if err != nil {
+		return nil, err
+	}
 
+	err = s.fillHostsTags(hosts)
 	if err != nil {
-		return blacklistEntities.BlacklistImportEvent{}, err
-	} else if event.ID == 0 {
-		return blacklistEntities.BlacklistImportEvent{}, errors.New("import event was not created")
+		return nil, err
 	}
 
-	var headerIndexes = struct {
-		TypeIOC   int
-		Value     int
-		Source    int
-		FirstSeen int
-		Comment   int
-	}{
-		TypeIOC:   slices.Index(data[0], "Type_IOC"),
-		Value:     slices.Index(data[0], "Value"),
-		Source:    slices.Index(data[0], "Source"),
-		FirstSeen: slices.Index(data[0], "First Seen"),
-		Comment:   slices.Index(data[0], "Comment"),
+	err = s.fillHostsSightings(hosts)
+	if err != nil {
+		return nil, err
 	}
 
-	// read all lines, remove header
-	for _, row := range data[1:] {
-		var IoCType string
-		if headerIndexes.TypeIOC != -1 {
-			IoCType = strings.ToLower(row[headerIndexes.TypeIOC])
-		} else {
-			slog.Warn("value not defined")
-			continue
-		}
+	return hosts, nil
+}
 
-		var value string
-		if headerIndexes.Value != -1 {
-			value = row[headerIndexes.Value]
-		} else {
-			slog.Warn("value not defined")
-			continue
-		}
+func (s *BlackListsServiceImpl) RetrieveEmailsByFilter(filter blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedEmail, error) {
+	return s.repo.SelectEmailsByFilter(filter)
+}
 
-		var source uint64
-		if headerIndexes.Source != -1 {
-			switch row[headerIndexes.Source] {
-			case "Vendor-Kaspersky":
-				source = blacklistEntities.SourceKaspersky
-			case "Vendor-DRWEB":
-				source = blacklistEntities.SourceDrWeb
-			case "FinCERT", "Vendor":
-				source = blacklistEntities.SourceFinCERT
-			default:
-				source = blacklistEntities.SourceUnknown
-			}
-		} else {
-			source = blacklistEntities.SourceUnknown
-		}
+func (s *BlackListsServiceImpl) SaveEmails(emails []blacklistEntities.BlacklistedEmail, userID *uint64) (int64, error) {
+	if len(emails) == 0 {
+		return 0, nil
+	}
 
-		var comment string
-		if headerIndexes.Comment != -1 {
-			comment = strings.Trim(row[headerIndexes.Comment], "\"")
-		}
+	// email reported by several sources is saved once with all sightings
+	var unique = make(map[string]int)
+	var merged = make([]blacklistEntities.BlacklistedEmail, 0, len(emails))
 
-		var discoveryDate time.Time
-		if headerIndexes.FirstSeen != -1 {
-			var err error
+	for _, v := range emails {
+		// every source which reported host is saved as its sighting
+		v.Sightings = blacklistEntities.MergeSightings(v.Sightings, blacklistEntities.NewBlacklistSighting(v.SourceID, v.ImportEventID, v.Description, v.DiscoveredAt))
 
-			discoveryDate, err = time.Parse("02.01.2006", row[headerIndexes.FirstSeen])
-			if err != nil {
-				discoveryDate = discoveredAt
-			}
-		} else {
-			discoveryDate = discoveredAt
+		key := v.Email
+		if i, ok := unique[key]; ok {
+			merged[i].Sightings = blacklistEntities.MergeSightings(merged[i].Sightings, v.Sightings...)
+			merged[i].Tags = append(merged[i].Tags, v.Tags...)
+			continue
 		}
 
-		switch IoCType {
-		case "domain":
-			domainMap[value] = &blacklistEntities.BlacklistedDomain{
-				URN:           value,
-				Description:   comment,
-				SourceID:      source,
-				ImportEventID: &event.ID,
-				DiscoveredAt:  discoveryDate,
-			}
-		case "email":
-			emailMap[value] = &blacklistEntities.BlacklistedEmail{
-				Email:         value,
-				Description:   comment,
-				SourceID:      source,
-				ImportEventID: &event.ID,
-				DiscoveredAt:  discoveryDate,
-			}
-
-			if !extractAll {
-				break
-			}
-
-			p := strings.Split("@", value)
-			if len(p) > 1 {
-				domain := p[len(p)]
-				if len(domain) > 0 {
-					domainMap[domain] = &blacklistEntities.BlacklistedDomain{
-						URN:           domain,
-						Description:   comment,
-						SourceID:      source,
-						ImportEventID: &event.ID,
-						DiscoveredAt:  discoveryDate,
-					}
-				}
-			}
-		case "url":
-			urlMap[value] = &blacklistEntities.BlacklistedURL{
-				URL:           value,
-				Description:   comment,
-				SourceID:      source,
-				ImportEventID: &event.ID,
-				DiscoveredAt:  discoveryDate,
-			}
-
-			if !extractAll {
-				break
-			}
-
-			// find IP address in URL or extract domain name
-			p := blacklistEntities.ExtractIPFromPattern(value)
-			if len(p) > 0 {
-				ip := pgtype.Inet{}
-				err = ip.Set(p)
-				if err == nil {
-					ipMap[ip.IPNet.String()] = &blacklistEntities.BlacklistedIP{
-						IPAddress:     ip,
-						Description:   comment,
-						SourceID:      source,
-						ImportEventID: &event.ID,
-						DiscoveredAt:  discoveryDate,
-					}
-				}
-			} else {
-				d := value
-				if !strings.Contains(value, "http") {
-					d = "//" + value
-				}
+		unique[key] = len(merged)
+		merged = append(merged, v)
+	}
 
-				domain, err := url.Parse(d)
-				if err == nil {
-					domainMap[domain.Hostname()] = &blacklistEntities.BlacklistedDomain{
-						URN:           value,
-						Description:   comment,
-						SourceID:      source,
-						ImportEventID: &event.ID,
-						DiscoveredAt:  discoveryDate,
-					}
-				}
-			}
-		case "ip", "ip-addres", "ip-address":
-			ip := pgtype.Inet{}
-			err := ip.Set(value)
-			if err != nil {
-				continue
-			}
+	emails = merged
 
-			ipMap[ip.IPNet.String()] = &blacklistEntities.BlacklistedIP{
-				IPAddress:     ip,
-				Description:   comment,
-				SourceID:      source,
-				ImportEventID: &event.ID,
-				DiscoveredAt:  discoveryDate,
-			}
-		default:
-			summary.Skipped += 1 // sha values skipped
-		}
+	policies, err := s.ttlPolicies()
+	if err != nil {
+		return 0, err
 	}
 
-	// async saving of all host types
-	wg := sync.WaitGroup{}
-	wg.Add(4)
-
-	go func() {
-		var ips = make([]blacklistEntities.BlacklistedIP, 0, len(ipMap))
-		for _, v := range ipMap {
-			ips = append(ips, *v)
+	// hosts without explicit expiration date expire by TTL policy, unknown threat attributes are dropped
+	now := time.Now()
+	for i, v := range emails {
+		if v.ValidUntil == nil {
+			emails[i].ValidUntil = policies.ValidUntil(v.SourceID, "email", now)
 		}
 
-		rows, err := s.SaveIPs(ips)
-		if err != nil {
-			slog.Warn("failed to save IP addresses: " + err.Error())
-			summary.Errored += len(ips)
-		} else {
-			summary.Imported.IPs += rows
-		}
+		emails[i].ThreatAttributes = v.ThreatAttributes.Normalize()
+	}
 
-		wg.Done()
-	}()
+	allowlist, err := s.allowlist()
+	if err != nil {
+		return 0, err
+	}
 
-	go func() {
-		var urls = make([]blacklistEntities.BlacklistedURL, 0, len(urlMap))
-		for _, v := range urlMap {
-			urls = append(urls, *v)
-		}
+	// allowlisted hosts are never saved
+	emails = withoutAllowlisted(emails, func(v blacklistEntities.BlacklistedEmail) bool {
+		_, ok := allowlist.MatchEmail(v.Email)
+		return ok
+	})
 
-		rows, err := s.SaveURLs(urls)
-		if err != nil {
-			slog.Warn("failed to save URLs: " + err.Error())
-			summary.Errored += len(urls)
-		} else {
-			summary.Imported.URLs += rows
-		}
+	if len(emails) == 0 {
+		return 0, nil
+	}
 
-		wg.Done()
-	}()
+	// tags parsed from imported data are created if missing
+	tags := make([]*[]blacklistEntities.BlacklistTag, len(emails))
+	for i := range emails {
+		tags[i] = &emails[i].Tags
+	}
 
-	go func() {
-		var domains = make([]blacklistEntities.BlacklistedDomain, 0, len(domainMap))
-		for _, v := range domainMap {
-			domains = append(domains, *v)
-		}
+	err = s.resolveTags(tags)
+	if err != nil {
+		return 0, err
+	}
 
-		rows, err := s.SaveDomains(domains)
-		if err != nil {
-			slog.Warn("failed to save domains: " + err.Error())
-			summary.Errored += len(domains)
-		} else {
-			summary.Imported.Domains += rows
-		}
+	return s.repo.SaveEmails(emails, userID)
+}
 
-		wg.Done()
-	}()
+func (s *BlackListsServiceImpl) DeleteEmail(uuid pgtype.UUID, userID *uint64) (int64, error) {
+	return s.repo.DeleteEmail(uuid, userID)
+}
 
-	go func() {
-		var emails = make([]blacklistEntities.BlacklistedEmail, 0, len(emailMap))
-		for _, v := range emailMap {
-			emails = append(emails, *v)
-		}
+func (s *BlackListsServiceImpl) SaveImportEvent(event blacklistEntities.BlacklistImportEvent) (blacklistEntities.BlacklistImportEvent, error) {
+	return s.repo.SaveImportEvent(event)
+}
 
-		rows, err := s.SaveEmails(emails)
-		if err != nil {
-			slog.Warn("failed to save emails: " + err.Error())
-			summary.Errored += len(emails)
-		} else {
-			summary.Imported.Emails += rows
-		}
+func (s *BlackListsServiceImpl) RetrieveImportEventsByFilter(filter blacklistEntities.BlacklistImportEventFilter) ([]blacklistEntities.BlacklistImportEvent, error) {
+	return s.repo.SelectImportEventsByFilter(filter)
+}
 
-		wg.Done()
-	}()
+func (s *BlackListsServiceImpl) RetrieveImportEvent(id uint64) (blacklistEntities.BlacklistImportEvent, error) {
+	return s.repo.SelectImportEvent(id)
+}
 
-	wg.Wait()
+func (s *BlackListsServiceImpl) DeleteImportEvent(id uint64) (int64, error) {
+	return s.repo.DeleteImportEvent(id)
+}
 
-	// saving import event updates
-	summary.Imported.Total = summary.Imported.IPs + summary.Imported.Domains + summary.Imported.URLs + summary.Imported.Emails
+func (s *BlackListsServiceImpl) RetrieveImportErrors(eventID uint64, filter blacklistEntities.BlacklistImportErrorFilter) ([]blacklistEntities.BlacklistImportError, error) {
+	return s.repo.SelectImportErrors(eventID, filter)
+}
 
-	event.Summary = datatypes.NewJSONType(summary)
-	event.IsComplete = true
+func (s *BlackListsServiceImpl) RetrieveAllImportProfiles() ([]blacklistEntities.BlacklistImportProfile, error) {
+	return s.repo.SelectAllImportProfiles()
+}
 
-	return s.SaveImportEvent(event)
+func (s *BlackListsServiceImpl) RetrieveImportProfile(id uint64) (blacklistEntities.BlacklistImportProfile, error) {
+	return s.repo.SelectImportProfile(id)
 }
 
-func (s *BlackListsServiceImpl) ExportToJSON(filter blacklistEntities.BlacklistSearchFilter) ([]byte, error) {
-	hosts, err := s.repo.SelectHostsUnionByFilter(filter)
-	if err != nil {
-		return nil, err
+// SaveImportProfile creates new CSV import profile or updates existing one
+func (s *BlackListsServiceImpl) SaveImportProfile(profile blacklistEntities.BlacklistImportProfile) (blacklistEntities.BlacklistImportProfile, error) {
+	if len(profile.Delimiter) == 0 {
+		profile.Delimiter = ","
 	}
 
-	bytes_, err := json.Marshal(hosts)
+	err := profile.Validate()
 	if err != nil {
-		return nil, err
+		return blacklistEntities.BlacklistImportProfile{}, err
 	}
 
-	return bytes_, nil
+	return s.repo.SaveImportProfile(profile)
 }
 
-func (s *BlackListsServiceImpl) ExportToCSV(filter blacklistEntities.BlacklistSearchFilter) ([]byte, error) {
-	hosts, err := s.repo.SelectHostsUnionByFilter(filter)
+func (s *BlackListsServiceImpl) DeleteImportProfile(id uint64) (int64, error) {
+	return s.repo.DeleteImportProfile(id)
+}
 
+// ExportImportErrorsToCSV exports import error log. Type and value columns are named same as in FinCERT CSV,
+// so fixed file can be imported again.
+func (s *BlackListsServiceImpl) ExportImportErrorsToCSV(eventID uint64) ([]byte, error) {
+	importErrors, err := s.repo.SelectImportErrors(eventID, blacklistEntities.BlacklistImportErrorFilter{})
 	if err != nil {
 		return nil, err
 	}
 
 	var lines [][]string
 
-	lines = append(lines, []string{"UUID", "Type", "Identity", "Source", "CreatedAt", "UpdatedAt"})
+	lines = append(lines, []string{"Location", "Type_IOC", "Value", "Reason"})
 
-	for _, v := range hosts {
-		lines = append(lines, []string{fmt.Sprintf("%x", v.UUID.Bytes), v.Type, v.Host, v.Source.Name, v.CreatedAt.Format("02.01.2006"), v.UpdatedAt.Format("02.01.2006")})
+	for _, v := range importErrors {
+		lines = append(lines, []string{v.Location, v.Type, v.Value, v.Reason})
 	}
 
 	var buf bytes.Buffer
@@ -585,6 +471,910 @@ func (s *BlackListsServiceImpl) ExportToCSV(filter blacklistEntities.BlacklistSe
 	return buf.Bytes(), nil
 }
 
+// ImportFromSTIX2 imports indicators from STIX 2.0 bundles. If sourceID defined, overrides sources found in indicators.
+func (s *BlackListsServiceImpl) ImportFromSTIX2(bundles []blacklistEntities.STIX2Bundle, extractAll bool, sourceID uint64) (blacklistEntities.BlacklistImportEvent, error) {
+	return s.runImportJob(blacklistEntities.BlacklistImportJob{
+		Type:         blacklistEntities.ImportJobSTIX,
+		SourceID:     sourceID,
+		ExtractAll:   extractAll,
+		STIX2Bundles: bundles,
+	})
+}
+
+// ImportFromCSV imports indicators from FinCERT CSV file. If sourceID defined, overrides sources found in file.
+func (s *BlackListsServiceImpl) ImportFromCSV(data [][]string, discoveredAt time.Time, extractAll bool, sourceID uint64) (blacklistEntities.BlacklistImportEvent, error) {
+	return s.runImportJob(blacklistEntities.BlacklistImportJob{
+		Type:         blacklistEntities.ImportJobCSV,
+		SourceID:     sourceID,
+		ExtractAll:   extractAll,
+		DiscoveredAt: discoveredAt,
+		CSV:          data,
+	})
+}
+
+// ImportFromMISP imports indicators from MISP events. Only attributes marked with to_ids flag are imported.
+// If sourceID not defined, all hosts are attributed to unknown source.
+func (s *BlackListsServiceImpl) ImportFromMISP(events []blacklistEntities.MISPEvent, extractAll bool, sourceID uint64) (blacklistEntities.BlacklistImportEvent, error) {
+	return s.runImportJob(blacklistEntities.BlacklistImportJob{
+		Type:       blacklistEntities.ImportJobMISP,
+		SourceID:   sourceID,
+		ExtractAll: extractAll,
+		MISPEvents: events,
+	})
+}
+
+// parsedHosts contains hosts parsed from imported data, deduplicated by value, and values that can not be imported
+type parsedHosts struct {
+	ips     map[string]*blacklistEntities.BlacklistedIP
+	domains map[string]*blacklistEntities.BlacklistedDomain
+	urls    map[string]*blacklistEntities.BlacklistedURL
+	emails  map[string]*blacklistEntities.BlacklistedEmail
+
+	skipped int64
+	invalid []blacklistEntities.BlacklistImportError
+	// allowlisted contains hosts rejected by allowlist
+	allowlisted []blacklistEntities.BlacklistImportError
+}
+
+func newParsedHosts() *parsedHosts {
+	return &parsedHosts{
+		ips:     make(map[string]*blacklistEntities.BlacklistedIP),
+		domains: make(map[string]*blacklistEntities.BlacklistedDomain),
+		urls:    make(map[string]*blacklistEntities.BlacklistedURL),
+		emails:  make(map[string]*blacklistEntities.BlacklistedEmail),
+	}
+}
+
+func (p *parsedHosts) addInvalid(location, type_, value, reason string) {
+	p.invalid = append(p.invalid, blacklistEntities.BlacklistImportError{
+		Location: location,
+		Type:     type_,
+		Value:    value,
+		Reason:   reason,
+	})
+}
+
+// addDomain adds parsed domain. Wildcard patterns are saved as domains with all subdomains, public suffixes are rejected.
+func (p *parsedHosts) addDomain(location string, d *blacklistEntities.BlacklistedDomain) {
+	urn, includeSubdomains, err := blacklistEntities.ParseDomainPattern(d.URN)
+	if err != nil {
+		p.addInvalid(location, "domain", d.URN, err.Error())
+		return
+	}
+
+	if len(d.OriginalURN) == 0 {
+		d.OriginalURN = d.URN
+	}
+
+	d.URN = urn
+	d.IncludeSubdomains = d.IncludeSubdomains || includeSubdomains
+
+	d.Sightings = withParsedSighting(d.Sightings, d.SourceID, d.Description, d.DiscoveredAt)
+
+	if existing, ok := p.domains[urn]; ok {
+		existing.IncludeSubdomains = existing.IncludeSubdomains || d.IncludeSubdomains
+		existing.Sightings = blacklistEntities.MergeSightings(existing.Sightings, d.Sightings...)
+		existing.Tags = append(existing.Tags, d.Tags...)
+		return
+	}
+
+	p.domains[urn] = d
+}
+
+// addIP adds parsed IP address or network. Address reported by several sources is imported once with all sightings.
+func (p *parsedHosts) addIP(i *blacklistEntities.BlacklistedIP) {
+	key := i.IPAddress.IPNet.String()
+	i.Sightings = withParsedSighting(i.Sightings, i.SourceID, i.Description, i.DiscoveredAt)
+
+	if existing, ok := p.ips[key]; ok {
+		existing.Sightings = blacklistEntities.MergeSightings(existing.Sightings, i.Sightings...)
+		existing.Tags = append(existing.Tags, i.Tags...)
+		return
+	}
+
+	p.ips[key] = i
+}
+
+// addEmail adds parsed email. Email reported by several sources is imported once with all sightings.
+func (p *parsedHosts) addEmail(e *blacklistEntities.BlacklistedEmail) {
+	e.Sightings = withParsedSighting(e.Sightings, e.SourceID, e.Description, e.DiscoveredAt)
+
+	if existing, ok := p.emails[e.Email]; ok {
+		existing.Sightings = blacklistEntities.MergeSightings(existing.Sightings, e.Sightings...)
+		existing.Tags = append(existing.Tags, e.Tags...)
+		return
+	}
+
+	p.emails[e.Email] = e
+}
+
+// withParsedSighting adds sighting of source which reported parsed host, import event is linked when import starts
+func withParsedSighting(sightings []blacklistEntities.BlacklistSighting, sourceID uint64, description string, discoveredAt time.Time) []blacklistEntities.BlacklistSighting {
+	return blacklistEntities.MergeSightings(sightings, blacklistEntities.NewBlacklistSighting(sourceID, nil, description, discoveredAt))
+}
+
+// addURL adds parsed URL in canonical form, so same URL written differently is imported once. Original value is kept.
+func (p *parsedHosts) addURL(u *blacklistEntities.BlacklistedURL) {
+	if len(u.OriginalURL) == 0 {
+		u.OriginalURL = u.URL
+	}
+
+	u.URL = blacklistEntities.NormalizeURL(u.URL)
+	u.MD5 = blacklistEntities.URLHash(u.URL)
+	u.Sightings = withParsedSighting(u.Sightings, u.SourceID, u.Description, u.DiscoveredAt)
+
+	// URL reported by several sources is imported once with all sightings
+	if existing, ok := p.urls[u.URL]; ok {
+		existing.Sightings = blacklistEntities.MergeSightings(existing.Sightings, u.Sightings...)
+		existing.Tags = append(existing.Tags, u.Tags...)
+		return
+	}
+
+	p.urls[u.URL] = u
+}
+
+// parseSTIX2 parses indicators from STIX 2.0 bundles. If sourceID defined, overrides sources found in indicators.
+func parseSTIX2(bundles []blacklistEntities.STIX2Bundle, extractAll bool, sourceID uint64) *parsedHosts {
+	parsed := newParsedHosts()
+
+	for bIndex, b := range bundles {
+		for iIndex, object := range b.Objects {
+			if object.Type != "indicator" { // skip all other object types
+				parsed.skipped++
+				continue
+			}
+
+			i, d, u, e, err := object.ToBlacklisted(extractAll)
+			if err != nil {
+				slog.Error(fmt.Sprintf("error in bundle #%d, value #%d; error: %s", bIndex, iIndex, err.Error()))
+				parsed.addInvalid(fmt.Sprintf("bundle #%d, object #%d", bIndex, iIndex), "", object.Pattern, err.Error())
+				continue
+			}
+
+			threat := object.ThreatAttributes()
+			tags := object.Tags()
+
+			if i != nil {
+				i.ThreatAttributes = threat
+				i.Tags = tags
+
+				if sourceID != 0 {
+					i.SourceID = sourceID
+				}
+
+				parsed.addIP(i)
+			}
+
+			if d != nil {
+				d.ThreatAttributes = threat
+				d.Tags = tags
+
+				if sourceID != 0 {
+					d.SourceID = sourceID
+				}
+
+				parsed.addDomain(fmt.Sprintf("bundle #%d, object #%d", bIndex, iIndex), d)
+			}
+
+			if u != nil {
+				u.ThreatAttributes = threat
+				u.Tags = tags
+
+				if sourceID != 0 {
+					u.SourceID = sourceID
+				}
+
+				parsed.addURL(u)
+			}
+
+			if e != nil {
+				e.ThreatAttributes = threat
+				e.Tags = tags
+
+				if sourceID != 0 {
+					e.SourceID = sourceID
+				}
+
+				parsed.addEmail(e)
+			}
+		}
+	}
+
+	return parsed
+}
+
+// parseCSV parses indicators from CSV file with columns described by import profile.
+// If sourceID defined, overrides sources found in file.
+func parseCSV(data [][]string, profile blacklistEntities.BlacklistImportProfile, discoveredAt time.Time, extractAll bool, sourceID uint64) (*parsedHosts, error) {
+	if len(data) == 0 {
+		return nil, errors.New("csv file is empty")
+	}
+
+	header := make([]string, len(data[0]))
+	for i, h := range data[0] {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(h, "\uFEFF")) // excel adds BOM to the first column
+	}
+
+	index := func(name string) int {
+		if len(name) == 0 {
+			return -1
+		}
+
+		return slices.Index(header, name)
+	}
+
+	var headerIndexes = struct {
+		Type         int
+		Value        int
+		Source       int
+		DiscoveredAt int
+		Description  int
+
+		Confidence     int
+		Severity       int
+		Category       int
+		KillChainPhase int
+	}{
+		Type:         index(profile.TypeColumn),
+		Value:        index(profile.ValueColumn),
+		Source:       index(profile.SourceColumn),
+		DiscoveredAt: index(profile.DiscoveredAtColumn),
+		Description:  index(profile.DescriptionColumn),
+
+		Confidence:     index(profile.ConfidenceColumn),
+		Severity:       index(profile.SeverityColumn),
+		Category:       index(profile.CategoryColumn),
+		KillChainPhase: index(profile.KillChainPhaseColumn),
+	}
+
+	if headerIndexes.Value == -1 {
+		return nil, fmt.Errorf("required csv column '%s' not found", profile.ValueColumn)
+	}
+
+	if len(profile.TypeColumn) > 0 && headerIndexes.Type == -1 {
+		return nil, fmt.Errorf("required csv column '%s' not found", profile.TypeColumn)
+	}
+
+	parsed := newParsedHosts()
+
+	// read all lines, remove header
+	for rIndex, row := range data[1:] {
+		location := fmt.Sprintf("row #%d", rIndex+2)
+
+		column := func(index int) string {
+			if index == -1 || index >= len(row) {
+				return ""
+			}
+
+			return row[index]
+		}
+
+		value := strings.TrimSpace(column(headerIndexes.Value))
+		typeValue := strings.TrimSpace(column(headerIndexes.Type))
+
+		if len(value) == 0 {
+			parsed.addInvalid(location, typeValue, value, "value not defined")
+			continue
+		}
+
+		var IoCType string
+		switch {
+		case len(typeValue) > 0:
+			IoCType = profile.HostType(typeValue)
+		case len(profile.DefaultType) > 0:
+			IoCType = profile.DefaultType
+		default:
+			IoCType = blacklistEntities.DetectHostType(value)
+		}
+
+		var source uint64
+		if sourceID != 0 {
+			source = sourceID
+		} else {
+			source = profile.Source(column(headerIndexes.Source))
+		}
+
+		comment := strings.Trim(column(headerIndexes.Description), "\"")
+
+		threat := profile.ThreatAttributes(
+			column(headerIndexes.Confidence),
+			column(headerIndexes.Severity),
+			column(headerIndexes.Category),
+			column(headerIndexes.KillChainPhase),
+		)
+
+		discoveryDate := discoveredAt
+		if len(profile.DateLayout) > 0 {
+			d, err := time.Parse(profile.DateLayout, strings.TrimSpace(column(headerIndexes.DiscoveredAt)))
+			if err == nil {
+				discoveryDate = d
+			}
+		}
+
+		switch IoCType {
+		case "domain":
+			parsed.addDomain(location, &blacklistEntities.BlacklistedDomain{
+				URN:              value,
+				Description:      comment,
+				ThreatAttributes: threat,
+				SourceID:         source,
+				DiscoveredAt:     discoveryDate,
+			})
+		case "email":
+			parsed.addEmail(&blacklistEntities.BlacklistedEmail{
+				Email:            value,
+				Description:      comment,
+				ThreatAttributes: threat,
+				SourceID:         source,
+				DiscoveredAt:     discoveryDate,
+			})
+
+			if !extractAll {
+				break
+			}
+
+			if i := strings.LastIndex(value, "@"); i != -1 && i < len(value)-1 {
+				domain := value[i+1:]
+
+				parsed.addDomain(location, &blacklistEntities.BlacklistedDomain{
+					URN:              domain,
+					Description:      comment,
+					ThreatAttributes: threat,
+					SourceID:         source,
+					DiscoveredAt:     discoveryDate,
+				})
+			}
+		case "url":
+			parsed.addURL(&blacklistEntities.BlacklistedURL{
+				URL:              value,
+				Description:      comment,
+				ThreatAttributes: threat,
+				SourceID:         source,
+				DiscoveredAt:     discoveryDate,
+			})
+
+			if !extractAll {
+				break
+			}
+
+			// extract IPv4 or IPv6 address or domain name from URL host
+			d := value
+			if !strings.Contains(value, "//") {
+				d = "//" + value
+			}
+
+			domain, err := url.Parse(d)
+			if err != nil || len(domain.Hostname()) == 0 {
+				break
+			}
+
+			if ip, err := blacklistEntities.ParseInet(domain.Hostname()); err == nil {
+				parsed.addIP(&blacklistEntities.BlacklistedIP{
+					IPAddress:        ip,
+					Description:      comment,
+					ThreatAttributes: threat,
+					SourceID:         source,
+					DiscoveredAt:     discoveryDate,
+				})
+			} else {
+				parsed.addDomain(location, &blacklistEntities.BlacklistedDomain{
+					URN:              domain.Hostname(),
+					Description:      comment,
+					ThreatAttributes: threat,
+					SourceID:         source,
+					DiscoveredAt:     discoveryDate,
+				})
+			}
+		case "ip":
+			ip, err := blacklistEntities.ParseInet(value)
+			if err != nil {
+				parsed.addInvalid(location, IoCType, value, "invalid IP address: "+err.Error())
+				continue
+			}
+
+			parsed.addIP(&blacklistEntities.BlacklistedIP{
+				IPAddress:        ip,
+				Description:      comment,
+				ThreatAttributes: threat,
+				SourceID:         source,
+				DiscoveredAt:     discoveryDate,
+			})
+		default:
+			parsed.skipped++ // sha values and unknown types skipped
+		}
+	}
+
+	return parsed, nil
+}
+
+// parseValues parses plain list of single hosts of any type. If sourceID not defined, all hosts are attributed to unknown source.
+func parseValues(values []string, discoveredAt time.Time, extractAll bool, sourceID uint64) *parsedHosts {
+	parsed := newParsedHosts()
+
+	if sourceID == 0 {
+		sourceID = blacklistEntities.SourceUnknown
+	}
+
+	for vIndex, v := range values {
+		value := strings.TrimSpace(v)
+		location := fmt.Sprintf("line #%d", vIndex+1)
+
+		// skip empty lines and comments
+		if len(value) == 0 || strings.HasPrefix(value, "#") {
+			continue
+		}
+
+		switch blacklistEntities.DetectHostType(value) {
+		case "ip":
+			ip, err := blacklistEntities.ParseInet(value)
+			if err != nil {
+				parsed.addInvalid(location, "ip", value, "invalid IP address: "+err.Error())
+				continue
+			}
+
+			parsed.addIP(&blacklistEntities.BlacklistedIP{
+				IPAddress:    ip,
+				SourceID:     sourceID,
+				DiscoveredAt: discoveredAt,
+			})
+		case "domain":
+			parsed.addDomain(location, &blacklistEntities.BlacklistedDomain{
+				URN:          value,
+				SourceID:     sourceID,
+				DiscoveredAt: discoveredAt,
+			})
+		case "email":
+			parsed.addEmail(&blacklistEntities.BlacklistedEmail{
+				Email:        value,
+				SourceID:     sourceID,
+				DiscoveredAt: discoveredAt,
+			})
+		case "url":
+			parsed.addURL(&blacklistEntities.BlacklistedURL{
+				URL:          value,
+				SourceID:     sourceID,
+				DiscoveredAt: discoveredAt,
+			})
+
+			if !extractAll {
+				break
+			}
+
+			u := value
+			if !strings.Contains(value, "//") {
+				u = "//" + value
+			}
+
+			parsedURL, err := url.Parse(u)
+			if err != nil || len(parsedURL.Hostname()) == 0 {
+				break
+			}
+
+			if ip, err := blacklistEntities.ParseInet(parsedURL.Hostname()); err == nil {
+				parsed.addIP(&blacklistEntities.BlacklistedIP{
+					IPAddress:    ip,
+					SourceID:     sourceID,
+					DiscoveredAt: discoveredAt,
+				})
+			} else {
+				parsed.addDomain(location, &blacklistEntities.BlacklistedDomain{
+					URN:          parsedURL.Hostname(),
+					SourceID:     sourceID,
+					DiscoveredAt: discoveredAt,
+				})
+			}
+		default:
+			parsed.addInvalid(location, "", value, "host type not detected")
+		}
+	}
+
+	return parsed
+}
+
+// parseMISP parses MISP events attributes. Only attributes marked with to_ids flag are imported.
+// If sourceID not defined, all hosts are attributed to unknown source.
+func parseMISP(events []blacklistEntities.MISPEvent, extractAll bool, sourceID uint64) *parsedHosts {
+	parsed := newParsedHosts()
+
+	if sourceID == 0 {
+		sourceID = blacklistEntities.SourceUnknown
+	}
+
+	for eIndex, e := range events {
+		for aIndex, a := range e.AllAttributes() {
+			if !bool(a.ToIDs) || bool(a.Deleted) || !slices.Contains(blacklistEntities.MISPAttributeTypes, a.Type) {
+				parsed.skipped++
+				continue
+			}
+
+			i, d, u, em, err := a.ToBlacklisted(e, extractAll, sourceID)
+			if err != nil {
+				slog.Error(fmt.Sprintf("error in event #%d, attribute #%d; error: %s", eIndex, aIndex, err.Error()))
+				parsed.addInvalid(fmt.Sprintf("event #%d, attribute #%d", eIndex, aIndex), a.Type, a.Value, err.Error())
+				continue
+			}
+
+			tags := a.Tags(e)
+
+			if i != nil {
+				i.Tags = tags
+				parsed.addIP(i)
+			}
+
+			if d != nil {
+				d.Tags = tags
+				parsed.addDomain(fmt.Sprintf("event #%d, attribute #%d", eIndex, aIndex), d)
+			}
+
+			if u != nil {
+				u.Tags = tags
+				parsed.addURL(u)
+			}
+
+			if em != nil {
+				em.Tags = tags
+				parsed.addEmail(em)
+			}
+		}
+	}
+
+	return parsed
+}
+
+// createImportEvent creates new incomplete import event. If sourceID defined, event is attributed to source.
+func (s *BlackListsServiceImpl) createImportEvent(type_ string, sourceID uint64, userID *uint64, feedID *uint64) (blacklistEntities.BlacklistImportEvent, error) {
+	event := blacklistEntities.BlacklistImportEvent{
+		Type:        type_,
+		IsComplete:  false,
+		CreatedByID: userID,
+		FeedID:      feedID,
+		CreatedAt:   time.Now(),
+	}
+
+	if sourceID != 0 {
+		event.SourceID = &sourceID
+	}
+
+	event, err := s.SaveImportEvent(event)
+	if err != nil {
+		return blacklistEntities.BlacklistImportEvent{}, err
+	} else if event.ID == 0 {
+		return blacklistEntities.BlacklistImportEvent{}, errors.New("import event was not created")
+	}
+
+	return event, nil
+}
+
+func (s *BlackListsServiceImpl) ExportToJSON(filter blacklistEntities.BlacklistSearchFilter) ([]byte, error) {
+	hosts, err := s.repo.SelectHostsUnionByFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.fillHostsTags(hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.fillHostsSightings(hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	bytes_, err := json.Marshal(hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes_, nil
+}
+
+func (s *BlackListsServiceImpl) ExportToCSV(filter blacklistEntities.BlacklistSearchFilter) ([]byte, error) {
+	hosts, err := s.repo.SelectHostsUnionByFilter(filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.fillHostsSightings(hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines [][]string
+
+	lines = append(lines, []string{"UUID", "Type", "Identity", "Sources", "CreatedAt", "UpdatedAt"})
+
+	for _, v := range hosts {
+		lines = append(lines, []string{fmt.Sprintf("%x", v.UUID.Bytes), v.Type, v.Host, strings.Join(v.SourceNames(), ", "), v.CreatedAt.Format("02.01.2006"), v.UpdatedAt.Format("02.01.2006")})
+	}
+
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+	err = w.WriteAll(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ExportToXLSX returns workbook with sheet for every host type, dates are written as date cells
+func (s *BlackListsServiceImpl) ExportToXLSX(filter blacklistEntities.BlacklistSearchFilter) ([]byte, error) {
+	hosts, err := s.repo.SelectHostsUnionByFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// hosts union does not include sources, so they are loaded with sightings
+	err = s.fillHostsSightings(hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	sheets := []xlsx.Sheet{
+		{Name: "IPs"},
+		{Name: "Domains"},
+		{Name: "URLs"},
+		{Name: "Emails"},
+	}
+
+	sheetIndexes := map[string]int{"ip": 0, "domain": 1, "url": 2, "email": 3}
+
+	for i := range sheets {
+		sheets[i].Header = []string{"UUID", "Identity", "Sources", "Description", "Confidence", "Severity", "Categories", "KillChainPhase", "DiscoveredAt", "CreatedAt", "UpdatedAt"}
+	}
+
+	for _, v := range hosts {
+		i, ok := sheetIndexes[v.Type]
+		if !ok {
+			continue
+		}
+
+		sheets[i].Rows = append(sheets[i].Rows, []any{fmt.Sprintf("%x", v.UUID.Bytes), v.Host, strings.Join(v.SourceNames(), ", "), v.Description, v.Confidence, v.Severity, strings.Join(v.Categories, ", "), v.KillChainPhase, v.DiscoveredAt, v.CreatedAt, v.UpdatedAt})
+	}
+
+	return xlsx.Write(sheets)
+}
+
+// newSTIX2Bundle creates STIX 2.1 bundle with identity object for every source, indicator for every host
+// and sighting for every source which reported host
+func newSTIX2Bundle(sources []blacklistEntities.BlacklistSource, hosts []blacklistEntities.BlacklistedHost) blacklistEntities.STIX2Bundle {
+	bundle := blacklistEntities.STIX2Bundle{
+		Type:    "bundle",
+		ID:      "bundle--" + uuid.New().String(),
+		Objects: make([]blacklistEntities.STIX2Object, 0, len(sources)+len(hosts)),
+	}
+
+	for _, source := range sources {
+		bundle.Objects = append(bundle.Objects, blacklistEntities.NewSTIX2Identity(source))
+	}
+
+	for _, h := range hosts {
+		indicator, err := blacklistEntities.NewSTIX2Indicator(h)
+		if err != nil {
+			slog.Warn("failed to export host to stix: " + err.Error())
+			continue
+		}
+
+		bundle.Objects = append(bundle.Objects, indicator)
+		bundle.Objects = append(bundle.Objects, blacklistEntities.NewSTIX2Sightings(h)...)
+	}
+
+	return bundle
+}
+
+// ExportToSTIX returns STIX 2.1 bundle with identity object for every blacklist source and indicator for every host
+func (s *BlackListsServiceImpl) ExportToSTIX(filter blacklistEntities.BlacklistSearchFilter) ([]byte, error) {
+	hosts, err := s.repo.SelectHostsUnionByFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.fillHostsTags(hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.fillHostsSightings(hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	sources, err := s.repo.SelectAllSources()
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := newSTIX2Bundle(sources, hosts)
+
+	bytes_, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes_, nil
+}
+
+// ExportToFormat returns hosts selected by filter, rendered for network devices or DNS servers in defined format
+func (s *BlackListsServiceImpl) ExportToFormat(format blacklistEntities.ExportFormat, filter blacklistEntities.BlacklistExportFilter) ([]byte, error) {
+	render, ok := exportRenderers[format]
+	if !ok {
+		return nil, fmt.Errorf("export format '%s' not supported", format)
+	}
+
+	hosts, err := s.repo.SelectHostsUnionByFilter(renderSearchFilter(filter))
+	if err != nil {
+		return nil, err
+	}
+
+	return render(hosts), nil
+}
+
+// deltaSafetyWindow holds delta back from current time. Hosts saved by transaction still in progress become visible
+// after commit with change time older than changes already exported, so they would be skipped by cursor moved to the
+// latest change. Changes within window are exported by later delta.
+const deltaSafetyWindow = 5 * time.Minute
+
+const (
+	deltaDefaultLimit = 10000
+	deltaMaxLimit     = 100000
+)
+
+func (s *BlackListsServiceImpl) ExportDelta(format blacklistEntities.ExportFormat, filter blacklistEntities.BlacklistDeltaFilter) ([]byte, string, bool, error) {
+	since := filter.Since
+
+	var afterUUID pgtype.UUID
+
+	if len(filter.Cursor) > 0 {
+		t, id, err := blacklistEntities.DecodeDeltaCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", false, err
+		}
+
+		since, afterUUID = &t, id
+	}
+
+	limit := deltaDefaultLimit
+	if filter.Limit > 0 {
+		limit = min(filter.Limit, deltaMaxLimit)
+	}
+
+	// removed hosts are selected only if cursor defined, first delta contains only active hosts
+	var isActive *bool
+	if since != nil {
+		isActive = new(bool)
+	}
+
+	horizon := time.Now().Add(-deltaSafetyWindow)
+
+	// one more host requested to find out if delta was cut by limit
+	hosts, err := s.repo.SelectHostsUnionByFilter(blacklistEntities.BlacklistSearchFilter{
+		Limit:            limit + 1,
+		SourceIDs:        filter.SourceIDs,
+		IsActive:         isActive,
+		UpdatedAfter:     since,
+		UpdatedAfterUUID: afterUUID,
+		UpdatedBefore:    &horizon,
+		SortByUpdated:    true,
+	})
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	delta := blacklistEntities.BlacklistDelta{
+		Since:   since,
+		Added:   make([]blacklistEntities.BlacklistedHost, 0),
+		Updated: make([]blacklistEntities.BlacklistedHost, 0),
+		Removed: make([]blacklistEntities.BlacklistedHost, 0),
+	}
+
+	if len(hosts) > limit {
+		hosts, delta.More = hosts[:limit], true
+	}
+
+	// cursor points to the last exported change. If nothing changed, cursor is not moved, first delta without changes
+	// points to safety window, as all hosts changed before it were exported
+	switch {
+	case len(hosts) > 0:
+		last := hosts[len(hosts)-1]
+		delta.Cursor = blacklistEntities.EncodeDeltaCursor(last.ChangedAt(), last.UUID)
+	case since != nil:
+		delta.Cursor = blacklistEntities.EncodeDeltaCursor(*since, afterUUID)
+	default:
+		delta.Cursor = blacklistEntities.EncodeDeltaCursor(horizon, pgtype.UUID{})
+	}
+
+	sources, err := s.repo.SelectAllSources()
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	setHostsSources(hosts, sources)
+
+	err = s.fillHostsTags(hosts)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	err = s.fillHostsSightings(hosts)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	for _, h := range hosts {
+		switch h.ChangeSince(since) {
+		case blacklistEntities.DeltaChangeAdded:
+			delta.Added = append(delta.Added, h)
+		case blacklistEntities.DeltaChangeUpdated:
+			delta.Updated = append(delta.Updated, h)
+		case blacklistEntities.DeltaChangeRemoved:
+			delta.Removed = append(delta.Removed, h)
+		}
+	}
+
+	var bytes_ []byte
+
+	switch format {
+	case blacklistEntities.ExportFormatJSON:
+		bytes_, err = json.Marshal(delta)
+	case blacklistEntities.ExportFormatCSV:
+		bytes_, err = deltaToCSV(hosts, since)
+	case blacklistEntities.ExportFormatSTIX:
+		// removed hosts are exported as revoked indicators
+		bytes_, err = json.Marshal(newSTIX2Bundle(sources, hosts))
+	default:
+		return nil, "", false, fmt.Errorf("delta export format '%s' not supported", format)
+	}
+
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	return bytes_, delta.Cursor, delta.More, nil
+}
+
+func deltaToCSV(hosts []blacklistEntities.BlacklistedHost, since *time.Time) ([]byte, error) {
+	var lines [][]string
+
+	lines = append(lines, []string{"UUID", "Type", "Identity", "Sources", "Change", "CreatedAt", "UpdatedAt", "DeletedAt"})
+
+	for _, v := range hosts {
+		var deletedAt string
+
+		if v.DeletedAt.Valid {
+			deletedAt = v.DeletedAt.Time.Format(time.RFC3339)
+		}
+
+		lines = append(lines, []string{fmt.Sprintf("%x", v.UUID.Bytes), v.Type, v.Host, strings.Join(v.SourceNames(), ", "), string(v.ChangeSince(since)), v.CreatedAt.Format(time.RFC3339), v.UpdatedAt.Format(time.RFC3339), deletedAt})
+	}
+
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+	err := w.WriteAll(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// setHostsSources sets source of every host by its source ID, as hosts union query fills only default sources
+func setHostsSources(hosts []blacklistEntities.BlacklistedHost, sources []blacklistEntities.BlacklistSource) {
+	var byID = make(map[uint64]*blacklistEntities.BlacklistSource, len(sources))
+	for i := range sources {
+		byID[sources[i].ID] = &sources[i]
+	}
+
+	for i, h := range hosts {
+		if source, ok := byID[h.SourceID]; ok {
+			hosts[i].Source = source
+		}
+	}
+}
+
 func (s *BlackListsServiceImpl) ExportToNaumen(filter blacklistEntities.BlacklistSearchFilter) (serviceDeskEntities.ServiceDeskTicket, error) {
 	if !s.desk.IsAvailable() {
 		return serviceDeskEntities.ServiceDeskTicket{}, errors.New("service desk not configured")
@@ -0,0 +1,1175 @@
+package services
+
+import (
+	"bytes"
+	"domain_threat_intelligence_api/cmd/core"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"domain_threat_intelligence_api/cmd/xlsx"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgtype"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeBlacklistsRepo keeps saved hosts and import events in memory
+type fakeBlacklistsRepo struct {
+	core.IBlacklistsRepo
+
+	events  []blacklistEntities.BlacklistImportEvent
+	ips     []blacklistEntities.BlacklistedIP
+	urls    []blacklistEntities.BlacklistedURL
+	domains []blacklistEntities.BlacklistedDomain
+	emails  []blacklistEntities.BlacklistedEmail
+
+	hosts   []blacklistEntities.BlacklistedHost
+	filters []blacklistEntities.BlacklistSearchFilter
+	sources []blacklistEntities.BlacklistSource
+
+	importErrors []blacklistEntities.BlacklistImportError
+	// saveIPsErr is returned on every save of IP addresses
+	saveIPsErr error
+
+	ttlPolicies []blacklistEntities.BlacklistTTLPolicy
+
+	allowlist []blacklistEntities.AllowlistEntry
+
+	tags     []blacklistEntities.BlacklistTag
+	hostTags map[[16]byte][]blacklistEntities.BlacklistTag
+	// tagged counts hosts linked with tags by host type
+	tagged map[string]int
+
+	hostSightings map[[16]byte][]blacklistEntities.BlacklistSighting
+
+	// restored keeps valid until dates of restored hosts by host UUID
+	restored map[[16]byte]*time.Time
+}
+
+func (r *fakeBlacklistsRepo) SelectHost(uuid pgtype.UUID) (blacklistEntities.BlacklistedHost, error) {
+	for _, h := range r.hosts {
+		if h.UUID == uuid {
+			return h, nil
+		}
+	}
+
+	return blacklistEntities.BlacklistedHost{}, nil
+}
+
+func (r *fakeBlacklistsRepo) RestoreHost(type_ string, uuid pgtype.UUID, validUntil *time.Time, userID *uint64) (int64, error) {
+	if r.restored == nil {
+		r.restored = make(map[[16]byte]*time.Time)
+	}
+
+	r.restored[uuid.Bytes] = validUntil
+	return 1, nil
+}
+
+func (r *fakeBlacklistsRepo) SelectHostsSightings(type_ string, uuids []pgtype.UUID) (map[[16]byte][]blacklistEntities.BlacklistSighting, error) {
+	var sightings = make(map[[16]byte][]blacklistEntities.BlacklistSighting)
+
+	for _, u := range uuids {
+		if s, ok := r.hostSightings[u.Bytes]; ok {
+			sightings[u.Bytes] = s
+		}
+	}
+
+	return sightings, nil
+}
+
+// SaveTagsByKey creates missing tags, identified by kind and name
+func (r *fakeBlacklistsRepo) SaveTagsByKey(tags []blacklistEntities.BlacklistTag) ([]blacklistEntities.BlacklistTag, error) {
+	var saved []blacklistEntities.BlacklistTag
+
+	for _, t := range tags {
+		i := slices.IndexFunc(r.tags, func(v blacklistEntities.BlacklistTag) bool { return v.Key() == t.Key() })
+		if i == -1 {
+			t.ID = uint64(len(r.tags) + 1)
+			r.tags = append(r.tags, t)
+			i = len(r.tags) - 1
+		}
+
+		saved = append(saved, r.tags[i])
+	}
+
+	return saved, nil
+}
+
+func (r *fakeBlacklistsRepo) SaveHostTags(type_ string, uuids []pgtype.UUID, tagIDs []uint64) (int64, error) {
+	if r.tagged == nil {
+		r.tagged = make(map[string]int)
+	}
+
+	r.tagged[type_] += len(uuids)
+	return int64(len(uuids) * len(tagIDs)), nil
+}
+
+func (r *fakeBlacklistsRepo) SelectHostsTags(type_ string, uuids []pgtype.UUID) (map[[16]byte][]blacklistEntities.BlacklistTag, error) {
+	var tags = make(map[[16]byte][]blacklistEntities.BlacklistTag)
+
+	for _, u := range uuids {
+		if t, ok := r.hostTags[u.Bytes]; ok {
+			tags[u.Bytes] = t
+		}
+	}
+
+	return tags, nil
+}
+
+func (r *fakeBlacklistsRepo) SelectAllowlist() ([]blacklistEntities.AllowlistEntry, error) {
+	return r.allowlist, nil
+}
+
+func (r *fakeBlacklistsRepo) SaveAllowlistEntry(entry blacklistEntities.AllowlistEntry) (blacklistEntities.AllowlistEntry, error) {
+	entry.ID = uint64(len(r.allowlist) + 1)
+	r.allowlist = append(r.allowlist, entry)
+	return entry, nil
+}
+
+// SelectAllowlistConflicts returns all kept hosts as already blacklisted ones
+func (r *fakeBlacklistsRepo) SelectAllowlistConflicts(entry blacklistEntities.AllowlistEntry) ([]blacklistEntities.BlacklistedHost, error) {
+	return r.hosts, nil
+}
+
+func (r *fakeBlacklistsRepo) SelectAllTTLPolicies() ([]blacklistEntities.BlacklistTTLPolicy, error) {
+	return r.ttlPolicies, nil
+}
+
+func (r *fakeBlacklistsRepo) SelectHostsUnionByFilter(filter blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedHost, error) {
+	r.filters = append(r.filters, filter)
+	return r.hosts, nil
+}
+
+// SelectAllSources returns default sources, if sources are not defined
+func (r *fakeBlacklistsRepo) SelectAllSources() ([]blacklistEntities.BlacklistSource, error) {
+	if r.sources == nil {
+		return blacklistEntities.DefaultSources[:], nil
+	}
+
+	return r.sources, nil
+}
+
+// SelectHostsByValues selects hosts of defined type and values from hosts defined for union query
+func (r *fakeBlacklistsRepo) SelectHostsByValues(type_ string, values []string) ([]blacklistEntities.BlacklistedHost, error) {
+	var hosts []blacklistEntities.BlacklistedHost
+	for _, h := range r.hosts {
+		if h.Type == type_ && slices.Contains(values, h.Host) {
+			hosts = append(hosts, h)
+		}
+	}
+
+	return hosts, nil
+}
+
+// SelectIPsContainingAny selects saved networks containing any of addresses
+func (r *fakeBlacklistsRepo) SelectIPsContainingAny(addrs []string) ([]blacklistEntities.BlacklistedIP, error) {
+	var result []blacklistEntities.BlacklistedIP
+	for _, ip := range r.ips {
+		for _, addr := range addrs {
+			checked, err := blacklistEntities.ParseInet(addr)
+			if err == nil && ip.IPAddress.IPNet.Contains(checked.IPNet.IP) {
+				result = append(result, ip)
+				break
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (r *fakeBlacklistsRepo) SelectDomainsByURNs(urns []string) ([]blacklistEntities.BlacklistedDomain, error) {
+	var result []blacklistEntities.BlacklistedDomain
+	for _, d := range r.domains {
+		if slices.Contains(urns, d.URN) {
+			result = append(result, d)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *fakeBlacklistsRepo) SelectURLsByMD5(hashes []string) ([]blacklistEntities.BlacklistedURL, error) {
+	var result []blacklistEntities.BlacklistedURL
+	for _, u := range r.urls {
+		if slices.Contains(hashes, u.MD5) {
+			result = append(result, u)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *fakeBlacklistsRepo) SelectEmailsByValues(emails []string) ([]blacklistEntities.BlacklistedEmail, error) {
+	var result []blacklistEntities.BlacklistedEmail
+	for _, e := range r.emails {
+		if slices.Contains(emails, e.Email) {
+			result = append(result, e)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *fakeBlacklistsRepo) SaveImportEvent(event blacklistEntities.BlacklistImportEvent) (blacklistEntities.BlacklistImportEvent, error) {
+	if event.ID == 0 {
+		event.ID = uint64(len(r.events) + 1)
+		r.events = append(r.events, event)
+	} else {
+		r.events[event.ID-1] = event
+	}
+
+	return event, nil
+}
+
+func (r *fakeBlacklistsRepo) SaveImportErrors(importErrors []blacklistEntities.BlacklistImportError) error {
+	r.importErrors = append(r.importErrors, importErrors...)
+	return nil
+}
+
+func (r *fakeBlacklistsRepo) SelectImportErrors(eventID uint64, filter blacklistEntities.BlacklistImportErrorFilter) ([]blacklistEntities.BlacklistImportError, error) {
+	var result []blacklistEntities.BlacklistImportError
+	for _, e := range r.importErrors {
+		if e.ImportEventID == eventID {
+			result = append(result, e)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *fakeBlacklistsRepo) SaveIPs(ips []blacklistEntities.BlacklistedIP, userID *uint64) (int64, error) {
+	if r.saveIPsErr != nil {
+		return 0, r.saveIPsErr
+	}
+
+	r.ips = append(r.ips, ips...)
+	return int64(len(ips)), nil
+}
+
+func (r *fakeBlacklistsRepo) SaveURLs(urls []blacklistEntities.BlacklistedURL, userID *uint64) (int64, error) {
+	r.urls = append(r.urls, urls...)
+	return int64(len(urls)), nil
+}
+
+func (r *fakeBlacklistsRepo) SaveDomains(domains []blacklistEntities.BlacklistedDomain, userID *uint64) (int64, error) {
+	r.domains = append(r.domains, domains...)
+	return int64(len(domains)), nil
+}
+
+func (r *fakeBlacklistsRepo) SaveEmails(emails []blacklistEntities.BlacklistedEmail, userID *uint64) (int64, error) {
+	r.emails = append(r.emails, emails...)
+	return int64(len(emails)), nil
+}
+
+// savedHosts returns all saved hosts as sorted "type:value (source)" strings
+func (r *fakeBlacklistsRepo) savedHosts() []string {
+	var hosts []string
+
+	for _, v := range r.ips {
+		hosts = append(hosts, fmt.Sprintf("ip:%s (%d)", v.IPAddress.IPNet, v.SourceID))
+	}
+
+	for _, v := range r.domains {
+		hosts = append(hosts, fmt.Sprintf("domain:%s (%d)", v.URN, v.SourceID))
+	}
+
+	for _, v := range r.urls {
+		hosts = append(hosts, fmt.Sprintf("url:%s (%d)", v.URL, v.SourceID))
+	}
+
+	for _, v := range r.emails {
+		hosts = append(hosts, fmt.Sprintf("email:%s (%d)", v.Email, v.SourceID))
+	}
+
+	slices.Sort(hosts)
+	return hosts
+}
+
+func TestRunImportJobTxt(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     []string
+		extractAll bool
+		sourceID   uint64
+
+		want        []string
+		wantSkipped int64
+	}{
+		{
+			name:   "comments and empty lines",
+			values: []string{"# list of hosts", "", "  10.0.0.1  ", "evil.com", "user@evil.com", "http://evil.com/a"},
+			want:   []string{"domain:evil.com (5)", "email:user@evil.com (5)", "ip:10.0.0.1/32 (5)", "url:http://evil.com/a (5)"},
+		},
+		{
+			name:     "duplicates and source",
+			values:   []string{"evil.com", "evil.com", "10.0.0.0/24"},
+			sourceID: blacklistEntities.SourceDrWeb,
+			want:     []string{"domain:evil.com (4)", "ip:10.0.0.0/24 (4)"},
+		},
+		{
+			name:       "hosts extracted from urls",
+			values:     []string{"http://10.0.0.1/a", "https://evil.com/b"},
+			extractAll: true,
+			want:       []string{"domain:evil.com (5)", "ip:10.0.0.1/32 (5)", "url:http://10.0.0.1/a (5)", "url:https://evil.com/b (5)"},
+		},
+		{
+			name:        "undetected values",
+			values:      []string{"not a host", "10.0.0.1"},
+			want:        []string{"ip:10.0.0.1/32 (5)"},
+			wantSkipped: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeBlacklistsRepo{}
+
+			event, err := NewBlackListsServiceImpl(repo, nil).runImportJob(blacklistEntities.BlacklistImportJob{
+				Type:         blacklistEntities.ImportJobTxt,
+				SourceID:     tt.sourceID,
+				ExtractAll:   tt.extractAll,
+				DiscoveredAt: time.Now(),
+				Values:       tt.values,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := repo.savedHosts(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+
+			summary := event.Summary.Data()
+			if !event.IsComplete || summary.Imported.Total != int64(len(tt.want)) || summary.Skipped != tt.wantSkipped {
+				t.Errorf("unexpected import event: %+v", event)
+			}
+		})
+	}
+}
+
+func TestExportToSTIX(t *testing.T) {
+	repo := &fakeBlacklistsRepo{hosts: []blacklistEntities.BlacklistedHost{
+		{Type: "ip", Host: "10.0.0.1", SourceID: blacklistEntities.SourceKaspersky},
+		{Type: "domain", Host: "evil.com", SourceID: blacklistEntities.SourceDrWeb},
+		{Type: "ip", Host: "malformed"},
+	}}
+
+	data, err := NewBlackListsServiceImpl(repo, nil).ExportToSTIX(blacklistEntities.BlacklistSearchFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var bundle blacklistEntities.STIX2Bundle
+	if err = json.Unmarshal(data, &bundle); err != nil {
+		t.Fatal(err)
+	}
+
+	var identities, patterns []string
+	for _, o := range bundle.Objects {
+		switch o.Type {
+		case "identity":
+			identities = append(identities, o.Id)
+		case "indicator":
+			patterns = append(patterns, o.Pattern)
+		}
+	}
+
+	// every source is exported as identity, hosts which can not be exported are skipped
+	if len(identities) != len(blacklistEntities.DefaultSources) {
+		t.Errorf("got %d identities, want %d", len(identities), len(blacklistEntities.DefaultSources))
+	}
+
+	wantPatterns := []string{"[ipv4-addr:value = '10.0.0.1']", "[domain-name:value = 'evil.com']"}
+	if !reflect.DeepEqual(patterns, wantPatterns) {
+		t.Errorf("got patterns %v, want %v", patterns, wantPatterns)
+	}
+}
+
+// hostUUID returns host UUID with defined first byte
+func hostUUID(n byte) pgtype.UUID {
+	return pgtype.UUID{Bytes: [16]byte{n}, Status: pgtype.Present}
+}
+
+func TestExportDelta(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	repo := &fakeBlacklistsRepo{
+		sources: []blacklistEntities.BlacklistSource{{ID: 7, Name: "MISP"}},
+		hosts: []blacklistEntities.BlacklistedHost{
+			{UUID: hostUUID(1), Type: "domain", Host: "added.com", SourceID: 7, CreatedAt: since.Add(time.Hour), UpdatedAt: since.Add(time.Hour)},
+			{UUID: hostUUID(2), Type: "domain", Host: "updated.com", SourceID: 7, CreatedAt: since.Add(-time.Hour), UpdatedAt: since.Add(2 * time.Hour)},
+			{
+				UUID: hostUUID(3), Type: "domain", Host: "removed.com", SourceID: 7, CreatedAt: since.Add(-time.Hour), UpdatedAt: since.Add(-time.Hour),
+				DeletedAt: gorm.DeletedAt{Time: since.Add(3 * time.Hour), Valid: true},
+			},
+		},
+	}
+
+	s := NewBlackListsServiceImpl(repo, nil)
+
+	data, cursor, more, err := s.ExportDelta(blacklistEntities.ExportFormatJSON, blacklistEntities.BlacklistDeltaFilter{
+		Cursor:    blacklistEntities.EncodeDeltaCursor(since, pgtype.UUID{}),
+		SourceIDs: []uint64{7},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// removed hosts are selected by cursor, changes within safety window are not selected
+	filter := repo.filters[0]
+	if filter.IsActive == nil || *filter.IsActive || filter.UpdatedAfter == nil || !filter.UpdatedAfter.Equal(since) || filter.UpdatedAfterUUID.Status == pgtype.Present || !reflect.DeepEqual(filter.SourceIDs, []uint64{7}) {
+		t.Errorf("unexpected hosts filter: %+v", filter)
+	}
+
+	if filter.Limit != deltaDefaultLimit+1 || filter.UpdatedBefore == nil || filter.UpdatedBefore.After(time.Now().Add(-deltaSafetyWindow)) {
+		t.Errorf("unexpected hosts limit or safety window: %+v", filter)
+	}
+
+	var delta blacklistEntities.BlacklistDelta
+	if err = json.Unmarshal(data, &delta); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(delta.Added) != 1 || len(delta.Updated) != 1 || len(delta.Removed) != 1 || delta.Removed[0].Host != "removed.com" {
+		t.Errorf("hosts not split by change: %+v", delta)
+	}
+
+	// cursor points to the last exported change, which is deletion
+	if next, id, _ := blacklistEntities.DecodeDeltaCursor(cursor); !next.Equal(since.Add(3*time.Hour)) || id != hostUUID(3) || delta.Cursor != cursor || more || delta.More {
+		t.Errorf("cursor not moved to the last change: %s %v", next, id)
+	}
+
+	data, _, _, err = s.ExportDelta(blacklistEntities.ExportFormatCSV, blacklistEntities.BlacklistDeltaFilter{Since: &since})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantLines := [][]string{{"added.com", "MISP", "added"}, {"updated.com", "MISP", "updated"}, {"removed.com", "MISP", "removed"}}
+	for i, want := range wantLines {
+		if got := []string{lines[i+1][2], lines[i+1][3], lines[i+1][4]}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got csv line %v, want %v", got, want)
+		}
+	}
+
+	if _, _, _, err = s.ExportDelta(blacklistEntities.ExportFormatJSON, blacklistEntities.BlacklistDeltaFilter{Cursor: "malformed"}); err == nil {
+		t.Error("expected error of malformed cursor")
+	}
+}
+
+func TestExportDeltaLimit(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	repo := &fakeBlacklistsRepo{hosts: []blacklistEntities.BlacklistedHost{
+		{UUID: hostUUID(1), Type: "domain", Host: "first.com", CreatedAt: since.Add(time.Hour), UpdatedAt: since.Add(time.Hour)},
+		{UUID: hostUUID(2), Type: "domain", Host: "second.com", CreatedAt: since.Add(time.Hour), UpdatedAt: since.Add(time.Hour)},
+		{UUID: hostUUID(3), Type: "domain", Host: "third.com", CreatedAt: since.Add(time.Hour), UpdatedAt: since.Add(time.Hour)},
+	}}
+
+	s := NewBlackListsServiceImpl(repo, nil)
+
+	cursor := blacklistEntities.EncodeDeltaCursor(since, hostUUID(1))
+
+	data, next, more, err := s.ExportDelta(blacklistEntities.ExportFormatJSON, blacklistEntities.BlacklistDeltaFilter{Cursor: cursor, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// hosts changed at the same time are paged through by UUID
+	if filter := repo.filters[0]; filter.Limit != 3 || filter.UpdatedAfterUUID != hostUUID(1) || !filter.UpdatedAfter.Equal(since) {
+		t.Errorf("unexpected hosts filter: %+v", filter)
+	}
+
+	var delta blacklistEntities.BlacklistDelta
+	if err = json.Unmarshal(data, &delta); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(delta.Added) != 2 || !more || !delta.More {
+		t.Errorf("delta not cut by limit: %+v", delta)
+	}
+
+	if at, id, _ := blacklistEntities.DecodeDeltaCursor(next); !at.Equal(since.Add(time.Hour)) || id != hostUUID(2) {
+		t.Errorf("cursor does not point to the last exported host: %s %v", at, id)
+	}
+
+	// cursor is not moved if nothing changed
+	repo.hosts = nil
+
+	_, next, more, err = s.ExportDelta(blacklistEntities.ExportFormatJSON, blacklistEntities.BlacklistDeltaFilter{Cursor: cursor, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if next != cursor || more {
+		t.Errorf("cursor moved without changes: %s", next)
+	}
+
+	// limit is capped
+	if _, _, _, err = s.ExportDelta(blacklistEntities.ExportFormatJSON, blacklistEntities.BlacklistDeltaFilter{Limit: 10 * deltaMaxLimit}); err != nil {
+		t.Fatal(err)
+	}
+
+	if filter := repo.filters[len(repo.filters)-1]; filter.Limit != deltaMaxLimit+1 {
+		t.Errorf("limit not capped: %d", filter.Limit)
+	}
+}
+
+func TestImportFromMISP(t *testing.T) {
+	attribute := func(type_, value string) blacklistEntities.MISPAttribute {
+		return blacklistEntities.MISPAttribute{Type: type_, Value: value, ToIDs: true}
+	}
+
+	events := []blacklistEntities.MISPEvent{
+		{
+			Info:      "phishing",
+			Timestamp: "1704067200",
+			Tag:       []blacklistEntities.MISPTag{{Name: "tlp:amber"}},
+			Attribute: []blacklistEntities.MISPAttribute{
+				attribute("ip-dst", "10.0.0.1"),
+				attribute("domain|ip", "evil.com|10.0.0.2"),
+				attribute("url", "http://evil.com/a"),
+				attribute("email-src", "user@evil.com"),
+				attribute("sha256", "e3b0c44298fc1c149afbf4c8996fb924"),
+				{Type: "domain", Value: "ignored.com", ToIDs: false},
+				{Type: "domain", Value: "deleted.com", ToIDs: true, Deleted: true},
+			},
+		},
+		{
+			Info: "campaign",
+			Attribute: []blacklistEntities.MISPAttribute{
+				attribute("ip-src", "not an address"),
+			},
+			Object: []blacklistEntities.MISPObject{{Name: "url", Attribute: []blacklistEntities.MISPAttribute{
+				attribute("hostname", "EVIL.com."),
+			}}},
+		},
+	}
+
+	repo := &fakeBlacklistsRepo{}
+
+	event, err := NewBlackListsServiceImpl(repo, nil).ImportFromMISP(events, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"domain:evil.com (5)", "email:user@evil.com (5)", "ip:10.0.0.1/32 (5)", "ip:10.0.0.2/32 (5)", "url:http://evil.com/a (5)"}
+	if got := repo.savedHosts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// not supported, not marked for IDS, deleted and malformed attributes are skipped
+	if summary := event.Summary.Data(); summary.Skipped != 4 || event.Type != "misp" {
+		t.Errorf("unexpected import event: %+v", event)
+	}
+
+	if ip := repo.ips[0]; ip.Description != "MISP: phishing; tags: tlp:amber" || !ip.DiscoveredAt.Equal(time.Unix(1704067200, 0)) {
+		t.Errorf("event info not attached to host: %+v", ip)
+	}
+}
+
+// parsedSources maps every parsed host as "type:value" to sorted IDs of sources which reported it
+func parsedSources(p *parsedHosts) map[string][]uint64 {
+	result := make(map[string][]uint64)
+
+	add := func(key string, sightings []blacklistEntities.BlacklistSighting) {
+		sources := []uint64{}
+		for _, s := range sightings {
+			sources = append(sources, s.SourceID)
+		}
+
+		slices.Sort(sources)
+		result[key] = sources
+	}
+
+	for k, v := range p.ips {
+		add("ip:"+k, v.Sightings)
+	}
+
+	for k, v := range p.domains {
+		add("domain:"+k, v.Sightings)
+	}
+
+	for k, v := range p.urls {
+		add("url:"+k, v.Sightings)
+	}
+
+	for k, v := range p.emails {
+		add("email:"+k, v.Sightings)
+	}
+
+	return result
+}
+
+func TestParseCSV(t *testing.T) {
+	kaspersky, drweb, unknown := blacklistEntities.SourceKaspersky, blacklistEntities.SourceDrWeb, blacklistEntities.SourceUnknown
+
+	header := []string{"\uFEFFType_IOC", "Value", "Source", "First Seen", "Comment"}
+
+	tests := []struct {
+		name       string
+		data       [][]string
+		extractAll bool
+		sourceID   uint64
+
+		want        map[string][]uint64
+		wantInvalid int
+		wantSkipped int64
+		wantErr     bool
+	}{
+		{
+			name: "all host types",
+			data: [][]string{
+				header,
+				{"ip-address", "10.0.0.1", "Vendor-Kaspersky", "01.02.2024", "c2"},
+				{"domain", "Evil.COM", "Vendor-DRWEB", "01.02.2024", ""},
+				{"url", "http://evil.com/a", "Vendor-Kaspersky", "", ""},
+				{"email", "user@evil.com", "Unknown vendor", "", ""},
+				{"sha256", "e3b0c44298fc1c149afbf4c8996fb924", "", "", ""},
+			},
+			want: map[string][]uint64{
+				"ip:10.0.0.1/32":        {kaspersky},
+				"domain:evil.com":       {drweb},
+				"url:http://evil.com/a": {kaspersky},
+				"email:user@evil.com":   {unknown},
+			},
+			wantSkipped: 1,
+		},
+		{
+			name: "same host of several sources",
+			data: [][]string{
+				header,
+				{"domain", "evil.com", "Vendor-Kaspersky", "", ""},
+				{"domain", "EVIL.com.", "Vendor-DRWEB", "", ""},
+				{"domain", "evil.com", "Vendor-Kaspersky", "", ""},
+			},
+			want: map[string][]uint64{"domain:evil.com": {kaspersky, drweb}},
+		},
+		{
+			name: "urls written differently",
+			data: [][]string{
+				header,
+				{"url", "HTTP://EVIL.com/a/", "Vendor-Kaspersky", "", ""},
+				{"url", "http://evil.com/a", "Vendor-DRWEB", "", ""},
+			},
+			want: map[string][]uint64{"url:http://evil.com/a": {kaspersky, drweb}},
+		},
+		{
+			name: "source override and extraction",
+			data: [][]string{
+				header,
+				{"url", "http://10.0.0.1/a", "Vendor-Kaspersky", "", ""},
+				{"email", "user@evil.com", "Vendor-Kaspersky", "", ""},
+			},
+			extractAll: true,
+			sourceID:   drweb,
+			want: map[string][]uint64{
+				"url:http://10.0.0.1/a": {drweb},
+				"ip:10.0.0.1/32":        {drweb},
+				"email:user@evil.com":   {drweb},
+				"domain:evil.com":       {drweb},
+			},
+		},
+		{
+			name: "invalid values",
+			data: [][]string{
+				header,
+				{"ip-address", "10.0.0.256", "", "", ""},
+				{"domain", "", "", "", ""},
+				{"domain", "com", "", "", ""},
+			},
+			want:        map[string][]uint64{},
+			wantInvalid: 3,
+		},
+		{
+			name:    "value column missing",
+			data:    [][]string{{"Type_IOC", "Host"}, {"ip-address", "10.0.0.1"}},
+			wantErr: true,
+		},
+		{
+			name:    "empty file",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parseCSV(tt.data, blacklistEntities.DefaultImportProfile(), time.Now(), tt.extractAll, tt.sourceID)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := parsedSources(parsed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+
+			if len(parsed.invalid) != tt.wantInvalid {
+				t.Errorf("got %d invalid values, want %d: %v", len(parsed.invalid), tt.wantInvalid, parsed.invalid)
+			}
+
+			if parsed.skipped != tt.wantSkipped {
+				t.Errorf("got %d skipped values, want %d", parsed.skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestParseCSVKeepsOriginalValues(t *testing.T) {
+	parsed, err := parseCSV([][]string{
+		{"Type_IOC", "Value", "Source", "First Seen", "Comment"},
+		{"url", "HTTP://EVIL.com/a/", "Vendor-Kaspersky", "01.02.2024", "first"},
+		{"url", "http://evil.com/a", "Vendor-Kaspersky", "01.01.2024", "second"},
+	}, blacklistEntities.DefaultImportProfile(), time.Now(), false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, ok := parsed.urls["http://evil.com/a"]
+	if !ok {
+		t.Fatalf("canonical url not parsed: %v", parsedSources(parsed))
+	}
+
+	if u.OriginalURL != "HTTP://EVIL.com/a/" || u.MD5 != blacklistEntities.URLHash("http://evil.com/a") {
+		t.Errorf("unexpected original value or hash: %s, %s", u.OriginalURL, u.MD5)
+	}
+
+	// sightings of same source are merged: the earliest discovery date and the latest description are kept
+	if len(u.Sightings) != 1 {
+		t.Fatalf("expected single sighting, got %v", u.Sightings)
+	}
+
+	if s := u.Sightings[0]; s.FirstSeenAt.Format("02.01.2006") != "01.01.2024" || s.Description != "second" {
+		t.Errorf("sightings not merged: %+v", s)
+	}
+}
+
+func TestParseCSVWithProfile(t *testing.T) {
+	profile := blacklistEntities.BlacklistImportProfile{
+		Name:               "vendor",
+		ValueColumn:        "indicator",
+		TypeColumn:         "kind",
+		SourceColumn:       "feed",
+		DiscoveredAtColumn: "seen",
+		DateLayout:         "2006-01-02",
+		TypeMapping:        datatypes.NewJSONType(map[string]string{"IPv4": "ip", "FQDN": "domain"}),
+		SourceMapping:      datatypes.NewJSONType(map[string]uint64{"kl": blacklistEntities.SourceKaspersky}),
+		DefaultSourceID:    blacklistEntities.SourceDrWeb,
+	}
+
+	parsed, err := parseCSV([][]string{
+		{"indicator", "kind", "feed", "seen"},
+		{"10.0.0.1", "ipv4", "kl", "2024-02-01"},
+		{"evil.com", "FQDN", "other"},
+		{"user@evil.com", "", "kl"},
+		{"e3b0c44298fc1c149afbf4c8996fb924", "sha256", "kl"},
+	}, profile, time.Now(), false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// type is detected from value if type column is empty, not mapped sources are attributed to default source
+	want := map[string][]uint64{
+		"ip:10.0.0.1/32":      {blacklistEntities.SourceKaspersky},
+		"domain:evil.com":     {blacklistEntities.SourceDrWeb},
+		"email:user@evil.com": {blacklistEntities.SourceKaspersky},
+	}
+
+	if got := parsedSources(parsed); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if ip := parsed.ips["10.0.0.1/32"]; ip.DiscoveredAt.Format(time.DateOnly) != "2024-02-01" {
+		t.Errorf("discovery date not parsed by profile layout: %s", ip.DiscoveredAt)
+	}
+
+	if parsed.skipped != 1 {
+		t.Errorf("got %d skipped values, want 1", parsed.skipped)
+	}
+
+	if _, err = parseCSV([][]string{{"indicator"}, {"10.0.0.1"}}, profile, time.Now(), false, 0); err == nil {
+		t.Error("expected error of missing type column")
+	}
+}
+
+func TestParseSTIX2(t *testing.T) {
+	indicator := func(pattern string) blacklistEntities.STIX2Object {
+		return blacklistEntities.STIX2Object{Type: "indicator", Id: "indicator--" + pattern, Pattern: pattern, PatternType: "stix"}
+	}
+
+	bundles := []blacklistEntities.STIX2Bundle{
+		{Type: "bundle", ID: "bundle--1", Objects: []blacklistEntities.STIX2Object{
+			indicator("[ipv4-addr:value = '10.0.0.1']"),
+			indicator("[domain-name:value = 'evil.com']"),
+			indicator("[url:value = 'HTTP://EVIL.com/a/']"),
+			{Type: "identity", Id: "identity--1", Name: "vendor"},
+		}},
+		{Type: "bundle", ID: "bundle--2", Objects: []blacklistEntities.STIX2Object{
+			indicator("[domain-name:value = 'EVIL.com']"),
+			indicator("[ipv4-addr:value = 'not an address']"),
+		}},
+	}
+
+	parsed := parseSTIX2(bundles, false, blacklistEntities.SourceKaspersky)
+
+	want := map[string][]uint64{
+		"ip:10.0.0.1/32":        {blacklistEntities.SourceKaspersky},
+		"domain:evil.com":       {blacklistEntities.SourceKaspersky},
+		"url:http://evil.com/a": {blacklistEntities.SourceKaspersky},
+	}
+
+	if got := parsedSources(parsed); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if parsed.skipped != 1 || len(parsed.invalid) != 1 {
+		t.Errorf("got %d skipped and %d invalid objects, want 1 and 1", parsed.skipped, len(parsed.invalid))
+	}
+
+	if len(parsed.invalid) == 1 && parsed.invalid[0].Location != "bundle #1, object #1" {
+		t.Errorf("unexpected location of invalid object: %s", parsed.invalid[0].Location)
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	unknown, drweb := blacklistEntities.SourceUnknown, blacklistEntities.SourceDrWeb
+
+	tests := []struct {
+		name       string
+		values     []string
+		extractAll bool
+		sourceID   uint64
+
+		want        map[string][]uint64
+		wantInvalid int
+	}{
+		{
+			name:   "comments and empty lines",
+			values: []string{"# list of hosts", "", "  10.0.0.1  ", "evil.com", "user@evil.com", "http://evil.com/a"},
+			want: map[string][]uint64{
+				"ip:10.0.0.1/32":        {unknown},
+				"domain:evil.com":       {unknown},
+				"email:user@evil.com":   {unknown},
+				"url:http://evil.com/a": {unknown},
+			},
+		},
+		{
+			name:     "duplicates written differently",
+			values:   []string{"evil.com", "EVIL.com.", "HTTP://EVIL.com/a/", "http://evil.com/a"},
+			sourceID: drweb,
+			want: map[string][]uint64{
+				"domain:evil.com":       {drweb},
+				"url:http://evil.com/a": {drweb},
+			},
+		},
+		{
+			name:       "hosts extracted from urls",
+			values:     []string{"http://10.0.0.1/a", "https://evil.com/b"},
+			extractAll: true,
+			want: map[string][]uint64{
+				"url:http://10.0.0.1/a":  {unknown},
+				"url:https://evil.com/b": {unknown},
+				"ip:10.0.0.1/32":         {unknown},
+				"domain:evil.com":        {unknown},
+			},
+		},
+		{
+			name:        "undetected values",
+			values:      []string{"not a host", "10.0.0.1"},
+			want:        map[string][]uint64{"ip:10.0.0.1/32": {unknown}},
+			wantInvalid: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := parseValues(tt.values, time.Now(), tt.extractAll, tt.sourceID)
+
+			if got := parsedSources(parsed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+
+			if len(parsed.invalid) != tt.wantInvalid {
+				t.Errorf("got %d invalid values, want %d: %v", len(parsed.invalid), tt.wantInvalid, parsed.invalid)
+			}
+		})
+	}
+}
+
+func TestParseMISP(t *testing.T) {
+	attribute := func(type_, value string) blacklistEntities.MISPAttribute {
+		return blacklistEntities.MISPAttribute{Type: type_, Value: value, ToIDs: true}
+	}
+
+	events := []blacklistEntities.MISPEvent{
+		{
+			Info:      "phishing",
+			Timestamp: "1704067200",
+			Tag:       []blacklistEntities.MISPTag{{Name: "tlp:amber"}},
+			Attribute: []blacklistEntities.MISPAttribute{
+				attribute("ip-dst", "10.0.0.1"),
+				attribute("domain|ip", "evil.com|10.0.0.2"),
+				attribute("url", "HTTP://EVIL.com/a/"),
+				attribute("email-src", "user@evil.com"),
+				attribute("sha256", "e3b0c44298fc1c149afbf4c8996fb924"),
+				{Type: "domain", Value: "ignored.com", ToIDs: false},
+				{Type: "domain", Value: "deleted.com", ToIDs: true, Deleted: true},
+			},
+		},
+		{
+			Info: "campaign",
+			Attribute: []blacklistEntities.MISPAttribute{
+				attribute("ip-src", "not an address"),
+			},
+			Object: []blacklistEntities.MISPObject{{Name: "url", Attribute: []blacklistEntities.MISPAttribute{
+				attribute("url", "http://evil.com/a"),
+				attribute("hostname", "EVIL.com"),
+			}}},
+		},
+	}
+
+	parsed := parseMISP(events, false, 0)
+
+	unknown := blacklistEntities.SourceUnknown
+	want := map[string][]uint64{
+		"ip:10.0.0.1/32":        {unknown},
+		"ip:10.0.0.2/32":        {unknown},
+		"domain:evil.com":       {unknown},
+		"url:http://evil.com/a": {unknown},
+		"email:user@evil.com":   {unknown},
+	}
+
+	if got := parsedSources(parsed); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if parsed.skipped != 3 || len(parsed.invalid) != 1 {
+		t.Errorf("got %d skipped and %d invalid attributes, want 3 and 1", parsed.skipped, len(parsed.invalid))
+	}
+
+	if len(parsed.invalid) == 1 && parsed.invalid[0].Location != "event #1, attribute #0" {
+		t.Errorf("unexpected location of invalid attribute: %s", parsed.invalid[0].Location)
+	}
+
+	if ip := parsed.ips["10.0.0.1/32"]; ip == nil || len(ip.Tags) != 1 || ip.Tags[0].Name != "tlp:amber" {
+		t.Errorf("event tags not attached to host: %+v", ip)
+	}
+}
+
+func TestImportErrorLog(t *testing.T) {
+	repo := &fakeBlacklistsRepo{saveIPsErr: errors.New("connection lost")}
+	s := NewBlackListsServiceImpl(repo, nil)
+
+	event, err := s.ImportFromCSV([][]string{
+		{"Type_IOC", "Value"},
+		{"domain", ""},
+		{"ip-address", "10.0.0.256"},
+		{"ip-address", "10.0.0.1"},
+		{"domain", "evil.com"},
+	}, time.Now(), false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary := event.Summary.Data(); summary.Skipped != 2 || summary.Errored != 1 {
+		t.Errorf("unexpected import event summary: %+v", summary)
+	}
+
+	data, err := s.ExportImportErrorsToCSV(event.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// invalid values are logged with their rows, failed batches are logged with reason
+	want := [][]string{
+		{"Location", "Type_IOC", "Value", "Reason"},
+		{"row #2", "domain", "", "value not defined"},
+		{"row #3", "ip", "10.0.0.256", lines[2][3]},
+		{"batch of 1 hosts", "ip", "", "failed to save: connection lost"},
+	}
+
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("got %v, want %v", lines, want)
+	}
+
+	if other, _ := s.RetrieveImportErrors(event.ID+1, blacklistEntities.BlacklistImportErrorFilter{}); len(other) != 0 {
+		t.Errorf("errors of other import event returned: %v", other)
+	}
+}
+
+func TestExportToXLSX(t *testing.T) {
+	discoveredAt := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	repo := &fakeBlacklistsRepo{hosts: []blacklistEntities.BlacklistedHost{
+		{UUID: hostUUID(1), Type: "domain", Host: "evil.com", SourceID: blacklistEntities.SourceDrWeb, DiscoveredAt: discoveredAt},
+		{UUID: hostUUID(2), Type: "hash", Host: "e3b0c44298fc1c149afbf4c8996fb924"},
+	}}
+
+	// sources are loaded with sightings of hosts
+	repo.hostSightings = map[[16]byte][]blacklistEntities.BlacklistSighting{
+		hostUUID(1).Bytes: {{SourceID: blacklistEntities.SourceDrWeb, Source: &blacklistEntities.BlacklistSource{Name: "DrWEB"}}},
+	}
+
+	data, err := NewBlackListsServiceImpl(repo, nil).ExportToXLSX(blacklistEntities.BlacklistSearchFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := xlsx.ReadSheet(data, "Domains", time.DateOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sources are written by name, hosts of unknown types are skipped
+	if len(rows) != 2 || rows[1][1] != "evil.com" || rows[1][2] != "DrWEB" || rows[1][8] != "2024-02-01" {
+		t.Errorf("unexpected domains sheet: %v", rows)
+	}
+
+	if rows, _ = xlsx.ReadSheet(data, "IPs", time.DateOnly); len(rows) != 1 {
+		t.Errorf("expected only header in IPs sheet, got %v", rows)
+	}
+}
+
+func TestSaveCanonicalHosts(t *testing.T) {
+	repo := &fakeBlacklistsRepo{}
+	s := NewBlackListsServiceImpl(repo, nil)
+
+	_, err := s.SaveURLs([]blacklistEntities.BlacklistedURL{
+		{URL: "HTTP://EVIL.com:80/a/", SourceID: blacklistEntities.SourceKaspersky},
+		{URL: "http://evil.com/a", SourceID: blacklistEntities.SourceKaspersky},
+		{URL: "http://evil.com/a", SourceID: blacklistEntities.SourceDrWeb},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.SaveDomains([]blacklistEntities.BlacklistedDomain{
+		{URN: "ПРИМЕР.рф.", SourceID: blacklistEntities.SourceKaspersky},
+		{URN: "xn--e1afmkfd.xn--p1ai", SourceID: blacklistEntities.SourceKaspersky, IncludeSubdomains: true},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// same host written differently is saved once with sightings of all sources, the first original value is kept
+	want := []string{"domain:xn--e1afmkfd.xn--p1ai (3)", "url:http://evil.com/a (3)"}
+	if got := repo.savedHosts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if u := repo.urls[0]; u.OriginalURL != "HTTP://EVIL.com:80/a/" || u.MD5 != blacklistEntities.URLHash("http://evil.com/a") {
+		t.Errorf("unexpected original value or hash: %+v", u)
+	}
+
+	if s := repo.urls[0].Sightings; len(s) != 2 || s[0].SourceID != blacklistEntities.SourceKaspersky || s[1].SourceID != blacklistEntities.SourceDrWeb {
+		t.Errorf("unexpected sightings: %+v", s)
+	}
+
+	if d := repo.domains[0]; d.OriginalURN != "ПРИМЕР.рф." || !d.IncludeSubdomains {
+		t.Errorf("duplicates not merged: %+v", d)
+	}
+}
+
+func TestSaveHostsWithTTL(t *testing.T) {
+	explicit := time.Now().AddDate(1, 0, 0)
+
+	repo := &fakeBlacklistsRepo{ttlPolicies: []blacklistEntities.BlacklistTTLPolicy{
+		{TTLDays: 30},
+		{SourceID: blacklistEntities.SourceDrWeb, TTLDays: 7},
+	}}
+	s := NewBlackListsServiceImpl(repo, nil)
+
+	_, err := s.SaveIPs([]blacklistEntities.BlacklistedIP{
+		{SourceID: blacklistEntities.SourceKaspersky},
+		{SourceID: blacklistEntities.SourceDrWeb},
+		{SourceID: blacklistEntities.SourceDrWeb, ValidUntil: &explicit},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// expiration date defined on save is kept, others are set by the most specific policy
+	wantDays := []int{30, 7, 365}
+	for i, ip := range repo.ips {
+		if ip.ValidUntil == nil {
+			t.Fatalf("expiration date of host #%d not set", i)
+		}
+
+		if days := int(time.Until(*ip.ValidUntil).Hours()/24 + 0.5); days < wantDays[i]-1 || days > wantDays[i]+1 {
+			t.Errorf("host #%d expires in %d days, want %d", i, days, wantDays[i])
+		}
+	}
+}
+
+func TestParseCSVThreatAttributes(t *testing.T) {
+	profile := blacklistEntities.DefaultImportProfile()
+	profile.ConfidenceColumn = "confidence"
+	profile.SeverityColumn = "severity"
+	profile.CategoryColumn = "category"
+	profile.KillChainPhaseColumn = "phase"
+
+	parsed, err := parseCSV([][]string{
+		{"Type_IOC", "Value", "confidence", "severity", "category", "phase"},
+		{"domain", "evil.com", "80", "4", "Phishing; spam|unknown", "Delivery"},
+		{"domain", "other.com"},
+	}, profile, time.Now(), false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := blacklistEntities.ThreatAttributes{
+		Confidence:     80,
+		Severity:       blacklistEntities.SeverityCritical,
+		Categories:     []string{blacklistEntities.ThreatCategoryPhishing, blacklistEntities.ThreatCategorySpam},
+		KillChainPhase: "delivery",
+	}
+
+	if got := parsed.domains["evil.com"].ThreatAttributes; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// missing columns leave attributes undefined
+	if got := parsed.domains["other.com"].ThreatAttributes; got.Confidence != 0 || got.Severity != "" || len(got.Categories) != 0 {
+		t.Errorf("unexpected attributes: %+v", got)
+	}
+}
+
+func TestSaveHostsNormalizesThreatAttributes(t *testing.T) {
+	repo := &fakeBlacklistsRepo{}
+
+	_, err := NewBlackListsServiceImpl(repo, nil).SaveDomains([]blacklistEntities.BlacklistedDomain{{
+		URN:      "evil.com",
+		SourceID: blacklistEntities.SourceManual,
+		ThreatAttributes: blacklistEntities.ThreatAttributes{
+			Confidence: 200,
+			Severity:   "urgent",
+			Categories: []string{"C2", "unknown"},
+		},
+	}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := blacklistEntities.ThreatAttributes{Confidence: 100, Categories: []string{blacklistEntities.ThreatCategoryC2}}
+	if got := repo.domains[0].ThreatAttributes; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
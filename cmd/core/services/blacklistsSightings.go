@@ -0,0 +1,36 @@
+package services
+
+import (
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+)
+
+// hostSightingsBatchSize limits number of host UUIDs in single sightings query
+const hostSightingsBatchSize = 1000
+
+// fillHostsSightings loads sightings of hosts selected as union of all host types, so every host lists all sources
+// which reported it
+func (s *BlackListsServiceImpl) fillHostsSightings(hosts []blacklistEntities.BlacklistedHost) error {
+	var indexes = make(map[[16]byte]int, len(hosts))
+	for i, h := range hosts {
+		indexes[h.UUID.Bytes] = i
+	}
+
+	for type_, uuids := range hostUUIDsByType(hosts) {
+		for start := 0; start < len(uuids); start += hostSightingsBatchSize {
+			end := min(start+hostSightingsBatchSize, len(uuids))
+
+			sightings, err := s.repo.SelectHostsSightings(type_, uuids[start:end])
+			if err != nil {
+				return err
+			}
+
+			for uuid, v := range sightings {
+				if i, ok := indexes[uuid]; ok {
+					hosts[i].Sightings = v
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"bytes"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"encoding/csv"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSaveHostsWithSightings(t *testing.T) {
+	repo := &fakeBlacklistsRepo{}
+	s := NewBlackListsServiceImpl(repo, nil)
+
+	ip, err := blacklistEntities.ParseInet("10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.SaveIPs([]blacklistEntities.BlacklistedIP{
+		{IPAddress: ip, SourceID: blacklistEntities.SourceKaspersky, Description: "c2"},
+		{IPAddress: ip, SourceID: blacklistEntities.SourceDrWeb, Description: "botnet"},
+		{IPAddress: ip, SourceID: blacklistEntities.SourceKaspersky},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// host reported by several sources is saved once, the first source is kept as host source
+	if got := repo.savedHosts(); !reflect.DeepEqual(got, []string{"ip:10.0.0.1/32 (3)"}) {
+		t.Fatalf("got %v", got)
+	}
+
+	var got []string
+	for _, v := range repo.ips[0].Sightings {
+		got = append(got, v.Description)
+	}
+
+	if !reflect.DeepEqual(got, []string{"c2", "botnet"}) {
+		t.Errorf("got sightings %v", got)
+	}
+}
+
+func TestExportHostsWithSightings(t *testing.T) {
+	repo := &fakeBlacklistsRepo{
+		hosts: []blacklistEntities.BlacklistedHost{{UUID: hostUUID(1), Type: "domain", Host: "evil.com"}},
+		hostSightings: map[[16]byte][]blacklistEntities.BlacklistSighting{hostUUID(1).Bytes: {
+			{SourceID: blacklistEntities.SourceKaspersky, Source: &blacklistEntities.BlacklistSource{Name: "Kaspersky"}},
+			{SourceID: blacklistEntities.SourceDrWeb, Source: &blacklistEntities.BlacklistSource{Name: "DrWEB"}},
+		}},
+	}
+	s := NewBlackListsServiceImpl(repo, nil)
+
+	hosts, err := s.RetrieveHostsByFilter(blacklistEntities.BlacklistSearchFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hosts[0].Sightings) != 2 {
+		t.Errorf("sightings not loaded: %+v", hosts[0])
+	}
+
+	data, err := s.ExportToCSV(blacklistEntities.BlacklistSearchFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// all sources which reported host are listed
+	if len(lines) != 2 || lines[0][3] != "Sources" || lines[1][3] != "Kaspersky, DrWEB" {
+		t.Errorf("unexpected export: %v", lines)
+	}
+}
+
+func TestImportLinksSightingsWithEvent(t *testing.T) {
+	repo := &fakeBlacklistsRepo{}
+	s := NewBlackListsServiceImpl(repo, nil)
+
+	event, err := s.ImportFromCSV([][]string{
+		{"Type_IOC", "Value", "Source"},
+		{"domain", "evil.com", "Vendor-Kaspersky"},
+		{"domain", "EVIL.com.", "Vendor-DRWEB"},
+	}, time.Now(), false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// host reported by several sources in single file is saved once, every sighting refers to import event
+	if len(repo.domains) != 1 || len(repo.domains[0].Sightings) != 2 {
+		t.Fatalf("unexpected domains: %+v", repo.domains)
+	}
+
+	for _, v := range repo.domains[0].Sightings {
+		if v.ImportEventID == nil || *v.ImportEventID != event.ID {
+			t.Errorf("sighting of source %d not linked with import event", v.SourceID)
+		}
+	}
+}
+
+func TestExportDeltaWithSightings(t *testing.T) {
+	repo := &fakeBlacklistsRepo{
+		hosts: []blacklistEntities.BlacklistedHost{{UUID: hostUUID(1), Type: "domain", Host: "evil.com", SourceID: blacklistEntities.SourceKaspersky}},
+		hostSightings: map[[16]byte][]blacklistEntities.BlacklistSighting{hostUUID(1).Bytes: {
+			{SourceID: blacklistEntities.SourceKaspersky, Source: &blacklistEntities.BlacklistSource{Name: "Kaspersky"}},
+			{SourceID: blacklistEntities.SourceDrWeb, Source: &blacklistEntities.BlacklistSource{Name: "DrWEB"}},
+		}},
+	}
+	s := NewBlackListsServiceImpl(repo, nil)
+
+	data, _, _, err := s.ExportDelta(blacklistEntities.ExportFormatCSV, blacklistEntities.BlacklistDeltaFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// delta lists all sources which reported host
+	if len(rows) != 2 || rows[1][3] != "Kaspersky, DrWEB" {
+		t.Errorf("unexpected delta: %v", rows)
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"fmt"
+	"github.com/jackc/pgtype"
+)
+
+// hostTagsBatchSize limits number of host UUIDs in single tags query
+const hostTagsBatchSize = 1000
+
+func (s *BlackListsServiceImpl) RetrieveAllTags() ([]blacklistEntities.BlacklistTag, error) {
+	return s.repo.SelectAllTags()
+}
+
+func (s *BlackListsServiceImpl) SaveTag(tag blacklistEntities.BlacklistTag) (blacklistEntities.BlacklistTag, error) {
+	err := tag.Validate()
+	if err != nil {
+		return blacklistEntities.BlacklistTag{}, err
+	}
+
+	return s.repo.SaveTag(tag)
+}
+
+// DeleteTag deletes tag, hosts tagged by it are unlinked
+func (s *BlackListsServiceImpl) DeleteTag(id uint64) (int64, error) {
+	return s.repo.DeleteTag(id)
+}
+
+func (s *BlackListsServiceImpl) TagHosts(tagIDs []uint64, hosts []blacklistEntities.BlacklistedHost) (int64, error) {
+	var total int64
+
+	for type_, uuids := range hostUUIDsByType(hosts) {
+		rows, err := s.repo.SaveHostTags(type_, uuids, tagIDs)
+		if err != nil {
+			return total, err
+		}
+
+		total += rows
+	}
+
+	return total, nil
+}
+
+func (s *BlackListsServiceImpl) UntagHosts(tagIDs []uint64, hosts []blacklistEntities.BlacklistedHost) (int64, error) {
+	var total int64
+
+	for type_, uuids := range hostUUIDsByType(hosts) {
+		rows, err := s.repo.DeleteHostTags(type_, uuids, tagIDs)
+		if err != nil {
+			return total, err
+		}
+
+		total += rows
+	}
+
+	return total, nil
+}
+
+func (s *BlackListsServiceImpl) RetrieveTagStatistics() ([]blacklistEntities.BlacklistTagCount, error) {
+	return s.repo.CountTagStatistics()
+}
+
+// hostUUIDsByType groups host UUIDs by host type
+func hostUUIDsByType(hosts []blacklistEntities.BlacklistedHost) map[string][]pgtype.UUID {
+	var uuids = make(map[string][]pgtype.UUID)
+
+	for _, h := range hosts {
+		uuids[h.Type] = append(uuids[h.Type], h.UUID)
+	}
+
+	return uuids
+}
+
+// resolveTags replaces tags parsed from imported data with saved tags, missing tags are created.
+// Every list belongs to single host, so hosts are linked with tags on save.
+func (s *BlackListsServiceImpl) resolveTags(lists []*[]blacklistEntities.BlacklistTag) error {
+	var tags []blacklistEntities.BlacklistTag
+	for _, l := range lists {
+		tags = append(tags, *l...)
+	}
+
+	tags = blacklistEntities.UniqueTags(tags)
+	if len(tags) == 0 {
+		return nil
+	}
+
+	saved, err := s.repo.SaveTagsByKey(tags)
+	if err != nil {
+		return fmt.Errorf("failed to save tags: %s", err.Error())
+	}
+
+	var byKey = make(map[string]blacklistEntities.BlacklistTag, len(saved))
+	for _, t := range saved {
+		byKey[t.Key()] = t
+	}
+
+	for _, l := range lists {
+		resolved := make([]blacklistEntities.BlacklistTag, 0, len(*l))
+		for _, t := range blacklistEntities.UniqueTags(*l) {
+			resolved = append(resolved, byKey[t.Key()])
+		}
+
+		*l = resolved
+	}
+
+	return nil
+}
+
+// fillHostsTags loads tags of hosts selected as union of all host types
+func (s *BlackListsServiceImpl) fillHostsTags(hosts []blacklistEntities.BlacklistedHost) error {
+	var indexes = make(map[[16]byte]int, len(hosts))
+	for i, h := range hosts {
+		indexes[h.UUID.Bytes] = i
+	}
+
+	for type_, uuids := range hostUUIDsByType(hosts) {
+		for start := 0; start < len(uuids); start += hostTagsBatchSize {
+			end := min(start+hostTagsBatchSize, len(uuids))
+
+			tags, err := s.repo.SelectHostsTags(type_, uuids[start:end])
+			if err != nil {
+				return err
+			}
+
+			for uuid, t := range tags {
+				if i, ok := indexes[uuid]; ok {
+					hosts[i].Tags = t
+				}
+			}
+		}
+	}
+
+	return nil
+}
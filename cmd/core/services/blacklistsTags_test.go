@@ -0,0 +1,80 @@
+package services
+
+import (
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"reflect"
+	"testing"
+)
+
+func TestSaveHostsWithTags(t *testing.T) {
+	actor := blacklistEntities.BlacklistTag{Name: "APT28", Kind: blacklistEntities.TagKindActor}
+	tlp := blacklistEntities.BlacklistTag{Name: "tlp:amber"}
+
+	repo := &fakeBlacklistsRepo{tags: []blacklistEntities.BlacklistTag{{ID: 1, Name: "APT28", Kind: blacklistEntities.TagKindActor}}}
+	s := NewBlackListsServiceImpl(repo, nil)
+
+	_, err := s.SaveDomains([]blacklistEntities.BlacklistedDomain{
+		{URN: "evil.com", SourceID: blacklistEntities.SourceManual, Tags: []blacklistEntities.BlacklistTag{actor}},
+		{URN: "EVIL.com", SourceID: blacklistEntities.SourceManual, Tags: []blacklistEntities.BlacklistTag{actor, tlp}},
+		{URN: "other.com", SourceID: blacklistEntities.SourceManual},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// existing tags are reused, missing tags are created, tags of duplicates are merged
+	want := []blacklistEntities.BlacklistTag{{ID: 1, Name: "APT28", Kind: blacklistEntities.TagKindActor}, {ID: 2, Name: "tlp:amber"}}
+	if got := repo.domains[0].Tags; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if got := repo.domains[1].Tags; len(got) != 0 {
+		t.Errorf("unexpected tags of untagged host: %+v", got)
+	}
+}
+
+func TestRetrieveHostsWithTags(t *testing.T) {
+	tag := blacklistEntities.BlacklistTag{ID: 1, Name: "APT28", Kind: blacklistEntities.TagKindActor}
+
+	repo := &fakeBlacklistsRepo{
+		hosts: []blacklistEntities.BlacklistedHost{
+			{UUID: hostUUID(1), Type: "domain", Host: "evil.com"},
+			{UUID: hostUUID(2), Type: "ip", Host: "10.0.0.1/32"},
+		},
+		hostTags: map[[16]byte][]blacklistEntities.BlacklistTag{hostUUID(1).Bytes: {tag}},
+	}
+	s := NewBlackListsServiceImpl(repo, nil)
+
+	hosts, err := s.RetrieveHostsByFilter(blacklistEntities.BlacklistSearchFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(hosts[0].Tags, []blacklistEntities.BlacklistTag{tag}) || len(hosts[1].Tags) != 0 {
+		t.Errorf("unexpected tags: %+v, %+v", hosts[0].Tags, hosts[1].Tags)
+	}
+
+	// hosts are tagged in groups by type
+	rows, err := s.TagHosts([]uint64{1, 2}, hosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows != 4 || !reflect.DeepEqual(repo.tagged, map[string]int{"domain": 1, "ip": 1}) {
+		t.Errorf("unexpected tagged hosts: %d, %v", rows, repo.tagged)
+	}
+}
+
+func TestParseMISPTags(t *testing.T) {
+	parsed := parseMISP([]blacklistEntities.MISPEvent{{
+		Tag: []blacklistEntities.MISPTag{{Name: `misp-galaxy:threat-actor="APT28"`}},
+		Attribute: []blacklistEntities.MISPAttribute{
+			{Type: "domain", Value: "evil.com", ToIDs: true, Tag: []blacklistEntities.MISPTag{{Name: "tlp:amber"}}},
+		},
+	}}, false, blacklistEntities.SourceManual)
+
+	want := []blacklistEntities.BlacklistTag{{Name: "APT28", Kind: blacklistEntities.TagKindActor}, {Name: "tlp:amber"}}
+	if d, ok := parsed.domains["evil.com"]; !ok || !reflect.DeepEqual(d.Tags, want) {
+		t.Errorf("unexpected parsed domains: %+v", parsed.domains)
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"domain_threat_intelligence_api/cmd/integrations/misp"
 	"domain_threat_intelligence_api/cmd/integrations/naumen"
 	"domain_threat_intelligence_api/cmd/mail"
 )
@@ -11,6 +12,7 @@ type SystemStateServiceImpl struct {
 
 type ISystemDynamicConfig interface {
 	naumen.INaumenDynamicConfig
+	misp.IMISPDynamicConfig
 	mail.ISMTPDynamicConfig
 
 	GetCurrentState() ([]byte, error)
@@ -40,3 +42,7 @@ func (s *SystemStateServiceImpl) UpdateNSDCredentials(enabled bool, host, client
 func (s *SystemStateServiceImpl) UpdateNSDBlacklistServiceConfig(agreementID, slm uint64, callType string, types []string) error {
 	return s.dynamicConfig.SetNaumenBlacklistServiceConfig(agreementID, slm, callType, types)
 }
+
+func (s *SystemStateServiceImpl) UpdateMISPConfig(enabled, verifyTLS bool, host, key string, syncInterval, sourceID uint64) error {
+	return s.dynamicConfig.SetMISPConfig(enabled, verifyTLS, host, key, syncInterval, sourceID)
+}
@@ -0,0 +1,175 @@
+package misp
+
+import (
+	"bytes"
+	"crypto/tls"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client is MISP REST API client. Instance URL and auth key are read from dynamic config on every request.
+// reference: https://www.misp-project.org/openapi/
+type Client struct {
+	dynamicConfig IMISPDynamicConfig
+
+	httpClient         *http.Client
+	insecureHTTPClient *http.Client
+}
+
+type IMISPDynamicConfig interface {
+	IsMISPEnabled() bool
+	GetMISPCredentials() (url, key string, verifyTLS bool, err error)
+	GetMISPSyncConfig() (interval, sourceID uint64, err error)
+	SetMISPConfig(enabled, verifyTLS bool, host, key string, interval, sourceID uint64) (err error)
+}
+
+func NewClient(dynamicConfig IMISPDynamicConfig) *Client {
+	return &Client{
+		dynamicConfig: dynamicConfig,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Minute,
+		},
+		insecureHTTPClient: &http.Client{
+			Timeout: 5 * time.Minute,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		},
+	}
+}
+
+func (c *Client) IsAvailable() bool {
+	_, _, _, err := c.dynamicConfig.GetMISPCredentials()
+	return err == nil
+}
+
+// searchPageSize defines number of events requested at once, events are returned with all attributes,
+// so large instances can not return all events by single request
+const searchPageSize = 100
+
+// SearchEvents returns all published events with IDS attributes, changed since defined time.
+// Events are requested page by page until page with fewer events than page size is returned.
+func (c *Client) SearchEvents(since time.Time) ([]blacklistEntities.MISPEvent, error) {
+	var events []blacklistEntities.MISPEvent
+
+	for page := 1; ; page++ {
+		query := map[string]any{
+			"returnFormat":     "json",
+			"timestamp":        since.Unix(),
+			"to_ids":           true,
+			"published":        true,
+			"includeEventTags": true,
+			"limit":            searchPageSize,
+			"page":             page,
+		}
+
+		body, err := c.request(http.MethodPost, "/events/restSearch", query)
+		if err != nil {
+			return nil, err
+		}
+
+		found, err := decodeSearchResponse(body)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, found...)
+
+		if len(found) < searchPageSize {
+			return events, nil
+		}
+	}
+}
+
+// decodeSearchResponse decodes events found by restSearch
+func decodeSearchResponse(body []byte) ([]blacklistEntities.MISPEvent, error) {
+	events, err := blacklistEntities.ParseMISPEvents(body)
+	if err != nil {
+		// empty search result is returned as {"response": []} or []
+		var empty struct {
+			Response []json.RawMessage `json:"response"`
+		}
+
+		if string(bytes.TrimSpace(body)) == "[]" || (json.Unmarshal(body, &empty) == nil && len(empty.Response) == 0) {
+			return nil, nil
+		}
+
+		return nil, errors.New("failed to decode misp response: " + err.Error())
+	}
+
+	return events, nil
+}
+
+// AddEvent creates new event on MISP instance and returns it
+func (c *Client) AddEvent(event blacklistEntities.MISPEvent) (blacklistEntities.MISPEvent, error) {
+	body, err := c.request(http.MethodPost, "/events/add", struct {
+		Event blacklistEntities.MISPEvent `json:"Event"`
+	}{event})
+	if err != nil {
+		return blacklistEntities.MISPEvent{}, err
+	}
+
+	events, err := blacklistEntities.ParseMISPEvents(body)
+	if err != nil {
+		return blacklistEntities.MISPEvent{}, errors.New("failed to decode misp response: " + err.Error())
+	}
+
+	return events[0], nil
+}
+
+func (c *Client) request(method, path string, payload any) ([]byte, error) {
+	url, key, verifyTLS, err := c.dynamicConfig.GetMISPCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	bytes_, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest(method, strings.TrimSuffix(url, "/")+path, bytes.NewBuffer(bytes_))
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Authorization", key)
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("Content-Type", "application/json")
+
+	client := c.httpClient
+	if !verifyTLS {
+		client = c.insecureHTTPClient
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		var mispErr struct {
+			Message string `json:"message"`
+		}
+
+		if json.Unmarshal(body, &mispErr) == nil && len(mispErr.Message) > 0 {
+			return nil, fmt.Errorf("misp responded with status %s: %s", response.Status, mispErr.Message)
+		}
+
+		return nil, errors.New("misp responded with status: " + response.Status)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,180 @@
+package misp
+
+import (
+	"domain_threat_intelligence_api/cmd/core"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// syncImportEventType marks import events created by synchronization, latest of them is used to restore sync cursor
+const syncImportEventType = "misp-sync"
+
+// initialSyncPeriod limits first synchronization to events changed during this period
+const initialSyncPeriod = 30 * 24 * time.Hour
+
+// Synchronizer pulls events from MISP instance and imports their attributes into blacklists,
+// and pushes blacklisted hosts back to MISP as new events
+type Synchronizer struct {
+	client     *Client
+	blacklists core.IBlacklistsService
+
+	// cursor defines MISP timestamp of the latest synchronized event
+	cursor   time.Time
+	lastSync time.Time
+	mu       sync.Mutex
+}
+
+func NewSynchronizer(client *Client, blacklists core.IBlacklistsService) *Synchronizer {
+	s := Synchronizer{
+		client:     client,
+		blacklists: blacklists,
+	}
+
+	if !client.IsAvailable() {
+		slog.Warn("required config values not provided. misp integration not available.")
+	} else {
+		slog.Info("misp integration configured successfully.")
+	}
+
+	return &s
+}
+
+func (s *Synchronizer) IsAvailable() bool {
+	return s.client.IsAvailable()
+}
+
+// Sync pulls events changed since last synchronization and imports their IDS attributes
+func (s *Synchronizer) Sync() (blacklistEntities.BlacklistImportEvent, error) {
+	if !s.mu.TryLock() {
+		return blacklistEntities.BlacklistImportEvent{}, errors.New("misp synchronization is already running")
+	}
+	defer s.mu.Unlock()
+
+	_, sourceID, err := s.client.dynamicConfig.GetMISPSyncConfig()
+	if err != nil {
+		return blacklistEntities.BlacklistImportEvent{}, err
+	}
+
+	since, err := s.restoreCursor()
+	if err != nil {
+		return blacklistEntities.BlacklistImportEvent{}, err
+	}
+
+	slog.Info(fmt.Sprintf("synchronizing misp events changed since %s...", since.Format(time.RFC3339)))
+
+	events, err := s.client.SearchEvents(since)
+	if err != nil {
+		return blacklistEntities.BlacklistImportEvent{}, err
+	}
+
+	if len(events) == 0 {
+		s.lastSync = time.Now()
+		return blacklistEntities.BlacklistImportEvent{}, nil
+	}
+
+	event, err := s.blacklists.ImportFromMISP(events, false, sourceID)
+	if err != nil {
+		return blacklistEntities.BlacklistImportEvent{}, err
+	}
+
+	event.Type = syncImportEventType
+	event, err = s.blacklists.SaveImportEvent(event)
+	if err != nil {
+		return blacklistEntities.BlacklistImportEvent{}, err
+	}
+
+	// MISP timestamp filter is inclusive, so latest events are pulled again on next sync
+	for _, e := range events {
+		if t := e.EventTime(); t.After(since) {
+			since = t
+		}
+	}
+
+	s.cursor = since
+	s.lastSync = time.Now()
+
+	return event, nil
+}
+
+// PushHosts creates MISP event containing all hosts selected by filter
+func (s *Synchronizer) PushHosts(filter blacklistEntities.BlacklistSearchFilter) (blacklistEntities.MISPEvent, error) {
+	hosts, err := s.blacklists.RetrieveHostsByFilter(filter)
+	if err != nil {
+		return blacklistEntities.MISPEvent{}, err
+	}
+
+	event := blacklistEntities.MISPEvent{
+		Info:          fmt.Sprintf("Domain Threat Intelligence blacklist export %s", time.Now().Format(time.DateOnly)),
+		Date:          time.Now().Format(time.DateOnly),
+		Distribution:  "0",
+		ThreatLevelID: "4",
+		Analysis:      "2",
+	}
+
+	for _, h := range hosts {
+		attribute, err := blacklistEntities.NewMISPAttribute(h)
+		if err != nil {
+			slog.Warn("failed to push host to misp: " + err.Error())
+			continue
+		}
+
+		event.Attribute = append(event.Attribute, attribute)
+	}
+
+	if len(event.Attribute) == 0 {
+		return blacklistEntities.MISPEvent{}, errors.New("no hosts to push found")
+	}
+
+	return s.client.AddEvent(event)
+}
+
+// StartScheduler checks with defined interval if synchronization is due and runs it
+func (s *Synchronizer) StartScheduler(interval time.Duration) {
+	slog.Info("starting misp synchronization scheduler...")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		syncInterval, _, err := s.client.dynamicConfig.GetMISPSyncConfig()
+		if err != nil || !s.IsAvailable() {
+			continue
+		}
+
+		if s.lastSync.Add(time.Duration(syncInterval) * time.Minute).After(time.Now()) {
+			continue
+		}
+
+		_, err = s.Sync()
+		if err != nil {
+			slog.Warn("misp synchronization failed: " + err.Error())
+		}
+	}
+}
+
+// restoreCursor returns cursor of the latest synchronization. If application was restarted,
+// cursor is restored from the latest synchronization import event.
+func (s *Synchronizer) restoreCursor() (time.Time, error) {
+	if !s.cursor.IsZero() {
+		return s.cursor, nil
+	}
+
+	events, err := s.blacklists.RetrieveImportEventsByFilter(blacklistEntities.BlacklistImportEventFilter{
+		Limit: 1,
+		Type:  syncImportEventType,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if len(events) == 0 {
+		return time.Now().Add(-initialSyncPeriod), nil
+	}
+
+	// import event is created after events were pulled, so small overlap is used
+	return events[0].CreatedAt.Add(-10 * time.Minute), nil
+}
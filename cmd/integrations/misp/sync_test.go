@@ -0,0 +1,311 @@
+package misp
+
+import (
+	"domain_threat_intelligence_api/cmd/core"
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeConfig returns credentials of local MISP server
+type fakeConfig struct {
+	url string
+}
+
+func (c fakeConfig) IsMISPEnabled() bool { return true }
+
+func (c fakeConfig) GetMISPCredentials() (string, string, bool, error) {
+	return c.url, "key", true, nil
+}
+
+func (c fakeConfig) GetMISPSyncConfig() (uint64, uint64, error) { return 60, 7, nil }
+
+func (c fakeConfig) SetMISPConfig(enabled, verifyTLS bool, host, key string, interval, sourceID uint64) error {
+	return nil
+}
+
+// fakeBlacklists records imported events instead of saving hosts
+type fakeBlacklists struct {
+	core.IBlacklistsService
+
+	imported []blacklistEntities.MISPEvent
+	sourceID uint64
+	saved    []blacklistEntities.BlacklistImportEvent
+	hosts    []blacklistEntities.BlacklistedHost
+}
+
+func (f *fakeBlacklists) ImportFromMISP(events []blacklistEntities.MISPEvent, extractAll bool, sourceID uint64) (blacklistEntities.BlacklistImportEvent, error) {
+	f.imported = append(f.imported, events...)
+	f.sourceID = sourceID
+
+	return blacklistEntities.BlacklistImportEvent{ID: 1, Type: "misp"}, nil
+}
+
+func (f *fakeBlacklists) SaveImportEvent(event blacklistEntities.BlacklistImportEvent) (blacklistEntities.BlacklistImportEvent, error) {
+	f.saved = append(f.saved, event)
+	return event, nil
+}
+
+func (f *fakeBlacklists) RetrieveImportEventsByFilter(filter blacklistEntities.BlacklistImportEventFilter) ([]blacklistEntities.BlacklistImportEvent, error) {
+	return nil, nil
+}
+
+func (f *fakeBlacklists) RetrieveHostsByFilter(filter blacklistEntities.BlacklistSearchFilter) ([]blacklistEntities.BlacklistedHost, error) {
+	return f.hosts, nil
+}
+
+// searchRequest describes restSearch request received by fake MISP server
+type searchRequest struct {
+	Timestamp int64 `json:"timestamp"`
+	Limit     int   `json:"limit"`
+	Page      int   `json:"page"`
+}
+
+// newMISPServer starts MISP server returning defined number of events by restSearch pages,
+// events are timestamped by seconds following defined time
+func newMISPServer(t *testing.T, total int, since time.Time, requests *[]searchRequest) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "key" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message": "Authentication failed."}`))
+			return
+		}
+
+		if r.URL.Path != "/events/restSearch" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var request searchRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+
+		*requests = append(*requests, request)
+
+		var response struct {
+			Response []map[string]blacklistEntities.MISPEvent `json:"response"`
+		}
+		response.Response = []map[string]blacklistEntities.MISPEvent{}
+
+		for i := (request.Page - 1) * request.Limit; i < min(request.Page*request.Limit, total); i++ {
+			response.Response = append(response.Response, map[string]blacklistEntities.MISPEvent{"Event": {
+				ID:        strconv.Itoa(i + 1),
+				Info:      fmt.Sprintf("event %d", i+1),
+				Timestamp: json.Number(strconv.FormatInt(since.Unix()+int64(i)+1, 10)),
+				Attribute: []blacklistEntities.MISPAttribute{{Type: "ip-dst", Value: "10.0.0.1", ToIDs: true}},
+			}})
+		}
+
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+}
+
+func TestSearchEventsPaging(t *testing.T) {
+	tests := []struct {
+		name      string
+		total     int
+		wantPages int
+	}{
+		{name: "no events", total: 0, wantPages: 1},
+		{name: "single page", total: 3, wantPages: 1},
+		{name: "full page", total: searchPageSize, wantPages: 2},
+		{name: "several pages", total: 2*searchPageSize + 1, wantPages: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []searchRequest
+
+			server := newMISPServer(t, tt.total, time.Now(), &requests)
+			defer server.Close()
+
+			events, err := NewClient(fakeConfig{url: server.URL}).SearchEvents(time.Unix(1600000000, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(events) != tt.total {
+				t.Errorf("got %d events, want %d", len(events), tt.total)
+			}
+
+			if len(requests) != tt.wantPages {
+				t.Fatalf("got %d requests, want %d", len(requests), tt.wantPages)
+			}
+
+			for i, r := range requests {
+				if r.Page != i+1 || r.Limit != searchPageSize || r.Timestamp != 1600000000 {
+					t.Errorf("unexpected request #%d: %+v", i, r)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchEventsAuthFailed(t *testing.T) {
+	var requests []searchRequest
+
+	server := newMISPServer(t, 1, time.Now(), &requests)
+	defer server.Close()
+
+	client := NewClient(fakeConfig{url: server.URL})
+	client.dynamicConfig = wrongKeyConfig{fakeConfig{url: server.URL}}
+
+	_, err := client.SearchEvents(time.Now())
+	if err == nil || err.Error() != "misp responded with status 403 Forbidden: Authentication failed." {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// wrongKeyConfig returns credentials with invalid auth key
+type wrongKeyConfig struct {
+	fakeConfig
+}
+
+func (c wrongKeyConfig) GetMISPCredentials() (string, string, bool, error) {
+	return c.url, "wrong", true, nil
+}
+
+func TestSync(t *testing.T) {
+	var requests []searchRequest
+
+	now := time.Now()
+
+	server := newMISPServer(t, 3, now, &requests)
+	defer server.Close()
+
+	blacklists := &fakeBlacklists{}
+	s := NewSynchronizer(NewClient(fakeConfig{url: server.URL}), blacklists)
+
+	event, err := s.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(blacklists.imported) != 3 || blacklists.sourceID != 7 {
+		t.Errorf("expected 3 events imported with configured source, got %d with source %d", len(blacklists.imported), blacklists.sourceID)
+	}
+
+	if event.Type != syncImportEventType || len(blacklists.saved) != 1 {
+		t.Errorf("import event not saved as synchronization event: %+v", event)
+	}
+
+	// next synchronization starts from the latest event timestamp
+	_, err = s.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if last := requests[len(requests)-1]; last.Timestamp != now.Unix()+3 {
+		t.Errorf("cursor not moved to the latest event: %d", last.Timestamp)
+	}
+}
+
+func TestSyncWithoutEvents(t *testing.T) {
+	var requests []searchRequest
+
+	server := newMISPServer(t, 0, time.Now(), &requests)
+	defer server.Close()
+
+	blacklists := &fakeBlacklists{}
+	s := NewSynchronizer(NewClient(fakeConfig{url: server.URL}), blacklists)
+
+	event, err := s.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if event.ID != 0 || len(blacklists.imported) != 0 || len(blacklists.saved) != 0 {
+		t.Errorf("import event created without events: %+v", event)
+	}
+}
+
+func TestPushHosts(t *testing.T) {
+	tests := []struct {
+		name      string
+		hosts     []blacklistEntities.BlacklistedHost
+		wantTypes []string
+		wantErr   bool
+	}{
+		{
+			name: "all host types",
+			hosts: []blacklistEntities.BlacklistedHost{
+				{Host: "10.0.0.1", Type: "ip"},
+				{Host: "evil.com", Type: "domain"},
+				{Host: "https://evil.com/", Type: "url"},
+				{Host: "user@evil.com", Type: "email"},
+			},
+			wantTypes: []string{"ip-dst", "domain", "url", "email-src"},
+		},
+		{
+			name:      "unsupported hosts skipped",
+			hosts:     []blacklistEntities.BlacklistedHost{{Host: "10.0.0.1", Type: "ip"}, {Host: "file", Type: "hash"}},
+			wantTypes: []string{"ip-dst"},
+		},
+		{
+			name:    "no hosts",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pushed *blacklistEntities.MISPEvent
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/events/add" || r.Header.Get("Authorization") != "key" {
+					t.Errorf("unexpected request to %s", r.URL.Path)
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				var request struct {
+					Event blacklistEntities.MISPEvent `json:"Event"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+					t.Error(err)
+				}
+
+				pushed = &request.Event
+				request.Event.ID = "42"
+
+				_ = json.NewEncoder(w).Encode(request)
+			}))
+			defer server.Close()
+
+			s := NewSynchronizer(NewClient(fakeConfig{url: server.URL}), &fakeBlacklists{hosts: tt.hosts})
+
+			event, err := s.PushHosts(blacklistEntities.BlacklistSearchFilter{})
+			if tt.wantErr {
+				if err == nil || pushed != nil {
+					t.Error("expected error without pushing event")
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if event.ID != "42" {
+				t.Errorf("created event not returned: %+v", event)
+			}
+
+			if pushed == nil || len(pushed.Attribute) != len(tt.wantTypes) {
+				t.Fatalf("got pushed event %+v, want %d attributes", pushed, len(tt.wantTypes))
+			}
+
+			for i, a := range pushed.Attribute {
+				if a.Type != tt.wantTypes[i] || !bool(a.ToIDs) {
+					t.Errorf("unexpected attribute #%d: %+v", i, a)
+				}
+			}
+		})
+	}
+}
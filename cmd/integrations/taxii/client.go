@@ -0,0 +1,194 @@
+package taxii
+
+import (
+	"domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// MediaType defines TAXII 2.1 content type, used in Accept and Content-Type headers
+const MediaType = "application/taxii+json;version=2.1"
+
+// Client is TAXII 2.1 client, used to discover API roots, collections and pull STIX objects from them.
+// reference: https://docs.oasis-open.org/cti/taxii/v2.1/taxii-v2.1.html
+type Client struct {
+	httpClient *http.Client
+
+	login    string
+	password string
+	token    string
+}
+
+// NewClient creates TAXII client. If login defined, basic auth is used, if token defined, bearer auth is used.
+func NewClient(httpClient *http.Client, login, password, token string) *Client {
+	return &Client{
+		httpClient: httpClient,
+		login:      login,
+		password:   password,
+		token:      token,
+	}
+}
+
+// Discover requests TAXII server discovery resource and returns absolute URLs of all API roots
+func (c *Client) Discover(discoveryURL string) (Discovery, error) {
+	var discovery Discovery
+
+	_, err := c.get(discoveryURL, &discovery)
+	if err != nil {
+		return Discovery{}, err
+	}
+
+	for i, root := range discovery.APIRoots {
+		discovery.APIRoots[i], err = resolveURL(discoveryURL, root)
+		if err != nil {
+			return Discovery{}, err
+		}
+	}
+
+	return discovery, nil
+}
+
+// Collections returns all collections from defined API root
+func (c *Client) Collections(apiRootURL string) ([]Collection, error) {
+	var collections struct {
+		Collections []Collection `json:"collections"`
+	}
+
+	_, err := c.get(strings.TrimSuffix(apiRootURL, "/")+"/collections/", &collections)
+	if err != nil {
+		return nil, err
+	}
+
+	return collections.Collections, nil
+}
+
+// Objects returns single page of objects from collection. If next is defined, returns page following previous request.
+// Returns value of X-TAXII-Date-Added-Last header, which should be used as added_after bookmark.
+func (c *Client) Objects(apiRootURL, collectionID, addedAfter, next string, limit int) (Envelope, string, error) {
+	query := url.Values{}
+
+	if len(addedAfter) > 0 {
+		query.Set("added_after", addedAfter)
+	}
+
+	if len(next) > 0 {
+		query.Set("next", next)
+	}
+
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	objectsURL := fmt.Sprintf("%s/collections/%s/objects/", strings.TrimSuffix(apiRootURL, "/"), url.PathEscape(collectionID))
+	if len(query) > 0 {
+		objectsURL += "?" + query.Encode()
+	}
+
+	var envelope Envelope
+
+	header, err := c.get(objectsURL, &envelope)
+	if err != nil {
+		return Envelope{}, "", err
+	}
+
+	return envelope, header.Get("X-TAXII-Date-Added-Last"), nil
+}
+
+// AllObjects pages through all objects in collection added after bookmark.
+// Returns all objects and new bookmark, which is equal to previous one if no objects found.
+func (c *Client) AllObjects(apiRootURL, collectionID, addedAfter string, limit int) ([]blacklistEntities.STIX2Object, string, error) {
+	var objects []blacklistEntities.STIX2Object
+	var next string
+
+	bookmark := addedAfter
+
+	for {
+		envelope, addedLast, err := c.Objects(apiRootURL, collectionID, addedAfter, next, limit)
+		if err != nil {
+			return nil, "", err
+		}
+
+		objects = append(objects, envelope.Objects...)
+
+		if len(addedLast) > 0 {
+			bookmark = addedLast
+		}
+
+		// server can not support "next" parameter, so added_after is moved instead
+		if !envelope.More || len(envelope.Objects) == 0 {
+			break
+		} else if len(envelope.Next) > 0 {
+			next = envelope.Next
+		} else if len(addedLast) > 0 && addedLast != addedAfter {
+			addedAfter = addedLast
+		} else {
+			break
+		}
+	}
+
+	return objects, bookmark, nil
+}
+
+func (c *Client) get(url_ string, v any) (http.Header, error) {
+	request, err := http.NewRequest(http.MethodGet, url_, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Accept", MediaType)
+
+	if len(c.login) > 0 {
+		request.SetBasicAuth(c.login, c.password)
+	} else if len(c.token) > 0 {
+		request.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		var taxiiErr Error
+
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 1<<16))
+		if json.Unmarshal(body, &taxiiErr) == nil && len(taxiiErr.Title) > 0 {
+			return nil, fmt.Errorf("taxii server responded with status %s: %s", response.Status, taxiiErr.Title)
+		}
+
+		return nil, errors.New("taxii server responded with status: " + response.Status)
+	}
+
+	err = json.NewDecoder(response.Body).Decode(v)
+	if err != nil {
+		return nil, errors.New("failed to decode taxii response: " + err.Error())
+	}
+
+	return response.Header, nil
+}
+
+func resolveURL(base, ref string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	r, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+
+	resolved := b.ResolveReference(r).String()
+	if !strings.HasSuffix(resolved, "/") {
+		resolved += "/"
+	}
+
+	return resolved, nil
+}
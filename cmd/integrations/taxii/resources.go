@@ -0,0 +1,47 @@
+package taxii
+
+import "domain_threat_intelligence_api/cmd/core/entities/blacklistEntities"
+
+// Discovery describes TAXII server and lists its API roots
+type Discovery struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Contact     string   `json:"contact,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	APIRoots    []string `json:"api_roots,omitempty"`
+}
+
+// APIRoot describes single group of collections
+type APIRoot struct {
+	Title            string   `json:"title"`
+	Description      string   `json:"description,omitempty"`
+	Versions         []string `json:"versions"`
+	MaxContentLength int64    `json:"max_content_length"`
+}
+
+// Collection describes single set of STIX objects
+type Collection struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Alias       string   `json:"alias,omitempty"`
+	CanRead     bool     `json:"can_read"`
+	CanWrite    bool     `json:"can_write"`
+	MediaTypes  []string `json:"media_types,omitempty"`
+}
+
+// Envelope contains single page of STIX objects from collection
+type Envelope struct {
+	More    bool                            `json:"more"`
+	Next    string                          `json:"next,omitempty"`
+	Objects []blacklistEntities.STIX2Object `json:"objects,omitempty"`
+}
+
+// Error is returned by TAXII server on failed requests
+type Error struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	ErrorID     string `json:"error_id,omitempty"`
+	ErrorCode   string `json:"error_code,omitempty"`
+	HTTPStatus  string `json:"http_status,omitempty"`
+}
@@ -0,0 +1,360 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPartSize limits size of single unpacked workbook part, so zip bombs are not read into memory
+const maxPartSize = 256 << 20
+
+// ReadSheet reads all rows of workbook sheet as strings. If sheet name not defined, first sheet is read.
+// Date cells are formatted with dateLayout, so they can be parsed same as CSV values.
+func ReadSheet(data []byte, sheet string, dateLayout string) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.New("xlsx file malformed: " + err.Error())
+	}
+
+	r := &reader{files: make(map[string]*zip.File, len(archive.File))}
+	for _, f := range archive.File {
+		r.files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+
+	sheetPath, date1904, err := r.sheetPath(sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.readSharedStrings()
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.readStyles()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.readRows(sheetPath, date1904, dateLayout)
+}
+
+type reader struct {
+	files map[string]*zip.File
+
+	sharedStrings []string
+	// dateStyles contains indexes of cell styles with date number format
+	dateStyles map[int]bool
+}
+
+// decode unmarshalls workbook part, missing optional part is not an error
+func (r *reader) decode(name string, v any, optional bool) (bool, error) {
+	f, ok := r.files[name]
+	if !ok {
+		if optional {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("xlsx part '%s' not found", name)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return false, err
+	}
+	defer rc.Close()
+
+	err = xml.NewDecoder(io.LimitReader(rc, maxPartSize)).Decode(v)
+	if err != nil {
+		return false, fmt.Errorf("xlsx part '%s' malformed: %s", name, err.Error())
+	}
+
+	return true, nil
+}
+
+type xmlWorkbook struct {
+	WorkbookPr struct {
+		Date1904 string `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xmlRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// sheetPath finds archive path of sheet by its name, or of the first sheet if name not defined
+func (r *reader) sheetPath(name string) (string, bool, error) {
+	var workbook xmlWorkbook
+	if _, err := r.decode("xl/workbook.xml", &workbook, false); err != nil {
+		return "", false, err
+	}
+
+	if len(workbook.Sheets) == 0 {
+		return "", false, errors.New("xlsx file does not contain any sheets")
+	}
+
+	date1904 := workbook.WorkbookPr.Date1904 == "1" || workbook.WorkbookPr.Date1904 == "true"
+
+	rID := workbook.Sheets[0].RID
+	if len(name) > 0 {
+		rID = ""
+		for _, s := range workbook.Sheets {
+			if strings.EqualFold(s.Name, name) {
+				rID = s.RID
+				break
+			}
+		}
+
+		if len(rID) == 0 {
+			return "", false, fmt.Errorf("sheet '%s' not found", name)
+		}
+	}
+
+	var rels xmlRelationships
+	if _, err := r.decode("xl/_rels/workbook.xml.rels", &rels, false); err != nil {
+		return "", false, err
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.ID != rID {
+			continue
+		}
+
+		// targets are relative to xl directory, or absolute inside archive
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), date1904, nil
+		}
+
+		return path.Join("xl", rel.Target), date1904, nil
+	}
+
+	return "", false, errors.New("sheet relationship not found")
+}
+
+type xmlRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xmlRichText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+
+	return b.String()
+}
+
+func (r *reader) readSharedStrings() error {
+	var sst struct {
+		Items []xmlRichText `xml:"si"`
+	}
+
+	if _, err := r.decode("xl/sharedStrings.xml", &sst, true); err != nil {
+		return err
+	}
+
+	r.sharedStrings = make([]string, len(sst.Items))
+	for i, item := range sst.Items {
+		r.sharedStrings[i] = item.String()
+	}
+
+	return nil
+}
+
+func (r *reader) readStyles() error {
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+
+	if _, err := r.decode("xl/styles.xml", &styles, true); err != nil {
+		return err
+	}
+
+	customDates := make(map[int]bool)
+	for _, f := range styles.NumFmts {
+		customDates[f.ID] = isDateFormatCode(f.Code)
+	}
+
+	r.dateStyles = make(map[int]bool)
+	for i, xf := range styles.CellXfs {
+		id := xf.NumFmtID
+
+		// built-in date and time formats
+		if (id >= 14 && id <= 22) || (id >= 45 && id <= 47) || customDates[id] {
+			r.dateStyles[i] = true
+		}
+	}
+
+	return nil
+}
+
+// isDateFormatCode checks if custom number format displays date, quoted literals and colors are ignored
+func isDateFormatCode(code string) bool {
+	var quoted, bracket bool
+
+	for _, c := range strings.ToLower(code) {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '[':
+			bracket = true
+		case c == ']':
+			bracket = false
+		case bracket:
+		case c == 'd' || c == 'm' || c == 'y' || c == 'h' || c == 's':
+			return true
+		}
+	}
+
+	return false
+}
+
+type xmlCell struct {
+	Ref    string      `xml:"r,attr"`
+	Type   string      `xml:"t,attr"`
+	Style  int         `xml:"s,attr"`
+	Value  string      `xml:"v"`
+	Inline xmlRichText `xml:"is"`
+}
+
+func (r *reader) readRows(sheetPath string, date1904 bool, dateLayout string) ([][]string, error) {
+	var sheet struct {
+		Rows []struct {
+			Index int       `xml:"r,attr"`
+			Cells []xmlCell `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+
+	if _, err := r.decode(sheetPath, &sheet, false); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+
+	for _, row := range sheet.Rows {
+		// empty rows are not stored, so row index is used to keep row numbers same as in spreadsheet
+		for row.Index > len(rows)+1 {
+			rows = append(rows, []string{})
+		}
+
+		var values []string
+
+		for _, cell := range row.Cells {
+			column := len(values)
+			if len(cell.Ref) > 0 {
+				column = columnIndex(cell.Ref)
+			}
+
+			for len(values) < column {
+				values = append(values, "")
+			}
+
+			values = append(values, r.cellValue(cell, date1904, dateLayout))
+		}
+
+		rows = append(rows, values)
+	}
+
+	return rows, nil
+}
+
+func (r *reader) cellValue(cell xmlCell, date1904 bool, dateLayout string) string {
+	switch cell.Type {
+	case "s":
+		i, err := strconv.Atoi(cell.Value)
+		if err != nil || i < 0 || i >= len(r.sharedStrings) {
+			return ""
+		}
+
+		return r.sharedStrings[i]
+	case "inlineStr":
+		return cell.Inline.String()
+	case "str", "e":
+		return cell.Value
+	case "b":
+		if cell.Value == "1" {
+			return "true"
+		}
+
+		return "false"
+	}
+
+	if len(dateLayout) > 0 && r.dateStyles[cell.Style] {
+		serial, err := strconv.ParseFloat(cell.Value, 64)
+		if err == nil {
+			return FromSerial(serial, date1904).Format(dateLayout)
+		}
+	}
+
+	return cell.Value
+}
+
+// columnIndex converts cell reference, like AB12, to zero-based column index
+func columnIndex(ref string) int {
+	index := 0
+
+	for _, c := range strings.ToUpper(ref) {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+
+		index = index*26 + int(c-'A'+1)
+	}
+
+	return index - 1
+}
+
+var (
+	epoch1900 = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	epoch1904 = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// FromSerial converts spreadsheet date serial number to time
+func FromSerial(serial float64, date1904 bool) time.Time {
+	epoch := epoch1900
+	if date1904 {
+		epoch = epoch1904
+	}
+
+	days := math.Floor(serial)
+	seconds := math.Round((serial - days) * 24 * 60 * 60)
+
+	return epoch.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+}
+
+// ToSerial converts time to spreadsheet date serial number, 1900 date system is used
+func ToSerial(t time.Time) float64 {
+	t = t.UTC()
+
+	return float64(t.Sub(epoch1900)) / float64(24*time.Hour)
+}
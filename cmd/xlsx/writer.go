@@ -0,0 +1,248 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sheet defines single workbook sheet. Row cells can be strings, numbers or time values, zero time is written as empty cell.
+type Sheet struct {
+	Name   string
+	Header []string
+	Rows   [][]any
+}
+
+// cell styles defined in styles part
+const (
+	styleDefault = 0
+	styleDate    = 1
+	styleHeader  = 2
+)
+
+const (
+	contentTypesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+%s</Types>`
+	contentTypeSheet = `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+`
+
+	rootRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+	workbookXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets>
+%s</sheets>
+</workbook>`
+	workbookSheet = `<sheet name="%s" sheetId="%d" r:id="rId%d"/>
+`
+
+	workbookRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+%s<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`
+	workbookRelsSheet = `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>
+`
+
+	stylesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy\-mm\-dd\ hh:mm:ss"/></numFmts>
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="3">
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>
+<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>
+</cellXfs>
+</styleSheet>`
+)
+
+// maxSheetNameLength is limited by spreadsheet applications
+const maxSheetNameLength = 31
+
+// Write creates workbook with defined sheets. Header row is frozen and written in bold, time values are written as date cells.
+func Write(sheets []Sheet) ([]byte, error) {
+	var buf bytes.Buffer
+
+	archive := zip.NewWriter(&buf)
+
+	var contentTypes, workbookSheets, workbookRels strings.Builder
+	for i, s := range sheets {
+		n := i + 1
+
+		contentTypes.WriteString(fmt.Sprintf(contentTypeSheet, n))
+		workbookSheets.WriteString(fmt.Sprintf(workbookSheet, escape(sheetName(s.Name, n)), n, n))
+		workbookRels.WriteString(fmt.Sprintf(workbookRelsSheet, n, n))
+	}
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", fmt.Sprintf(contentTypesXML, contentTypes.String())},
+		{"_rels/.rels", rootRelsXML},
+		{"xl/workbook.xml", fmt.Sprintf(workbookXML, workbookSheets.String())},
+		{"xl/_rels/workbook.xml.rels", fmt.Sprintf(workbookRelsXML, workbookRels.String(), len(sheets)+1)},
+		{"xl/styles.xml", stylesXML},
+	}
+
+	for _, p := range parts {
+		w, err := archive.Create(p.name)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = io.WriteString(w, p.content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i, s := range sheets {
+		w, err := archive.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1))
+		if err != nil {
+			return nil, err
+		}
+
+		err = writeSheet(w, s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := archive.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeSheet(w io.Writer, s Sheet) error {
+	var b strings.Builder
+
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+
+	if len(s.Header) > 0 {
+		b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+
+	b.WriteString(`<sheetData>`)
+
+	rowIndex := 0
+	writeRow := func(cells []any, style int) {
+		rowIndex++
+
+		b.WriteString(`<row r="` + strconv.Itoa(rowIndex) + `">`)
+		for i, c := range cells {
+			writeCell(&b, cellRef(i, rowIndex), c, style)
+		}
+		b.WriteString(`</row>`)
+	}
+
+	if len(s.Header) > 0 {
+		header := make([]any, len(s.Header))
+		for i, h := range s.Header {
+			header[i] = h
+		}
+
+		writeRow(header, styleHeader)
+	}
+
+	for _, row := range s.Rows {
+		writeRow(row, styleDefault)
+	}
+
+	b.WriteString(`</sheetData></worksheet>`)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeCell(b *strings.Builder, ref string, value any, style int) {
+	switch v := value.(type) {
+	case nil:
+		return
+	case time.Time:
+		if v.IsZero() {
+			return
+		}
+
+		b.WriteString(fmt.Sprintf(`<c r="%s" s="%d"><v>%s</v></c>`, ref, styleDate, strconv.FormatFloat(ToSerial(v), 'f', -1, 64)))
+	case int, int64, uint64, float64:
+		b.WriteString(fmt.Sprintf(`<c r="%s" s="%d"><v>%v</v></c>`, ref, style, v))
+	case bool:
+		var n = 0
+		if v {
+			n = 1
+		}
+
+		b.WriteString(fmt.Sprintf(`<c r="%s" s="%d" t="b"><v>%d</v></c>`, ref, style, n))
+	default:
+		b.WriteString(fmt.Sprintf(`<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escape(fmt.Sprint(v))))
+	}
+}
+
+// cellRef converts zero-based column index and row number to cell reference, like AB12
+func cellRef(column int, row int) string {
+	var letters []byte
+
+	for column++; column > 0; column = (column - 1) / 26 {
+		letters = append([]byte{byte('A' + (column-1)%26)}, letters...)
+	}
+
+	return string(letters) + strconv.Itoa(row)
+}
+
+// sheetName removes characters not allowed in sheet names and limits name length
+func sheetName(name string, n int) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+
+		return r
+	}, name)
+
+	if len([]rune(name)) > maxSheetNameLength {
+		name = string([]rune(name)[:maxSheetNameLength])
+	}
+
+	if len(strings.TrimSpace(name)) == 0 {
+		return "Sheet" + strconv.Itoa(n)
+	}
+
+	return name
+}
+
+func escape(s string) string {
+	var b strings.Builder
+
+	// invalid XML characters are dropped, so file can be opened by spreadsheet applications
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			return -1
+		}
+
+		return r
+	}, s)
+
+	_ = xml.EscapeText(&b, []byte(s))
+
+	return b.String()
+}
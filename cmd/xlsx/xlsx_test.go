@@ -0,0 +1,226 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteAndReadSheet(t *testing.T) {
+	seen := time.Date(2024, 2, 1, 10, 30, 0, 0, time.UTC)
+
+	data, err := Write([]Sheet{
+		{
+			Name:   "Hosts",
+			Header: []string{"Type_IOC", "Value", "First Seen", "Score", "Active"},
+			Rows: [][]any{
+				{"domain", "evil.com", seen, 42, true},
+				{"url", "http://evil.com/?a=1&b=<2>", time.Time{}, 0.5, false},
+				{"email", nil, nil, uint64(7)},
+			},
+		},
+		{Name: "Bad:name/[1]", Header: []string{"Value"}, Rows: [][]any{{"10.0.0.1"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		sheet      string
+		dateLayout string
+		want       [][]string
+		wantErr    bool
+	}{
+		{
+			name:       "first sheet with dates",
+			dateLayout: "02.01.2006 15:04",
+			want: [][]string{
+				{"Type_IOC", "Value", "First Seen", "Score", "Active"},
+				{"domain", "evil.com", "01.02.2024 10:30", "42", "true"},
+				{"url", "http://evil.com/?a=1&b=<2>", "", "0.5", "false"},
+				{"email", "", "", "7"},
+			},
+		},
+		{
+			name:  "dates as serial numbers",
+			sheet: "hosts",
+			want: [][]string{
+				{"Type_IOC", "Value", "First Seen", "Score", "Active"},
+				{"domain", "evil.com", "45323.4375", "42", "true"},
+				{"url", "http://evil.com/?a=1&b=<2>", "", "0.5", "false"},
+				{"email", "", "", "7"},
+			},
+		},
+		{
+			name:  "sheet with replaced name",
+			sheet: "Bad_name__1_",
+			want:  [][]string{{"Value"}, {"10.0.0.1"}},
+		},
+		{
+			name:    "missing sheet",
+			sheet:   "Other",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadSheet(data, tt.sheet, tt.dateLayout)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// zipParts packs workbook parts into archive
+func zipParts(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	archive := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = w.Write([]byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestReadSheetSpreadsheetFeatures(t *testing.T) {
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<workbookPr date1904="1"/><sheets><sheet name="Data" sheetId="1" r:id="rId3"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId3" Target="/xl/worksheets/data.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>domain</t></si><si><r><t>evil</t></r><r><t>.com</t></r></si></sst>`,
+		"xl/styles.xml": `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts><numFmt numFmtId="165" formatCode="dd/mm/yyyy"/><numFmt numFmtId="166" formatCode="&quot;day&quot; 0"/></numFmts>
+<cellXfs><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="165"/><xf numFmtId="166"/></cellXfs></styleSheet>`,
+		"xl/worksheets/data.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>
+<row r="3"><c r="B3" s="1"><v>0</v></c><c r="C3" s="2"><v>1</v></c><c r="D3" s="3"><v>5</v></c><c r="E3" t="str"><v>formula</v></c><c r="F3" t="s"><v>9</v></c></row>
+<row r="4"><c r="AA4" t="inlineStr"><is><t>inline</t></is></c></row>
+</sheetData></worksheet>`,
+	}
+
+	got, err := ReadSheet(zipParts(t, parts), "", time.DateOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// empty rows and cells are kept, so values stay in the same rows and columns
+	inline := make([]string, 27)
+	inline[26] = "inline"
+
+	want := [][]string{
+		{"domain", "", "evil.com"},
+		{},
+		{"", "1904-01-01", "1904-01-02", "5", "formula", ""},
+		inline,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestReadSheetMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "not archive", data: []byte("Type_IOC,Value")},
+		{name: "workbook missing", data: zipParts(t, map[string]string{"xl/styles.xml": "<styleSheet/>"})},
+		{name: "no sheets", data: zipParts(t, map[string]string{"xl/workbook.xml": "<workbook><sheets/></workbook>"})},
+		{name: "sheet part malformed", data: zipParts(t, map[string]string{
+			"xl/workbook.xml":            `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="a" r:id="rId1"/></sheets></workbook>`,
+			"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+			"xl/worksheets/sheet1.xml":   `<worksheet><sheetData><row>`,
+		})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadSheet(tt.data, "", ""); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestCellRef(t *testing.T) {
+	tests := []struct {
+		column int
+		row    int
+		want   string
+	}{
+		{column: 0, row: 1, want: "A1"},
+		{column: 25, row: 2, want: "Z2"},
+		{column: 26, row: 3, want: "AA3"},
+		{column: 701, row: 4, want: "ZZ4"},
+		{column: 702, row: 5, want: "AAA5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := cellRef(tt.column, tt.row); got != tt.want {
+				t.Errorf("cellRef(%d, %d) = %s, want %s", tt.column, tt.row, got, tt.want)
+			}
+
+			if got := columnIndex(tt.want); got != tt.column {
+				t.Errorf("columnIndex(%s) = %d, want %d", tt.want, got, tt.column)
+			}
+		})
+	}
+}
+
+func TestSerialDates(t *testing.T) {
+	tests := []struct {
+		name     string
+		serial   float64
+		date1904 bool
+		want     time.Time
+	}{
+		{name: "1900 date system", serial: 45323.4375, want: time.Date(2024, 2, 1, 10, 30, 0, 0, time.UTC)},
+		{name: "1904 date system", serial: 43861.4375, date1904: true, want: time.Date(2024, 2, 1, 10, 30, 0, 0, time.UTC)},
+		{name: "epoch", serial: 0, want: time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromSerial(tt.serial, tt.date1904); !got.Equal(tt.want) {
+				t.Errorf("FromSerial(%v) = %s, want %s", tt.serial, got, tt.want)
+			}
+
+			if !tt.date1904 && ToSerial(tt.want) != tt.serial {
+				t.Errorf("ToSerial(%s) = %v, want %v", tt.want, ToSerial(tt.want), tt.serial)
+			}
+		})
+	}
+}
@@ -37,6 +37,20 @@ type smtpConfig struct {
 
 type integrationsConfig struct {
 	Naumen naumenConfig `env-required:"false" json:"Naumen"`
+	MISP   mispConfig   `env-required:"false" json:"MISP"`
+}
+
+type mispConfig struct {
+	Enabled bool `env-default:"false" json:"Enabled"`
+
+	Url       string `json:"URL"`
+	Key       string `json:"Key"`
+	VerifyTLS bool   `json:"VerifyTLS"`
+
+	// SyncInterval defines delay between synchronizations in minutes
+	SyncInterval uint64 `json:"SyncInterval"`
+	// SourceID defines source all synchronized hosts are attributed to
+	SourceID uint64 `json:"SourceID"`
 }
 
 type naumenConfig struct {
@@ -267,6 +281,65 @@ func (d *DynamicConfigProvider) GetBlacklistServiceConfig() (aID, slm uint64, ca
 	return s.AgreementID, s.Slm, s.CallType, s.Types, nil
 }
 
+func (d *DynamicConfigProvider) IsMISPEnabled() bool {
+	return d.config.Integrations.MISP.Enabled
+}
+
+func (d *DynamicConfigProvider) GetMISPCredentials() (url string, key string, verifyTLS bool, err error) {
+	if !d.IsMISPEnabled() {
+		return "", "", false, errors.New("misp integration disabled")
+	}
+
+	m := d.config.Integrations.MISP
+
+	if len(m.Url) == 0 || len(m.Key) == 0 {
+		return "", "", false, errors.New("misp configuration incomplete")
+	}
+
+	return m.Url, m.Key, m.VerifyTLS, nil
+}
+
+func (d *DynamicConfigProvider) GetMISPSyncConfig() (interval, sourceID uint64, err error) {
+	if !d.IsMISPEnabled() {
+		return 0, 0, errors.New("misp integration disabled")
+	}
+
+	m := d.config.Integrations.MISP
+
+	if m.SyncInterval == 0 || m.SourceID == 0 {
+		return 0, 0, errors.New("misp sync configuration incomplete")
+	}
+
+	return m.SyncInterval, m.SourceID, nil
+}
+
+func (d *DynamicConfigProvider) SetMISPConfig(enabled, verifyTLS bool, host, key string, interval, sourceID uint64) (err error) {
+	if len(key) == 0 || len(host) == 0 || interval == 0 || sourceID == 0 {
+		return errors.New("misp configuration incomplete")
+	}
+
+	_, err = url.Parse(host)
+	if err != nil {
+		return errors.New("host malformed: " + err.Error())
+	}
+
+	d.config.Integrations.MISP.Enabled = enabled
+	d.config.Integrations.MISP.VerifyTLS = verifyTLS
+
+	d.config.Integrations.MISP.Url = host
+	d.config.Integrations.MISP.Key = key
+
+	d.config.Integrations.MISP.SyncInterval = interval
+	d.config.Integrations.MISP.SourceID = sourceID
+
+	err = d.WriteToFile()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (d *DynamicConfigProvider) IsSMTPEnabled() bool {
 	return d.config.SMTP.Enabled
 }